@@ -70,6 +70,7 @@ func (e *NumberExpr) BinTo(bins *binstmt.BinStmts, reg int, lid *int, inStmt boo
 type StringExpr struct {
 	ExprImpl
 	Lit string
+	Src string // исходная запись литерала в кавычках или обратных апострофах, сохраняется при форматировании
 }
 
 func (x *StringExpr) Simplify() Expr {
@@ -124,8 +125,9 @@ func (e *ArrayExpr) BinTo(bins *binstmt.BinStmts, reg int, lid *int, inStmt bool
 // PairExpr provide one of Map key/value pair.
 type PairExpr struct {
 	ExprImpl
-	Key   string
-	Value Expr
+	Key    string
+	KeySrc string // исходная запись ключа вместе с кавычками
+	Value  Expr
 }
 
 func (x *PairExpr) Simplify() Expr {
@@ -139,6 +141,21 @@ func (e *PairExpr) BinTo(bins *binstmt.BinStmts, reg int, lid *int, inStmt bool,
 type MapExpr struct {
 	ExprImpl
 	MapExpr map[string]Expr
+	Keys    []string // ключи в порядке следования в исходном тексте
+	KeySrc  []string // исходная запись ключей в порядке Keys
+}
+
+// PairKeySrc возвращает исходную запись ключей пар в порядке первого появления каждого ключа
+func PairKeySrc(pairs []Expr) []string {
+	seen := make(map[string]bool, len(pairs))
+	src := make([]string, 0, len(pairs))
+	for _, v := range pairs {
+		if p := v.(*PairExpr); !seen[p.Key] {
+			seen[p.Key] = true
+			src = append(src, p.KeySrc)
+		}
+	}
+	return src
 }
 
 func (x *MapExpr) Simplify() Expr {
@@ -487,6 +504,11 @@ func (x *AnonCallExpr) Simplify() Expr {
 }
 
 func (e *AnonCallExpr) BinTo(bins *binstmt.BinStmts, reg int, lid *int, inStmt bool, maxreg *int) {
+	// Новый Тип(параметры) - создание значения с параметрами конструктора
+	if me, ok := e.Expr.(*MakeExpr); ok && !e.VarArg && !e.Go {
+		me.binMakeTo(bins, reg, lid, maxreg, e.SubExprs)
+		return
+	}
 	// помещаем в регистр значение функции (тип func, или ссылку на него, или интерфейс с ним)
 	e.Expr.BinTo(bins, reg, lid, false, maxreg)
 	// далее аргументы, как при вызове обычной функции
@@ -639,6 +661,7 @@ func (e *SliceExpr) BinTo(bins *binstmt.BinStmts, reg int, lid *int, inStmt bool
 // FuncExpr provide function expression.
 type FuncExpr struct {
 	ExprImpl
+	BlockEnd
 	Name   int //string
 	Stmts  Stmts
 	Args   []int //string
@@ -881,13 +904,25 @@ func (x *MakeExpr) Simplify() Expr {
 }
 
 func (e *MakeExpr) BinTo(bins *binstmt.BinStmts, reg int, lid *int, inStmt bool, maxreg *int) {
+	e.binMakeTo(bins, reg, lid, maxreg, nil)
+}
+
+// binMakeTo создает значение типа с параметрами конструктора args, которые помещаются в регистры после reg
+func (e *MakeExpr) binMakeTo(bins *binstmt.BinStmts, reg int, lid *int, maxreg *int, args []Expr) {
 	if e.TypeExpr == nil {
 		bins.Append(binstmt.NewBinLOAD(reg, core.VMInt(e.Type), true, e))
 	} else {
 		e.TypeExpr.BinTo(bins, reg, lid, false, maxreg)
 		bins.Append(binstmt.NewBinSETNAME(reg, e))
 	}
-	bins.Append(binstmt.NewBinMAKE(reg, e))
+	for i, ee := range args {
+		ri := reg + 1 + i
+		ee.BinTo(bins, ri, lid, false, maxreg)
+		if ri > *maxreg {
+			*maxreg = ri
+		}
+	}
+	bins.Append(binstmt.NewBinMAKE(reg, len(args), e))
 	if reg > *maxreg {
 		*maxreg = reg
 	}
@@ -48,6 +48,12 @@ type StmtImpl struct {
 // stmt provide restraint interface.
 func (x *StmtImpl) stmt() {}
 
+// BlockEnd хранит позицию завершающего блок ключевого слова (КонецЕсли, КонецЦикла и т.п.),
+// используется при форматировании исходного текста
+type BlockEnd struct {
+	End pos.Position
+}
+
 type Stmts []Stmt
 
 func (x Stmts) BinTo(bins *binstmt.BinStmts, reg int, lid *int, maxreg *int) {
@@ -94,10 +100,12 @@ func (s *ExprStmt) BinTo(bins *binstmt.BinStmts, reg int, lid *int, maxreg *int)
 // IfStmt provide "if/else" statement.
 type IfStmt struct {
 	StmtImpl
-	If     Expr
-	Then   Stmts
-	ElseIf Stmts // This is array of IfStmt
-	Else   Stmts
+	BlockEnd
+	If      Expr
+	Then    Stmts
+	ElseIf  Stmts // This is array of IfStmt
+	Else    Stmts
+	ElsePos pos.Position // позиция ключевого слова Иначе
 }
 
 func (x *IfStmt) Simplify() {
@@ -169,7 +177,9 @@ func (s *IfStmt) BinTo(bins *binstmt.BinStmts, reg int, lid *int, maxreg *int) {
 // TryStmt provide "try/catch/finally" statement.
 type TryStmt struct {
 	StmtImpl
-	Try Stmts
+	BlockEnd
+	Try      Stmts
+	CatchPos pos.Position // позиция ключевого слова Исключение
 	// Var     string
 	Catch Stmts
 	// Finally Stmts
@@ -221,6 +231,7 @@ func (s *TryStmt) BinTo(bins *binstmt.BinStmts, reg int, lid *int, maxreg *int)
 // ForStmt provide "for in" expression statement.
 type ForStmt struct {
 	StmtImpl
+	BlockEnd
 	Var   int //string
 	Value Expr
 	Stmts Stmts
@@ -279,6 +290,7 @@ func (s *ForStmt) BinTo(bins *binstmt.BinStmts, reg int, lid *int, maxreg *int)
 // NumForStmt name = expr1 to expr2
 type NumForStmt struct {
 	StmtImpl
+	BlockEnd
 	Name  int //string
 	Expr1 Expr
 	Expr2 Expr
@@ -366,6 +378,7 @@ func (s *NumForStmt) BinTo(bins *binstmt.BinStmts, reg int, lid *int, maxreg *in
 // LoopStmt provide "for expr" expression statement.
 type LoopStmt struct {
 	StmtImpl
+	BlockEnd
 	Expr  Expr
 	Stmts Stmts
 }
@@ -527,7 +540,7 @@ func (x *ModuleStmt) Simplify() {
 }
 
 func (s *ModuleStmt) BinTo(bins *binstmt.BinStmts, reg int, lid *int, maxreg *int) {
-	if s.Name == names.UniqueNames.Set("_") {
+	if s.Name == names.DefaultModule {
 		// добавляем все операторы в текущий контекст
 		s.Stmts.BinTo(bins, reg, lid, maxreg)
 	} else {
@@ -541,6 +554,7 @@ func (s *ModuleStmt) BinTo(bins *binstmt.BinStmts, reg int, lid *int, maxreg *in
 // SwitchStmt provide switch statement.
 type SwitchStmt struct {
 	StmtImpl
+	BlockEnd
 	Expr  Expr
 	Cases Stmts
 }
@@ -587,6 +601,7 @@ func (s *SwitchStmt) BinTo(bins *binstmt.BinStmts, reg int, lid *int, maxreg *in
 // SelectStmt provide switch statement.
 type SelectStmt struct {
 	StmtImpl
+	BlockEnd
 	Cases Stmts
 }
 
@@ -6,4 +6,5 @@ type Token struct {
 	pos.PosImpl // StmtImpl provide Pos() function.
 	Tok         int
 	Lit         string
+	Src         string // исходная запись литерала строки вместе с кавычками
 }
@@ -0,0 +1,90 @@
+package bincode
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const binarySrc = `дд = ДвоичныеДанные("abc") + HexЗначение("00ff")
+Сообщить(дд, Длина(дд), дд.Размер(), дд[3], дд[-1], дд[1:3], ТипЗнч(дд))
+Сообщить(Base64Строка(дд), Base64Значение(Base64Строка(дд)) = дд, HexСтрока(дд))
+Сообщить(Строка(дд[0:3]), Массив(дд[3:]))
+для каждого б из дд[0:2] цикл
+	Сообщить(б)
+конеццикла
+п = Новый ПотокЗаписи
+п.Записать(дд)
+п.Записать("строка\nвторая")
+ф = СоздатьФайл(Файл)
+ф.Записать(Новый ПотокЧтения(п.Содержимое()))
+ф.Закрыть()
+ч = ОткрытьФайл(Файл)
+Сообщить(ч.Прочитать(5) = дд)
+с, есть = ч.ПрочитатьСтроку()
+Сообщить(с, есть)
+с, есть = ч.ПрочитатьСтроку()
+Сообщить(с, есть)
+с, есть = ч.ПрочитатьСтроку()
+Сообщить(с, есть, Длина(ч.Прочитать(10)))
+ч.Закрыть()
+`
+
+func TestBinaryData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "binary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "данные.bin")
+
+	var out bytes.Buffer
+	env := core.NewEnv()
+	env.SetStdOut(&out)
+	env.DefineS("файл", core.VMString(fn))
+	_, bins, err := ParseSrc(binarySrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil {
+		t.Fatal(err)
+	}
+	want := `61626300ff 5 5 0 255 6263 двоичныеданные
+YWJjAP8= true 61626300ff
+abc [0,255]
+97
+98
+true
+строка true
+вторая true
+ false 0
+`
+	if out.String() != want {
+		t.Errorf("вывод:\n%s\nожидается:\n%s", out.String(), want)
+	}
+	data, _ := ioutil.ReadFile(fn)
+	if string(data) != "abc\x00\xffстрока\nвторая" {
+		t.Errorf("файл %q", data)
+	}
+
+	for src, msg := range map[string]string{
+		`р = Base64Значение("!")`:             "illegal base64",
+		`р = ДвоичныеДанные("a") + "b"`:       core.VMErrorIncorrectOperation.Error(),
+		`р = Новый ПотокЧтения(1)`:            core.VMErrorNeedBinaryData.Error(),
+		`р = ДвоичныеДанные("a")[1]`:          "Индекс за пределами границ",
+		`р = (Новый ПотокЗаписи).Записать(1)`: core.VMErrorNeedBinaryData.Error(),
+	} {
+		_, bins, err := ParseSrc(src, env.Names())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Run(bins, env); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: ошибка %v, ожидается %q", src, err, msg)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package bincode
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/covrom/gonec/core"
+)
+
+// runCtx исполняет код с контекстом и возвращает ошибку и время исполнения
+func runCtx(t *testing.T, ctx context.Context, env *core.Env, src string) (error, time.Duration) {
+	t.Helper()
+	_, bins, err := ParseSrc(src, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = RunContext(ctx, bins, env)
+	return err, time.Since(start)
+}
+
+func TestRunContextBlocking(t *testing.T) {
+	// сервер отвечает только после завершения теста
+	stop := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-stop:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(stop)
+
+	env := core.NewEnv()
+	env.SetStdOut(&bytes.Buffer{})
+	for src, msg := range map[string]string{
+		`Пауза(10)`: core.VMErrorDeadlineExceeded.Error(),
+		`к = Новый Канал(0); з = <-к`:       core.VMErrorDeadlineExceeded.Error(),
+		`к = Новый Канал(0); к <- 1`:        core.VMErrorDeadlineExceeded.Error(),
+		`Контекст.СТаймаутом(10).Ожидать()`: core.VMErrorDeadlineExceeded.Error(),
+		`соед = (Новый Клиент).Соединить("http", ""); соед.Запрос({"Метод": "GET", "Путь": "` + srv.URL + `"})`: core.VMErrorDeadlineExceeded.Error(),
+		// горутины программы прерываются вместе с ней
+		`к = Новый Канал(0); старт Функция() Пауза(10); к <- 1 КонецФункции(); з = <-к`: core.VMErrorDeadlineExceeded.Error(),
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		err, d := runCtx(t, ctx, env, src)
+		cancel()
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: ошибка %v, ожидается %q", src, err, msg)
+		}
+		if d > 5*time.Second {
+			t.Errorf("%s: прервано только через %v", src, d)
+		}
+	}
+
+	// отмена контекста прерывает сетевой запрос
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	err, _ := runCtx(t, ctx, env, `соед = (Новый Клиент).Соединить("http", ""); соед.Запрос({"Метод": "GET", "Путь": "`+srv.URL+`"})`)
+	if err == nil || !strings.Contains(err.Error(), core.VMErrorInterrupted.Error()) {
+		t.Errorf("отмена запроса: %v", err)
+	}
+
+	// контекст, переданный в запрос, ограничивает только его
+	var out bytes.Buffer
+	env.SetStdOut(&out)
+	err, _ = runCtx(t, context.Background(), env, `соед = (Новый Клиент).Соединить("http", "")
+к = Контекст.СТаймаутом(0.1)
+попытка
+	соед.Запрос({"Метод": "GET", "Путь": "`+srv.URL+`", "Контекст": к})
+исключение
+	Сообщить(к.Отменен(), к.Ошибка())
+конецпопытки`)
+	if err != nil || out.String() != "true "+core.VMErrorDeadlineExceeded.Error()+"\n" {
+		t.Errorf("запрос с контекстом: %q, %v", out.String(), err)
+	}
+}
+
+func TestRunAfterInterrupt(t *testing.T) {
+	var out bytes.Buffer
+	env := core.NewEnv()
+	env.SetStdOut(&out)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err, _ := runCtx(t, ctx, env, `пока Истина цикл конеццикла`); err == nil || !strings.Contains(err.Error(), core.VMErrorInterrupted.Error()) {
+		t.Errorf("отмененный контекст: %v", err)
+	}
+
+	// прерывание через Interrupt
+	_, loop, err := ParseSrc(`пока Истина цикл конеццикла`, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(50*time.Millisecond, env.Interrupt)
+	if _, err := Run(loop, env); err == nil || !strings.Contains(err.Error(), core.VMErrorInterrupted.Error()) {
+		t.Errorf("Interrupt: %v", err)
+	}
+
+	// после прерывания окружение пригодно для следующих запусков, в том числе через Run без контекста
+	_, bins, err := ParseSrc(`с = 0; для н = 1 по 100 цикл с = с + н; конеццикла; Сообщить(с)`, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		out.Reset()
+		if _, err := Run(bins, env); err != nil || out.String() != "5050\n" {
+			t.Errorf("запуск %d после прерывания: %q, %v", i, out.String(), err)
+		}
+	}
+	out.Reset()
+	if err, _ := runCtx(t, context.Background(), env, `Сообщить("снова")`); err != nil || out.String() != "снова\n" {
+		t.Errorf("RunContext после прерывания: %q, %v", out.String(), err)
+	}
+}
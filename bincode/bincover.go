@@ -0,0 +1,199 @@
+package bincode
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/covrom/gonec/bincode/binstmt"
+	"github.com/covrom/gonec/cover"
+)
+
+var (
+	ErrCoverageRunning    = errors.New("Сбор покрытия уже запущен")
+	ErrCoverageNotRunning = errors.New("Сбор покрытия не запущен")
+)
+
+var (
+	coverMu     sync.Mutex // запуск и остановка сбора покрытия
+	activeCover atomic.Value
+)
+
+// StartCoverage запускает сбор покрытия для всего кода, исполняемого вирт. машиной через Run и RunContext.
+// Учитываются исполнения операторов и исходы условных переходов (Если, Пока, Для, И, Или, ?()).
+// Имя файла берется из BinCode.File, если оно не задано - используется filename
+func StartCoverage(filename string) error {
+	coverMu.Lock()
+	defer coverMu.Unlock()
+	if currentCoverage() != nil {
+		return ErrCoverageRunning
+	}
+	activeCover.Store(&coverage{
+		filename: filename,
+		codes:    make(map[*binstmt.BinStmt]*coverCode),
+	})
+	return nil
+}
+
+// StopCoverage останавливает сбор покрытия и возвращает профиль
+func StopCoverage() (*cover.Profile, error) {
+	coverMu.Lock()
+	defer coverMu.Unlock()
+	c := currentCoverage()
+	if c == nil {
+		return nil, ErrCoverageNotRunning
+	}
+	activeCover.Store((*coverage)(nil))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prof := &cover.Profile{}
+	for _, cc := range c.order {
+		prof.Merge(cc.profile())
+	}
+	return prof, nil
+}
+
+func currentCoverage() *coverage {
+	c, _ := activeCover.Load().(*coverage)
+	return c
+}
+
+type coverage struct {
+	filename string
+
+	mu    sync.Mutex
+	codes map[*binstmt.BinStmt]*coverCode // ключ - адрес первой инструкции кода
+	order []*coverCode
+}
+
+// coverCode - счетчики исполнения инструкций одного скомпилированного кода (модуля со всеми его функциями)
+type coverCode struct {
+	file   string
+	code   binstmt.BinCode
+	counts []uint64    // по индексу инструкции, изменяются атомарно
+	jumps  [][2]uint64 // исходы условных переходов по индексу инструкции: истина, ложь
+}
+
+// coverRegister регистрирует код перед исполнением, повторный запуск того же кода использует те же счетчики
+func coverRegister(code binstmt.BinCode) {
+	c := currentCoverage()
+	if c == nil || len(code.Code) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.codes[&code.Code[0]]; ok {
+		return
+	}
+	file := code.File
+	if file == "" {
+		file = c.filename
+	}
+	cc := &coverCode{
+		file:   file,
+		code:   code,
+		counts: make([]uint64, len(code.Code)),
+		jumps:  make([][2]uint64, len(code.Code)),
+	}
+	c.codes[&code.Code[0]] = cc
+	c.order = append(c.order, cc)
+}
+
+// coverCurrent возвращает счетчики кода stmts, или nil, если покрытие не собирается
+func coverCurrent(stmts binstmt.BinStmts) *coverCode {
+	c := currentCoverage()
+	if c == nil || len(stmts) == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.codes[&stmts[0]]
+}
+
+func (cc *coverCode) step(idx int) {
+	atomic.AddUint64(&cc.counts[idx], 1)
+}
+
+func (cc *coverCode) jump(idx int, cond bool) {
+	if cond {
+		atomic.AddUint64(&cc.jumps[idx][0], 1)
+	} else {
+		atomic.AddUint64(&cc.jumps[idx][1], 1)
+	}
+}
+
+// profile собирает профиль по позициям исходного кода. Из одного оператора получается несколько инструкций,
+// оператор считается исполненным столько раз, сколько раз исполнялась его самая частая инструкция
+func (cc *coverCode) profile() *cover.Profile {
+	stmts := cc.code.Code
+	prof := &cover.Profile{}
+	// определение функции исполняется при загрузке модуля, покрытие функции определяют ее операторы
+	funcs := make(map[cover.Pos]bool)
+	for _, st := range stmts {
+		if s, ok := st.(*binstmt.BinFUNC); ok {
+			if f, ok := cc.funcPos(s); ok {
+				prof.Funcs = append(prof.Funcs, f)
+				funcs[f.Pos] = true
+			}
+		}
+	}
+	idx := make(map[cover.Pos]int)
+	for i, st := range stmts {
+		if _, ok := st.(*binstmt.BinLABEL); ok {
+			continue
+		}
+		pos, ok := cc.pos(st)
+		// с позицией функции компилируется и неявный возврат в конце ее тела, он не является оператором
+		if !ok || funcs[pos] {
+			continue
+		}
+		n := atomic.LoadUint64(&cc.counts[i])
+		if j, ok := idx[pos]; ok {
+			if n > prof.Stmts[j].Count {
+				prof.Stmts[j].Count = n
+			}
+		} else {
+			idx[pos] = len(prof.Stmts)
+			prof.Stmts = append(prof.Stmts, cover.Stmt{Pos: pos, Count: n})
+		}
+		switch st.(type) {
+		case *binstmt.BinJTRUE, *binstmt.BinJFALSE:
+			prof.Branches = append(prof.Branches, cover.Branch{
+				Pos:   pos,
+				True:  atomic.LoadUint64(&cc.jumps[i][0]),
+				False: atomic.LoadUint64(&cc.jumps[i][1]),
+			})
+		}
+	}
+	return prof
+}
+
+// pos возвращает позицию инструкции в исходном тексте с учетом вставки модуля _ по умолчанию
+func (cc *coverCode) pos(st binstmt.BinStmt) (cover.Pos, bool) {
+	p := st.Position()
+	if p.Line <= 1 {
+		return cover.Pos{}, false
+	}
+	return cover.Pos{File: cc.file, Line: p.Line - 1, Column: p.Column}, true
+}
+
+// funcPos возвращает позицию функции и последнюю строку ее операторов
+func (cc *coverCode) funcPos(s *binstmt.BinFUNC) (cover.Func, bool) {
+	pos, ok := cc.pos(s)
+	if !ok {
+		return cover.Func{}, false
+	}
+	name := anonFuncName
+	if s.Name != 0 {
+		name = cc.code.Names().Get(s.Name)
+	}
+	f := cover.Func{Pos: pos, Name: name, EndLine: pos.Line}
+	labels := cc.code.Labels
+	for i := labels[s.LabelStart]; i < labels[s.LabelEnd] && i < len(cc.code.Code); i++ {
+		if p, ok := cc.pos(cc.code.Code[i]); ok && p.Line > f.EndLine {
+			f.EndLine = p.Line
+		}
+	}
+	return f, true
+}
@@ -0,0 +1,87 @@
+package bincode
+
+import (
+	"testing"
+
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/cover"
+)
+
+const coverSrc = `функция знак(х)
+	если х > 0 тогда
+		возврат 1
+	иначеесли х < 0 тогда
+		возврат -1
+	конецесли
+	возврат 0
+конецфункции
+
+функция неиспользуемая()
+	сообщить("нет")
+конецфункции
+
+для к = 1 по 3 цикл
+	знак(к)
+конеццикла
+`
+
+func TestCoverage(t *testing.T) {
+	if err := StartCoverage("покрытие.gnc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := StartCoverage(""); err != ErrCoverageRunning {
+		t.Errorf("повторный запуск: %v", err)
+	}
+
+	env := core.NewEnv()
+	_, bins, err := ParseSrc(coverSrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// повторное исполнение того же кода суммирует счетчики
+	for i := 0; i < 2; i++ {
+		if _, err := Run(bins, env); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prof, err := StopCoverage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StopCoverage(); err != ErrCoverageNotRunning {
+		t.Errorf("повторная остановка: %v", err)
+	}
+
+	lines := make(map[int]uint64)
+	for _, s := range prof.Stmts {
+		if s.File != "покрытие.gnc" {
+			t.Fatalf("неверное имя файла %q", s.File)
+		}
+		if n, ok := lines[s.Line]; !ok || s.Count > n {
+			lines[s.Line] = s.Count
+		}
+	}
+	for ln, want := range map[int]uint64{2: 6, 3: 6, 4: 0, 5: 0, 7: 0, 11: 0, 15: 6} {
+		if n, ok := lines[ln]; !ok || n != want {
+			t.Errorf("строка %d: исполнений %d, ожидалось %d", ln, n, want)
+		}
+	}
+	if _, ok := lines[1]; ok {
+		t.Error("объявление функции учтено как оператор")
+	}
+
+	var br *cover.Branch
+	for i := range prof.Branches {
+		if prof.Branches[i].Line == 2 {
+			br = &prof.Branches[i]
+		}
+	}
+	if br == nil || br.True != 6 || br.False != 0 {
+		t.Errorf("ветвление в строке 2: %+v", br)
+	}
+
+	if len(prof.Funcs) != 2 || prof.Funcs[0].Name != "знак" || prof.Funcs[0].EndLine != 7 {
+		t.Errorf("функции: %+v", prof.Funcs)
+	}
+}
@@ -0,0 +1,106 @@
+package bincode
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const csvSrc = `ч = Новый ЧтениеCSV("Код;Наименование;Цена\n1;\"Молоко; 3,2%\";59.90\n2;\"Хлеб \"\"Бородинский\"\"\";45\n", {"Заголовки": Истина})
+Сообщить(ч.Заголовки())
+с, есть = ч.Прочитать()
+Сообщить(с.Наименование, с.Цена, есть)
+с, есть = ч.Прочитать()
+Сообщить(с.Наименование, есть)
+с, есть = ч.Прочитать()
+Сообщить(с, есть)
+
+ч = Новый ЧтениеCSV("а,б\nв,г,д", {"Разделитель": ","})
+Сообщить(ч.ПрочитатьВсе())
+ч = Новый ЧтениеCSV("1;2", {"Заголовки": ["x", "y", "z"]})
+в = ч.ПрочитатьВсе()
+Сообщить(в[0].x, в[0].y, в[0].z = "", ч.ПрочитатьВсе())
+
+з = Новый ЗаписьCSV
+з.Записать(["а", "б;в", "с \"кавычкой\"", 1, 2.5, Неопределено])
+Сообщить(Строка(з.Содержимое()))
+
+з = Новый ЗаписьCSV(СоздатьФайл(Файл), {"Кодировка": "windows-1251", "Заголовки": ["Наименование", "Код"]})
+з.ЗаписатьВсе([{"Код": 1, "Наименование": "Ёжик"}, {"Код": 2, "Наименование": "Чай №1"}])
+з.Закрыть()
+ч = Новый ЧтениеCSV(ОткрытьФайл(Файл), {"Кодировка": "windows-1251", "Заголовки": Истина})
+для каждого с из ч.ПрочитатьВсе() цикл
+	Сообщить(с.Код, с.Наименование)
+конеццикла
+ч.Закрыть()
+
+з = Новый ЗаписьCSV({"Разделитель": ",", "ВсегдаВКавычках": Истина, "Кодировка": "UTF-8-BOM"})
+з.Записать({"б": 2, "а": 1})
+дд = з.Содержимое()
+Сообщить(дд[0:3] = HexЗначение("efbbbf"), Строка(дд[3:]))
+`
+
+func TestCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "выгрузка.csv")
+
+	var out bytes.Buffer
+	env := core.NewEnv()
+	env.SetStdOut(&out)
+	env.DefineS("файл", core.VMString(fn))
+	_, bins, err := ParseSrc(csvSrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil {
+		t.Fatal(err)
+	}
+	// строки CSV заканчиваются переводом строки Windows
+	want := `["Код","Наименование","Цена"]
+Молоко; 3,2% 59.90 true
+Хлеб "Бородинский" true
+Неопределено false
+[["а","б"],["в","г","д"]]
+1 2 true []
+а;"б;в";"с ""кавычкой""";1;2.5;
+
+1 Ёжик
+2 Чай №1
+true "а","б"
+"1","2"
+
+`
+	if got := strings.Replace(out.String(), "\r\n", "\n", -1); got != want {
+		t.Errorf("вывод:\n%s\nожидается:\n%s", got, want)
+	}
+	data, _ := ioutil.ReadFile(fn)
+	if want := "\xcd\xe0\xe8\xec\xe5\xed\xee\xe2\xe0\xed\xe8\xe5;\xca\xee\xe4\r\n\xa8\xe6\xe8\xea;1\r\n\xd7\xe0\xe9 \xb91;2\r\n"; string(data) != want {
+		t.Errorf("файл в windows-1251 %q", data)
+	}
+
+	for src, msg := range map[string]string{
+		`р = (Новый ЧтениеCSV("а;\"б")).ПрочитатьВсе()`:    "Ошибка чтения CSV в строке 1",
+		`р = Новый ЧтениеCSV("", {"Кодировка": "koi8-r"})`: core.VMErrorUnknownCharset.Error(),
+		`р = Новый ЧтениеCSV("", {"Разделитель": ";;"})`:   "одним символом",
+		`р = Новый ЧтениеCSV(1)`:                           core.VMErrorNeedBinaryData.Error(),
+		`р = Новый ЗаписьCSV(1)`:                           "поток записи",
+		`з = Новый ЗаписьCSV; з.Записать(1)`:               "массивом или структурой",
+	} {
+		_, bins, err := ParseSrc(src, env.Names())
+		if err != nil {
+			t.Fatal(src, err)
+		}
+		if _, err := Run(bins, env); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: ошибка %v, ожидается %q", src, err, msg)
+		}
+	}
+}
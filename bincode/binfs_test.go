@@ -0,0 +1,143 @@
+package bincode
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const fsSrc = `функция Имена(пути)
+	для каждого п из пути цикл
+		Сообщить(РазложитьПуть(п).Имя)
+	конеццикла
+конецфункции
+
+СоздатьКаталог("данные/вложенный")
+ЗаписатьФайл("данные/а.txt", "раз")
+ДописатьВФайл("данные/а.txt", ДвоичныеДанные(" два"))
+ЗаписатьФайл("данные/вложенный/б.txt", "три")
+ЗаписатьФайл("данные/в.log", "")
+Сообщить(ФайлСуществует("данные/а.txt"), ФайлСуществует("данные/нет.txt"))
+св = СвойстваФайла("данные/а.txt")
+Сообщить(св.Имя, св.Размер, св.ЭтоКаталог, св.Права, св.ПолноеИмя = ПолныйПуть("данные/а.txt"))
+Сообщить(СвойстваФайла("данные/вложенный").ЭтоКаталог)
+Имена(НайтиФайлы("данные"))
+Имена(НайтиФайлы("данные", "*.txt", Истина))
+КопироватьФайл("данные/а.txt", "данные/копия.txt")
+ПереместитьФайл("данные/копия.txt", "данные/вложенный/г.txt")
+с, есть = ПрочитатьФайл("данные/вложенный/г.txt")
+Сообщить(с, есть, ФайлСуществует("данные/копия.txt"))
+УдалитьФайлы("данные", "*.txt")
+Имена(НайтиФайлы("данные"))
+УдалитьФайлы("данные/вложенный")
+Сообщить(ФайлСуществует("данные/вложенный"))
+в = ВременныйФайл("tmp")
+Сообщить(ФайлСуществует(в), РазложитьПуть(в).Расширение)
+р = РазложитьПуть(ОбъединитьПути("а", "б", "файл.tar.gz"))
+Сообщить(р.Каталог, р.Имя, р.ИмяБезРасширения, р.Расширение)
+`
+
+func TestFileSystem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var out bytes.Buffer
+	env := core.NewEnv()
+	env.SetStdOut(&out)
+	env.SetSandbox(&core.Sandbox{Root: dir})
+	_, bins, err := ParseSrc(fsSrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil {
+		t.Fatal(err)
+	}
+	want := `true false
+а.txt 13 false 420 true
+true
+а.txt
+в.log
+вложенный
+а.txt
+б.txt
+раз два true false
+в.log
+вложенный
+false
+true .tmp
+а/б/ файл.tar.gz файл.tar .gz
+`
+	if out.String() != want {
+		t.Errorf("вывод:\n%s\nожидается:\n%s", out.String(), want)
+	}
+
+	// за пределы каталога песочницы не выйти ни по относительному пути, ни по ссылке
+	if err := os.Symlink(os.TempDir(), filepath.Join(dir, "ссылка")); err != nil {
+		t.Fatal(err)
+	}
+	outside := core.VMErrorFSOutsideRoot.Error()
+	cases := map[string]string{
+		`р = ПрочитатьФайл("../файл")`:                              outside,
+		`р = ФайлСуществует("/etc/passwd")`:                         outside,
+		`ЗаписатьФайл("ссылка/файл", "")`:                           outside,
+		`р = НайтиФайлы(".", "[")`:                                  "syntax error in pattern",
+		`р = СвойстваФайла("нет")`:                                  "no such file",
+		`ЗаписатьФайл("файл", 1)`:                                   core.VMErrorNeedBinaryData.Error(),
+		`ЗагрузитьИВыполнить("../модуль.gnx")`:                      outside,
+		`б = Новый ФайловаяБазаДанных; б.Открыть("ссылка/база.db")`: outside,
+	}
+	check := func() {
+		for src, msg := range cases {
+			_, bins, err := ParseSrc(src, env.Names())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Run(bins, env); err == nil || !strings.Contains(err.Error(), msg) {
+				t.Errorf("%s: ошибка %v, ожидается %q", src, err, msg)
+			}
+		}
+	}
+	check()
+
+	env.SetSandbox(&core.Sandbox{FS: core.FSReadOnly, Root: dir})
+	readonly := core.VMErrorFSReadOnly.Error()
+	cases = map[string]string{
+		`ЗаписатьФайл("файл", "")`:                           readonly,
+		`р = СоздатьФайл("файл")`:                            readonly,
+		`УдалитьФайлы("ссылка")`:                             readonly,
+		`р = ВременныйФайл()`:                                readonly,
+		`ПереместитьФайл("а", "б")`:                          readonly,
+		`б = Новый ФайловаяБазаДанных; б.Открыть("база.db")`: readonly,
+	}
+	check()
+
+	// так работает песочница веб-сервиса
+	env.SetSandbox(&core.Sandbox{FS: core.FSDenied})
+	denied := core.VMErrorFSDenied.Error()
+	cases = map[string]string{
+		`р = ФайлСуществует("файл")`:                              denied,
+		`р = ОткрытьФайл("файл")`:                                 denied,
+		`р = ПрочитатьФайл("файл")`:                               denied,
+		`ЗагрузитьИВыполнить("модуль.gnx")`:                       denied,
+		`б = Новый ФайловаяБазаДанных; б.Открыть("/tmp/база.db")`: denied,
+	}
+	check()
+
+	// исполнение строки кода не обращается к файловой системе
+	out.Reset()
+	_, bins, err = ParseSrc(`Выполнить("Сообщить(1+2)")`, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil || out.String() != "3\n" {
+		t.Errorf("Выполнить: %q, %v", out.String(), err)
+	}
+}
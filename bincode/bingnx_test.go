@@ -0,0 +1,435 @@
+package bincode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/gob"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/bincode/binstmt"
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/version"
+)
+
+const gnxSrc = `Функция ф(у...)
+	а = [у[0], 1]
+	Для Каждого б Из а Цикл
+		Если б > 0 Тогда
+			Продолжить
+		КонецЕсли
+	КонецЦикла
+	Возврат Функция() Возврат а КонецФункции
+КонецФункции
+Попытка
+	ф(1)
+Исключение
+	Сообщить(ОписаниеОшибки())
+КонецПопытки
+Модуль М
+в = 1
+`
+
+func compileGNX(t *testing.T, src string) binstmt.BinCode {
+	_, code, err := ParseSrc(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestGNXRoundTrip(t *testing.T) {
+	code := compileGNX(t, gnxSrc)
+	code.File = "исходный.gnc"
+	var buf bytes.Buffer
+	if err := binstmt.WriteBinCode(&buf, code); err != nil {
+		t.Fatal(err)
+	}
+	h, _, err := binstmt.ReadGNXHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil || h.Version != binstmt.GNXVersion || h.Interpreter != version.Version {
+		t.Fatalf("заголовок %+v, %v", h, err)
+	}
+	res, err := binstmt.ReadBinCode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.File != "исходный.gnc" || len(res.Code) != len(code.Code) {
+		t.Errorf("прочитан код %q из %d инструкций", res.File, len(res.Code))
+	}
+	// карта исходного кода содержит позицию каждой инструкции
+	if len(res.Source.Pos) != len(res.Code) {
+		t.Fatalf("карта исходного кода из %d позиций", len(res.Source.Pos))
+	}
+	for i, st := range res.Code {
+		file, p, ok := res.Source.Lookup(i)
+		if !ok || file != "исходный.gnc" || p != st.Position() {
+			t.Errorf("инструкция %d %v: %q %v", i, st, file, p)
+		}
+	}
+	if len(code.Source.Pos) != 0 {
+		t.Error("при записи изменен исходный код")
+	}
+
+	// файл без заголовка (версия 0)
+	buf.Reset()
+	zw := gzip.NewWriter(&buf)
+	enc := gob.NewEncoder(zw)
+	if err := enc.Encode(code.Names()); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(code); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	if _, err := binstmt.ReadBinCode(&buf); err != nil {
+		t.Errorf("файл версии 0: %v", err)
+	}
+
+	if _, err := binstmt.ReadBinCode(strings.NewReader("не байткод")); err != binstmt.ErrGNXFormat {
+		t.Errorf("неверный формат: %v", err)
+	}
+	if _, err := binstmt.ReadBinCode(strings.NewReader("GONECGNX\xff\x00\x00")); err != binstmt.ErrGNXVersion {
+		t.Errorf("новая версия: %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	files, _ := filepath.Glob("../test/*.gnc")
+	for _, fn := range files {
+		src, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := compileGNX(t, string(src)).Verify(); err != nil {
+			t.Errorf("%s: %v", fn, err)
+		}
+	}
+
+	find := func(code binstmt.BinCode, f func(binstmt.BinStmt) bool) {
+		for _, st := range code.Code {
+			if f(st) {
+				return
+			}
+		}
+		t.Fatal("инструкция не найдена")
+	}
+	cases := []struct {
+		name    string
+		corrupt func(code *binstmt.BinCode)
+		want    string
+	}{
+		{"регистр", func(code *binstmt.BinCode) {
+			find(*code, func(st binstmt.BinStmt) bool {
+				s, ok := st.(*binstmt.BinSET)
+				if ok {
+					s.Reg = 100
+				}
+				return ok
+			})
+		}, "регистр r100 за пределами"},
+		{"регистр функции", func(code *binstmt.BinCode) {
+			find(*code, func(st binstmt.BinStmt) bool {
+				s, ok := st.(*binstmt.BinFUNC)
+				if ok {
+					s.MaxReg = 0
+				}
+				return ok
+			})
+		}, "за пределами r0-r0"},
+		{"метка", func(code *binstmt.BinCode) {
+			find(*code, func(st binstmt.BinStmt) bool {
+				s, ok := st.(*binstmt.BinJFALSE)
+				if ok {
+					s.JumpTo = len(code.Labels)
+				}
+				return ok
+			})
+		}, "нет метки"},
+		{"адрес метки", func(code *binstmt.BinCode) {
+			code.Labels[1] = len(code.Code) + 1
+		}, "метка L1 указывает за пределы кода"},
+		{"имя", func(code *binstmt.BinCode) {
+			find(*code, func(st binstmt.BinStmt) bool {
+				s, ok := st.(*binstmt.BinCALL)
+				if ok && s.Name != 0 {
+					s.Name = 100000
+				}
+				return ok && s.Name != 0
+			})
+		}, "нет имени #100000"},
+		{"аргументы", func(code *binstmt.BinCode) {
+			find(*code, func(st binstmt.BinStmt) bool {
+				s, ok := st.(*binstmt.BinCALL)
+				if ok {
+					s.NumArgs = 100
+				}
+				return ok
+			})
+		}, "аргументы вызова за пределами"},
+		{"пустая инструкция", func(code *binstmt.BinCode) {
+			code.Code[0] = nil
+		}, "инструкция 0000: пустая инструкция"},
+		{"размер массива", func(code *binstmt.BinCode) {
+			find(*code, func(st binstmt.BinStmt) bool {
+				s, ok := st.(*binstmt.BinMAKESLICE)
+				if ok {
+					s.Len, s.Cap = -1, -1
+				}
+				return ok
+			})
+		}, "неверный размер массива -1"},
+		{"емкость массива", func(code *binstmt.BinCode) {
+			find(*code, func(st binstmt.BinStmt) bool {
+				s, ok := st.(*binstmt.BinMAKESLICE)
+				if ok {
+					s.Cap = 1 << 40
+				}
+				return ok
+			})
+		}, "неверный размер массива 2"},
+		{"индекс массива", func(code *binstmt.BinCode) {
+			find(*code, func(st binstmt.BinStmt) bool {
+				s, ok := st.(*binstmt.BinSETIDX)
+				if ok {
+					s.Index = -1
+				}
+				return ok
+			})
+		}, "неверный индекс элемента массива -1"},
+		{"карта исходного кода", func(code *binstmt.BinCode) {
+			*code = code.WithSourceMap()
+			code.Source.Pos = code.Source.Pos[1:]
+		}, "карта исходного кода содержит"},
+		{"файл в карте исходного кода", func(code *binstmt.BinCode) {
+			*code = code.WithSourceMap()
+			code.Source.Pos[3].File = 1
+		}, "инструкция 0003: нет файла #1"},
+	}
+	for _, c := range cases {
+		code := compileGNX(t, gnxSrc)
+		c.corrupt(&code)
+		err := code.Verify()
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: %v", c.name, err)
+		}
+	}
+
+	// поврежденный файл не загружается
+	code := compileGNX(t, gnxSrc)
+	code.MaxReg = 0
+	var buf bytes.Buffer
+	if err := binstmt.WriteBinCode(&buf, code); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := binstmt.ReadBinCode(&buf); err == nil {
+		t.Error("загружен поврежденный код")
+	} else if _, ok := err.(*binstmt.VerifyError); !ok {
+		t.Errorf("ошибка %T %v", err, err)
+	}
+}
+
+func TestErrorFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lib := filepath.Join(dir, "lib.gnc")
+	if err := ioutil.WriteFile(lib, []byte("Функция Ошибка()\n\tВозврат 1 / \"а\"\nКонецФункции\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// модуль компилируется в .gnx, в нем сохраняется имя исходного файла
+	libx := filepath.Join(dir, "lib.gnx")
+	libCode := compileGNX(t, "Функция Ошибка()\n\tВозврат 1 / \"а\"\nКонецФункции\n")
+	libCode.File = lib
+	f, err := os.Create(libx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := binstmt.WriteBinCode(f, libCode); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for _, load := range []string{lib, libx} {
+		code := compileGNX(t, "ЗагрузитьИВыполнить(\""+load+"\")\n\nОшибка()\n")
+		code.File = "главный.gnc"
+		env := core.NewEnv()
+		env.SetStdOut(ioutil.Discard)
+		_, err := Run(code, env)
+		e, ok := err.(*binstmt.Error)
+		if !ok || e.File != lib || e.Pos.Line-1 != 2 {
+			t.Errorf("%s: ошибка %#v", load, err)
+		}
+	}
+
+	// ошибка в коде вложенного модуля относится к файлу, в котором он определен
+	code := compileGNX(t, "Модуль М\nа = 1 / \"а\"\n")
+	code.File = "модуль.gnc"
+	_, err = Run(code, core.NewEnv())
+	if e, ok := err.(*binstmt.Error); !ok || e.File != "модуль.gnc" {
+		t.Errorf("ошибка в модуле %#v", err)
+	}
+
+	// файл ошибки в загруженном коде берется из карты исходного кода инструкции
+	code = compileGNX(t, "Функция Ошибка()\n\tВозврат 1 / \"а\"\nКонецФункции\nМодуль М\nОшибка()\n")
+	code.File = "главный.gnc"
+	code = code.WithSourceMap()
+	for i, st := range code.Code {
+		if _, ok := st.(*binstmt.BinFUNC); ok {
+			// тело функции скомпилировано из другого файла
+			code.Source.Files = append(code.Source.Files, "функции.gnc")
+			for j := i; j < len(code.Code); j++ {
+				if _, ok := code.Code[j].(*binstmt.BinMODULE); ok {
+					break
+				}
+				code.Source.Pos[j].File = 1
+			}
+			break
+		}
+	}
+	var buf bytes.Buffer
+	if err := binstmt.WriteBinCode(&buf, code); err != nil {
+		t.Fatal(err)
+	}
+	if code, err = binstmt.ReadBinCode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	code.File = ""
+	_, err = Run(code, core.NewEnv())
+	if e, ok := err.(*binstmt.Error); !ok || e.File != "функции.gnc" || e.Pos.Line-1 != 2 {
+		t.Errorf("ошибка в загруженном коде %#v", err)
+	}
+}
+
+func TestSignedGNX(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// ключи в формате PEM, как их создает openssl
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	other, _, _ := ed25519.GenerateKey(nil)
+	der, _ := x509.MarshalPKCS8PrivateKey(priv)
+	keyFile := filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	trustDir := filepath.Join(dir, "trusted")
+	os.Mkdir(trustDir, 0755)
+	if _, err := binstmt.LoadTrustedKeys(trustDir); err != binstmt.ErrNoTrustedKeys {
+		t.Errorf("пустой каталог ключей: %v", err)
+	}
+	der, _ = x509.MarshalPKIXPublicKey(pub)
+	ioutil.WriteFile(filepath.Join(trustDir, "ops.pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+
+	key, err := binstmt.LoadPrivateKey(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustedKeys, err := binstmt.LoadTrustedKeys(trustDir)
+	if err != nil || len(trustedKeys) != 1 || !bytes.Equal(trustedKeys[0], pub) {
+		t.Fatalf("доверенные ключи %v, %v", trustedKeys, err)
+	}
+
+	code := compileGNX(t, "Сообщить(1)\n")
+	var signed, unsigned bytes.Buffer
+	if err := binstmt.WriteSignedBinCode(&signed, code, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := binstmt.WriteBinCode(&unsigned, code); err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, signed.Bytes()...)
+	tampered[len(tampered)-10] ^= 1
+
+	read := func(data []byte) error {
+		_, err := binstmt.ReadBinCode(bytes.NewReader(data))
+		return err
+	}
+	check := func(name string, got, want error) {
+		if got != want {
+			t.Errorf("%s: %v, ожидалось %v", name, got, want)
+		}
+	}
+
+	// без доверенных ключей подпись проверяется, если она есть
+	check("подписанный", read(signed.Bytes()), nil)
+	check("без подписи", read(unsigned.Bytes()), nil)
+	check("измененный", read(tampered), binstmt.ErrGNXSignature)
+
+	binstmt.SetTrustedKeys(trustedKeys)
+	defer binstmt.SetTrustedKeys(nil)
+	check("подписанный доверенным ключом", read(signed.Bytes()), nil)
+	check("без подписи", read(unsigned.Bytes()), binstmt.ErrGNXUnsigned)
+	check("измененный", read(tampered), binstmt.ErrGNXSignature)
+	binstmt.SetTrustedKeys([]ed25519.PublicKey{other})
+	check("чужой ключ", read(signed.Bytes()), binstmt.ErrGNXUntrusted)
+
+	// ЗагрузитьИВыполнить загружает только подписанный код
+	binstmt.SetTrustedKeys(trustedKeys)
+	files := map[string][]byte{
+		"signed.gnx":   signed.Bytes(),
+		"unsigned.gnx": unsigned.Bytes(),
+		"source.gnc":   []byte("Сообщить(1)\n"),
+	}
+	for name, data := range files {
+		ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+	}
+	for name, want := range map[string]error{
+		"signed.gnx":   nil,
+		"unsigned.gnx": binstmt.ErrGNXUnsigned,
+		"source.gnc":   binstmt.ErrSourceDenied,
+	} {
+		env := core.NewEnv()
+		env.SetStdOut(ioutil.Discard)
+		_, err := Run(compileGNX(t, "ЗагрузитьИВыполнить(\""+filepath.Join(dir, name)+"\")\n"), env)
+		if (want == nil) != (err == nil) || want != nil && !strings.Contains(err.Error(), want.Error()) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestBindCache(t *testing.T) {
+	code := compileGNX(t, "перем1 = 2\nперем2 = перем1 * 3\nВозврат перем2\n")
+	env := core.NewEnv()
+	// идентификаторы кода в таблице интерпретатора отличаются от таблицы кода
+	env.Names().Set("другое1")
+	env.Names().Set("другое2")
+
+	res, err := code.Bind(env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &res.Code[0] == &code.Code[0] {
+		t.Fatal("код не скопирован")
+	}
+	again, err := code.Bind(env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &again.Code[0] != &res.Code[0] || again.Names() != env.Names() {
+		t.Error("копия кода для той же таблицы имен создана заново")
+	}
+	other, err := code.Bind(core.NewEnv().Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &other.Code[0] == &res.Code[0] {
+		t.Error("копия кода использована для другой таблицы имен")
+	}
+
+	for i := 0; i < 3; i++ {
+		// возврат из кода модуля завершает исполнение с ошибкой ReturnError, как в gonec.Interpreter
+		v, err := Run(code, env)
+		if err != binstmt.ReturnError || v != core.VMInt(6) {
+			t.Errorf("запуск %d: %v, %v", i, v, err)
+		}
+	}
+}
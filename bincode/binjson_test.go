@@ -0,0 +1,106 @@
+package bincode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const jsonSrc = `з = {}
+з.Имя = "<Товар>"
+з.Цена = 10.50
+з.Количество = 3
+з.Коды = [1, "два", Истина]
+Сообщить(ЗаписатьJSON(з, {"ПорядокКлючей": ["Имя", "Цена"]}))
+Сообщить(ЗаписатьJSON(з.Коды, {"Отступ": 2}))
+Сообщить(ЗаписатьJSON(з.Имя, {"ЭкранироватьHTML": Истина}))
+
+д = ПрочитатьJSON("{\"а\": [1, 2.5, {\"б\": \"2024-01-02T03:04:05Z\"}], \"в\": null}")
+Сообщить(ТипЗнч(д.а[0]), ТипЗнч(д.а[1]), ТипЗнч(д.а[2].б), д.в)
+д = ПрочитатьJSON(ДвоичныеДанные("[1, 2]"), {"ТипЧисел": "Число"})
+Сообщить(ТипЗнч(д[0]))
+д = ПрочитатьJSON("{\"б\": \"2024-01-02T03:04:05Z\"}", {"ЧитатьДаты": Истина})
+Сообщить(ТипЗнч(д.б), д.б.Год())
+
+попытка
+	ПрочитатьJSON("{\"а\": [1, 2}")
+исключение
+	Сообщить("ошибка:", ОписаниеОшибки())
+конецпопытки
+
+ч = Новый ЧтениеJSON(" [{\"н\": 1}, {\"н\": 2}, 3]")
+для сч = 1 по 5 цикл
+	эл, есть = ч.Следующий()
+	если не есть тогда
+		прервать
+	конецесли
+	Сообщить(ЗаписатьJSON(эл))
+конеццикла
+ч = Новый ЧтениеJSON(ДвоичныеДанные("{\"н\": 1}"+"\n"+"{\"н\": 2}"))
+эл, есть = ч.Следующий()
+Сообщить(эл.н, есть)
+эл, есть = ч.Следующий()
+Сообщить(эл.н, есть)
+эл, есть = ч.Следующий()
+Сообщить(эл, есть)
+
+д = ПрочитатьJSON("{\"а\": [{\"б\": 1}, {\"б\": 2, \"ключ с пробелом\": \"в\"}]}")
+Сообщить(ПолучитьПоПути(д, "$.а[0].б"), ПолучитьПоПути(д, "$.а[-1]['ключ с пробелом']"), ПолучитьПоПути(д, "$.а[5].б"))
+Сообщить(ПолучитьПоПути(д, "$.а[*].б"), ПолучитьПоПути(д, "а[1].*"))
+`
+
+func TestJSON(t *testing.T) {
+	var out bytes.Buffer
+	env := core.NewEnv()
+	env.SetStdOut(&out)
+	_, bins, err := ParseSrc(jsonSrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Имя":"<Товар>","Цена":10.50,"Коды":[1,"два",true],"Количество":3}
+[
+  1,
+  "два",
+  true
+]
+"\u003cТовар\u003e"
+целоечисло число строка Неопределено
+число
+дата 2024
+ошибка: [18:2] Ошибка чтения JSON в позиции 13: invalid character '}' after array element
+{"н":1}
+{"н":2}
+3
+1 true
+2 true
+Неопределено false
+1 в Неопределено
+[1,2] [2,"в"]
+`
+	if out.String() != want {
+		t.Errorf("вывод:\n%s\nожидается:\n%s", out.String(), want)
+	}
+
+	for src, msg := range map[string]string{
+		`р = ПрочитатьJSON("[1] 2")`:                        "лишние данные",
+		`р = ПрочитатьJSON("[1.5]", {"ТипЧисел": "Целое"})`: "не является целым",
+		`р = ПрочитатьJSON("[1", {"ТипЧисел": "Дробь"})`:    "ТипЧисел",
+		`р = ПрочитатьJSON("[1")`:                           "неожиданный конец",
+		`р = ЗаписатьJSON({"ф": Сообщить})`:                 "не может быть записано в JSON",
+		`р = ПолучитьПоПути({}, "$.а[")`:                    "Неверный путь",
+		`р = Новый ЧтениеJSON(1)`:                           core.VMErrorNeedBinaryData.Error(),
+	} {
+		_, bins, err := ParseSrc(src, env.Names())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Run(bins, env); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: ошибка %v, ожидается %q", src, err, msg)
+		}
+	}
+}
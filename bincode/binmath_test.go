@@ -0,0 +1,84 @@
+package bincode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const mathSrc = `Сообщить(Макс(3, 7.5, -1), Мин(3, 7.5, -1), Макс([2, 9, 4]), Мин("б", "а"), Макс(Дата("2020-01-01"), Дата("2021-01-01")).Год())
+Сообщить(Цел(7), Цел(-7.9), Цел(7.9), Abs(-5), Abs(-2.5))
+Сообщить(Sqrt(2), Sqrt(16), Sqrt(0.0001))
+Сообщить(Pow(2, 10), Pow(2, -2), Pow(1.1, 10), Pow(2, 0.5), Pow(3, 40), 2.0 ** 0.5)
+Сообщить(Exp(1), Log(10), Log10(1000), Log10(0.01), Exp(0))
+Сообщить(Pi(), Sin(Pi() / 6), Cos(0), Tan(1), Sin(100))
+Сообщить(ASin(1), ACos(0.5), ATan(1) * 4, ATan(-10000000000))
+
+г = Новый ГенераторСлучайныхЧисел(42)
+г2 = Новый ГенераторСлучайныхЧисел(42)
+а = []
+для н = 1 по 5 цикл
+	а += г.СлучайноеЧисло(1, 6)
+	если а[н-1] <> г2.СлучайноеЧисло(1, 6) тогда
+		ВызватьИсключение("последовательности с одним зерном различаются")
+	конецесли
+конеццикла
+м = [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+г.Перемешать(м)
+в = г.Выборка(м, 3)
+к = Новый ГенераторСлучайныхЧисел
+э = к.СлучайныйЭлемент(м)
+д = к.СлучайноеДробное()
+ч = СлучайноеЧисло(-3, 3)
+Сообщить(Длина(а), Длина(м), Длина(в), Мин(м), Макс(м), в[0] <> в[1] и в[1] <> в[2] и в[0] <> в[2], э >= 1 и э <= 10, д >= 0 и д < 1, ч >= -3 и ч <= 3)
+`
+
+func TestMath(t *testing.T) {
+	var out bytes.Buffer
+	env := core.NewEnv()
+	env.SetStdOut(&out)
+	_, bins, err := ParseSrc(mathSrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil {
+		t.Fatal(err)
+	}
+	want := `7.5 -1 9 а 2021
+7 -7 7 5 2.5
+1.41421356237309504880168872421 4 0.01
+1024 0.25 2.5937424601 1.41421356237309504880168872421 12157665459056928801 1.41421356237309504880168872421
+2.71828182845904523536028747135 2.30258509299404568401799145468 3 -2 1
+3.14159265358979323846264338328 0.5 1 1.55740772465490223050697480746 -0.50636564110975879365655761046
+1.57079632679489661923132169164 1.04719755119659774615421446109 3.14159265358979323846264338328 -1.57079632669489661923132169164
+5 10 3 1 10 true true true true
+`
+	if out.String() != want {
+		t.Errorf("вывод:\n%s\nожидается:\n%s", out.String(), want)
+	}
+
+	domain := core.VMErrorMathDomain.Error()
+	for src, msg := range map[string]string{
+		`р = Sqrt(-1)`:             domain,
+		`р = Log(0)`:               domain,
+		`р = ASin(1.5)`:            domain,
+		`р = Pow(0, -1)`:           domain,
+		`р = (-8.0) ** 0.5`:        domain,
+		`р = Exp(20000)`:           core.VMErrorMathOverflow.Error(),
+		`р = Sin("1")`:             core.VMErrorNeedDecNum.Error(),
+		`р = Макс()`:               core.VMErrorNeedLength.Error(),
+		`р = Макс(1, "а")`:         core.VMErrorIncorrectOperation.Error(),
+		`р = СлучайноеЧисло(5, 1)`: core.VMErrorNeedLess.Error(),
+		`р = Новый ГенераторСлучайныхЧисел(1); р.Выборка([1, 2], 3)`: core.VMErrorIndexOutOfBoundary.Error(),
+	} {
+		_, bins, err := ParseSrc(src, env.Names())
+		if err != nil {
+			t.Fatal(src, err)
+		}
+		if _, err := Run(bins, env); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: ошибка %v, ожидается %q", src, err, msg)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package bincode
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"time"
+)
+
+// profileBuilder записывает профиль в формате protobuf, который читает go tool pprof
+// (github.com/google/pprof/proto/profile.proto), сжатый gzip
+type profileBuilder struct {
+	types      [][2]string // тип и единица измерения значений замеров
+	periodType [2]string
+	period     int64
+	start      time.Time
+	duration   time.Duration
+
+	pb      protobuf
+	strings map[string]int64
+	strtab  []string
+	funcs   map[profFunc]uint64
+	locs    map[profLoc]uint64
+}
+
+// поля сообщения Profile
+const (
+	tagProfileSampleType    = 1
+	tagProfileSample        = 2
+	tagProfileLocation      = 4
+	tagProfileFunction      = 5
+	tagProfileStringTable   = 6
+	tagProfileTimeNanos     = 9
+	tagProfileDurationNanos = 10
+	tagProfilePeriodType    = 11
+	tagProfilePeriod        = 12
+
+	tagValueTypeType = 1
+	tagValueTypeUnit = 2
+
+	tagSampleLocation = 1
+	tagSampleValue    = 2
+
+	tagLocationID   = 1
+	tagLocationLine = 4
+
+	tagLineFunction = 1
+	tagLineLine     = 2
+
+	tagFunctionID         = 1
+	tagFunctionName       = 2
+	tagFunctionSystemName = 3
+	tagFunctionFilename   = 4
+	tagFunctionStartLine  = 5
+)
+
+func (b *profileBuilder) write(w io.Writer, samples map[string]*profSample) error {
+	b.strings = map[string]int64{"": 0}
+	b.strtab = []string{""}
+	b.funcs = make(map[profFunc]uint64)
+	b.locs = make(map[profLoc]uint64)
+
+	for _, t := range b.types {
+		b.valueType(tagProfileSampleType, t)
+	}
+
+	// порядок замеров не важен для pprof, но сортировка дает одинаковый результат для одинаковых данных
+	keys := make([]string, 0, len(samples))
+	for k := range samples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := samples[k]
+		ids := make([]uint64, len(s.stack))
+		for i, l := range s.stack {
+			ids[i] = b.location(l)
+		}
+		b.pb.message(tagProfileSample, func(pb *protobuf) {
+			pb.packedUint64(tagSampleLocation, ids)
+			pb.packedInt64(tagSampleValue, s.values)
+		})
+	}
+
+	b.pb.int64(tagProfileTimeNanos, b.start.UnixNano())
+	b.pb.int64(tagProfileDurationNanos, int64(b.duration))
+	b.valueType(tagProfilePeriodType, b.periodType)
+	b.pb.int64(tagProfilePeriod, b.period)
+
+	// таблица строк пишется последней, когда все строки уже известны
+	for _, s := range b.strtab {
+		b.pb.string(tagProfileStringTable, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.pb.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (b *profileBuilder) str(s string) int64 {
+	if i, ok := b.strings[s]; ok {
+		return i
+	}
+	i := int64(len(b.strtab))
+	b.strings[s] = i
+	b.strtab = append(b.strtab, s)
+	return i
+}
+
+func (b *profileBuilder) valueType(tag int, t [2]string) {
+	typ, unit := b.str(t[0]), b.str(t[1])
+	b.pb.message(tag, func(pb *protobuf) {
+		pb.int64(tagValueTypeType, typ)
+		pb.int64(tagValueTypeUnit, unit)
+	})
+}
+
+func (b *profileBuilder) function(f profFunc) uint64 {
+	if id, ok := b.funcs[f]; ok {
+		return id
+	}
+	id := uint64(len(b.funcs) + 1)
+	b.funcs[f] = id
+	name, file := b.str(f.name), b.str(f.file)
+	b.pb.message(tagProfileFunction, func(pb *protobuf) {
+		pb.uint64(tagFunctionID, id)
+		pb.int64(tagFunctionName, name)
+		pb.int64(tagFunctionSystemName, name)
+		pb.int64(tagFunctionFilename, file)
+		pb.int64(tagFunctionStartLine, int64(f.start))
+	})
+	return id
+}
+
+func (b *profileBuilder) location(l profLoc) uint64 {
+	if id, ok := b.locs[l]; ok {
+		return id
+	}
+	fid := b.function(l.fn)
+	id := uint64(len(b.locs) + 1)
+	b.locs[l] = id
+	b.pb.message(tagProfileLocation, func(pb *protobuf) {
+		pb.uint64(tagLocationID, id)
+		pb.message(tagLocationLine, func(pb *protobuf) {
+			pb.uint64(tagLineFunction, fid)
+			pb.int64(tagLineLine, int64(l.line))
+		})
+	})
+	return id
+}
+
+// protobuf - минимальный кодировщик сообщений protobuf
+type protobuf struct {
+	data []byte
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+// key записывает номер поля и тип значения: 0 - varint, 2 - данные с длиной
+func (b *protobuf) key(tag int, typ int) {
+	b.varint(uint64(tag)<<3 | uint64(typ))
+}
+
+func (b *protobuf) uint64(tag int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(tag, 0)
+	b.varint(x)
+}
+
+func (b *protobuf) int64(tag int, x int64) {
+	b.uint64(tag, uint64(x))
+}
+
+func (b *protobuf) string(tag int, s string) {
+	b.key(tag, 2)
+	b.varint(uint64(len(s)))
+	b.data = append(b.data, s...)
+}
+
+func (b *protobuf) packedUint64(tag int, xs []uint64) {
+	b.message(tag, func(pb *protobuf) {
+		for _, x := range xs {
+			pb.varint(x)
+		}
+	})
+}
+
+func (b *protobuf) packedInt64(tag int, xs []int64) {
+	b.message(tag, func(pb *protobuf) {
+		for _, x := range xs {
+			pb.varint(uint64(x))
+		}
+	})
+}
+
+func (b *protobuf) message(tag int, f func(*protobuf)) {
+	var pb protobuf
+	f(&pb)
+	b.key(tag, 2)
+	b.varint(uint64(len(pb.data)))
+	b.data = append(b.data, pb.data...)
+}
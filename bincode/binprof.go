@@ -0,0 +1,325 @@
+package bincode
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/covrom/gonec/core"
+)
+
+// ProfileOptions - параметры профилирования исходного кода на языке Гонец
+type ProfileOptions struct {
+	CPU      io.Writer // профиль исполнения по строкам исходного кода в формате pprof, nil - не собирается
+	Alloc    io.Writer // количество созданных массивов и структур по строкам исходного кода, nil - не собирается
+	Filename string    // имя файла основного модуля, подставляется в профиль
+	Rate     int       // количество замеров в секунду, по умолчанию 100
+}
+
+var (
+	ErrProfileRunning    = errors.New("Профилирование уже запущено")
+	ErrProfileNotRunning = errors.New("Профилирование не запущено")
+)
+
+const (
+	defaultProfileRate = 100
+	mainModuleName     = "[главный модуль]"
+	anonFuncName       = "[анонимная функция]"
+)
+
+var (
+	profMu     sync.Mutex // запуск и остановка профилирования
+	activeProf atomic.Value
+)
+
+// StartProfile запускает профилирование кода, исполняемого вирт. машиной во всех окружениях.
+// С заданной частотой запоминаются текущие строки исходного кода и стеки вызовов функций Гонец
+// всех горутин, которые исполняли инструкции после предыдущего замера
+// (горутины, ожидающие канал, паузу или сеть, не учитываются).
+// Результат записывается при вызове StopProfile
+func StartProfile(opts ProfileOptions) error {
+	profMu.Lock()
+	defer profMu.Unlock()
+	if currentProfiler() != nil {
+		return ErrProfileRunning
+	}
+	if opts.Rate <= 0 {
+		opts.Rate = defaultProfileRate
+	}
+	p := &profiler{
+		opts:    opts,
+		period:  time.Second / time.Duration(opts.Rate),
+		start:   time.Now(),
+		threads: make(map[*profThread]struct{}),
+		cpu:     make(map[string]*profSample),
+		alloc:   make(map[string]*profSample),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	activeProf.Store(p)
+	if opts.CPU != nil {
+		go p.sampling()
+	} else {
+		close(p.done)
+	}
+	return nil
+}
+
+// StopProfile останавливает профилирование и записывает собранные профили
+func StopProfile() error {
+	profMu.Lock()
+	defer profMu.Unlock()
+	p := currentProfiler()
+	if p == nil {
+		return ErrProfileNotRunning
+	}
+	activeProf.Store((*profiler)(nil))
+	close(p.stop)
+	<-p.done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	dur := time.Since(p.start)
+	if p.opts.CPU != nil {
+		pb := &profileBuilder{
+			types:      [][2]string{{"samples", "count"}, {"cpu", "nanoseconds"}},
+			periodType: [2]string{"cpu", "nanoseconds"},
+			period:     int64(p.period),
+			start:      p.start,
+			duration:   dur,
+		}
+		if err := pb.write(p.opts.CPU, p.cpu); err != nil {
+			return err
+		}
+	}
+	if p.opts.Alloc != nil {
+		pb := &profileBuilder{
+			types:      [][2]string{{"alloc_objects", "count"}},
+			periodType: [2]string{"alloc_objects", "count"},
+			period:     1,
+			start:      p.start,
+			duration:   dur,
+		}
+		if err := pb.write(p.opts.Alloc, p.alloc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func currentProfiler() *profiler {
+	p, _ := activeProf.Load().(*profiler)
+	return p
+}
+
+type profiler struct {
+	opts   ProfileOptions
+	period time.Duration
+	start  time.Time
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu      sync.Mutex
+	threads map[*profThread]struct{} // горутины, исполняющие код Гонец
+	cpu     map[string]*profSample
+	alloc   map[string]*profSample
+}
+
+// profFunc - функция Гонец, в которой находится строка исходного кода
+type profFunc struct {
+	name  string
+	file  string
+	start int
+}
+
+// profLoc - строка исходного кода в функции
+type profLoc struct {
+	fn   profFunc
+	line int
+}
+
+type profSample struct {
+	stack  []profLoc // от текущей функции к вызывающим
+	values []int64
+}
+
+// profThread - стек вызовов функций Гонец одной горутины
+type profThread struct {
+	steps  uint64 // количество исполненных инструкций, изменяется атомарно (первое поле для выравнивания на 32-битных платформах)
+	seen   uint64 // значение steps при последнем замере, используется только при замерах
+	prof   *profiler
+	mu     sync.Mutex
+	frames []*profFrame
+}
+
+// profFrame - вызов функции Гонец
+type profFrame struct {
+	line int64 // текущая строка, изменяется атомарно
+	th   *profThread
+	fn   profFunc
+
+	env  *core.Env
+	prev interface{} // состояние профилировщика в env до вызова
+}
+
+// profEnter регистрирует вызов функции, код которой будет исполняться в окружении callee.
+// Стек вызовов продолжается от окружения caller, если caller равно nil или еще не профилируется -
+// начинается новый стек (например, в горутине). Если file пустой, берется файл вызывающей функции.
+// Возвращает nil, если профилирование не запущено
+func profEnter(caller, callee *core.Env, name, file string, start int) *profFrame {
+	p := currentProfiler()
+	if p == nil {
+		return nil
+	}
+	th, _ := caller.ProfState().(*profThread)
+	if th == nil || th.prof != p {
+		th = &profThread{prof: p}
+	}
+	fr := &profFrame{th: th, env: callee, prev: callee.ProfState()}
+	th.mu.Lock()
+	if file == "" {
+		if n := len(th.frames); n > 0 {
+			file = th.frames[n-1].fn.file
+		} else {
+			file = p.opts.Filename
+		}
+	}
+	fr.fn = profFunc{name: name, file: file, start: start}
+	fr.line = int64(start)
+	th.frames = append(th.frames, fr)
+	first := len(th.frames) == 1
+	th.mu.Unlock()
+	if first {
+		p.mu.Lock()
+		p.threads[th] = struct{}{}
+		p.mu.Unlock()
+	}
+	callee.SetProfState(th)
+	return fr
+}
+
+// leave завершает вызов, зарегистрированный в profEnter
+func (fr *profFrame) leave() {
+	if fr == nil {
+		return
+	}
+	th := fr.th
+	th.mu.Lock()
+	if n := len(th.frames); n > 0 && th.frames[n-1] == fr {
+		th.frames[n-1] = nil
+		th.frames = th.frames[:n-1]
+	}
+	last := len(th.frames) == 0
+	th.mu.Unlock()
+	if last {
+		th.prof.mu.Lock()
+		delete(th.prof.threads, th)
+		th.prof.mu.Unlock()
+	}
+	fr.env.SetProfState(fr.prev)
+}
+
+// profCurrent возвращает вызов, код которого исполняется в окружении env, или nil
+func profCurrent(env *core.Env) *profFrame {
+	if currentProfiler() == nil {
+		return nil
+	}
+	th, _ := env.ProfState().(*profThread)
+	if th == nil {
+		return nil
+	}
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	if n := len(th.frames); n > 0 {
+		return th.frames[n-1]
+	}
+	return nil
+}
+
+// step отмечает исполнение инструкции в строке line
+func (fr *profFrame) step(line int) {
+	atomic.AddUint64(&fr.th.steps, 1)
+	if line > 0 {
+		atomic.StoreInt64(&fr.line, int64(line))
+	}
+}
+
+// allocated учитывает создание массива или структуры в текущей строке
+func (fr *profFrame) allocated() {
+	p := fr.th.prof
+	if p.opts.Alloc == nil {
+		return
+	}
+	stack := fr.th.stack()
+	p.mu.Lock()
+	addSample(p.alloc, stack, 1)
+	p.mu.Unlock()
+}
+
+// stack возвращает текущий стек вызовов горутины, начиная с исполняемой функции
+func (th *profThread) stack() []profLoc {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	stack := make([]profLoc, len(th.frames))
+	for i, fr := range th.frames {
+		stack[len(stack)-1-i] = profLoc{fn: fr.fn, line: int(atomic.LoadInt64(&fr.line))}
+	}
+	return stack
+}
+
+func (p *profiler) sampling() {
+	defer close(p.done)
+	t := time.NewTicker(p.period)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-t.C:
+			p.sample()
+		}
+	}
+}
+
+func (p *profiler) sample() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for th := range p.threads {
+		steps := atomic.LoadUint64(&th.steps)
+		if steps == th.seen {
+			// горутина ожидает или исполняет системную функцию
+			continue
+		}
+		th.seen = steps
+		if stack := th.stack(); len(stack) > 0 {
+			addSample(p.cpu, stack, 1, int64(p.period))
+		}
+	}
+}
+
+func addSample(m map[string]*profSample, stack []profLoc, values ...int64) {
+	var sb strings.Builder
+	for _, l := range stack {
+		sb.WriteString(l.fn.file)
+		sb.WriteByte(0)
+		sb.WriteString(l.fn.name)
+		sb.WriteByte(0)
+		sb.WriteString(strconv.Itoa(l.fn.start))
+		sb.WriteByte(0)
+		sb.WriteString(strconv.Itoa(l.line))
+		sb.WriteByte(0)
+	}
+	k := sb.String()
+	s, ok := m[k]
+	if !ok {
+		s = &profSample{stack: stack, values: make([]int64, len(values))}
+		m[k] = s
+	}
+	for i, v := range values {
+		s.values[i] += v
+	}
+}
@@ -0,0 +1,91 @@
+package bincode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const profSrc = `функция медленная(н)
+	с = 0
+	для к = 1 по н цикл
+		с = с + к * 2
+	конеццикла
+	возврат с
+конецфункции
+
+функция создать()
+	для к = 1 по 10 цикл
+		м = [к]
+		с = {"к": к}
+	конеццикла
+конецфункции
+
+создать()
+медленная(300000)
+`
+
+func TestProfile(t *testing.T) {
+	var cpu, alloc bytes.Buffer
+	if err := StartProfile(ProfileOptions{CPU: &cpu, Alloc: &alloc, Filename: "проф.gnc", Rate: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := StartProfile(ProfileOptions{}); err != ErrProfileRunning {
+		t.Errorf("повторный запуск: %v", err)
+	}
+
+	env := core.NewEnv()
+	_, bins, err := ParseSrc(profSrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := StopProfile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := StopProfile(); err != ErrProfileNotRunning {
+		t.Errorf("повторная остановка: %v", err)
+	}
+	if env.ProfState() != nil {
+		t.Error("состояние профилировщика осталось в окружении")
+	}
+
+	for name, buf := range map[string]*bytes.Buffer{"cpu": &cpu, "alloc": &alloc} {
+		zr, err := gzip.NewReader(buf)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		data, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := []string{"проф.gnc", mainModuleName}
+		if name == "cpu" {
+			want = append(want, "медленная", "nanoseconds")
+		} else {
+			want = append(want, "создать", "alloc_objects")
+		}
+		for _, s := range want {
+			if !bytes.Contains(data, []byte(s)) {
+				t.Errorf("%s: в профиле нет %q", name, s)
+			}
+		}
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	var pb protobuf
+	pb.uint64(1, 300)
+	pb.string(2, "аб")
+	pb.packedInt64(3, []int64{1, 2})
+	want := []byte{0x08, 0xac, 0x02, 0x12, 0x04, 0xd0, 0xb0, 0xd0, 0xb1, 0x1a, 0x02, 0x01, 0x02}
+	if !bytes.Equal(pb.data, want) {
+		t.Errorf("получено % x, ожидалось % x", pb.data, want)
+	}
+}
@@ -0,0 +1,64 @@
+package bincode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const regExpSrc = `р = Новый РегулярноеВыражение("(?P<year>\\d{4})-(\\d\\d)")
+Сообщить(р.Совпадает("дата 2024-05"), р.Совпадает("нет даты"))
+для каждого м из р.НайтиВсе("2024-05 и 1999-12") цикл
+	Сообщить(м["Текст"], м["Позиция"], м["Группы"], м["ИменованныеГруппы"]["year"])
+конеццикла
+Сообщить(р.Заменить("2024-05", "$2.$1"))
+Функция Год(м)
+	Возврат "<" + м["ИменованныеГруппы"]["year"] + ">"
+КонецФункции
+Сообщить(р.Заменить("с 2024-05 по 1999-12", Год))
+Сообщить(Новый РегулярноеВыражение("\\s*,\\s*").Разделить("а , б,в"))
+Сообщить(СтрСовпадает("abc", "^a.c$"), СтрЗаменитьРег("a1b22", "\\d+", "#"))
+`
+
+func TestRegExp(t *testing.T) {
+	var out bytes.Buffer
+	env := core.NewEnv()
+	env.SetStdOut(&out)
+	_, bins, err := ParseSrc(regExpSrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil {
+		t.Fatal(err)
+	}
+	want := `true false
+2024-05 0 ["2024","05"] 2024
+1999-12 11 ["1999","12"] 1999
+05.2024
+с <2024> по <1999>
+["а","б","в"]
+true a#b#
+`
+	if out.String() != want {
+		t.Errorf("вывод:\n%s\nожидается:\n%s", out.String(), want)
+	}
+
+	// ошибки шаблона и параметров конструктора
+	for src, msg := range map[string]string{
+		`р = Новый РегулярноеВыражение("(")`:       "Неверное регулярное выражение",
+		`р = Новый РегулярноеВыражение(1)`:         core.VMErrorNeedString.Error(),
+		`р = Новый Сервер("tcp")`:                  "Тип не принимает параметры при создании",
+		`р = СтрЗаменитьРег("а", "а", 1)`:          "Замена должна быть строкой или функцией",
+		`р = (Новый РегулярноеВыражение).Шаблон()`: core.VMErrorNoRegExp.Error(),
+	} {
+		_, bins, err := ParseSrc(src, env.Names())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Run(bins, env); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: ошибка %v, ожидается %q", src, err, msg)
+		}
+	}
+}
@@ -1,16 +1,23 @@
 package binstmt
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
+	"crypto/ed25519"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/covrom/gonec/core"
 	"github.com/covrom/gonec/names"
 	"github.com/covrom/gonec/pos"
+	"github.com/covrom/gonec/version"
 )
 
 type BinStmt interface {
@@ -33,16 +40,181 @@ func (x *BinStmts) Append(bs BinStmt) {
 	*x = append(*x, bs)
 }
 
+// NameStringer реализуется инструкциями, которые содержат идентификаторы имен
+type NameStringer interface {
+	StringNames(*names.EnvNames) string // представление инструкции с названиями из таблицы имен
+}
+
+// nameOf возвращает название по идентификатору, если таблица имен неизвестна - то сам идентификатор
+func nameOf(nm *names.EnvNames, id int) string {
+	if nm != nil {
+		if _, ok := nm.GetLowerCaseOk(id); ok {
+			return nm.Get(id)
+		}
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
 type BinCode struct {
 	Code   BinStmts
 	MaxReg int
-	Labels []int //индекс - это номер метки, значение = индекс stmt в Code
+	Labels []int     //индекс - это номер метки, значение = индекс stmt в Code
+	File   string    // имя файла исходного кода для профилирования, покрытия и сообщений об ошибках, пустое - файл вызывающего кода
+	Source SourceMap // карта исходного кода, сохраняется в .gnx, см. WithSourceMap
+
+	names *names.EnvNames // таблица имен, в которой зарегистрированы идентификаторы кода
+	bound *bindCache      // копии кода для других таблиц имен, общие для всех копий BinCode
+}
+
+// bindCacheSize - сколько копий кода для разных таблиц имен хранится в bindCache
+const bindCacheSize = 16
+
+// bindCache хранит инструкции кода, идентификаторы которых заменены для таблицы имен интерпретатора, см. Bind
+type bindCache struct {
+	mu   sync.Mutex
+	code map[bindKey]BinStmts
+}
+
+type bindKey struct {
+	nm   *names.EnvNames
+	code *BinStmt // первая инструкция исходного кода, замененный код привязывается заново
+}
+
+// SrcPos - позиция инструкции в исходном коде, File - индекс файла в SourceMap.Files
+type SrcPos struct {
+	File   int
+	Line   int
+	Column int
+}
+
+// SourceMap - карта исходного кода: для каждой инструкции кода файл и позиция, из которых она скомпилирована
+type SourceMap struct {
+	Files []string
+	Pos   []SrcPos // индекс - номер инструкции в Code
+}
+
+// Lookup возвращает файл и позицию инструкции idx, ok=false - для инструкции нет записи в карте
+func (m *SourceMap) Lookup(idx int) (file string, p pos.Position, ok bool) {
+	if m == nil || idx < 0 || idx >= len(m.Pos) {
+		return "", p, false
+	}
+	sp := m.Pos[idx]
+	if sp.File < 0 || sp.File >= len(m.Files) {
+		return "", p, false
+	}
+	return m.Files[sp.File], pos.Position{Line: sp.Line, Column: sp.Column}, true
+}
+
+// WithSourceMap возвращает код с картой исходного кода, построенной по позициям инструкций и File.
+// Вложенные модули получают свои карты, модули без имени файла относятся к файлу кода.
+// Если карта уже есть (например, код загружен из .gnx), код возвращается без изменений
+func (v BinCode) WithSourceMap() BinCode {
+	if len(v.Source.Pos) == len(v.Code) {
+		return v
+	}
+	v.Source = SourceMap{Files: []string{v.File}, Pos: make([]SrcPos, len(v.Code))}
+	code := make(BinStmts, len(v.Code))
+	for i, st := range v.Code {
+		p := st.Position()
+		v.Source.Pos[i] = SrcPos{Line: p.Line, Column: p.Column}
+		if m, ok := st.(*BinMODULE); ok {
+			// исходная инструкция не меняется, код может исполняться параллельно
+			mm := *m
+			if mm.Code.File == "" {
+				mm.Code.File = v.File
+			}
+			mm.Code = mm.Code.WithSourceMap()
+			st = &mm
+		}
+		code[i] = st
+	}
+	v.Code = code
+	return v
+}
+
+// Names возвращает таблицу имен, в которой зарегистрированы идентификаторы кода
+func (v BinCode) Names() *names.EnvNames {
+	return v.names
+}
+
+// SetNames устанавливает таблицу имен, в которой были зарегистрированы идентификаторы при компиляции
+func (v *BinCode) SetNames(nm *names.EnvNames) {
+	v.names = nm
+	v.bound = &bindCache{}
+}
+
+// Bind возвращает код, идентификаторы которого соответствуют таблице имен nm.
+// Названия из таблицы кода переносятся в nm, при расхождении идентификаторов возвращается измененная копия кода,
+// исходный код не меняется и может исполняться в других интерпретаторах.
+// Копия кода запоминается для nm, повторные вызовы с той же таблицей имен ее не создают заново
+func (v BinCode) Bind(nm *names.EnvNames) (BinCode, error) {
+	if v.names == nil || v.names == nm {
+		v.names = nm
+		return v, nil
+	}
+	if v.bound == nil || len(v.Code) == 0 {
+		return v.bind(nm)
+	}
+
+	key := bindKey{nm: nm, code: &v.Code[0]}
+	v.bound.mu.Lock()
+	defer v.bound.mu.Unlock()
+	if code, ok := v.bound.code[key]; ok {
+		// названия кода уже перенесены в nm, их идентификаторы не меняются
+		v.Code = code
+		v.names = nm
+		return v, nil
+	}
+	res, err := v.bind(nm)
+	if err != nil {
+		return res, err
+	}
+	if v.bound.code == nil || len(v.bound.code) >= bindCacheSize {
+		v.bound.code = make(map[bindKey]BinStmts)
+	}
+	v.bound.code[key] = res.Code
+	return res, nil
+}
+
+func (v BinCode) bind(nm *names.EnvNames) (BinCode, error) {
+	swapIdents := v.names.MergeInto(nm)
+	if len(swapIdents) == 0 {
+		v.names = nm
+		return v, nil
+	}
+
+	// копируем код через сериализацию, т.к. инструкции - это указатели
+	var res BinCode
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(gob.NewEncoder(pw).Encode(v))
+	}()
+	err := gob.NewDecoder(pr).Decode(&res)
+	pr.Close()
+	if err != nil {
+		return res, err
+	}
+
+	for _, st := range res.Code {
+		st.SwapId(swapIdents)
+	}
+	res.names = nm
+	return res, nil
 }
 
 func (v BinCode) String() string {
+	return v.StringNames(v.names)
+}
+
+// StringNames возвращает листинг кода с названиями из таблицы имен nm
+func (v BinCode) StringNames(nm *names.EnvNames) string {
 	s := ""
 	for _, e := range v.Code {
-		s += fmt.Sprintf("%v\n", e)
+		if ns, ok := e.(NameStringer); ok {
+			s += ns.StringNames(nm) + "\n"
+		} else {
+			s += fmt.Sprintf("%v\n", e)
+		}
 	}
 	return s
 }
@@ -57,8 +229,53 @@ func (v *BinCode) MapLabels(lastlabel int) {
 	}
 }
 
+// Формат файла .gnx: заголовок (gnxMagic, версия формата uint16, длина и строка версии интерпретатора,
+// с версии 2 - длина и подпись), затем сжатые gzip таблица имен и код в формате gob.
+// Файлы версии 0 (до появления заголовка) начинаются сразу с данных gzip и читаются без проверки версии
+const (
+	gnxMagic = "GONECGNX"
+
+	// GNXVersion - текущая версия формата .gnx
+	GNXVersion = 2
+)
+
+var (
+	ErrGNXFormat  = errors.New("Файл не является скомпилированным кодом Гонец (.gnx)")
+	ErrGNXVersion = errors.New("Файл .gnx создан более новой версией интерпретатора")
+)
+
+// GNXHeader - заголовок файла .gnx
+type GNXHeader struct {
+	Version     int               // версия формата, 0 - файл без заголовка
+	Interpreter string            // версия интерпретатора, которым скомпилирован код
+	PublicKey   ed25519.PublicKey // ключ, которым подписан код, nil - код не подписан
+	Signature   []byte
+
+	signed []byte // подписанная часть заголовка
+}
+
+// WriteBinCode записывает код в формате .gnx без подписи
 func WriteBinCode(w io.Writer, v BinCode) error {
-	zw := gzip.NewWriter(w)
+	return writeBinCode(w, v, nil)
+}
+
+// WriteSignedBinCode записывает код в формате .gnx с подписью ключом key.
+// Подпись охватывает заголовок, таблицу имен и код
+func WriteSignedBinCode(w io.Writer, v BinCode, key ed25519.PrivateKey) error {
+	if len(key) != ed25519.PrivateKeySize {
+		return ErrBadKey
+	}
+	return writeBinCode(w, v, key)
+}
+
+func writeBinCode(w io.Writer, v BinCode, key ed25519.PrivateKey) error {
+	hdr := make([]byte, 0, len(gnxMagic)+3+len(version.Version))
+	hdr = append(hdr, gnxMagic...)
+	hdr = append(hdr, byte(GNXVersion), byte(GNXVersion>>8), byte(len(version.Version)))
+	hdr = append(hdr, version.Version...)
+
+	var payload bytes.Buffer
+	zw := gzip.NewWriter(&payload)
 	zw.Name = "Gonec binary code"
 	zw.Comment = "Created with https://covrom.github.io/gonec/ by Roman TSovanyan rs@tsov.pro"
 	zw.ModTime = time.Now()
@@ -66,21 +283,107 @@ func WriteBinCode(w io.Writer, v BinCode) error {
 	enc := gob.NewEncoder(zw)
 
 	// так же сохраняем уникальные имена
-	if err := enc.Encode(*names.UniqueNames); err != nil {
+	nm := v.names
+	if nm == nil {
+		nm = names.NewEnvNames()
+	}
+	if err := enc.Encode(nm); err != nil {
 		return err
 	}
 
-	if err := enc.Encode(v); err != nil {
+	// карта исходного кода позволяет указывать в ошибках загруженного кода файлы .gnc
+	if err := enc.Encode(v.WithSourceMap()); err != nil {
 		return err
 	}
 
 	if err := zw.Close(); err != nil {
 		return err
 	}
+
+	// подпись: открытый ключ и подпись заголовка вместе с данными
+	sig := []byte{0}
+	if key != nil {
+		sig = append(sig, key.Public().(ed25519.PublicKey)...)
+		sig = append(sig, ed25519.Sign(key, signedMessage(hdr, payload.Bytes()))...)
+		sig[0] = byte(len(sig) - 1)
+	}
+
+	for _, b := range [][]byte{hdr, sig, payload.Bytes()} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// ReadGNXHeader читает заголовок файла .gnx. Возвращается reader, из которого читаются данные после заголовка
+func ReadGNXHeader(r io.Reader) (GNXHeader, io.Reader, error) {
+	var h GNXHeader
+	br := bufio.NewReader(r)
+	b, err := br.Peek(len(gnxMagic))
+	if len(b) >= 2 && b[0] == 0x1f && b[1] == 0x8b {
+		// версия 0 - данные gzip без заголовка
+		return h, br, nil
+	}
+	if err != nil || string(b) != gnxMagic {
+		return h, br, ErrGNXFormat
+	}
+	br.Discard(len(gnxMagic))
+	var vb [3]byte
+	if _, err := io.ReadFull(br, vb[:]); err != nil {
+		return h, br, ErrGNXFormat
+	}
+	h.Version = int(vb[0]) | int(vb[1])<<8
+	iv := make([]byte, vb[2])
+	if _, err := io.ReadFull(br, iv); err != nil {
+		return h, br, ErrGNXFormat
+	}
+	h.Interpreter = string(iv)
+	if h.Version > GNXVersion {
+		return h, br, ErrGNXVersion
+	}
+	h.signed = append(append([]byte(gnxMagic), vb[:]...), iv...)
+
+	if h.Version >= 2 {
+		n, err := br.ReadByte()
+		if err != nil {
+			return h, br, ErrGNXFormat
+		}
+		if n > 0 {
+			if int(n) != ed25519.PublicKeySize+ed25519.SignatureSize {
+				return h, br, ErrGNXFormat
+			}
+			sig := make([]byte, n)
+			if _, err := io.ReadFull(br, sig); err != nil {
+				return h, br, ErrGNXFormat
+			}
+			h.PublicKey = ed25519.PublicKey(sig[:ed25519.PublicKeySize])
+			h.Signature = sig[ed25519.PublicKeySize:]
+		}
+	}
+	return h, br, nil
+}
+
+// ReadBinCode читает код в формате .gnx и проверяет его, см. Verify.
+// Подпись кода проверяется, если она есть, а если заданы доверенные ключи (SetTrustedKeys) -
+// код без подписи доверенным ключом не загружается
 func ReadBinCode(r io.Reader) (res BinCode, err error) {
+	h, r, err := ReadGNXHeader(r)
+	if err != nil {
+		return res, err
+	}
+
+	if h.Signature != nil || SignatureRequired() {
+		payload, err := ioutil.ReadAll(r)
+		if err != nil {
+			return res, err
+		}
+		if err := h.verify(payload); err != nil {
+			return res, err
+		}
+		r = bytes.NewReader(payload)
+	}
+
 	zr, err := gzip.NewReader(r)
 	if err != nil {
 		return res, err
@@ -100,39 +403,13 @@ func ReadBinCode(r io.Reader) (res BinCode, err error) {
 		return res, err
 	}
 
-	// переносим загруженные имена в текущий контекст
-	// и заменяем идентификаторы в загружаемом коде в случае конфликта
-	swapIdents := make(map[int]int)
-
-	// log.Println(gnxNames)
-
-	for i, v := range gnxNames.Handlow {
-
-		// log.Printf("Проверяем %d, %q", i, v)
-
-		if vv, ok := names.UniqueNames.GetLowerCaseOk(i); ok {
-			// под тем же идентификатором находится другая строка, без учета регистра
-			if v != vv {
-				// новый id
-				ii := names.UniqueNames.Set(gnxNames.Handles[i])
-				swapIdents[i] = ii
-
-				// log.Printf("Заменяем %d на %d для загружаемого %q, уже есть %q\n", i, ii, v, vv)
-
-			}
-		} else {
-			// такого идентификатора еще нет - устанавливаем значение на него
-			// последующие идентификаторы names.UniqueNames будут идти после него
+	// идентификаторы загруженного кода соответствуют сохраненной таблице имен,
+	// перенос в таблицу интерпретатора выполняется при запуске, см. Bind
+	res.names = gnxNames
+	res.bound = &bindCache{}
 
-			// log.Printf("Устанавливаем %d для загружаемого %q\n", i, gnxNames.Handles[i])
-
-			names.UniqueNames.SetToId(gnxNames.Handles[i], i)
-		}
-	}
-
-	// заменяем идентификаторы, если при слиянии были конфликты
-	for _, v := range res.Code {
-		v.SwapId(swapIdents)
+	if err := res.Verify(); err != nil {
+		return res, err
 	}
 
 	return res, nil
@@ -237,8 +514,12 @@ func (v *BinLOAD) SwapId(m map[int]int) {
 }
 
 func (v BinLOAD) String() string {
+	return v.StringNames(nil)
+}
+
+func (v BinLOAD) StringNames(nm *names.EnvNames) string {
 	if v.IsId {
-		return fmt.Sprintf("LOAD r%d, %#v", v.Reg, names.UniqueNames.Get(int(v.Val.(core.VMInt))))
+		return fmt.Sprintf("LOAD r%d, %#v", v.Reg, nameOf(nm, int(v.Val.(core.VMInt))))
 	}
 	return fmt.Sprintf("LOAD r%d, %#v", v.Reg, v.Val)
 }
@@ -414,7 +695,11 @@ func (v *BinGET) SwapId(m map[int]int) {
 }
 
 func (v BinGET) String() string {
-	return fmt.Sprintf("GET r%d, %q", v.Reg, names.UniqueNames.Get(v.Id))
+	return v.StringNames(nil)
+}
+
+func (v BinGET) StringNames(nm *names.EnvNames) string {
+	return fmt.Sprintf("GET r%d, %q", v.Reg, nameOf(nm, v.Id))
 }
 
 func NewBinGET(reg, id int, e pos.Pos) *BinGET {
@@ -441,7 +726,11 @@ func (v *BinSET) SwapId(m map[int]int) {
 }
 
 func (v BinSET) String() string {
-	return fmt.Sprintf("SET %q, r%d", names.UniqueNames.Get(v.Id), v.Reg)
+	return v.StringNames(nil)
+}
+
+func (v BinSET) StringNames(nm *names.EnvNames) string {
+	return fmt.Sprintf("SET %q, r%d", nameOf(nm, v.Id), v.Reg)
 }
 
 func NewBinSET(reg, id int, e pos.Pos) *BinSET {
@@ -469,7 +758,11 @@ func (v *BinSETMEMBER) SwapId(m map[int]int) {
 }
 
 func (v BinSETMEMBER) String() string {
-	return fmt.Sprintf("SETMEMBER r%d.%q, r%d", v.Reg, names.UniqueNames.Get(v.Id), v.RegVal)
+	return v.StringNames(nil)
+}
+
+func (v BinSETMEMBER) StringNames(nm *names.EnvNames) string {
+	return fmt.Sprintf("SETMEMBER r%d.%q, r%d", v.Reg, nameOf(nm, v.Id), v.RegVal)
 }
 
 func NewBinSETMEMBER(reg, id, regv int, e pos.Pos) *BinSETMEMBER {
@@ -485,7 +778,7 @@ func NewBinSETMEMBER(reg, id, regv int, e pos.Pos) *BinSETMEMBER {
 type BinSETNAME struct {
 	BinStmtImpl
 
-	Reg int // регистр с именем (строкой), сюда же возвращается id имени, записанного в names.UniqueNames.Set()
+	Reg int // регистр с именем (строкой), сюда же возвращается id имени, записанного в таблицу имен окружения
 }
 
 func (v BinSETNAME) String() string {
@@ -584,7 +877,11 @@ func (v *BinADDRID) SwapId(m map[int]int) {
 }
 
 func (v BinADDRID) String() string {
-	return fmt.Sprintf("ADDRID r%d, %q", v.Reg, names.UniqueNames.Get(v.Name))
+	return v.StringNames(nil)
+}
+
+func (v BinADDRID) StringNames(nm *names.EnvNames) string {
+	return fmt.Sprintf("ADDRID r%d, %q", v.Reg, nameOf(nm, v.Name))
 }
 
 func NewBinADDRID(reg, name int, e pos.Pos) *BinADDRID {
@@ -610,7 +907,11 @@ func (v *BinADDRMBR) SwapId(m map[int]int) {
 }
 
 func (v BinADDRMBR) String() string {
-	return fmt.Sprintf("ADDRMBR r%d, r%d.%q", v.Reg, v.Reg, names.UniqueNames.Get(v.Name))
+	return v.StringNames(nil)
+}
+
+func (v BinADDRMBR) StringNames(nm *names.EnvNames) string {
+	return fmt.Sprintf("ADDRMBR r%d, r%d.%q", v.Reg, v.Reg, nameOf(nm, v.Name))
 }
 
 func NewBinADDRMBR(reg, name int, e pos.Pos) *BinADDRMBR {
@@ -636,7 +937,11 @@ func (v *BinUNREFID) SwapId(m map[int]int) {
 }
 
 func (v BinUNREFID) String() string {
-	return fmt.Sprintf("UNREFID r%d, %q", v.Reg, names.UniqueNames.Get(v.Name))
+	return v.StringNames(nil)
+}
+
+func (v BinUNREFID) StringNames(nm *names.EnvNames) string {
+	return fmt.Sprintf("UNREFID r%d, %q", v.Reg, nameOf(nm, v.Name))
 }
 
 func NewBinUNREFID(reg, name int, e pos.Pos) *BinUNREFID {
@@ -662,7 +967,11 @@ func (v *BinUNREFMBR) SwapId(m map[int]int) {
 }
 
 func (v BinUNREFMBR) String() string {
-	return fmt.Sprintf("UNREFMBR r%d, r%d.%q", v.Reg, v.Reg, names.UniqueNames.Get(v.Name))
+	return v.StringNames(nil)
+}
+
+func (v BinUNREFMBR) StringNames(nm *names.EnvNames) string {
+	return fmt.Sprintf("UNREFMBR r%d, r%d.%q", v.Reg, v.Reg, nameOf(nm, v.Name))
 }
 
 func NewBinUNREFMBR(reg, name int, e pos.Pos) *BinUNREFMBR {
@@ -775,7 +1084,7 @@ func NewBinOPER(regl, regr int, op core.VMOperation, e pos.Pos) *BinOPER {
 type BinCALL struct {
 	BinStmtImpl
 
-	Name int // либо вызов по имени из names.UniqueNames, если Name != 0
+	Name int // либо вызов по имени из таблицы имен, если Name != 0
 	// либо вызов обработчика (Name==0), напр. для анонимной функции
 	// (выражение типа func, или ссылка или интерфейс с ним, находится в reg, а параметры начиная с reg+1)
 	NumArgs int // число аргументов, которое надо взять на входе из массива (Reg)
@@ -801,10 +1110,14 @@ func (v *BinCALL) SwapId(m map[int]int) {
 }
 
 func (v BinCALL) String() string {
+	return v.StringNames(nil)
+}
+
+func (v BinCALL) StringNames(nm *names.EnvNames) string {
 	if v.Name == 0 {
 		return fmt.Sprintf("CALL REG r%d, ARGS r%d, ARGS_COUNT %d, VARARG %v, GO %v, RETURN r%d", v.RegArgs, v.RegArgs+1, v.NumArgs, v.VarArg, v.Go, v.RegRets)
 	}
-	return fmt.Sprintf("CALL %q, ARGS r%d, ARGS_COUNT %d, VARARG %v, GO %v, RETURN r%d", names.UniqueNames.Get(v.Name), v.RegArgs, v.NumArgs, v.VarArg, v.Go, v.RegRets)
+	return fmt.Sprintf("CALL %q, ARGS r%d, ARGS_COUNT %d, VARARG %v, GO %v, RETURN r%d", nameOf(nm, v.Name), v.RegArgs, v.NumArgs, v.VarArg, v.Go, v.RegRets)
 }
 
 func NewBinCALL(name, numargs, regargs, regrets int, vararg, isgo bool, e pos.Pos) *BinCALL {
@@ -835,7 +1148,11 @@ func (v *BinGETMEMBER) SwapId(m map[int]int) {
 }
 
 func (v BinGETMEMBER) String() string {
-	return fmt.Sprintf("GETMEMBER r%d, %q", v.Reg, names.UniqueNames.Get(v.Name))
+	return v.StringNames(nil)
+}
+
+func (v BinGETMEMBER) StringNames(nm *names.EnvNames) string {
+	return fmt.Sprintf("GETMEMBER r%d, %q", v.Reg, nameOf(nm, v.Name))
 }
 
 func NewBinGETMEMBER(reg, name int, e pos.Pos) *BinGETMEMBER {
@@ -916,18 +1233,22 @@ func (v *BinFUNC) SwapId(m map[int]int) {
 	}
 }
 func (v BinFUNC) String() string {
+	return v.StringNames(nil)
+}
+
+func (v BinFUNC) StringNames(nm *names.EnvNames) string {
 	s := ""
 	for _, a := range v.Args {
 		if s != "" {
 			s += ", "
 		}
-		s += names.UniqueNames.Get(a)
+		s += nameOf(nm, a)
 	}
 	vrg := ""
 	if v.VarArg {
 		vrg = "..."
 	}
-	return fmt.Sprintf("FUNC r%d, %q (%s%s) BEGIN L%d END L%d", v.Reg, names.UniqueNames.Get(v.Name), s, vrg, v.LabelStart, v.LabelEnd)
+	return fmt.Sprintf("FUNC r%d, %q (%s%s) BEGIN L%d END L%d", v.Reg, nameOf(nm, v.Name), s, vrg, v.LabelStart, v.LabelEnd)
 }
 
 func NewBinFUNC(reg, name int, args []int, vararg bool, lbeg, lend int, e pos.Pos) *BinFUNC {
@@ -966,16 +1287,21 @@ func NewBinCASTTYPE(reg, regtype int, e pos.Pos) *BinCASTTYPE {
 type BinMAKE struct {
 	BinStmtImpl
 
-	Reg int // здесь id типа, и сюда же пишем новое значение
+	Reg     int // здесь id типа, и сюда же пишем новое значение
+	NumArgs int // количество параметров конструктора в регистрах после Reg
 }
 
 func (v BinMAKE) String() string {
+	if v.NumArgs > 0 {
+		return fmt.Sprintf("MAKE r%d AS TYPE r%d, ARGS_COUNT %d", v.Reg, v.Reg, v.NumArgs)
+	}
 	return fmt.Sprintf("MAKE r%d AS TYPE r%d", v.Reg, v.Reg)
 }
 
-func NewBinMAKE(reg int, e pos.Pos) *BinMAKE {
+func NewBinMAKE(reg, numargs int, e pos.Pos) *BinMAKE {
 	v := &BinMAKE{
-		Reg: reg,
+		Reg:     reg,
+		NumArgs: numargs,
 	}
 	v.SetPosition(e.Position())
 	return v
@@ -1372,9 +1698,16 @@ func (v *BinMODULE) SwapId(m map[int]int) {
 		v.Name = newid
 		// log.Printf("Замена в %#v %v\n",v, v)
 	}
+	for _, st := range v.Code.Code {
+		st.SwapId(m)
+	}
 }
 func (v BinMODULE) String() string {
-	return fmt.Sprintf("MODULE %s\n{\n%v}\n", names.UniqueNames.Get(v.Name), v.Code)
+	return v.StringNames(nil)
+}
+
+func (v BinMODULE) StringNames(nm *names.EnvNames) string {
+	return fmt.Sprintf("MODULE %s\n{\n%s}\n", nameOf(nm, v.Name), v.Code.StringNames(nm))
 }
 
 func NewBinMODULE(name int, code BinCode, e pos.Pos) *BinMODULE {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/covrom/gonec/core"
 	posit "github.com/covrom/gonec/pos"
 )
 
@@ -12,13 +13,14 @@ import (
 type Error struct {
 	Message string
 	Pos     posit.Position
+	File    string // файл исходного кода, в котором возникла ошибка, пустое - неизвестен
 }
 
 var (
 	BreakError     = errors.New("Неверное применение оператора Прервать")
 	ContinueError  = errors.New("Неверное применение оператора Продолжить")
 	ReturnError    = errors.New("Неверное применение оператора Возврат")
-	InterruptError = errors.New("Выполнение прервано")
+	InterruptError = core.VMErrorInterrupted
 )
 
 // NewStringError makes error interface with message.
@@ -40,7 +42,7 @@ func NewError(pos posit.Pos, err error) error {
 	if err == nil {
 		return nil
 	}
-	if err == BreakError || err == ContinueError || err == ReturnError {
+	if err == BreakError || err == ContinueError || err == ReturnError || err == InterruptError {
 		return err
 	}
 	// if pe, ok := err.(*parser.Error); ok {
@@ -0,0 +1,167 @@
+package binstmt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrGNXUnsigned   = errors.New("Файл .gnx не подписан, а исполняется только код, подписанный доверенным ключом")
+	ErrGNXSignature  = errors.New("Подпись файла .gnx неверна: код изменен после подписания")
+	ErrGNXUntrusted  = errors.New("Файл .gnx подписан ключом, который не входит в доверенные")
+	ErrSourceDenied  = errors.New("Исполнение исходного кода запрещено: исполняется только код .gnx, подписанный доверенным ключом")
+	ErrBadKey        = errors.New("Неверный ключ Ed25519")
+	ErrNoTrustedKeys = errors.New("Не найдено ни одного доверенного ключа")
+)
+
+var (
+	trustMu sync.RWMutex
+	trusted []ed25519.PublicKey
+)
+
+// SetTrustedKeys задает открытые ключи, подпись одним из которых обязательна для загрузки кода .gnx.
+// Пока ключи заданы, загрузка кода без подписи и исполнение исходного текста запрещены, nil - проверка отключена
+func SetTrustedKeys(keys []ed25519.PublicKey) {
+	trustMu.Lock()
+	trusted = keys
+	trustMu.Unlock()
+}
+
+// SignatureRequired возвращает истину, если заданы доверенные ключи и исполняется только подписанный ими код
+func SignatureRequired() bool {
+	trustMu.RLock()
+	defer trustMu.RUnlock()
+	return len(trusted) > 0
+}
+
+func isTrusted(key ed25519.PublicKey) bool {
+	trustMu.RLock()
+	defer trustMu.RUnlock()
+	for _, k := range trusted {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// signedMessage - данные, которые подписываются: заголовок без подписи, таблица имен и код
+func signedMessage(hdr, payload []byte) []byte {
+	msg := make([]byte, 0, len(hdr)+len(payload))
+	return append(append(msg, hdr...), payload...)
+}
+
+// verify проверяет подпись данных payload, следующих за заголовком
+func (h GNXHeader) verify(payload []byte) error {
+	if h.Signature == nil {
+		return ErrGNXUnsigned
+	}
+	if !ed25519.Verify(h.PublicKey, signedMessage(h.signed, payload), h.Signature) {
+		return ErrGNXSignature
+	}
+	if SignatureRequired() && !isTrusted(h.PublicKey) {
+		return ErrGNXUntrusted
+	}
+	return nil
+}
+
+// ParsePrivateKey читает закрытый ключ Ed25519 в формате PEM (PKCS #8, как создает
+// openssl genpkey -algorithm ed25519)
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	for {
+		var b *pem.Block
+		b, data = pem.Decode(data)
+		if b == nil {
+			return nil, ErrBadKey
+		}
+		if b.Type != "PRIVATE KEY" {
+			continue
+		}
+		k, err := x509.ParsePKCS8PrivateKey(b.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := k.(ed25519.PrivateKey); ok {
+			return key, nil
+		}
+		return nil, ErrBadKey
+	}
+}
+
+// ParsePublicKeys читает открытые ключи Ed25519 в формате PEM (PKIX, как создает openssl pkey -pubout)
+func ParsePublicKeys(data []byte) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for {
+		var b *pem.Block
+		b, data = pem.Decode(data)
+		if b == nil {
+			return keys, nil
+		}
+		if b.Type != "PUBLIC KEY" {
+			continue
+		}
+		k, err := x509.ParsePKIXPublicKey(b.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(ed25519.PublicKey)
+		if !ok {
+			return nil, ErrBadKey
+		}
+		keys = append(keys, key)
+	}
+}
+
+// LoadPrivateKey читает закрытый ключ из файла fn, см. ParsePrivateKey
+func LoadPrivateKey(fn string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(data)
+}
+
+// LoadTrustedKeys читает открытые ключи из файла или из всех файлов .pem и .pub каталога path.
+// Если ключей не найдено, возвращается ErrNoTrustedKeys
+func LoadTrustedKeys(path string) ([]ed25519.PublicKey, error) {
+	files := []string{path}
+	if fi, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if fi.IsDir() {
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, fi := range infos {
+			ext := strings.ToLower(filepath.Ext(fi.Name()))
+			if !fi.IsDir() && (ext == ".pem" || ext == ".pub") {
+				files = append(files, filepath.Join(path, fi.Name()))
+			}
+		}
+	}
+	var keys []ed25519.PublicKey
+	for _, fn := range files {
+		data, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, err
+		}
+		k, err := ParsePublicKeys(data)
+		if err != nil {
+			return nil, errors.New(fn + ": " + err.Error())
+		}
+		keys = append(keys, k...)
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoTrustedKeys
+	}
+	return keys, nil
+}
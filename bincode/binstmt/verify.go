@@ -0,0 +1,191 @@
+package binstmt
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/names"
+)
+
+// VerifyError - ошибка проверки байткода
+type VerifyError struct {
+	Index   int     // индекс инструкции в коде модуля, -1 - ошибка относится к коду в целом
+	Stmt    BinStmt // инструкция, если она есть
+	Message string
+}
+
+func (e *VerifyError) Error() string {
+	if e.Index < 0 {
+		return "Байткод поврежден: " + e.Message
+	}
+	if e.Stmt == nil {
+		return fmt.Sprintf("Байткод поврежден: инструкция %04d: %s", e.Index, e.Message)
+	}
+	return fmt.Sprintf("Байткод поврежден: инструкция %04d %v: %s", e.Index, e.Stmt, e.Message)
+}
+
+// поля инструкций с номерами меток, остальные целые поля с префиксом Reg содержат номера регистров,
+// поля Id, Name и Args - идентификаторы из таблицы имен
+var labelFields = map[string]bool{
+	"JumpTo":        true,
+	"Label":         true,
+	"LabelStart":    true,
+	"LabelEnd":      true,
+	"BreakLabel":    true,
+	"ContinueLabel": true,
+	"CatchLabel":    true,
+}
+
+// Verify проверяет, что код можно исполнять: номера регистров не превышают MaxReg (в теле функции - MaxReg функции),
+// метки есть в Labels и указывают на инструкции кода, идентификаторы есть в таблице имен кода,
+// размеры и индексы литералов неотрицательны, карта исходного кода соответствует инструкциям.
+// Вложенные модули проверяются с их собственными регистрами и метками
+func (v BinCode) Verify() error {
+	return v.verify(v.names)
+}
+
+func (v BinCode) verify(nm *names.EnvNames) error {
+	if v.MaxReg < 0 {
+		return &VerifyError{Index: -1, Message: fmt.Sprintf("неверный максимальный регистр %d", v.MaxReg)}
+	}
+	for l, idx := range v.Labels {
+		if idx < 0 || idx > len(v.Code) {
+			return &VerifyError{Index: -1, Message: fmt.Sprintf("метка L%d указывает за пределы кода (%d)", l, idx)}
+		}
+	}
+	if n := len(v.Source.Pos); n != 0 && n != len(v.Code) {
+		return &VerifyError{Index: -1, Message: fmt.Sprintf("карта исходного кода содержит %d позиций для %d инструкций", n, len(v.Code))}
+	}
+	for i, sp := range v.Source.Pos {
+		if sp.File < 0 || sp.File >= len(v.Source.Files) {
+			return &VerifyError{Index: i, Message: fmt.Sprintf("нет файла #%d в карте исходного кода", sp.File)}
+		}
+	}
+	return v.verifyBlock(nm, 0, len(v.Code), v.MaxReg)
+}
+
+// verifyBlock проверяет инструкции с from по to, не включая to, регистры которых не превышают maxReg
+func (v BinCode) verifyBlock(nm *names.EnvNames, from, to, maxReg int) error {
+	for i := from; i < to; i++ {
+		st := v.Code[i]
+		if st == nil {
+			return &VerifyError{Index: i, Message: "пустая инструкция"}
+		}
+		if msg := v.verifyStmt(nm, st, maxReg); msg != "" {
+			return &VerifyError{Index: i, Stmt: st, Message: msg}
+		}
+		switch s := st.(type) {
+		case *BinFUNC:
+			// тело функции исполняется со своими регистрами
+			end := v.Labels[s.LabelEnd]
+			if end <= i || end > to || v.Labels[s.LabelStart] <= i || v.Labels[s.LabelStart] > end {
+				return &VerifyError{Index: i, Stmt: st, Message: "неверные границы тела функции"}
+			}
+			if s.MaxReg < 0 {
+				return &VerifyError{Index: i, Stmt: st, Message: fmt.Sprintf("неверный максимальный регистр %d", s.MaxReg)}
+			}
+			if s.VarArg && len(s.Args) != 1 {
+				return &VerifyError{Index: i, Stmt: st, Message: "переменное число аргументов передается в одном параметре"}
+			}
+			if err := v.verifyBlock(nm, i+1, end, s.MaxReg); err != nil {
+				return err
+			}
+			i = end - 1
+		case *BinMODULE:
+			if err := s.Code.verify(nm); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// verifyStmt возвращает описание ошибки в инструкции или пустую строку
+func (v BinCode) verifyStmt(nm *names.EnvNames, st BinStmt, maxReg int) string {
+	rv := reflect.ValueOf(st)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return "неизвестная инструкция"
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		fv := rv.Field(i)
+		switch {
+		case f.Name == "Args" && fv.Kind() == reflect.Slice:
+			for _, id := range fv.Interface().([]int) {
+				if !hasName(nm, id) {
+					return fmt.Sprintf("нет имени #%d в таблице имен", id)
+				}
+			}
+		case fv.Kind() != reflect.Int:
+		case labelFields[f.Name]:
+			if l := int(fv.Int()); l < 0 || l >= len(v.Labels) {
+				return fmt.Sprintf("нет метки L%d", l)
+			}
+		case strings.HasPrefix(f.Name, "Reg") || f.Name == "TypeReg":
+			if r := int(fv.Int()); r < 0 || r > maxReg {
+				return fmt.Sprintf("регистр r%d за пределами r0-r%d", r, maxReg)
+			}
+		case f.Name == "Id" || f.Name == "Name":
+			id := int(fv.Int())
+			if id == 0 && f.Name == "Name" {
+				// вызов значения из регистра или анонимная функция
+				if _, ok := st.(*BinMODULE); !ok {
+					continue
+				}
+			}
+			if !hasName(nm, id) {
+				return fmt.Sprintf("нет имени #%d в таблице имен", id)
+			}
+		}
+	}
+
+	switch s := st.(type) {
+	case *BinLOAD:
+		if s.IsId {
+			id, ok := s.Val.(core.VMInt)
+			if !ok || !hasName(nm, int(id)) {
+				return fmt.Sprintf("нет имени %v в таблице имен", s.Val)
+			}
+		}
+	case *BinCALL:
+		// аргументы берутся из регистров после RegArgs, при вызове значения в RegArgs находится функция
+		last := s.RegArgs + s.NumArgs - 1
+		if s.Name == 0 {
+			last++
+		}
+		if s.NumArgs < 0 || last > maxReg {
+			return fmt.Sprintf("аргументы вызова за пределами r0-r%d", maxReg)
+		}
+	case *BinMAKE:
+		if s.NumArgs < 0 || s.Reg+s.NumArgs > maxReg {
+			return fmt.Sprintf("параметры конструктора за пределами r0-r%d", maxReg)
+		}
+	// литерал не может содержать больше элементов, чем инструкций в коде
+	case *BinMAKESLICE:
+		if s.Len < 0 || s.Cap < s.Len || s.Cap > len(v.Code) {
+			return fmt.Sprintf("неверный размер массива %d (емкость %d)", s.Len, s.Cap)
+		}
+	case *BinMAKEMAP:
+		if s.Len < 0 || s.Len > len(v.Code) {
+			return fmt.Sprintf("неверный размер структуры %d", s.Len)
+		}
+	case *BinSETIDX:
+		if s.Index < 0 || s.Index >= len(v.Code) {
+			return fmt.Sprintf("неверный индекс элемента массива %d", s.Index)
+		}
+	}
+	return ""
+}
+
+// hasName проверяет, что идентификатор есть в таблице имен, без таблицы имен проверка не выполняется
+func hasName(nm *names.EnvNames, id int) bool {
+	if nm == nil {
+		return true
+	}
+	_, ok := nm.GetLowerCaseOk(id)
+	return ok && id > 0
+}
@@ -0,0 +1,76 @@
+package bincode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const strFuncSrc = `с = "Привет, мир! 😀"
+Сообщить(СтрДлина(с), СтрДлина(""), СтрДлина(12345))
+Сообщить("[" + СокрЛП(" \t а б \n") + "]", "[" + СокрЛ("  а ") + "]", "[" + СокрП("  а ") + "]")
+Сообщить(Лев(с, 6), Прав(с, 1), Прав(с, 100), "[" + Лев(с, 0) + "]", Лев(12345, 2))
+Сообщить(Сред(с, 9), Сред(с, 9, 3), Сред(с, 0, 2), "[" + Сред(с, 100) + "]", Сред(с, СтрДлина(с) / 2, 2))
+Сообщить(СтрНачинаетсяС(с, "Прив"), СтрНачинаетсяС(с, "мир"), СтрЗаканчиваетсяНа(с, "😀"), СтрЗаканчиваетсяНа(с, "!"))
+Сообщить(СтрРазделить("а,б;;в", ",;"), СтрРазделить("а,б;;в", ",;", Ложь), СтрРазделить("", ","), СтрРазделить("", ",", Ложь), СтрРазделить("а б", ""))
+Сообщить(СтрСоединить(["а", 1, Истина]), СтрСоединить(СтрРазделить("1 2 3", " "), ", "))
+Сообщить(СтрШаблон("%1 + %2 = %3, %10%%, %(10)", 2, 2, 4, Неопределено, 5, 6, 7, 8, 9, "десять"))
+Сообщить(СтрПовторить("ab", 3), "[" + СтрПовторить("ab", 0) + "]", Символ(1071), Символ(128512), КодСимвола(Символ(10)))
+Сообщить(ТРег("иВАН иванович-петров 2дом"))
+т = "первая\r\nвторая\n\nчетвертая"
+Сообщить(СтрЧислоСтрок(т), СтрЧислоСтрок(""), СтрПолучитьСтроку(т, 1) + "|", СтрПолучитьСтроку(т, 4), "[" + СтрПолучитьСтроку(т, 5) + "]")
+Сообщить(ПустаяСтрока(""), ПустаяСтрока(" \t\n"), ПустаяСтрока(" а "))
+Сообщить(СтрСравнить("Ёж", "ёж"), СтрСравнить("а", "Б"), СтрСравнить("в", "Б"))
+`
+
+func TestStrFuncs(t *testing.T) {
+	var out bytes.Buffer
+	env := core.NewEnv()
+	env.SetStdOut(&out)
+	_, bins, err := ParseSrc(strFuncSrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil {
+		t.Fatal(err)
+	}
+	want := `14 0 5
+[а б] [а ] [  а]
+Привет 😀 Привет, мир! 😀 [] 12
+мир! 😀 мир Пр [] , 
+true false true false
+["а","б","","в"] ["а","б","в"] [""] [] ["а б"]
+а1true 1, 2, 3
+2 + 2 = 4, 20%, десять
+ababab [] Я 😀 10
+Иван Иванович-Петров 2дом
+4 1 первая| четвертая []
+true true false
+0 -1 1
+`
+	if out.String() != want {
+		t.Errorf("вывод:\n%s\nожидается:\n%s", out.String(), want)
+	}
+
+	for src, msg := range map[string]string{
+		`р = Лев("абв", 1.5)`:           core.VMErrorNeedInt.Error(),
+		`р = СтрНачинаетсяС("абв", "")`: core.VMErrorNeedLength.Error(),
+		`р = СтрШаблон("%2", 1)`:        core.VMErrorStrTemplate.Error(),
+		`р = СтрШаблон("%(x)", 1)`:      core.VMErrorStrTemplate.Error(),
+		`р = СтрПовторить("а", -1)`:     core.VMErrorNeedLength.Error(),
+		`р = Символ(-1)`:                core.VMErrorIncorrectRune.Error(),
+		`р = Символ(55296)`:             core.VMErrorIncorrectRune.Error(),
+		`р = СтрСоединить("а")`:         core.VMErrorNeedSlice.Error(),
+		`р = Сред("а")`:                 "Неверное количество параметров",
+	} {
+		_, bins, err := ParseSrc(src, env.Names())
+		if err != nil {
+			t.Fatal(src, err)
+		}
+		if _, err := Run(bins, env); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: ошибка %v, ожидается %q", src, err, msg)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package bincode
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const templateSrc = `функция Громко(с)
+	возврат ВРег(с) + "!"
+конецфункции
+
+ш = Новый Шаблон("<ul>{{range .Товары}}<li>{{.Имя}}: {{.Цена}}{{if .Акция}} (акция){{end}}</li>{{end}}</ul> {{громко .Заголовок}}", {"Функции": {"громко": Громко}})
+Сообщить(ш.Выполнить({"Заголовок": "товары", "Товары": [{"Имя": "<Хлеб>", "Цена": 30.50, "Акция": Истина}, {"Имя": "Сыр & масло", "Цена": 250, "Акция": Ложь}]}))
+
+т = Новый Шаблон("{{.}} & {{len .}}", {"ЭкранироватьHTML": Ложь})
+Сообщить(т.Выполнить("<б>"))
+
+ЗаписатьФайл("макет.html", "<title>{{block \"заголовок\" .}}Сайт{{end}}</title>{{template \"меню\" .}}<main>{{block \"тело\" .}}{{end}}</main>")
+ЗаписатьФайл("меню.html", "{{define \"меню\"}}<nav>{{range $i, $п := .Меню}}{{if $i}} | {{end}}{{$п}}{{end}}</nav>{{end}}")
+м = ШаблонИзФайла("макет.html")
+м.ДобавитьФайл("меню.html")
+м.Добавить("страница", "{{define \"заголовок\"}}Главная{{end}}{{define \"тело\"}}Привет, {{.Имя}}{{end}}")
+Сообщить(м.Выполнить({"Меню": ["а", "б"], "Имя": "<мир>"}))
+Сообщить(м.Выполнить({"Меню": []}, "меню"))
+`
+
+func TestTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var out bytes.Buffer
+	env := core.NewEnv()
+	env.SetStdOut(&out)
+	env.SetSandbox(&core.Sandbox{Root: dir})
+	_, bins, err := ParseSrc(templateSrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil {
+		t.Fatal(err)
+	}
+	want := `<ul><li>&lt;Хлеб&gt;: 30.50 (акция)</li><li>Сыр &amp; масло: 250</li></ul> ТОВАРЫ!
+<б> & 4
+<title>Главная</title><nav>а | б</nav><main>Привет, &lt;мир&gt;</main>
+<nav></nav>
+`
+	if out.String() != want {
+		t.Errorf("вывод:\n%s\nожидается:\n%s", out.String(), want)
+	}
+
+	for src, msg := range map[string]string{
+		`ш = Новый Шаблон("строка 1\n{{if .а}}")`:                   "Ошибка в шаблоне шаблон, строка 2",
+		`ш = Новый Шаблон("{{нет .}}")`:                             `function "нет" not defined`,
+		`ш = Новый Шаблон("а\nб\n{{index . 5}}"); ш.Выполнить([1])`: "Ошибка в шаблоне шаблон, строка 3",
+		`ш = Новый Шаблон("{{.}}"); ш.Выполнить(1, "нет")`:          `"нет" is undefined`,
+		`ш = Новый Шаблон("{{.}}", {"Функции": {"ф": 1}})`:          "не является функцией",
+		`ш = ШаблонИзФайла("../макет.html")`:                        core.VMErrorFSOutsideRoot.Error(),
+		`ш = Новый Шаблон("{{.}}"); ш.ДобавитьФайл("/etc/passwd")`:  core.VMErrorFSOutsideRoot.Error(),
+	} {
+		_, bins, err := ParseSrc(src, env.Names())
+		if err != nil {
+			t.Fatal(src, err)
+		}
+		if _, err := Run(bins, env); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: ошибка %v, ожидается %q", src, err, msg)
+		}
+	}
+}
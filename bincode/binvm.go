@@ -3,10 +3,10 @@ package bincode
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"os"
 	"reflect"
 	"runtime"
 	"strings"
@@ -24,7 +24,9 @@ func Interrupt(env *core.Env) {
 }
 
 // ParseSrc provides way to parse the code from source.
-func ParseSrc(src string) (prs ast.Stmts, bin binstmt.BinCode, err error) {
+// Идентификаторы регистрируются в таблице имен nm (обычно это env.Names() интерпретатора),
+// если nil - создается отдельная таблица, которая сохраняется в скомпилированном коде
+func ParseSrc(src string, nm *names.EnvNames) (prs ast.Stmts, bin binstmt.BinCode, err error) {
 	defer func() {
 		// если это не паника из кода языка
 		// if os.Getenv("GONEC_DEBUG") == "" {
@@ -46,7 +48,10 @@ func ParseSrc(src string) (prs ast.Stmts, bin binstmt.BinCode, err error) {
 	scanner := &parser.Scanner{}
 	scanner.Init(src)
 
-	prs, err = parser.Parse(scanner)
+	if nm == nil {
+		nm = names.NewEnvNames()
+	}
+	prs, err = parser.Parse(scanner, nm)
 	if err != nil {
 		panic(err)
 	}
@@ -56,6 +61,7 @@ func ParseSrc(src string) (prs ast.Stmts, bin binstmt.BinCode, err error) {
 	// компиляция в бинарный код
 	lid := 0
 	bin = prs.BinaryCode(0, &lid)
+	bin.SetNames(nm)
 
 	return prs, bin, err
 }
@@ -77,8 +83,15 @@ func putRegs(sl core.VMSlice) {
 	binRegsPool.Put(sl)
 }
 
-// Run запускает код на исполнение, например, после загрузки из файла
+// Run запускает код на исполнение, например, после загрузки из файла.
+// Признак прерывания, оставшийся в окружении от предыдущего запуска, снимается
 func Run(stmts binstmt.BinCode, env *core.Env) (retval core.VMValuer, reterr error) {
+	env.ResetInterrupt()
+	return run(stmts, env, env)
+}
+
+// run исполняет код модуля в окружении env, вызванный из окружения caller
+func run(stmts binstmt.BinCode, env, caller *core.Env) (retval core.VMValuer, reterr error) {
 	defer func() {
 		// если это не паника из кода языка
 		// if os.Getenv("GONEC_DEBUG") == "" {
@@ -94,16 +107,97 @@ func Run(stmts binstmt.BinCode, env *core.Env) (retval core.VMValuer, reterr err
 	}()
 
 	// стандартная библиотека - загружаем, если она еще не была загружена в это или в родительское окружение
+	LoadBuiltins(env)
+
+	// идентификаторы кода должны соответствовать таблице имен интерпретатора
+	stmts, reterr = stmts.Bind(env.Names())
+	if reterr != nil {
+		return nil, reterr
+	}
 
+	mname := env.GetName()
+	if mname == "" {
+		mname = mainModuleName
+	}
+	coverRegister(stmts)
+	fr := profEnter(caller, env, mname, stmts.File, 0)
+	retval, reterr = runWorker(stmts.Code, stmts.Labels, stmts.MaxReg+1, env, 0, srcFiles{stmts.File, &stmts.Source})
+	fr.leave()
+
+	return
+}
+
+var (
+	embeddedMu sync.RWMutex
+	embedded   map[string]binstmt.BinCode
+)
+
+// SetEmbedded задает скомпилированные модули, которые ЗагрузитьИВыполнить берет вместо чтения файлов
+// (например, из пакета исполняемого файла, собранного gonec build). Ключ - путь так, как он указан в вызове
+func SetEmbedded(modules map[string]binstmt.BinCode) {
+	embeddedMu.Lock()
+	embedded = modules
+	embeddedMu.Unlock()
+}
+
+func embeddedModule(path string) (binstmt.BinCode, bool) {
+	embeddedMu.RLock()
+	defer embeddedMu.RUnlock()
+	code, ok := embedded[path]
+	return code, ok
+}
+
+// LoadBuiltins загружает стандартную библиотеку в окружение, если она еще не была загружена в него или в родительское окружение
+func LoadBuiltins(env *core.Env) {
 	if !env.IsBuiltsLoaded() {
+		// исполняет исходный код модуля file
+		evSrc := func(src, file string, caller *core.Env) core.VMValuer {
+			if binstmt.SignatureRequired() {
+				// исполняется только подписанный код
+				panic(binstmt.ErrSourceDenied)
+			}
+			_, bins, err := ParseSrc(src, env.Names())
+			if err != nil {
+				if pe, ok := err.(*parser.Error); ok {
+					pe.Filename = file
+					panic(pe)
+				}
+				panic(err)
+			}
+			// env.Dump()
+			bins.File = file
+			rv, err := run(bins, env, caller)
+			// env.Dump()
+			if err != nil {
+				panic(err)
+			}
+			return rv
+		}
 		// эту функцию определяем тут, чтобы исключить циклические зависимости пакетов
 		evFunc := func(args core.VMSlice, rets *core.VMSlice, envout *(*core.Env)) error {
+			caller := *envout
 			*envout = env
 			if len(args) != 1 {
 				return errors.New("Должен быть один параметр")
 			}
 			if s, ok := args[0].(core.VMString); ok {
-				body, err := ioutil.ReadFile(string(s))
+				if bins, ok := embeddedModule(string(s)); ok {
+					if bins.File == "" {
+						bins.File = string(s)
+					}
+					rv, err := run(bins, env, caller)
+					if err != nil {
+						panic(err)
+					}
+					rets.Append(rv)
+					return nil
+				}
+				// чтение модуля с диска подчиняется политике ограничений
+				fn, err := env.Sandbox().Path(string(s), false)
+				if err != nil {
+					panic(err)
+				}
+				body, err := ioutil.ReadFile(fn)
 				if err != nil {
 					panic(err)
 				}
@@ -115,24 +209,10 @@ func Run(stmts binstmt.BinCode, env *core.Env) (retval core.VMValuer, reterr err
 						panic(err)
 					}
 					// env.Dump()
-					rv, err := Run(bins, env)
-					// env.Dump()
-					if err != nil {
-						panic(err)
-					}
-					rets.Append(rv)
-					return nil
-				} else {
-					_, bins, err := ParseSrc(string(body))
-					if err != nil {
-						if pe, ok := err.(*parser.Error); ok {
-							pe.Filename = string(s)
-							panic(pe)
-						}
-						panic(err)
+					if bins.File == "" {
+						bins.File = string(s)
 					}
-					// env.Dump()
-					rv, err := Run(bins, env)
+					rv, err := run(bins, env, caller)
 					// env.Dump()
 					if err != nil {
 						panic(err)
@@ -140,45 +220,79 @@ func Run(stmts binstmt.BinCode, env *core.Env) (retval core.VMValuer, reterr err
 					rets.Append(rv)
 					return nil
 				}
+				rets.Append(evSrc(string(body), string(s), caller))
 				return nil
 			}
 			return errors.New("Должен быть параметр-строка")
 		}
 		env.DefineS("загрузитьивыполнить", core.VMFunc(evFunc))
 		env.DefineS("выполнить", core.VMFunc(func(args core.VMSlice, rets *core.VMSlice, envout *(*core.Env)) error {
+			caller := *envout
+			*envout = env
 			if len(args) != 1 {
 				return errors.New("Должен быть один параметр")
 			}
-			var s string
-			if vms, ok := args[0].(core.VMString); ok {
-				s = vms.String()
-			} else {
-				errors.New("Должен быть параметр-строка")
-			}
-			f, err := ioutil.TempFile("", "eval")
-			if err != nil {
-				return nil
-			}
-			fmt.Fprintln(f, s)
-			if err := f.Close(); err != nil {
-				return err
+			vms, ok := args[0].(core.VMString)
+			if !ok {
+				return errors.New("Должен быть параметр-строка")
 			}
-			defer os.Remove(f.Name())
-			var nArgs core.VMSlice
-			nArgs = append(nArgs, core.VMString(f.Name()))
-			return evFunc(nArgs, rets, envout)
+			// код исполняется без обращения к файловой системе
+			rets.Append(evSrc(string(vms), "", caller))
+			return nil
 		}))
 
 		core.LoadAllBuiltins(env)
 	}
+}
 
-	retval, reterr = RunWorker(stmts.Code, stmts.Labels, stmts.MaxReg+1, env, 0)
+// RunContext запускает код на исполнение с контекстом ctx.
+// При отмене контекста или истечении его срока исполнение прерывается,
+// в том числе прерываются блокирующие операции (каналы, пауза, сетевые запросы)
+func RunContext(ctx context.Context, stmts binstmt.BinCode, env *core.Env) (retval core.VMValuer, reterr error) {
+	old := env.SetContext(ctx)
+	defer env.SetContext(old)
+	return Run(stmts, env)
+}
 
-	return
+// interruptError возвращает ошибку, с которой было прервано исполнение в окружении
+func interruptError(env *core.Env) error {
+	if err := env.Context().Err(); err != nil {
+		return core.VMErrorFromContext(err)
+	}
+	return binstmt.InterruptError
 }
 
 // RunWorker исполняет кусок кода, начиная с инструкции idx
 func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.Env, idx int) (retval core.VMValuer, reterr error) {
+	return runWorker(stmts, labels, numofregs, env, idx, srcFiles{})
+}
+
+// srcFiles - файлы исходного кода инструкций: по карте исходного кода (код из .gnx),
+// а для инструкций без записи в карте - файл file
+type srcFiles struct {
+	file string
+	m    *binstmt.SourceMap
+}
+
+// at возвращает имя файла исходного кода инструкции idx
+func (s srcFiles) at(idx int) string {
+	if f, _, ok := s.m.Lookup(idx); ok && f != "" {
+		return f
+	}
+	return s.file
+}
+
+// runWorker исполняет код из файлов src. Ошибкам, возникшим в этом коде, присваивается имя файла инструкции
+func runWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.Env, idx int, src srcFiles) (retval core.VMValuer, reterr error) {
+	if src.file != "" || src.m != nil && len(src.m.Pos) > 0 {
+		// отложенные функции выполняются в обратном порядке - имя присваивается и ошибкам из паники,
+		// idx к этому моменту указывает на инструкцию, в которой возникла ошибка
+		defer func() {
+			if e, ok := reterr.(*binstmt.Error); ok && e.File == "" {
+				e.File = src.at(idx)
+			}
+		}()
+	}
 	defer func() {
 		// если это не паника из кода языка
 		// if os.Getenv("GONEC_DEBUG") == "" {
@@ -213,6 +327,11 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 
 	cntInterrupt := 0
 
+	// вызов функции для профилировщика, nil - если профилирование не запущено
+	fr := profCurrent(env)
+	// счетчики покрытия кода, nil - если покрытие не собирается
+	cv := coverCurrent(stmts)
+
 	for idx < len(stmts) {
 
 		// проверка прерывания каждые 10 команд
@@ -221,11 +340,18 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 			cntInterrupt = 0
 			if regs.Env.CheckInterrupt() {
 				// проверяем, был ли прерван интерпретатор
-				return nil, binstmt.InterruptError
+				return nil, interruptError(regs.Env)
 			}
 		}
 
 		stmt := stmts[idx]
+		if fr != nil {
+			// учитываем вставку модуля _ по умолчанию - вычитаем 1 из номера строки
+			fr.step(stmt.Position().Line - 1)
+		}
+		if cv != nil {
+			cv.step(idx)
+		}
 		switch s := stmt.(type) {
 
 		case *binstmt.BinJMP:
@@ -234,6 +360,9 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 
 		case *binstmt.BinJFALSE:
 			if b, ok := registers[s.Reg].(core.VMBool); ok {
+				if cv != nil {
+					cv.jump(idx, bool(b))
+				}
 				if !bool(b) {
 					idx = regs.Labels[s.JumpTo]
 					continue
@@ -245,6 +374,9 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 
 		case *binstmt.BinJTRUE:
 			if b, ok := registers[s.Reg].(core.VMBool); ok {
+				if cv != nil {
+					cv.jump(idx, bool(b))
+				}
 				if bool(b) {
 					idx = regs.Labels[s.JumpTo]
 					continue
@@ -334,6 +466,9 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 
 		case *binstmt.BinMAKESLICE:
 			registers[s.Reg] = make(core.VMSlice, s.Len, s.Cap)
+			if fr != nil {
+				fr.allocated()
+			}
 
 		case *binstmt.BinSETIDX:
 			if v, ok := registers[s.Reg].(core.VMSlice); ok {
@@ -344,6 +479,9 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 			}
 		case *binstmt.BinMAKEMAP:
 			registers[s.Reg] = make(core.VMStringMap, s.Len)
+			if fr != nil {
+				fr.allocated()
+			}
 
 		case *binstmt.BinSETKEY:
 			if v, ok := registers[s.Reg].(core.VMStringMap); ok {
@@ -358,9 +496,9 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 			mv := registers[s.RegVal]
 			switch mm := m.(type) {
 			case core.VMMetaObject:
-				mm.VMSetField(s.Id, mv.(core.VMInterfacer))
+				mm.VMSetField(env.Names().GetLowerCase(s.Id), mv.(core.VMInterfacer))
 			case core.VMStringMap:
-				mm[names.UniqueNames.Get(s.Id)] = mv
+				mm[env.Names().Get(s.Id)] = mv
 			default:
 				catcherr = binstmt.NewStringError(stmt, "Невозможно установить поле у значения")
 				goto catching
@@ -391,12 +529,15 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 					rets := core.GetGlobalVMSlice()   // для каждой горутины отдельный массив возвратов, который потом не используется
 					goargs := core.GetGlobalVMSlice() // для горутин аргументы надо скопировать!
 					goargs = append(goargs, argsl...)
+					// горутина получает собственное окружение под глобальным,
+					// т.к. окружение вызывающей функции уничтожается при выходе из нее, не дожидаясь горутины
+					genv := env.NewEnv()
 					go func(a, r core.VMSlice) {
-						var e *core.Env
+						e := genv // функция может заменить окружение своим
 						err := fnc(a, &r, &e)
 						core.PutGlobalVMSlice(a) // всегда возвращаем в пул
 						core.PutGlobalVMSlice(r) // всегда возвращаем в пул
-						if err != nil && e.Valid {
+						if err != nil && e != nil && e.Valid {
 							e.Println(err)
 						}
 					}(goargs, rets)
@@ -406,7 +547,8 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 
 				rets := core.GetGlobalVMSlice()
 				// не в горутине
-				var fenv *core.Env
+				// передаем окружение вызывающего кода, из него системные функции получают контекст исполнения
+				fenv := env
 				err = fnc(argsl, &rets, &fenv)
 
 				// TODO: проверить, если был передан слайс, и он изменен внутри функции, то что происходит в исходном слайсе?
@@ -437,7 +579,7 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 
 		case *binstmt.BinFUNC:
 
-			f := func(expr *binstmt.BinFUNC, fstmts binstmt.BinStmts, flabels []int, fenv *core.Env) core.VMFunc {
+			f := func(expr *binstmt.BinFUNC, fstmts binstmt.BinStmts, flabels []int, fenv *core.Env, fsrc srcFiles, ffile string) core.VMFunc {
 				return func(args core.VMSlice, rets *core.VMSlice, envout *(*core.Env)) error {
 					if !expr.VarArg {
 						if len(args) != len(expr.Args) {
@@ -463,7 +605,14 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 					}
 					// вызов функции возвращает одиночное значение (в т.ч. VMNil) или VMSlice
 
-					rr, err := RunWorker(fstmts, flabels, expr.MaxReg+1, newenv, flabels[expr.LabelStart])
+					fname := anonFuncName
+					if expr.Name != 0 {
+						fname = newenv.Names().Get(expr.Name)
+					}
+					// в envout передано окружение вызывающего кода, стек вызовов продолжается от него
+					fr := profEnter(*envout, newenv, fname, ffile, expr.Position().Line-1)
+					rr, err := runWorker(fstmts, flabels, expr.MaxReg+1, newenv, flabels[expr.LabelStart], fsrc)
+					fr.leave()
 
 					*envout = newenv // указываем окружение после выполнения
 
@@ -479,7 +628,7 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 					newenv.Destroy()
 					return err
 				}
-			}(s, stmts, labels, env)
+			}(s, stmts, labels, env, src, src.at(idx))
 
 			env.Define(s.Name, f)
 			registers[s.Reg] = f
@@ -495,7 +644,7 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 				catcherr = binstmt.NewStringError(stmt, "Имя типа должно быть строкой")
 				break
 			}
-			eType := names.UniqueNames.Set(string(v))
+			eType := env.Names().Set(string(v))
 			registers[s.Reg] = core.VMInt(eType)
 
 		case *binstmt.BinGETMEMBER:
@@ -512,20 +661,21 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 				goto catching
 			case core.VMStringMap:
 				// Сначала ищем поле, в нем может быть переопределен метод как функция
-				if rv, ok := vv[names.UniqueNames.Get(s.Name)]; ok {
+				if rv, ok := vv[env.Names().Get(s.Name)]; ok {
 					registers[s.Reg] = rv
 				} else {
-					if ff, ok := vv.MethodMember(s.Name); ok {
+					if ff, ok := vv.MethodMember(env.Names().GetLowerCase(s.Name)); ok {
 						registers[s.Reg] = ff
 					} else {
 						registers[s.Reg] = core.VMNil
 					}
 				}
 			case core.VMMetaObject:
-				if vv.VMIsField(s.Name) {
-					registers[s.Reg] = vv.VMGetField(s.Name)
+				name := env.Names().GetLowerCase(s.Name)
+				if vv.VMIsField(name) {
+					registers[s.Reg] = vv.VMGetField(name)
 				} else {
-					if ff, ok := vv.VMGetMethod(s.Name); ok {
+					if ff, ok := vv.VMGetMethod(name); ok {
 						registers[s.Reg] = ff
 					} else {
 						catcherr = binstmt.NewStringError(stmt, "Нет поля или метода с таким именем")
@@ -533,7 +683,7 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 					}
 				}
 			case core.VMMethodImplementer:
-				if ff, ok := vv.MethodMember(s.Name); ok {
+				if ff, ok := vv.MethodMember(env.Names().GetLowerCase(s.Name)); ok {
 					registers[s.Reg] = ff
 				} else {
 					catcherr = binstmt.NewStringError(stmt, "Нет метода с таким именем")
@@ -579,6 +729,21 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 					catcherr = binstmt.NewStringError(stmt, "Индекс должен быть целым числом")
 					goto catching
 				}
+			case core.VMBinaryData:
+				if iv, ok := i.(core.VMInt); ok {
+					ii := int(iv)
+					if ii < 0 {
+						ii += len(vv)
+					}
+					if ii < 0 || ii >= len(vv) {
+						catcherr = binstmt.NewStringError(stmt, "Индекс за пределами границ")
+						goto catching
+					}
+					registers[s.Reg] = core.VMInt(vv[ii])
+				} else {
+					catcherr = binstmt.NewStringError(stmt, "Индекс должен быть целым числом")
+					goto catching
+				}
 			case core.VMStringMap:
 				if k, ok := i.(core.VMString); ok {
 					registers[s.Reg] = vv[string(k)]
@@ -586,6 +751,13 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 					catcherr = binstmt.NewStringError(stmt, "Ключ должен быть строкой")
 					goto catching
 				}
+			case *core.VMSyncMap:
+				if k, ok := i.(core.VMString); ok {
+					registers[s.Reg], _ = vv.Load(string(k))
+				} else {
+					catcherr = binstmt.NewStringError(stmt, "Ключ должен быть строкой")
+					goto catching
+				}
 			case core.VMIndexer:
 				if iv, ok := i.(core.VMInt); ok {
 					ii := int(iv)
@@ -634,6 +806,10 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 				if s, ok := i.(core.VMString); ok {
 					vv[string(s)] = rv
 				}
+			case *core.VMSyncMap:
+				if s, ok := i.(core.VMString); ok {
+					vv.Store(string(s), rv)
+				}
 			default:
 				catcherr = binstmt.NewStringError(stmt, "Неверная операция")
 				goto catching
@@ -840,6 +1016,28 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 
 				registers[s.Reg] = core.VMString(string(r[ii:ij]))
 
+			case core.VMBinaryData:
+				vlen := len(vv)
+
+				var re int
+				if registers[s.RegEnd] == nil {
+					re = vlen
+				} else if rev, ok := registers[s.RegEnd].(core.VMInt); ok {
+					re = int(rev)
+				} else {
+					catcherr = binstmt.NewStringError(stmt, "Индекс должен быть целым числом")
+					goto catching
+				}
+
+				ii, ij := LeftRightBounds(rb, re, vlen)
+
+				if ij < ii {
+					catcherr = binstmt.NewStringError(stmt, "Окончание диапазона не может быть раньше его начала")
+					goto catching
+				}
+
+				registers[s.Reg] = vv[ii:ij]
+
 			default:
 				catcherr = binstmt.NewStringError(stmt, "Неверная операция")
 				break
@@ -902,6 +1100,19 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 				catcherr = binstmt.NewStringError(stmt, "Неизвестный тип")
 				break
 			}
+			if s.NumArgs > 0 {
+				vcons, ok := registers[s.Reg].(core.VMConstructor)
+				if !ok {
+					catcherr = binstmt.NewStringError(stmt, "Тип не принимает параметры при создании")
+					break
+				}
+				args := make(core.VMSlice, s.NumArgs)
+				copy(args, registers[s.Reg+1:s.Reg+1+s.NumArgs])
+				if err := vcons.VMNew(args); err != nil {
+					catcherr = binstmt.NewError(stmt, err)
+					break
+				}
+			}
 
 		case *binstmt.BinMAKECHAN:
 			size, ok := registers[s.Reg].(core.VMInt)
@@ -926,6 +1137,9 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 
 			v := make(core.VMSlice, int(alen), int(acap))
 			registers[s.Reg] = v
+			if fr != nil {
+				fr.allocated()
+			}
 
 		case *binstmt.BinCHANRECV:
 			ch, ok := registers[s.Reg].(core.VMChan)
@@ -933,7 +1147,11 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 				catcherr = binstmt.NewStringError(stmt, "Не является каналом")
 				break
 			}
-			v, ok := ch.Recv()
+			v, ok, err := ch.RecvContext(env.Context())
+			if err != nil {
+				catcherr = binstmt.NewError(stmt, err)
+				break
+			}
 			if !ok {
 				// если закрыт, то пишем nil
 				registers[s.RegVal] = core.VMNil
@@ -948,7 +1166,10 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 				break
 			}
 			v := registers[s.RegVal]
-			ch.Send(v)
+			if err := ch.SendContext(env.Context(), v); err != nil {
+				catcherr = binstmt.NewError(stmt, err)
+				break
+			}
 
 		case *binstmt.BinISKIND:
 			v := reflect.ValueOf(registers).Index(s.Reg).Elem()
@@ -1028,7 +1249,11 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 					continue
 				}
 			case core.VMChan:
-				iv, ok := vv.Recv()
+				iv, ok, err := vv.RecvContext(env.Context())
+				if err != nil {
+					catcherr = binstmt.NewError(stmt, err)
+					goto catching
+				}
 				if !ok {
 					registers[s.RegVal] = core.VMNil
 				} else {
@@ -1097,8 +1322,15 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 
 		case *binstmt.BinMODULE:
 			// модуль регистрируется в глобальном контексте
-			newenv := env.NewModule(names.UniqueNames.Get(s.Name))
-			_, err := Run(s.Code, newenv) // инициируем модуль
+			newenv := env.NewModule(env.Names().Get(s.Name))
+			mcode := s.Code
+			if mcode.File == "" {
+				mcode.File = src.at(idx)
+			}
+			if mcode.File == "" && cv != nil {
+				mcode.File = cv.file
+			}
+			_, err := run(mcode, newenv, env) // инициируем модуль
 			if err != nil {
 				catcherr = binstmt.NewError(stmt, err)
 				break
@@ -1167,6 +1399,10 @@ func RunWorker(stmts binstmt.BinStmts, labels []int, numofregs int, env *core.En
 		if catcherr != nil {
 			nerr := binstmt.NewError(stmt, catcherr)
 			catcherr = nil
+			// прерванное исполнение не перехватывается блоком Попытка
+			if regs.Env.CheckInterrupt() {
+				return nil, interruptError(regs.Env)
+			}
 			// учитываем стек обработки ошибок
 			if regs.TopTryLabel() == -1 {
 				return nil, nerr
@@ -0,0 +1,118 @@
+package bincode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const xmlSrc = `т = "<?xml version=\"1.0\"?>\n<ed:Message xmlns:ed=\"http://v8.1c.ru/edi\" xmlns=\"urn:default\">\n\t<!-- выгрузка -->\n\t<ed:Header Номер=\"42\" ed:version=\"1.0\"/>\n\t<Body><Item code=\"1\">Молоко &amp; сыр</Item><Item code=\"2\"><![CDATA[<хлеб>]]></Item><Skip><Deep>x</Deep></Skip></Body>\n</ed:Message>"
+ч = Новый ЧтениеXML(т)
+пока ч.Прочитать() цикл
+	если ч.ТипУзла() = "НачалоЭлемента" и ч.ЛокальноеИмя() = "Skip" тогда
+		ч.Пропустить()
+		Сообщить("пропущен", ч.Имя())
+		продолжить
+	конецесли
+	Сообщить(ч.ТипУзла(), ч.Имя(), ч.URIПространстваИмен(), ч.Значение(), ч.ЗначениеАтрибута("Номер"), ч.ЗначениеАтрибута("version", "http://v8.1c.ru/edi"))
+конеццикла
+Сообщить(ч.ТипУзла())
+
+з = Новый ЗаписьXML({"Отступ": 2})
+з.ЗаписатьОбъявлениеXML()
+з.ЗаписатьНачалоЭлемента("Message", "urn:default")
+з.ЗаписатьСоответствиеПространстваИмен("ed", "http://v8.1c.ru/edi")
+з.ЗаписатьНачалоЭлемента("Header", "http://v8.1c.ru/edi")
+з.ЗаписатьАтрибут("version", "1.0", "http://v8.1c.ru/edi")
+з.ЗаписатьАтрибут("Дата", Дата("2024-01-02T03:04:05"))
+з.ЗаписатьКонецЭлемента()
+з.ЗаписатьКомментарий("товары")
+з.ЗаписатьНачалоЭлемента("Item")
+з.ЗаписатьАтрибут("name", "\"А\" & Б")
+з.ЗаписатьТекст("<1 & 2>")
+з.ЗаписатьКонецЭлемента()
+з.ЗаписатьКонецЭлемента()
+з.Закрыть()
+Сообщить(Строка(з.Содержимое()))
+
+д = ПрочитатьXML(т)
+Сообщить(ЗаписатьJSON(д))
+Сообщить(ЗаписатьXML(д, {"ОбъявлениеXML": Ложь}))
+з = Новый ЗаписьXML({"Кодировка": "windows-1251"})
+з.ЗаписатьОбъявлениеXML()
+з.ЗаписатьНачалоЭлемента("Товар")
+з.ЗаписатьТекст("Ёжик №1")
+з.ЗаписатьКонецЭлемента()
+дд = з.Содержимое()
+Сообщить(Длина(дд), ПрочитатьXML(дд).Товар)
+Сообщить(ЗаписатьXML({"Корень": {"Список": [1, 2], "@id": 5, "Пусто": Неопределено}}, {"Отступ": "\t"}))
+`
+
+func TestXML(t *testing.T) {
+	var out bytes.Buffer
+	env := core.NewEnv()
+	env.SetStdOut(&out)
+	_, bins, err := ParseSrc(xmlSrc, env.Names())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(bins, env); err != nil {
+		t.Fatal(err)
+	}
+	want := `НачалоЭлемента ed:Message http://v8.1c.ru/edi  Неопределено Неопределено
+Комментарий    выгрузка  Неопределено Неопределено
+НачалоЭлемента ed:Header http://v8.1c.ru/edi  42 1.0
+КонецЭлемента ed:Header http://v8.1c.ru/edi  Неопределено Неопределено
+НачалоЭлемента Body urn:default  Неопределено Неопределено
+НачалоЭлемента Item urn:default  Неопределено Неопределено
+Текст   Молоко & сыр Неопределено Неопределено
+КонецЭлемента Item urn:default  Неопределено Неопределено
+НачалоЭлемента Item urn:default  Неопределено Неопределено
+Текст   <хлеб> Неопределено Неопределено
+КонецЭлемента Item urn:default  Неопределено Неопределено
+пропущен Skip
+КонецЭлемента Body urn:default  Неопределено Неопределено
+КонецЭлемента ed:Message http://v8.1c.ru/edi  Неопределено Неопределено
+Ничего
+<?xml version="1.0" encoding="UTF-8"?>
+<Message xmlns="urn:default" xmlns:ed="http://v8.1c.ru/edi">
+  <ed:Header ed:version="1.0" Дата="2024-01-02T03:04:05"/>
+  <!--товары-->
+  <Item name="&quot;А&quot; &amp; Б">&lt;1 &amp; 2&gt;</Item>
+</Message>
+{"ed:Message":{"@xmlns":"urn:default","@xmlns:ed":"http://v8.1c.ru/edi","Body":{"Item":[{"#text":"Молоко & сыр","@code":"1"},{"#text":"<хлеб>","@code":"2"}],"Skip":{"Deep":"x"}},"ed:Header":{"@ed:version":"1.0","@Номер":"42"}}}
+<ed:Message xmlns="urn:default" xmlns:ed="http://v8.1c.ru/edi"><Body><Item code="1">Молоко &amp; сыр</Item><Item code="2">&lt;хлеб&gt;</Item><Skip><Deep>x</Deep></Skip></Body><ed:Header ed:version="1.0" Номер="42"/></ed:Message>
+67 Ёжик №1
+<?xml version="1.0" encoding="UTF-8"?>
+<Корень id="5">
+	<Пусто/>
+	<Список>1</Список>
+	<Список>2</Список>
+</Корень>
+`
+	if out.String() != want {
+		t.Errorf("вывод:\n%s\nожидается:\n%s", out.String(), want)
+	}
+
+	for src, msg := range map[string]string{
+		`р = ПрочитатьXML("<a><b></a>")`:    "ожидается </b>, получено </a>",
+		`р = ПрочитатьXML("<a>")`:           "не закрыт элемент <a>",
+		`р = ПрочитатьXML("<a x=1/>")`:      "Ошибка чтения XML в строке 1",
+		`р = ПрочитатьXML("")`:              "нет корневого элемента",
+		`р = ЗаписатьXML({"a": 1, "b": 2})`: "единственным ключом",
+		`з = Новый ЗаписьXML; з.ЗаписатьНачалоЭлемента("a"); з.Закрыть()`:                                    "Не закрыт элемент <a>",
+		`з = Новый ЗаписьXML; з.ЗаписатьТекст("a")`:                                                          "внутри элемента",
+		`з = Новый ЗаписьXML; з.ЗаписатьНачалоЭлемента("a"); з.ЗаписатьТекст(""); з.ЗаписатьАтрибут("b", 1)`: "сразу после начала элемента",
+		`з = Новый ЗаписьXML; з.ЗаписатьНачалоЭлемента("a"); з.ЗаписатьАтрибут("b", 1, "urn:x")`:             "Не задан префикс",
+	} {
+		_, bins, err := ParseSrc(src, env.Names())
+		if err != nil {
+			t.Fatal(src, err)
+		}
+		if _, err := Run(bins, env); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: ошибка %v, ожидается %q", src, err, msg)
+		}
+	}
+}
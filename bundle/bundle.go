@@ -0,0 +1,317 @@
+// Package bundle - пакет скомпилированного кода, дописываемый в конец исполняемого файла интерпретатора (gonec build).
+// Пакет содержит главный модуль и модули, подключаемые им через ЗагрузитьИВыполнить,
+// каждый модуль записан в формате .gnx (binstmt.WriteBinCode)
+package bundle
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/covrom/gonec/bincode"
+	"github.com/covrom/gonec/bincode/binstmt"
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/parser"
+)
+
+var (
+	ErrNoBundle   = errors.New("Исполняемый файл не содержит пакета кода")
+	ErrBadBundle  = errors.New("Пакет кода поврежден")
+	ErrNoMainFile = errors.New("В пакете кода нет главного модуля")
+)
+
+// magic завершает исполняемый файл с пакетом, перед ним записана длина пакета
+const magic = "GONECBUNDLE\x00\x01\x00\x00\x00"
+
+const trailerSize = 8 + len(magic)
+
+// loadFunc - функция стандартной библиотеки, подключающая модули
+const loadFunc = "загрузитьивыполнить"
+
+// Bundle - скомпилированный код программы
+type Bundle struct {
+	Main    string                     // путь главного модуля
+	Modules map[string]binstmt.BinCode // ключ - путь модуля так, как он указан в вызове ЗагрузитьИВыполнить
+	Key     ed25519.PrivateKey         // ключ подписи модулей при записи, nil - модули не подписываются
+	Files   []string                   // файлы, из которых скомпилированы модули (не записываются в пакет)
+}
+
+// Build компилирует главный модуль main (.gnc или .gnx) и все модули, которые подключаются из него
+// через ЗагрузитьИВыполнить с путем в виде строковой константы. Путь ищется относительно текущего каталога,
+// затем относительно каталога главного модуля. В warn передаются вызовы, модуль которых не удалось определить.
+// При ошибке компиляции возвращается и пакет с модулями, скомпилированными до ошибки, в Files есть и файл с ошибкой
+func Build(main string, warn func(string)) (*Bundle, error) {
+	b := &Bundle{Main: main, Modules: make(map[string]binstmt.BinCode)}
+	base := filepath.Dir(main)
+	queue := []string{main}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := b.Modules[name]; ok {
+			continue
+		}
+		fn := name
+		if _, err := os.Stat(fn); err != nil && !filepath.IsAbs(fn) {
+			if _, err := os.Stat(filepath.Join(base, fn)); err == nil {
+				fn = filepath.Join(base, fn)
+			}
+		}
+		b.Files = append(b.Files, fn)
+		code, err := compile(fn)
+		if err != nil {
+			return b, err
+		}
+		b.Modules[name] = code
+		queue = append(queue, loads(code, code, fn, warn)...)
+	}
+	return b, nil
+}
+
+func compile(fn string) (binstmt.BinCode, error) {
+	src, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return binstmt.BinCode{}, err
+	}
+	if strings.HasSuffix(strings.ToLower(fn), ".gnx") {
+		return binstmt.ReadBinCode(bytes.NewReader(src))
+	}
+	_, code, err := bincode.ParseSrc(string(src), nil)
+	if err != nil {
+		if pe, ok := err.(*parser.Error); ok {
+			pe.Filename = fn
+			if pe.Pos.Line > 1 {
+				// строки считаем без учета добавленного заголовка
+				pe.Pos.Line--
+			}
+			return code, pe
+		}
+		return code, fmt.Errorf("%s: %v", fn, err)
+	}
+	return code, nil
+}
+
+// loads возвращает пути модулей, подключаемых кодом через ЗагрузитьИВыполнить
+func loads(root, code binstmt.BinCode, fn string, warn func(string)) []string {
+	var res []string
+	nm := root.Names()
+	for i, st := range code.Code {
+		switch s := st.(type) {
+		case *binstmt.BinMODULE:
+			res = append(res, loads(root, s.Code, fn, warn)...)
+		case *binstmt.BinCALL:
+			if s.Name == 0 || nm == nil || nm.GetLowerCase(s.Name) != loadFunc {
+				continue
+			}
+			if path, ok := constArg(code.Code[:i], s.RegArgs); ok && s.NumArgs == 1 {
+				res = append(res, path)
+			} else if warn != nil {
+				warn(fmt.Sprintf("%s:%d: путь модуля вычисляется при исполнении, модуль не включен в пакет", fn, s.Position().Line-1))
+			}
+		}
+	}
+	return res
+}
+
+// constArg ищет загрузку строковой константы в регистр reg непосредственно перед вызовом
+func constArg(code binstmt.BinStmts, reg int) (string, bool) {
+	for i := len(code) - 1; i >= 0; i-- {
+		switch s := code[i].(type) {
+		case *binstmt.BinLOAD:
+			if s.Reg == reg {
+				v, ok := s.Val.(core.VMString)
+				return string(v), ok && !s.IsId
+			}
+		case *binstmt.BinLABEL, *binstmt.BinJMP, *binstmt.BinJTRUE, *binstmt.BinJFALSE, *binstmt.BinCALL:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// Write записывает пакет: количество модулей, затем для каждого модуля путь и код в формате .gnx,
+// подписанный ключом Key, если он задан
+func (b *Bundle) Write(w io.Writer) error {
+	if _, ok := b.Modules[b.Main]; !ok {
+		return ErrNoMainFile
+	}
+	bw := bufio.NewWriter(w)
+	names := make([]string, 0, len(b.Modules))
+	for name := range b.Modules {
+		if name != b.Main {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	// главный модуль записывается первым
+	names = append([]string{b.Main}, names...)
+	writeInt(bw, uint64(len(names)))
+	for _, name := range names {
+		var buf bytes.Buffer
+		var err error
+		if b.Key != nil {
+			err = binstmt.WriteSignedBinCode(&buf, b.Modules[name], b.Key)
+		} else {
+			err = binstmt.WriteBinCode(&buf, b.Modules[name])
+		}
+		if err != nil {
+			return err
+		}
+		writeInt(bw, uint64(len(name)))
+		bw.WriteString(name)
+		writeInt(bw, uint64(buf.Len()))
+		bw.Write(buf.Bytes())
+	}
+	return bw.Flush()
+}
+
+// Read читает пакет, записанный Write
+func Read(r io.Reader) (*Bundle, error) {
+	br := bufio.NewReader(r)
+	n, err := readInt(br)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{Modules: make(map[string]binstmt.BinCode)}
+	for i := uint64(0); i < n; i++ {
+		name, err := readBytes(br)
+		if err != nil {
+			return nil, err
+		}
+		data, err := readBytes(br)
+		if err != nil {
+			return nil, err
+		}
+		code, err := binstmt.ReadBinCode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			b.Main = string(name)
+		}
+		b.Modules[string(name)] = code
+	}
+	if n == 0 {
+		return nil, ErrNoMainFile
+	}
+	return b, nil
+}
+
+// WriteExecutable записывает в out копию исполняемого файла exe с дописанным пакетом.
+// Если exe уже содержит пакет, он заменяется
+func (b *Bundle) WriteExecutable(exe, out string) error {
+	f, err := os.Open(exe)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// копируется исполняемый файл без пакета
+	end, _, err := locate(f)
+	if err == ErrNoBundle {
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		end = fi.Size()
+	} else if err != nil {
+		return err
+	}
+
+	var payload bytes.Buffer
+	if err := b.Write(&payload); err != nil {
+		return err
+	}
+	o, err := os.OpenFile(out, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	writeInt(&payload, uint64(payload.Len()))
+	payload.WriteString(magic)
+	if _, err := io.Copy(o, io.NewSectionReader(f, 0, end)); err != nil {
+		o.Close()
+		return err
+	}
+	if _, err := o.Write(payload.Bytes()); err != nil {
+		o.Close()
+		return err
+	}
+	return o.Close()
+}
+
+// Open читает пакет, дописанный в конец исполняемого файла exe, или возвращает ErrNoBundle
+func Open(exe string) (*Bundle, error) {
+	f, err := os.Open(exe)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	start, size, err := locate(f)
+	if err != nil {
+		return nil, err
+	}
+	return Read(io.NewSectionReader(f, start, size))
+}
+
+// locate возвращает начало и длину пакета в файле
+func locate(f *os.File) (start, size int64, err error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	if fi.Size() < int64(trailerSize) {
+		return 0, 0, ErrNoBundle
+	}
+	trailer := make([]byte, trailerSize)
+	if _, err := f.ReadAt(trailer, fi.Size()-int64(trailerSize)); err != nil {
+		return 0, 0, err
+	}
+	if string(trailer[8:]) != magic {
+		return 0, 0, ErrNoBundle
+	}
+	size = int64(binary.LittleEndian.Uint64(trailer[:8]))
+	start = fi.Size() - int64(trailerSize) - size
+	if size < 0 || start < 0 {
+		return 0, 0, ErrBadBundle
+	}
+	return start, size, nil
+}
+
+func writeInt(w io.Writer, n uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], n)
+	w.Write(b[:])
+}
+
+func readInt(r io.Reader) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, ErrBadBundle
+		}
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
+
+func readBytes(r io.Reader) ([]byte, error) {
+	n, err := readInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > 1<<31 {
+		return nil, ErrBadBundle
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, ErrBadBundle
+	}
+	return b, nil
+}
@@ -0,0 +1,117 @@
+package bundle
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/bincode"
+	"github.com/covrom/gonec/core"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range files {
+		fn := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fn, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBuild(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.gnc":       "ЗагрузитьИВыполнить(\"lib/b.gnc\")\nЗагрузитьИВыполнить(\"lib/a.gnc\")\nЕсли Ложь Тогда\n\tЗагрузитьИВыполнить(п)\nКонецЕсли\nСообщить(А(2))\n",
+		"lib/a.gnc":      "Если Ложь Тогда\n\tЗагрузитьИВыполнить(\"lib/b.gnc\")\nКонецЕсли\nФункция А(х)\n\tВозврат Б(х) + 1\nКонецФункции\n",
+		"lib/b.gnc":      "Функция Б(х)\n\tВозврат х * 10\nКонецФункции\n",
+		"lib/лишний.gnc": "Сообщить(1)\n",
+	})
+	defer os.RemoveAll(dir)
+
+	var warns []string
+	b, err := Build(filepath.Join(dir, "main.gnc"), func(w string) { warns = append(warns, w) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mods []string
+	for name := range b.Modules {
+		mods = append(mods, name)
+	}
+	sort.Strings(mods)
+	want := []string{filepath.Join(dir, "main.gnc"), "lib/a.gnc", "lib/b.gnc"}
+	sort.Strings(want)
+	if !reflect.DeepEqual(mods, want) {
+		t.Errorf("модули %q, ожидалось %q", mods, want)
+	}
+	if len(warns) != 1 || !strings.Contains(warns[0], "main.gnc:4:") {
+		t.Errorf("предупреждения %q", warns)
+	}
+
+	// пакет дописывается к исполняемому файлу и заменяется при повторной сборке
+	exe := filepath.Join(dir, "exe")
+	if err := ioutil.WriteFile(exe, []byte("исполняемый файл"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(exe); err != ErrNoBundle {
+		t.Fatalf("Open без пакета: %v", err)
+	}
+	out1, out2 := filepath.Join(dir, "app1"), filepath.Join(dir, "app2")
+	if err := b.WriteExecutable(exe, out1); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.WriteExecutable(out1, out2); err != nil {
+		t.Fatal(err)
+	}
+	d1, _ := ioutil.ReadFile(out1)
+	d2, _ := ioutil.ReadFile(out2)
+	for _, d := range [][]byte{d1, d2} {
+		if !bytes.HasPrefix(d, []byte("исполняемый файл")) || bytes.Count(d, []byte(magic)) != 1 {
+			t.Errorf("неверный исполняемый файл с пакетом, длина %d", len(d))
+		}
+	}
+	rb, err := Open(out2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rb.Main != b.Main || len(rb.Modules) != len(b.Modules) {
+		t.Fatalf("прочитан пакет %q, модулей %d", rb.Main, len(rb.Modules))
+	}
+
+	// модули из пакета подключаются без чтения файлов
+	bincode.SetEmbedded(rb.Modules)
+	defer bincode.SetEmbedded(nil)
+	env := core.NewEnv()
+	var buf bytes.Buffer
+	env.SetStdOut(&buf)
+	if _, err := bincode.Run(rb.Modules[rb.Main], env); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "21\n" {
+		t.Errorf("вывод %q", buf.String())
+	}
+}
+
+func TestReadBad(t *testing.T) {
+	if _, err := Read(bytes.NewReader([]byte{1, 0, 0})); err != ErrBadBundle {
+		t.Errorf("неполный пакет: %v", err)
+	}
+	if _, err := Read(bytes.NewReader(make([]byte, 8))); err != ErrNoMainFile {
+		t.Errorf("пустой пакет: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := (&Bundle{Main: "нет"}).Write(&buf); err != ErrNoMainFile {
+		t.Errorf("запись без главного модуля: %v", err)
+	}
+}
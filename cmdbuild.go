@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/covrom/gonec/bincode"
+	"github.com/covrom/gonec/bincode/binstmt"
+	"github.com/covrom/gonec/bundle"
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/parser"
+)
+
+// runBuild реализует команду "gonec build [-o файл] [-sign ключ.pem] главный.gnc": создает исполняемый файл из копии
+// интерпретатора и пакета скомпилированного кода программы.
+// Возвращает 0 при успехе и 1 при ошибке.
+func runBuild(args []string) int {
+	bfs := flag.NewFlagSet("build", flag.ExitOnError)
+	out := bfs.String("o", "", "Имя исполняемого файла, по умолчанию - имя главного модуля")
+	sign := bfs.String("sign", "", "Подписать модули пакета закрытым ключом Ed25519 из файла PEM")
+	bfs.Parse(args)
+
+	if bfs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Использование: gonec build [-o файл] [-sign ключ.pem] главный.gnc")
+		return 1
+	}
+	main := bfs.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(filepath.Base(main), filepath.Ext(main))
+		if runtime.GOOS == "windows" {
+			*out += ".exe"
+		}
+	}
+
+	b, err := bundle.Build(main, func(w string) {
+		fmt.Fprintln(os.Stderr, w)
+	})
+	if err != nil {
+		if e, ok := err.(*parser.Error); ok {
+			fmt.Fprintf(os.Stderr, "%s:%d:%d %s\n", e.Filename, e.Pos.Line, e.Pos.Column, err)
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return 1
+	}
+	b.Key = signingKey(*sign)
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := b.WriteExecutable(exe, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// embeddedBundle возвращает пакет кода, дописанный в исполняемый файл gonec build, или nil
+func embeddedBundle() *bundle.Bundle {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil
+	}
+	b, err := bundle.Open(exe)
+	if err != nil {
+		if err != bundle.ErrNoBundle {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return nil
+	}
+	return b
+}
+
+// runBundle исполняет главный модуль пакета, все аргументы командной строки передаются в АргументыЗапуска.
+// Возвращает код завершения процесса
+func runBundle(b *bundle.Bundle) int {
+	bincode.SetEmbedded(b.Modules)
+	env := core.NewEnv()
+	env.DefineS("аргументызапуска", core.NewVMSliceFromStrings(os.Args[1:]))
+
+	bins := b.Modules[b.Main]
+	if bins.File == "" {
+		bins.File = b.Main
+	}
+	if _, err := bincode.Run(bins, env); err != nil {
+		if e, ok := err.(*binstmt.Error); ok {
+			fn := b.Main
+			if e.File != "" {
+				fn = e.File
+			}
+			fmt.Fprintf(os.Stderr, "%s:%d:%d %s\n", fn, e.Pos.Line, e.Pos.Column, err)
+		} else if e, ok := err.(*parser.Error); ok {
+			fmt.Fprintf(os.Stderr, "%s:%d:%d %s\n", e.Filename, e.Pos.Line, e.Pos.Column, err)
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return 1
+	}
+	return 0
+}
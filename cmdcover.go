@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/covrom/gonec/bincode"
+	"github.com/covrom/gonec/cover"
+)
+
+// startCoverage запускает сбор покрытия кода, если задан параметр -cover.
+// Возвращает функцию, которая записывает профиль покрытия в файл, ее можно вызывать повторно
+func startCoverage(source string) func() {
+	if *coverprofile == "" {
+		return func() {}
+	}
+	if err := bincode.StartCoverage(source); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	stopped := false
+	return func() {
+		if stopped {
+			return
+		}
+		stopped = true
+		prof, err := bincode.StopCoverage()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		f, err := os.Create(*coverprofile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		if err := prof.Write(f); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if err := f.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// runCover реализует команду "gonec cover [-html файл] профиль": выводит сводку покрытия по функциям
+// или записывает отчет HTML с размеченным исходным текстом. Возвращает код завершения программы
+func runCover(args []string) int {
+	cfs := flag.NewFlagSet("cover", flag.ExitOnError)
+	html := cfs.String("html", "", "Записать отчет HTML в файл (- для вывода на экран)")
+	cfs.Parse(args)
+
+	if cfs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Не указан файл профиля покрытия")
+		return 2
+	}
+	prof := &cover.Profile{}
+	for _, fn := range cfs.Args() {
+		f, err := os.Open(fn)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		p, err := cover.Parse(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", fn, err)
+			return 2
+		}
+		prof.Merge(p)
+	}
+
+	if *html == "" {
+		if err := prof.WriteSummary(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		return 0
+	}
+
+	var w io.Writer = os.Stdout
+	if *html != "-" {
+		f, err := os.Create(*html)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := prof.WriteHTML(w, ioutil.ReadFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
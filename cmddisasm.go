@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/covrom/gonec/bincode"
+	"github.com/covrom/gonec/bincode/binstmt"
+	"github.com/covrom/gonec/disasm"
+	"github.com/covrom/gonec/parser"
+)
+
+// runDisasm реализует команду "gonec disasm [-src=false] [-names=false] файл.gnc|файл.gnx ...".
+// Для файла .gnx строки исходного текста берутся из одноименного файла .gnc, если он есть.
+// Возвращает 0 при успехе и 2 при ошибках чтения или разбора.
+func runDisasm(args []string) int {
+	dfs := flag.NewFlagSet("disasm", flag.ExitOnError)
+	src := dfs.Bool("src", true, "Выводить строки исходного текста перед инструкциями")
+	nm := dfs.Bool("names", true, "Выводить таблицу имен")
+	dfs.Parse(args)
+
+	if dfs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Использование: gonec disasm [-src=false] [-names=false] файл.gnc|файл.gnx ...")
+		return 2
+	}
+	rc := 0
+	for i, fn := range dfs.Args() {
+		if i > 0 {
+			fmt.Println()
+		}
+		bins, source, err := loadCode(fn)
+		if err != nil {
+			reportFmtError(fn, err)
+			rc = 2
+			continue
+		}
+		opts := disasm.Options{File: fn, Names: *nm}
+		if *src {
+			opts.Source = source
+		}
+		if err := disasm.Fprint(os.Stdout, bins, opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	return rc
+}
+
+// loadCode компилирует файл .gnc или читает скомпилированный файл .gnx,
+// возвращает код и исходный текст (nil, если он недоступен)
+func loadCode(fn string) (binstmt.BinCode, []byte, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return binstmt.BinCode{}, nil, err
+	}
+	if strings.HasSuffix(strings.ToLower(fn), ".gnx") {
+		bins, err := binstmt.ReadBinCode(bytes.NewReader(b))
+		if err != nil {
+			return bins, nil, err
+		}
+		// исходный текст ищется по имени, сохраненному при компиляции, затем рядом с .gnx
+		var src []byte
+		for _, sfn := range []string{bins.File, strings.TrimSuffix(fn, filepath.Ext(fn)) + ".gnc"} {
+			if sfn == "" {
+				continue
+			}
+			if src, err = ioutil.ReadFile(sfn); err == nil {
+				break
+			}
+			src = nil
+		}
+		return bins, src, nil
+	}
+	_, bins, err := bincode.ParseSrc(string(b), nil)
+	if err != nil {
+		if e, ok := err.(*parser.Error); ok && e.Pos.Line > 1 {
+			// строки считаем без учета добавленного заголовка
+			e.Pos.Line--
+		}
+		return bins, nil, err
+	}
+	bins.File = fn
+	return bins, b, nil
+}
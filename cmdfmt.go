@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/covrom/gonec/format"
+	"github.com/covrom/gonec/parser"
+)
+
+// runFmt реализует команду "gonec fmt [-w] [-d] [-case стиль] [файлы и каталоги]",
+// без файлов форматируется стандартный ввод. Возвращает код завершения программы.
+func runFmt(args []string) int {
+	ffs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := ffs.Bool("w", false, "Записать результат в исходный файл вместо вывода на экран")
+	diff := ffs.Bool("d", false, "Вывести разницу между исходным и отформатированным текстом")
+	kwcase := ffs.String("case", "title", "Написание ключевых слов: title (КонецЕсли), lower (конецесли), upper (КОНЕЦЕСЛИ)")
+	ffs.Parse(args)
+
+	style, err := format.ParseStyle(*kwcase)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if ffs.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "Нельзя использовать -w при форматировании стандартного ввода")
+			return 2
+		}
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if err := fmtSource("<stdin>", b, style, false, *diff); err != nil {
+			reportFmtError("<stdin>", err)
+			return 2
+		}
+		return 0
+	}
+
+	rc := 0
+	for _, path := range ffs.Args() {
+		err := filepath.Walk(path, func(fn string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// в каталогах форматируются только исходные тексты .gnc, явно указанные файлы - любые
+			if fi.IsDir() || (fn != path && filepath.Ext(fn) != ".gnc") {
+				return nil
+			}
+			b, err := ioutil.ReadFile(fn)
+			if err != nil {
+				return err
+			}
+			if err := fmtSource(fn, b, style, *write, *diff); err != nil {
+				reportFmtError(fn, err)
+				rc = 2
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			rc = 2
+		}
+	}
+	return rc
+}
+
+func fmtSource(fn string, b []byte, style format.Style, write, diff bool) error {
+	src := string(b)
+	res, err := format.Source(src, style)
+	if err != nil {
+		return err
+	}
+	if diff {
+		fmt.Print(format.Diff(fn, src, res))
+	}
+	if write {
+		if res == src {
+			return nil
+		}
+		fi, err := os.Stat(fn)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(fn, []byte(res), fi.Mode().Perm())
+	}
+	if !diff {
+		fmt.Print(res)
+	}
+	return nil
+}
+
+func reportFmtError(fn string, err error) {
+	if e, ok := err.(*parser.Error); ok {
+		fmt.Fprintf(os.Stderr, "%s:%d:%d %s\n", fn, e.Pos.Line, e.Pos.Column, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", fn, err)
+	}
+}
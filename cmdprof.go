@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/covrom/gonec/bincode"
+)
+
+// startProfile запускает профилирование кода Гонец, если заданы параметры -cpuprofile или -memprofile.
+// Возвращает функцию, которая останавливает профилирование и закрывает файлы, ее можно вызывать повторно
+func startProfile(source string) func() {
+	if *cpuprofile == "" && *memprofile == "" {
+		return func() {}
+	}
+	var files []*os.File
+	create := func(fn string) io.Writer {
+		if fn == "" {
+			return nil
+		}
+		f, err := os.Create(fn)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		files = append(files, f)
+		return f
+	}
+	opts := bincode.ProfileOptions{
+		Filename: source,
+	}
+	// nil типа *os.File в интерфейсе не равен nil, поэтому writer присваивается только для заданных файлов
+	if w := create(*cpuprofile); w != nil {
+		opts.CPU = w
+	}
+	if w := create(*memprofile); w != nil {
+		opts.Alloc = w
+	}
+	if err := bincode.StartProfile(opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	stopped := false
+	return func() {
+		if stopped {
+			return
+		}
+		stopped = true
+		if err := bincode.StopProfile(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		for _, f := range files {
+			if err := f.Close(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
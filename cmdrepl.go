@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/repl"
+	"github.com/covrom/gonec/version"
+)
+
+// runREPL запускает интерактивный режим, профилирование и покрытие собираются за весь сеанс
+func runREPL() {
+	stopProfile := startProfile("typein")
+	defer stopProfile()
+	stopCoverage := startCoverage("typein")
+	defer stopCoverage()
+
+	s := repl.NewSession(func() *core.Env {
+		env := core.NewEnv()
+		env.DefineS("аргументызапуска", core.NewVMSliceFromStrings(fsArgs))
+		return env
+	}, os.Stdout, os.Stderr)
+	if istty {
+		fmt.Printf("Гонец %s, справка по командам - :помощь\n", version.Version)
+	}
+	if err := s.Run(os.Stdin); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+
+	"github.com/covrom/gonec/bincode/binstmt"
+)
+
+// trustedKeysEnv - переменная окружения с путем к файлу или каталогу доверенных ключей,
+// действует и для исполняемых файлов, собранных gonec build
+const trustedKeysEnv = "GONEC_TRUSTED_KEYS"
+
+// setupTrust включает проверку подписи кода ключами из файла или каталога path, пустой path - ничего не меняется.
+// При ошибке чтения ключей процесс завершается: запуск без проверки подписи недопустим
+func setupTrust(path string) {
+	if path == "" {
+		return
+	}
+	keys, err := binstmt.LoadTrustedKeys(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Доверенные ключи %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	binstmt.SetTrustedKeys(keys)
+}
+
+// signingKey читает закрытый ключ подписи из файла fn, пустой fn - код не подписывается (nil)
+func signingKey(fn string) ed25519.PrivateKey {
+	if fn == "" {
+		return nil
+	}
+	key, err := binstmt.LoadPrivateKey(fn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ключ подписи %s: %v\n", fn, err)
+		os.Exit(1)
+	}
+	return key
+}
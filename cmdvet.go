@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/vet"
+)
+
+// runVet реализует команду "gonec vet [файлы и каталоги]", без файлов проверяется стандартный ввод.
+// Возвращает 0, если замечаний нет, 1 при наличии замечаний и 2 при ошибках разбора.
+func runVet(args []string) int {
+	vfs := flag.NewFlagSet("vet", flag.ExitOnError)
+	vfs.Parse(args)
+
+	if vfs.NArg() == 0 {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		return vetSource("<stdin>", b)
+	}
+
+	rc := 0
+	for _, path := range vfs.Args() {
+		err := filepath.Walk(path, func(fn string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() || (fn != path && filepath.Ext(fn) != ".gnc") {
+				return nil
+			}
+			b, err := ioutil.ReadFile(fn)
+			if err != nil {
+				return err
+			}
+			if r := vetSource(fn, b); r > rc {
+				rc = r
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			rc = 2
+		}
+	}
+	return rc
+}
+
+func vetSource(fn string, b []byte) int {
+	// каждый файл проверяется в собственном окружении со встроенными функциями,
+	// как при запуске интерпретатора
+	env := core.NewEnv()
+	core.LoadAllBuiltins(env)
+	env.DefineS("аргументызапуска", core.NewVMSliceFromStrings(nil))
+
+	diags, err := vet.Check(string(b), env)
+	if err != nil {
+		reportFmtError(fn, err)
+		return 2
+	}
+	for _, d := range diags {
+		fmt.Printf("%s:%s\n", fn, d)
+	}
+	if len(diags) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/watch"
+	"github.com/daviddengcn/go-colortext"
+)
+
+// runWatch исполняет программу main в режиме наблюдения: она перезапускается при изменении ее файлов.
+// Ctrl+C останавливает программу и завершает наблюдение
+func runWatch(main string, args []string) {
+	w := watch.New(main, watch.Options{
+		NewEnv: func() *core.Env {
+			env := core.NewEnv()
+			env.DefineS("аргументызапуска", core.NewVMSliceFromStrings(args))
+			return env
+		},
+		Log: os.Stderr,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	if istty {
+		colortext(ct.Green, false, func() {
+			fmt.Fprintf(os.Stderr, "Наблюдение за %s, для завершения нажмите Ctrl+C\n", main)
+		})
+	}
+	w.Run(ctx)
+}
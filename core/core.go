@@ -13,7 +13,6 @@ import (
 
 	"github.com/covrom/decnum"
 
-	"github.com/covrom/gonec/names"
 	"github.com/satori/go.uuid"
 )
 
@@ -115,11 +114,19 @@ func Import(env *Env) *Env {
 	}))
 
 	env.DefineS("пауза", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		ctx := ContextOf(envout)
 		*envout = env
 		if v, ok := args[0].(VMNumberer); ok {
 			sec1 := NewVMDecNumFromInt64(int64(VMSecond))
-			time.Sleep(time.Duration(v.DecNum().Mul(sec1).Int()))
-			return nil
+			// пауза прерывается при отмене контекста исполнения
+			t := time.NewTimer(time.Duration(v.DecNum().Mul(sec1).Int()))
+			defer t.Stop()
+			select {
+			case <-t.C:
+				return nil
+			case <-ctx.Done():
+				return VMErrorFromContext(ctx.Err())
+			}
 		}
 		return VMErrorNeedSeconds
 	}))
@@ -134,12 +141,100 @@ func Import(env *Env) *Env {
 
 	env.DefineS("прочитатьфайл", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
 		*envout = env
-		if v, ok := args[0].(VMString); ok {
-			data, err := ioutil.ReadFile(v.String())
-			rets.Append(VMString(data), VMBool(err == nil))
-			return nil
+		fn, err := fsPath(env, args[0], false)
+		if err != nil {
+			return err
 		}
-		return VMErrorNeedString
+		data, err := ioutil.ReadFile(fn)
+		rets.Append(VMString(data), VMBool(err == nil))
+		return nil
+	}))
+
+	env.DefineS("открытьфайл", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		fn, err := fsPath(env, args[0], false)
+		if err != nil {
+			return err
+		}
+		r, err := OpenVMReader(fn)
+		if err != nil {
+			return err
+		}
+		rets.Append(r)
+		return nil
+	}))
+
+	env.DefineS("создатьфайл", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// второй необязательный параметр - дописывать в конец существующего файла
+		if len(args) != 1 && len(args) != 2 {
+			return VMErrorNeedArgs(1)
+		}
+		fn, err := fsPath(env, args[0], true)
+		if err != nil {
+			return err
+		}
+		var app VMBool
+		if len(args) == 2 {
+			var ok bool
+			if app, ok = args[1].(VMBool); !ok {
+				return VMErrorNeedBool
+			}
+		}
+		w, err := CreateVMWriter(fn, bool(app))
+		if err != nil {
+			return err
+		}
+		rets.Append(w)
+		return nil
+	}))
+
+	env.DefineS("base64строка", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		b, err := binaryArg(args[0])
+		if err != nil {
+			return err
+		}
+		rets.Append(VMBinaryData(b).Base64())
+		return nil
+	}))
+
+	env.DefineS("base64значение", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		v, ok := args[0].(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		b, err := ParseVMBinaryDataBase64(string(v))
+		if err != nil {
+			return err
+		}
+		rets.Append(b)
+		return nil
+	}))
+
+	env.DefineS("hexстрока", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		b, err := binaryArg(args[0])
+		if err != nil {
+			return err
+		}
+		rets.Append(VMString(VMBinaryData(b).String()))
+		return nil
+	}))
+
+	env.DefineS("hexзначение", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		v, ok := args[0].(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		b, err := ParseVMBinaryDataHex(string(v))
+		if err != nil {
+			return err
+		}
+		rets.Append(b)
+		return nil
 	}))
 
 	env.DefineS("хэш", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
@@ -277,6 +372,32 @@ func Import(env *Env) *Env {
 		return VMErrorNeedString
 	}))
 
+	env.DefineS("стрсовпадает", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		v2, ok := args[1].(VMStringer)
+		if !ok {
+			return VMErrorNeedString
+		}
+		re, err := NewVMRegExp(v2.String())
+		if err != nil {
+			return err
+		}
+		return re.Совпадает(args[:1], rets, envout)
+	}))
+
+	env.DefineS("стрзаменитьрег", VMFuncMustParams(3, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		v2, ok := args[1].(VMStringer)
+		if !ok {
+			return VMErrorNeedString
+		}
+		re, err := NewVMRegExp(v2.String())
+		if err != nil {
+			return err
+		}
+		return re.Заменить(VMSlice{args[0], args[2]}, rets, envout)
+	}))
+
 	env.DefineS("стрдекодироватьзапрос", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
 		if v, ok := args[0].(VMString); ok {
 			dec, err := url.QueryUnescape(v.String())
@@ -334,7 +455,7 @@ func Import(env *Env) *Env {
 			rets.Append(VMString("Неопределено"))
 			return nil
 		}
-		rets.Append(VMString(names.UniqueNames.Get(env.TypeName(reflect.TypeOf(args[0])))))
+		rets.Append(VMString(env.Names().Get(env.TypeName(reflect.TypeOf(args[0])))))
 		return nil
 	}))
 
@@ -389,12 +510,45 @@ func Import(env *Env) *Env {
 	env.DefineTypeS("структура", ReflectVMStringMap)
 	env.DefineTypeS("дата", ReflectVMTime)
 	env.DefineTypeS("длительность", ReflectVMTimeDuration)
+	env.DefineTypeS("двоичныеданные", ReflectVMBinaryData)
 
 	env.DefineTypeS("группаожидания", ReflectVMWaitGroup)
+	env.DefineTypeS("синхроннаяструктура", ReflectVMSyncMap)
 	env.DefineTypeS("файловаябазаданных", ReflectVMBoltDB)
 
+	// пакет для работы с контекстами исполнения
+	importContext(env)
+
+	// функции работы с файловой системой
+	importFS(env)
+
+	// функции работы с JSON
+	importJSON(env)
+
+	// функции и типы для работы с XML
+	importXML(env)
+
+	// строковые функции 1С
+	importStrings(env)
+
+	// шаблоны HTML и текста
+	importTemplate(env)
+
+	// хеширование, шифрование, подписи и JWT
+	importCrypto(env)
+	importJWT(env)
+
+	// математические функции и генератор случайных чисел
+	importMath(env)
+	importRandom(env)
+
 	env.DefineTypeStruct("сервер", &VMServer{})
 	env.DefineTypeStruct("клиент", &VMClient{})
+	env.DefineTypeStruct("регулярноевыражение", &VMRegExp{})
+	env.DefineTypeStruct("потокчтения", &VMReader{})
+	env.DefineTypeStruct("потокзаписи", &VMWriter{})
+	env.DefineTypeStruct("чтениеcsv", &VMCSVReader{})
+	env.DefineTypeStruct("записьcsv", &VMCSVWriter{})
 
 	env.DefineTypeStruct("таблицазначений", &VMTable{})
 	env.DefineTypeStruct("колонкатаблицызначений", &VMTableColumn{})
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// callBuiltin вызывает встроенную функцию name из окружения env с аргументами args
+func callBuiltin(env *Env, name string, args ...VMValuer) (VMSlice, error) {
+	v, err := env.Get(env.Names().Set(strings.ToLower(name)))
+	if err != nil {
+		return nil, err
+	}
+	f, ok := v.(VMFunc)
+	if !ok {
+		return nil, fmt.Errorf("%s не является функцией", name)
+	}
+	var rets VMSlice
+	envout := env
+	err = f(VMSlice(args), &rets, &envout)
+	return rets, err
+}
+
+// builtinErrCase - вызов встроенной функции, который должен вернуть ошибку с текстом want
+type builtinErrCase struct {
+	name string
+	args VMSlice
+	want string
+}
+
+// checkBuiltinErrors проверяет, что каждый вызов из cases возвращает ожидаемую ошибку
+func checkBuiltinErrors(t *testing.T, env *Env, cases []builtinErrCase) {
+	t.Helper()
+	for _, c := range cases {
+		rets, err := callBuiltin(env, c.name, c.args...)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s%v = %v, %v, ожидается ошибка %q", c.name, c.args, rets, err, c.want)
+		}
+	}
+}
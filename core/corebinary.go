@@ -0,0 +1,216 @@
+package core
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// VMBinaryData двоичные данные - неизменяемая последовательность байт.
+// В отличие от строки, длина и индексы считаются в байтах, а не в символах
+type VMBinaryData []byte
+
+var ReflectVMBinaryData = reflect.TypeOf(VMBinaryData(nil))
+
+func (x VMBinaryData) vmval() {}
+
+func (x VMBinaryData) Interface() interface{} {
+	return []byte(x)
+}
+
+// String возвращает данные в шестнадцатеричном виде, для получения байт как строки используется Строка(значение)
+func (x VMBinaryData) String() string {
+	return hex.EncodeToString(x)
+}
+
+func (x VMBinaryData) Length() VMInt {
+	return VMInt(len(x))
+}
+
+func (x VMBinaryData) IndexVal(i VMValuer) VMValuer {
+	if ii, ok := i.(VMInt); ok {
+		return VMInt(x[int(ii)])
+	}
+	panic("Индекс должен быть целым числом")
+}
+
+// Slice возвращает массив байт в виде целых чисел, используется и при обходе в цикле Для Каждого
+func (x VMBinaryData) Slice() VMSlice {
+	rv := make(VMSlice, len(x))
+	for i, b := range x {
+		rv[i] = VMInt(b)
+	}
+	return rv
+}
+
+func (x VMBinaryData) Hash() VMString {
+	h := make([]byte, 8)
+	binary.LittleEndian.PutUint64(h, HashBytes(x))
+	return VMString(hex.EncodeToString(h))
+}
+
+func (x VMBinaryData) BinaryType() VMBinaryType {
+	return VMBINARYDATA
+}
+
+func (x VMBinaryData) MethodMember(name string) (VMFunc, bool) {
+
+	// только эти методы будут доступны из кода на языке Гонец!
+
+	switch name {
+	case "размер":
+		return VMFuncMustParams(0, x.Размер), true
+	case "md5":
+		return VMFuncMustParams(0, x.MD5), true
+	case "sha1":
+		return VMFuncMustParams(0, x.SHA1), true
+	case "sha256":
+		return VMFuncMustParams(0, x.SHA256), true
+	}
+
+	return nil, false
+}
+
+func (x VMBinaryData) Размер(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rets.Append(x.Length())
+	return nil
+}
+
+// MD5 возвращает хэш MD5 в виде hex-строки
+func (x VMBinaryData) MD5(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	h := md5.Sum(x)
+	rets.Append(VMString(hex.EncodeToString(h[:])))
+	return nil
+}
+
+// SHA1 возвращает хэш SHA-1 в виде hex-строки
+func (x VMBinaryData) SHA1(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	h := sha1.Sum(x)
+	rets.Append(VMString(hex.EncodeToString(h[:])))
+	return nil
+}
+
+// SHA256 возвращает хэш SHA-256 в виде hex-строки
+func (x VMBinaryData) SHA256(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	h := sha256.Sum256(x)
+	rets.Append(VMString(hex.EncodeToString(h[:])))
+	return nil
+}
+
+// EvalBinOp сравнивает два значения или выполняет бинарную операцию
+func (x VMBinaryData) EvalBinOp(op VMOperation, y VMOperationer) (VMValuer, error) {
+	yy, ok := y.(VMBinaryData)
+	switch op {
+	case ADD:
+		if ok {
+			// результат всегда в новом массиве, чтобы не изменять исходные данные
+			res := make(VMBinaryData, 0, len(x)+len(yy))
+			return append(append(res, x...), yy...), nil
+		}
+		return VMNil, VMErrorIncorrectOperation
+	case EQL:
+		if ok {
+			return VMBool(bytes.Equal(x, yy)), nil
+		}
+		return VMNil, VMErrorIncorrectOperation
+	case NEQ:
+		if ok {
+			return VMBool(!bytes.Equal(x, yy)), nil
+		}
+		return VMNil, VMErrorIncorrectOperation
+	case SUB, MUL, QUO, REM, GTR, GEQ, LSS, LEQ, OR, LOR, AND, LAND, POW, SHR, SHL:
+		return VMNil, VMErrorIncorrectOperation
+	}
+	return VMNil, VMErrorUnknownOperation
+}
+
+func (x VMBinaryData) ConvertToType(nt reflect.Type) (VMValuer, error) {
+	switch nt {
+	case ReflectVMBinaryData:
+		return x, nil
+	case ReflectVMString:
+		return VMString(x), nil
+	case ReflectVMSlice:
+		return x.Slice(), nil
+	}
+	return VMNil, VMErrorNotConverted
+}
+
+// Base64 возвращает данные в кодировке base64 (RFC 4648)
+func (x VMBinaryData) Base64() VMString {
+	return VMString(base64.StdEncoding.EncodeToString(x))
+}
+
+// ParseVMBinaryDataBase64 декодирует данные из base64
+func ParseVMBinaryDataBase64(s string) (VMBinaryData, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return VMBinaryData(b), nil
+}
+
+// Base64URL возвращает данные в кодировке base64url без выравнивания (RFC 4648, раздел 5), как в JWT
+func (x VMBinaryData) Base64URL() VMString {
+	return VMString(base64.RawURLEncoding.EncodeToString(x))
+}
+
+// ParseVMBinaryDataBase64URL декодирует данные из base64url, выравнивание символами = необязательно
+func ParseVMBinaryDataBase64URL(s string) (VMBinaryData, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return VMBinaryData(b), nil
+}
+
+// ParseVMBinaryDataHex декодирует данные из шестнадцатеричной строки
+func ParseVMBinaryDataHex(s string) (VMBinaryData, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return VMBinaryData(b), nil
+}
+
+func (x VMBinaryData) MarshalBinary() ([]byte, error) {
+	return []byte(x), nil
+}
+
+func (x *VMBinaryData) UnmarshalBinary(data []byte) error {
+	// данные копируются, т.к. буфер источника может использоваться повторно
+	*x = append(VMBinaryData{}, data...)
+	return nil
+}
+
+func (x VMBinaryData) GobEncode() ([]byte, error) {
+	return x.MarshalBinary()
+}
+
+func (x *VMBinaryData) GobDecode(data []byte) error {
+	return x.UnmarshalBinary(data)
+}
+
+// MarshalJSON кодирует данные строкой base64
+func (x VMBinaryData) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]byte(x))
+}
+
+func (x *VMBinaryData) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var b []byte
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	*x = VMBinaryData(b)
+	return nil
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// двоичные данные со всеми значениями байт, в том числе недопустимыми в UTF-8
+func allBytes() VMBinaryData {
+	b := make(VMBinaryData, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func TestBinaryDataMarshal(t *testing.T) {
+	data := allBytes()
+	// так сообщения передаются по протоколу tcp и хранятся в файловой базе данных
+	src := VMStringMap{"Данные": data, "Имя": VMString("файл")}
+	b, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst VMStringMap
+	if err := (&dst).UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := dst["Данные"].(VMBinaryData)
+	if !ok || !bytes.Equal(got, data) {
+		t.Errorf("данные после десериализации %#v", dst["Данные"])
+	}
+	// изменение буфера не должно затрагивать прочитанные данные
+	for i := range b {
+		b[i] = 0
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("десериализованные данные ссылаются на буфер")
+	}
+
+	js, err := data[:3].MarshalJSON()
+	if err != nil || string(js) != `"AAEC"` {
+		t.Errorf("JSON %s, %v", js, err)
+	}
+}
+
+func TestHTTPBinaryBody(t *testing.T) {
+	data := allBytes()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("описание", "картинка")
+	fw, err := mw.CreateFormFile("файл", "image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	r := httptest.NewRequest("POST", "/upload", bytes.NewReader(body.Bytes()))
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	req := &VMHttpRequest{r: r}
+	msg, err := req.RequestAsVMStringMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg["Тело"] != VMString(body.String()) {
+		t.Error("тело составной формы не прочитано")
+	}
+	if msg["ПараметрыФормы"].(VMStringMap)["описание"] != VMString("картинка") {
+		t.Errorf("параметры формы %v", msg["ПараметрыФормы"])
+	}
+	f, ok := msg["Файлы"].(VMStringMap)["файл"].(VMStringMap)
+	if !ok {
+		t.Fatalf("файлы %v", msg["Файлы"])
+	}
+	if f["ИмяФайла"] != VMString("image.png") || f["Размер"] != VMInt(len(data)) {
+		t.Errorf("файл %v", f)
+	}
+	if got, ok := f["Данные"].(VMBinaryData); !ok || !bytes.Equal(got, data) {
+		t.Errorf("данные файла %v", f["Данные"])
+	}
+
+	// двоичное тело ответа отправляется без изменений
+	w := httptest.NewRecorder()
+	resp := &VMHttpResponse{w: w}
+	var rets VMSlice
+	err = resp.Отправить(VMSlice{VMStringMap{"Тело": data, "Заголовки": VMStringMap{"Content-Type": VMString("image/png")}}}, &rets, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := w.Result()
+	got, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || !bytes.Equal(got, data) {
+		t.Errorf("ответ %d, %d байт", res.StatusCode, len(got))
+	}
+
+	// поток тела запроса, которое еще не прочитано
+	r = httptest.NewRequest("POST", "/", bytes.NewReader(data))
+	req = &VMHttpRequest{r: r}
+	rets = nil
+	if err := req.Поток(nil, &rets, nil); err != nil {
+		t.Fatal(err)
+	}
+	rd := rets[0].(*VMReader)
+	rets = nil
+	if err := rd.ПрочитатьВсе(nil, &rets, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rets[0].(VMBinaryData), data) {
+		t.Error("данные потока тела запроса")
+	}
+}
+
+func TestBinaryDataErrors(t *testing.T) {
+	env := NewEnv()
+	LoadAllBuiltins(env)
+	checkBuiltinErrors(t, env, []builtinErrCase{
+		{"Base64Строка", VMSlice{VMInt(1)}, VMErrorNeedBinaryData.Error()},
+		{"Base64Строка", VMSlice{}, VMErrorNeedArgs(1).Error()},
+		{"Base64Значение", VMSlice{VMBinaryData("YQ==")}, VMErrorNeedString.Error()},
+		{"Base64Значение", VMSlice{VMString("не base64")}, "illegal base64 data"},
+		{"Base64Значение", VMSlice{VMString("YQ=")}, "illegal base64 data"},
+		{"HexСтрока", VMSlice{VMSlice{}}, VMErrorNeedBinaryData.Error()},
+		{"HexЗначение", VMSlice{VMInt(10)}, VMErrorNeedString.Error()},
+		{"HexЗначение", VMSlice{VMString("abc")}, "odd length"},
+		{"HexЗначение", VMSlice{VMString("zz")}, "invalid byte"},
+	})
+
+	data := VMBinaryData("аб")
+	for _, op := range []VMOperation{ADD, EQL, NEQ, SUB, LSS} {
+		if _, err := data.EvalBinOp(op, VMString("аб")); err != VMErrorIncorrectOperation {
+			t.Errorf("операция %v со строкой: %v", op, err)
+		}
+	}
+	if _, err := data.ConvertToType(ReflectVMInt); err != VMErrorNotConverted {
+		t.Errorf("преобразование в ЦелоеЧисло: %v", err)
+	}
+	if _, err := ParseVMBinaryDataBase64URL("a+b/"); err == nil {
+		t.Error("base64url с символами + и / прочитан без ошибки")
+	}
+	var b VMBinaryData
+	if err := b.UnmarshalJSON([]byte(`123`)); err == nil {
+		t.Error("двоичные данные прочитаны из числа JSON")
+	}
+	if err := b.UnmarshalJSON([]byte(`"не base64"`)); err == nil {
+		t.Error("двоичные данные прочитаны из строки не в base64")
+	}
+}
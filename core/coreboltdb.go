@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/boltdb/bolt"
-	"github.com/covrom/gonec/names"
 )
 
 // VMBoltDB - группа ожидания исполнения горутин
@@ -61,10 +60,10 @@ func (x *VMBoltDB) Begin(writable bool) (tr *VMBoltTransaction, err error) {
 	return
 }
 
-func (x *VMBoltDB) MethodMember(name int) (VMFunc, bool) {
+func (x *VMBoltDB) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "открыть":
 		return VMFuncMustParams(1, x.Открыть), true
 	case "закрыть":
@@ -80,7 +79,12 @@ func (x *VMBoltDB) Открыть(args VMSlice, rets *VMSlice, envout *(*Env)) e
 	if !ok {
 		return VMErrorNeedString
 	}
-	return x.Open(string(v))
+	// база создается, если ее нет, поэтому нужен доступ на запись
+	fn, err := SandboxOf(envout).Path(string(v), true)
+	if err != nil {
+		return err
+	}
+	return x.Open(fn)
 }
 
 func (x *VMBoltDB) НачатьТранзакцию(args VMSlice, rets *VMSlice, envout *(*Env)) error {
@@ -174,10 +178,10 @@ func (x *VMBoltTransaction) BackupDBToFile(name string) error {
 	return x.tx.CopyFile(name, 0644)
 }
 
-func (x *VMBoltTransaction) MethodMember(name int) (VMFunc, bool) {
+func (x *VMBoltTransaction) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "зафиксироватьтранзакцию":
 		return VMFuncMustParams(0, x.ЗафиксироватьТранзакцию), true
 	case "отменитьтранзакцию":
@@ -354,10 +358,10 @@ func (x *VMBoltTable) SetByMap(m VMStringMap) error {
 	return nil
 }
 
-func (x *VMBoltTable) MethodMember(name int) (VMFunc, bool) {
+func (x *VMBoltTable) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "получить":
 		return VMFuncMustParams(1, x.Получить), true
 	case "установить":
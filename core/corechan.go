@@ -1,7 +1,7 @@
 package core
 
 import (
-	"github.com/covrom/gonec/names"
+	"context"
 )
 
 // VMChan - канал для передачи любого типа вирт. машины
@@ -22,6 +22,26 @@ func (x VMChan) Recv() (VMValuer, bool) {
 	return rv, ok
 }
 
+// SendContext отправляет значение в канал, ожидание прерывается при отмене контекста
+func (x VMChan) SendContext(ctx context.Context, v VMValuer) error {
+	select {
+	case x <- v:
+		return nil
+	case <-ctx.Done():
+		return VMErrorFromContext(ctx.Err())
+	}
+}
+
+// RecvContext получает значение из канала, ожидание прерывается при отмене контекста
+func (x VMChan) RecvContext(ctx context.Context) (VMValuer, bool, error) {
+	select {
+	case rv, ok := <-x:
+		return rv, ok, nil
+	case <-ctx.Done():
+		return nil, false, VMErrorFromContext(ctx.Err())
+	}
+}
+
 func (x VMChan) TrySend(v VMValuer) (ok bool) {
 	select {
 	case x <- v:
@@ -47,10 +67,10 @@ func (x VMChan) Close() { close(x) }
 
 func (x VMChan) Size() int { return cap(x) }
 
-func (x VMChan) MethodMember(name int) (VMFunc, bool) {
+func (x VMChan) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "закрыть":
 		return VMFuncMustParams(0, x.Закрыть), true
 	case "размер":
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// cp1251 - символы Юникода для байт 0x80-0xBF кодировки windows-1251, байты 0xC0-0xFF - это А-я (U+0410-U+044F)
+var cp1251 = [64]rune{
+	'Ђ', 'Ѓ', '‚', 'ѓ', '„', '…', '†', '‡', '€', '‰', 'Љ', '‹', 'Њ', 'Ќ', 'Ћ', 'Џ',
+	'ђ', '‘', '’', '“', '”', '•', '–', '—', utf8.RuneError, '™', 'љ', '›', 'њ', 'ќ', 'ћ', 'џ',
+	'\u00a0', 'Ў', 'ў', 'Ј', '¤', 'Ґ', '¦', '§', 'Ё', '©', 'Є', '«', '¬', '\u00ad', '®', 'Ї',
+	'°', '±', 'І', 'і', 'ґ', 'µ', '¶', '·', 'ё', '№', 'є', '»', 'ј', 'Ѕ', 'ѕ', 'ї',
+}
+
+var cp1251Encode = func() map[rune]byte {
+	m := make(map[rune]byte, 128)
+	for i, r := range cp1251 {
+		if r != utf8.RuneError {
+			m[r] = byte(0x80 + i)
+		}
+	}
+	for i := 0; i < 64; i++ {
+		m[rune(0x410+i)] = byte(0xC0 + i)
+	}
+	return m
+}()
+
+func decode1251(b byte) rune {
+	switch {
+	case b < 0x80:
+		return rune(b)
+	case b < 0xC0:
+		return cp1251[b-0x80]
+	}
+	return rune(0x410 + int(b) - 0xC0)
+}
+
+// charsetName приводит имя кодировки к одному из поддерживаемых: utf-8, utf-8-bom, windows-1251
+func charsetName(enc string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(enc)) {
+	case "", "utf-8", "utf8":
+		return "utf-8", nil
+	case "utf-8-bom", "utf-8 bom", "utf8bom":
+		return "utf-8-bom", nil
+	case "windows-1251", "cp1251", "ansi":
+		return "windows-1251", nil
+	}
+	return "", VMErrorUnknownCharset
+}
+
+const utf8BOM = "\xef\xbb\xbf"
+
+// charsetReader возвращает поток чтения r, декодированный из кодировки enc в UTF-8.
+// Метка порядка байт UTF-8 в начале данных пропускается
+func charsetReader(r io.Reader, enc string) (io.Reader, error) {
+	enc, err := charsetName(enc)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+	if enc == "windows-1251" {
+		return &cp1251Reader{r: br}, nil
+	}
+	if b, err := br.Peek(len(utf8BOM)); err == nil && string(b) == utf8BOM {
+		br.Discard(len(utf8BOM))
+	}
+	return br, nil
+}
+
+type cp1251Reader struct {
+	r   *bufio.Reader
+	buf []byte // декодированные, но еще не отданные байты
+	enc [utf8.UTFMax]byte
+}
+
+func (x *cp1251Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(x.buf) > 0 {
+			c := copy(p[n:], x.buf)
+			x.buf = x.buf[c:]
+			n += c
+			continue
+		}
+		if n > 0 && x.r.Buffered() == 0 {
+			// не ждем новых данных, если уже есть что вернуть
+			break
+		}
+		b, err := x.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		l := utf8.EncodeRune(x.enc[:], decode1251(b))
+		x.buf = x.enc[:l]
+	}
+	return n, nil
+}
+
+// charsetWriter возвращает поток записи в w, перекодирующий текст UTF-8 в кодировку enc.
+// Для utf-8-bom метка порядка байт записывается сразу
+func charsetWriter(w io.Writer, enc string) (io.Writer, error) {
+	enc, err := charsetName(enc)
+	if err != nil {
+		return nil, err
+	}
+	switch enc {
+	case "windows-1251":
+		return &cp1251Writer{w: w}, nil
+	case "utf-8-bom":
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+type cp1251Writer struct {
+	w    io.Writer
+	tail []byte // неполный символ UTF-8 из предыдущей записи
+}
+
+// Write перекодирует символы, отсутствующие в windows-1251 символы заменяются на ?
+func (x *cp1251Writer) Write(p []byte) (int, error) {
+	src := append(x.tail, p...)
+	out := make([]byte, 0, len(src))
+	for len(src) > 0 {
+		if src[0] < utf8.RuneSelf {
+			out = append(out, src[0])
+			src = src[1:]
+			continue
+		}
+		if !utf8.FullRune(src) {
+			break
+		}
+		r, n := utf8.DecodeRune(src)
+		if b, ok := cp1251Encode[r]; ok {
+			out = append(out, b)
+		} else {
+			out = append(out, '?')
+		}
+		src = src[n:]
+	}
+	x.tail = append([]byte(nil), src...)
+	if _, err := x.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
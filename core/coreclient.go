@@ -18,7 +18,7 @@ func (x *VMClient) String() string {
 }
 
 func (x *VMClient) IsOnline() bool {
-	return x.conn != nil && !x.conn.closed
+	return x.conn != nil && !x.conn.IsClosed()
 }
 
 func (x *VMClient) Open(proto, addr string, handler VMFunc, data VMValuer, closeOnExitHandler bool) error {
@@ -12,16 +12,15 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/covrom/gonec/names"
 	uuid "github.com/satori/go.uuid"
 )
 
 func NewVMConn(data VMValuer) *VMConn {
 	return &VMConn{
 		id:     -1,
-		closed: false,
 		uid:    uuid.NewV4().String(),
 		data:   data,
 		httpcl: nil,
@@ -42,7 +41,7 @@ type VMConn struct {
 	cancel context.CancelFunc
 
 	id     int
-	closed bool
+	closed int32 // изменяется атомарно, т.к. соединение закрывается из разных горутин
 	uid    string
 	data   VMValuer
 	gzip   bool
@@ -55,7 +54,7 @@ func (c *VMConn) Interface() interface{} {
 }
 
 func (c *VMConn) String() string {
-	if c.closed {
+	if c.IsClosed() {
 		return fmt.Sprintf("Соединение (закрыто)")
 	}
 	if c.httpcl != nil {
@@ -112,7 +111,8 @@ func urlValuesFromMap(vals VMStringMap) (url.Values, error) {
 // HttpReq выполняет универсальный (с любыми методами) запрос к серверу и ждет ответа
 // hdrs - заголовки, которые будут помещены в запрос
 // vals - если это GET, то будут помещены в URL, если POST - помещаются в FormValues тела запроса, иначе - игнорируются
-func (x *VMConn) HttpReq(meth, rurl VMString, body []byte, hdrs, vals VMStringMap) (*VMHttpResponse, error) {
+// запрос прерывается при отмене контекста ctx или при закрытии соединения
+func (x *VMConn) HttpReq(ctx context.Context, meth, rurl VMString, body []byte, hdrs, vals VMStringMap) (*VMHttpResponse, error) {
 
 	var req *http.Request
 	var err error
@@ -147,7 +147,7 @@ func (x *VMConn) HttpReq(meth, rurl VMString, body []byte, hdrs, vals VMStringMa
 	}
 
 	// заворачиваем в контекст для возможности прерывания
-	x.ctx, x.cancel = context.WithCancel(context.Background())
+	x.ctx, x.cancel = context.WithCancel(ctx)
 	req = req.WithContext(x.ctx)
 
 	for k, v := range hdrs {
@@ -165,11 +165,18 @@ func (x *VMConn) HttpReq(meth, rurl VMString, body []byte, hdrs, vals VMStringMa
 	res := &VMHttpResponse{r: resp, data: x.data}
 	if err != nil {
 		res.Close()
+		if ctx.Err() != nil {
+			return nil, VMErrorFromContext(ctx.Err())
+		}
 		return nil, err
 	}
 
 	_, err = res.ReadBody() // читаем ответ и закрываем канал, оставив копию в слайсе, для множественного чтения
 
+	if err != nil && ctx.Err() != nil {
+		err = VMErrorFromContext(ctx.Err())
+	}
+
 	return res, err
 }
 
@@ -236,19 +243,27 @@ func (x *VMConn) Handle(f VMFunc, closeOnExitHandler bool) {
 	if closeOnExitHandler {
 		x.Close()
 	}
-	if err != nil && env.Valid {
+	if err != nil && env != nil && env.Valid {
 		env.Println(err)
 	}
 }
 
+// IsClosed возвращает Истина, если соединение было закрыто
+func (x *VMConn) IsClosed() bool {
+	return atomic.LoadInt32(&x.closed) != 0
+}
+
 func (x *VMConn) Close() (err error) {
+	if !atomic.CompareAndSwapInt32(&x.closed, 0, 1) {
+		// уже закрыто
+		return nil
+	}
 	if x.httpcl != nil {
 		x.cancel()
 	}
 	if x.conn != nil {
 		err = x.conn.Close()
 	}
-	x.closed = true
 	return
 }
 
@@ -259,6 +274,54 @@ type binTCPHead struct {
 	Gzip      byte    //==0 - без сжатия (зашифрован), иначе сжат и зашифрован
 }
 
+// watchContext прерывает текущие операции чтения и записи в соединении при отмене контекста.
+// Возвращаемую функцию нужно вызвать по окончании операции, она возвращает ошибку отмены контекста, если он был отменен
+func (x *VMConn) watchContext(ctx context.Context) func() error {
+	if ctx.Done() == nil {
+		return func() error { return nil }
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			// немедленно прерываем блокирующие операции
+			x.conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	return func() error {
+		close(done)
+		<-stopped
+		if err := ctx.Err(); err != nil {
+			x.conn.SetDeadline(time.Time{})
+			return VMErrorFromContext(err)
+		}
+		return nil
+	}
+}
+
+// SendContext отправляет сообщение, отправка прерывается при отмене контекста
+func (x *VMConn) SendContext(ctx context.Context, val VMStringMap) error {
+	stop := x.watchContext(ctx)
+	err := x.Send(val)
+	if cerr := stop(); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// ReceiveContext получает сообщение, ожидание прерывается при отмене контекста
+func (x *VMConn) ReceiveContext(ctx context.Context) (VMStringMap, error) {
+	stop := x.watchContext(ctx)
+	rv, err := x.Receive()
+	if cerr := stop(); cerr != nil {
+		return rv, cerr
+	}
+	return rv, err
+}
+
 func (x *VMConn) Send(val VMStringMap) error {
 
 	b, err := val.MarshalBinary()
@@ -375,11 +438,11 @@ func (x *VMConn) Receive() (VMStringMap, error) {
 	return rv, nil
 }
 
-func (c *VMConn) MethodMember(name int) (VMFunc, bool) {
+func (c *VMConn) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
 
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "получить":
 		return VMFuncMustParams(0, c.Получить), true
 	case "отправить":
@@ -409,7 +472,7 @@ func (x *VMConn) Получить(args VMSlice, rets *VMSlice, envout *(*Env)) e
 		return VMErrorWrongHTTPMethod
 	}
 	// TCP
-	v, err := x.Receive()
+	v, err := x.ReceiveContext(ContextOf(envout))
 	rets.Append(v)
 	return err // при ошибке вызовет исключение, нужно обрабатывать в попытке
 }
@@ -422,11 +485,11 @@ func (x *VMConn) Отправить(args VMSlice, rets *VMSlice, envout *(*Env))
 	if !ok {
 		return VMErrorNeedMap
 	}
-	return x.Send(v) // при ошибке вызовет исключение, нужно обрабатывать в попытке
+	return x.SendContext(ContextOf(envout), v) // при ошибке вызовет исключение, нужно обрабатывать в попытке
 }
 
 func (x *VMConn) Закрыто(args VMSlice, rets *VMSlice, envout *(*Env)) error {
-	rets.Append(VMBool(x.closed))
+	rets.Append(VMBool(x.IsClosed()))
 	return nil
 }
 
@@ -449,9 +512,13 @@ func (x *VMConn) Запрос(args VMSlice, rets *VMSlice, envout *(*Env)) error
 		return VMErrorNeedMap
 	}
 
-	var m, p, b VMString
+	var m, p VMString
+	var b []byte
 	var h, vals VMStringMap
 
+	// по умолчанию запрос прерывается вместе с исполнением вызывающего кода
+	ctx := ContextOf(envout)
+
 	if v, ok := vsm["Метод"]; ok {
 		if m, ok = v.(VMString); !ok {
 			return VMErrorNeedString
@@ -463,8 +530,9 @@ func (x *VMConn) Запрос(args VMSlice, rets *VMSlice, envout *(*Env)) error
 		}
 	}
 	if v, ok := vsm["Тело"]; ok {
-		if b, ok = v.(VMString); !ok {
-			return VMErrorNeedString
+		var err error
+		if b, err = binaryArg(v); err != nil {
+			return err
 		}
 	}
 	if v, ok := vsm["Заголовки"]; ok {
@@ -478,7 +546,15 @@ func (x *VMConn) Запрос(args VMSlice, rets *VMSlice, envout *(*Env)) error
 		}
 	}
 
-	r, err := x.HttpReq(m, p, []byte(b), h, vals)
+	if v, ok := vsm["Контекст"]; ok {
+		vc, ok := v.(*VMContext)
+		if !ok {
+			return VMErrorNeedContext
+		}
+		ctx = vc.Context()
+	}
+
+	r, err := x.HttpReq(ctx, m, p, b, h, vals)
 	if err != nil {
 		return err
 	}
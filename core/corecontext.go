@@ -0,0 +1,178 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// VMContext - контекст исполнения, позволяет прерывать блокирующие операции по отмене или по истечении времени
+type VMContext struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var ReflectVMContext = reflect.TypeOf(VMContext{})
+
+// NewVMContext создает контекст-обертку, cancel может быть nil
+func NewVMContext(ctx context.Context, cancel context.CancelFunc) *VMContext {
+	return &VMContext{ctx: ctx, cancel: cancel}
+}
+
+func (x *VMContext) vmval() {}
+
+func (x *VMContext) Interface() interface{} {
+	return x.ctx
+}
+
+func (x *VMContext) String() string {
+	if x.ctx.Err() != nil {
+		return "Контекст (отменен)"
+	}
+	return "Контекст"
+}
+
+// Context возвращает контекст Go
+func (x *VMContext) Context() context.Context {
+	return x.ctx
+}
+
+func (x *VMContext) Cancel() {
+	if x.cancel != nil {
+		x.cancel()
+	}
+}
+
+// secondsToDuration преобразует число секунд (допустимо с дробной частью) в длительность
+func secondsToDuration(v VMValuer) (time.Duration, error) {
+	if d, ok := v.(VMDurationer); ok {
+		return time.Duration(d.Duration()), nil
+	}
+	if n, ok := v.(VMNumberer); ok {
+		sec1 := NewVMDecNumFromInt64(int64(VMSecond))
+		return time.Duration(n.DecNum().Mul(sec1).Int()), nil
+	}
+	return 0, VMErrorNeedSeconds
+}
+
+func (x *VMContext) MethodMember(name string) (VMFunc, bool) {
+
+	// только эти методы будут доступны из кода на языке Гонец!
+	switch name {
+	case "отменить":
+		return VMFuncMustParams(0, x.Отменить), true
+	case "отменен":
+		return VMFuncMustParams(0, x.Отменен), true
+	case "ошибка":
+		return VMFuncMustParams(0, x.Ошибка), true
+	case "ожидать":
+		return VMFuncMustParams(0, x.Ожидать), true
+	case "канал":
+		return VMFuncMustParams(0, x.Канал), true
+	case "стаймаутом":
+		return VMFuncMustParams(1, x.СТаймаутом), true
+	case "сотменой":
+		return VMFuncMustParams(0, x.СОтменой), true
+	}
+	return nil, false
+}
+
+func (x *VMContext) Отменить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	x.Cancel()
+	return nil
+}
+
+func (x *VMContext) Отменен(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rets.Append(VMBool(x.ctx.Err() != nil))
+	return nil
+}
+
+// Ошибка возвращает описание причины отмены или пустую строку, если контекст еще действует
+func (x *VMContext) Ошибка(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if err := VMErrorFromContext(x.ctx.Err()); err != nil {
+		rets.Append(VMString(err.Error()))
+	} else {
+		rets.Append(VMString(""))
+	}
+	return nil
+}
+
+// Ожидать блокирует исполнение до отмены контекста, ожидание прерывается также и при отмене контекста вызывающего кода
+func (x *VMContext) Ожидать(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	ctx := ContextOf(envout)
+	select {
+	case <-x.ctx.Done():
+		return nil
+	case <-ctx.Done():
+		return VMErrorFromContext(ctx.Err())
+	}
+}
+
+// Канал возвращает канал, который закрывается при отмене контекста, его удобно использовать в операторе Выбор
+func (x *VMContext) Канал(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	ch := make(VMChan)
+	go func() {
+		<-x.ctx.Done()
+		close(ch)
+	}()
+	rets.Append(ch)
+	return nil
+}
+
+func (x *VMContext) СТаймаутом(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	d, err := secondsToDuration(args[0])
+	if err != nil {
+		return err
+	}
+	rets.Append(NewVMContext(context.WithTimeout(x.ctx, d)))
+	return nil
+}
+
+func (x *VMContext) СОтменой(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rets.Append(NewVMContext(context.WithCancel(x.ctx)))
+	return nil
+}
+
+// importContext регистрирует пакет Контекст с функциями создания контекстов.
+// Новые контексты порождаются от контекста исполнения вызывающего кода
+func importContext(env *Env) {
+	pkg := env.NewPackage("Контекст")
+
+	pkg.DefineS("текущий", VMFuncMustParams(0, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		ctx := ContextOf(envout)
+		*envout = env
+		rets.Append(NewVMContext(ctx, nil))
+		return nil
+	}))
+
+	pkg.DefineS("сотменой", VMFuncMustParams(0, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		ctx := ContextOf(envout)
+		*envout = env
+		rets.Append(NewVMContext(context.WithCancel(ctx)))
+		return nil
+	}))
+
+	pkg.DefineS("стаймаутом", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		ctx := ContextOf(envout)
+		*envout = env
+		d, err := secondsToDuration(args[0])
+		if err != nil {
+			return err
+		}
+		rets.Append(NewVMContext(context.WithTimeout(ctx, d)))
+		return nil
+	}))
+
+	pkg.DefineS("сдедлайном", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		ctx := ContextOf(envout)
+		*envout = env
+		t, ok := args[0].(VMDateTimer)
+		if !ok {
+			return VMErrorNeedDate
+		}
+		rets.Append(NewVMContext(context.WithDeadline(ctx, time.Time(t.Time()))))
+		return nil
+	}))
+
+	env.DefineS("контекст", pkg)
+}
@@ -3,9 +3,6 @@ package core
 import (
 	"bytes"
 	"compress/flate"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"crypto/tls"
 	"io"
 	"sync"
@@ -42,45 +39,14 @@ var TLSKeyPair, _ = tls.X509KeyPair(TLSCertGonec, TLSKeyGonec)
 
 var aesKey = []byte("oUwhsPdfj439pfoi")
 
-// TODO: перенести в core-функции языка
-
+// EncryptAES128 шифрует данные протокола обмена между клиентом и сервером встроенным ключом,
+// для шифрования в программах на языке Гонец используется EncryptAES с ключом пользователя
 func EncryptAES128(plaintext []byte) ([]byte, error) {
-	c, err := aes.NewCipher(aesKey)
-	if err != nil {
-		return nil, err
-	}
-
-	gcm, err := cipher.NewGCM(c)
-	if err != nil {
-		return nil, err
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, err
-	}
-
-	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+	return EncryptAES(aesKey, plaintext, nil)
 }
 
 func DecryptAES128(ciphertext []byte) ([]byte, error) {
-	c, err := aes.NewCipher(aesKey)
-	if err != nil {
-		return nil, err
-	}
-
-	gcm, err := cipher.NewGCM(c)
-	if err != nil {
-		return nil, err
-	}
-
-	nonceSize := gcm.NonceSize()
-	if len(ciphertext) < nonceSize {
-		return nil, VMErrorSmallDecodeBuffer
-	}
-
-	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	return gcm.Open(nil, nonce, ciphertext, nil)
+	return DecryptAES(aesKey, ciphertext, nil)
 }
 
 var zipPool sync.Pool
@@ -0,0 +1,567 @@
+package core
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// hashFunc возвращает функцию хеширования по имени алгоритма: SHA256, SHA384, SHA512, SHA1 или MD5
+func hashFunc(v VMValuer) (func() hash.Hash, error) {
+	s, ok := v.(VMString)
+	if !ok {
+		return nil, VMErrorNeedString
+	}
+	switch strings.ToUpper(strings.Replace(string(s), "-", "", -1)) {
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA384":
+		return sha512.New384, nil
+	case "SHA512":
+		return sha512.New, nil
+	case "SHA1":
+		return sha1.New, nil
+	case "MD5":
+		return md5.New, nil
+	}
+	return nil, VMErrorUnknownHash
+}
+
+// HMAC возвращает код аутентификации сообщения data с ключом key
+func HMAC(h func() hash.Hash, key, data []byte) VMBinaryData {
+	m := hmac.New(h, key)
+	m.Write(data)
+	return VMBinaryData(m.Sum(nil))
+}
+
+// RandomBytes возвращает n случайных байт из криптографически стойкого генератора
+func RandomBytes(n int) (VMBinaryData, error) {
+	if n < 0 {
+		return nil, VMErrorNeedLength
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	return VMBinaryData(b), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, VMErrorAESKeySize
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
+
+// EncryptAES шифрует данные AES-GCM ключом длиной 16, 24 или 32 байта.
+// Результат - случайный nonce, за которым следуют шифротекст и метка аутентификации
+func EncryptAES(key, plaintext, ad []byte) (VMBinaryData, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return VMBinaryData(gcm.Seal(nonce, nonce, plaintext, ad)), nil
+}
+
+// DecryptAES расшифровывает данные, зашифрованные EncryptAES, и проверяет их целостность
+func DecryptAES(key, ciphertext, ad []byte) (VMBinaryData, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, VMErrorSmallDecodeBuffer
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	b, err := gcm.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, VMErrorDecrypt
+	}
+	return VMBinaryData(b), nil
+}
+
+// keyPair возвращает структуру с закрытым (PKCS #8) и открытым (PKIX) ключами в формате PEM
+func keyPair(priv crypto.Signer) (VMStringMap, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	pubder, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	return VMStringMap{
+		"Закрытый": VMString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"Открытый": VMString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubder})),
+	}, nil
+}
+
+func pemBlock(v VMValuer) (*pem.Block, error) {
+	b, err := binaryArg(v)
+	if err != nil {
+		return nil, err
+	}
+	p, _ := pem.Decode(b)
+	if p == nil {
+		return nil, VMErrorNeedPEMKey
+	}
+	return p, nil
+}
+
+// privateKey разбирает закрытый ключ RSA или Ed25519 в формате PEM
+func privateKey(v VMValuer) (crypto.Signer, error) {
+	p, err := pemBlock(v)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch p.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(p.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(p.Bytes)
+	default:
+		return nil, VMErrorNeedPEMKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, VMErrorUnsupportedKey
+}
+
+// publicKey разбирает открытый ключ RSA или Ed25519 в формате PEM, ключ можно взять и из сертификата или закрытого ключа
+func publicKey(v VMValuer) (crypto.PublicKey, error) {
+	p, err := pemBlock(v)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch p.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(p.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(p.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(p.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		priv, err := privateKey(v)
+		if err != nil {
+			return nil, err
+		}
+		return priv.Public(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k, nil
+	case ed25519.PublicKey:
+		return k, nil
+	}
+	return nil, VMErrorUnsupportedKey
+}
+
+// Sign подписывает данные закрытым ключом: RSA - PKCS #1 v1.5 с хешем SHA-256, Ed25519 - по RFC 8032
+func Sign(priv crypto.Signer, data []byte) (VMBinaryData, error) {
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		h := sha256.Sum256(data)
+		b, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, h[:])
+		return VMBinaryData(b), err
+	case ed25519.PrivateKey:
+		return VMBinaryData(ed25519.Sign(k, data)), nil
+	}
+	return nil, VMErrorUnsupportedKey
+}
+
+// Verify проверяет подпись, созданную Sign
+func Verify(pub crypto.PublicKey, data, sig []byte) bool {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		h := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) == nil
+	case ed25519.PublicKey:
+		return len(k) == ed25519.PublicKeySize && ed25519.Verify(k, data, sig)
+	}
+	return false
+}
+
+// параметры scrypt для хешей паролей, сложность задает N = 2^сложность
+const (
+	passwordCost     = 15
+	passwordMaxCost  = 20
+	passwordBlock    = 8
+	passwordParallel = 1
+	passwordSaltLen  = 16
+	passwordKeyLen   = 32
+
+	// ограничения параметров хеша при проверке, чтобы подложенный хеш не исчерпал память и процессор
+	passwordMaxBlock    = 32
+	passwordMaxParallel = 16
+	passwordMaxKeyLen   = 64
+	passwordMaxMemory   = 1 << 30 // байт на 128*N*r
+)
+
+var passwordEncoding = base64.RawStdEncoding
+
+// HashPassword возвращает хеш пароля scrypt со случайной солью в виде строки
+// $scrypt$ln=15,r=8,p=1$соль$хеш (соль и хеш в base64), которую можно хранить в базе данных
+func HashPassword(password []byte, cost int) (VMString, error) {
+	if cost < 1 || cost > passwordMaxCost {
+		return "", fmt.Errorf("Сложность хеша пароля должна быть от 1 до %d", passwordMaxCost)
+	}
+	salt, err := RandomBytes(passwordSaltLen)
+	if err != nil {
+		return "", err
+	}
+	dk, err := scrypt.Key(password, salt, 1<<uint(cost), passwordBlock, passwordParallel, passwordKeyLen)
+	if err != nil {
+		return "", err
+	}
+	return VMString(fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s",
+		cost, passwordBlock, passwordParallel, passwordEncoding.EncodeToString(salt), passwordEncoding.EncodeToString(dk))), nil
+}
+
+// CheckPassword сравнивает пароль с хешем, полученным HashPassword
+func CheckPassword(password []byte, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 || parts[0] != "" || parts[1] != "scrypt" {
+		return false, VMErrorPasswordHash
+	}
+	var ln, r, p int
+	for _, kv := range strings.Split(parts[2], ",") {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			return false, VMErrorPasswordHash
+		}
+		n, err := strconv.Atoi(kv[i+1:])
+		if err != nil {
+			return false, VMErrorPasswordHash
+		}
+		switch kv[:i] {
+		case "ln":
+			ln = n
+		case "r":
+			r = n
+		case "p":
+			p = n
+		default:
+			return false, VMErrorPasswordHash
+		}
+	}
+	salt, err1 := passwordEncoding.DecodeString(parts[3])
+	want, err2 := passwordEncoding.DecodeString(parts[4])
+	if err1 != nil || err2 != nil || ln < 1 || ln > passwordMaxCost || len(want) == 0 || len(want) > passwordMaxKeyLen ||
+		r < 1 || r > passwordMaxBlock || p < 1 || p > passwordMaxParallel || 128*(1<<uint(ln))*r > passwordMaxMemory {
+		return false, VMErrorPasswordHash
+	}
+	dk, err := scrypt.Key(password, salt, 1<<uint(ln), r, p, len(want))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(dk, want) == 1, nil
+}
+
+// importCrypto регистрирует функции хеширования, шифрования и подписи
+func importCrypto(env *Env) {
+
+	for name, h := range map[string]func() hash.Hash{
+		"хешsha256": sha256.New,
+		"хешsha512": sha512.New,
+		"хешsha1":   sha1.New,
+		"хешmd5":    md5.New,
+	} {
+		h := h
+		env.DefineS(name, VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+			*envout = env
+			b, err := binaryArg(args[0])
+			if err != nil {
+				return err
+			}
+			hh := h()
+			hh.Write(b)
+			rets.Append(VMBinaryData(hh.Sum(nil)))
+			return nil
+		}))
+	}
+
+	env.DefineS("hmac", VMFuncMustParams(3, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		h, err := hashFunc(args[0])
+		if err != nil {
+			return err
+		}
+		key, err := binaryArg(args[1])
+		if err != nil {
+			return err
+		}
+		data, err := binaryArg(args[2])
+		if err != nil {
+			return err
+		}
+		rets.Append(HMAC(h, key, data))
+		return nil
+	}))
+
+	env.DefineS("сравнитьбезопасно", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		a, err := binaryArg(args[0])
+		if err != nil {
+			return err
+		}
+		b, err := binaryArg(args[1])
+		if err != nil {
+			return err
+		}
+		// время сравнения не зависит от того, в какой позиции данные различаются
+		rets.Append(VMBool(hmac.Equal(a, b)))
+		return nil
+	}))
+
+	env.DefineS("base64urlстрока", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		b, err := binaryArg(args[0])
+		if err != nil {
+			return err
+		}
+		rets.Append(VMBinaryData(b).Base64URL())
+		return nil
+	}))
+
+	env.DefineS("base64urlзначение", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		v, ok := args[0].(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		b, err := ParseVMBinaryDataBase64URL(string(v))
+		if err != nil {
+			return err
+		}
+		rets.Append(b)
+		return nil
+	}))
+
+	env.DefineS("случайныеданные", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		n, ok := args[0].(VMInt)
+		if !ok {
+			return VMErrorNeedInt
+		}
+		b, err := RandomBytes(int(n))
+		if err != nil {
+			return err
+		}
+		rets.Append(b)
+		return nil
+	}))
+
+	// aesArgs возвращает данные, ключ и необязательные дополнительные данные, которые аутентифицируются, но не шифруются
+	aesArgs := func(args VMSlice) (data, key, ad []byte, err error) {
+		if len(args) != 2 && len(args) != 3 {
+			return nil, nil, nil, VMErrorNeedArgs(3)
+		}
+		if data, err = binaryArg(args[0]); err != nil {
+			return
+		}
+		if key, err = binaryArg(args[1]); err != nil {
+			return
+		}
+		if len(args) == 3 {
+			ad, err = binaryArg(args[2])
+		}
+		return
+	}
+
+	env.DefineS("зашифроватьaes", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		data, key, ad, err := aesArgs(args)
+		if err != nil {
+			return err
+		}
+		b, err := EncryptAES(key, data, ad)
+		if err != nil {
+			return err
+		}
+		rets.Append(b)
+		return nil
+	}))
+
+	env.DefineS("расшифроватьaes", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		data, key, ad, err := aesArgs(args)
+		if err != nil {
+			return err
+		}
+		b, err := DecryptAES(key, data, ad)
+		if err != nil {
+			return err
+		}
+		rets.Append(b)
+		return nil
+	}))
+
+	env.DefineS("создатьключиrsa", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// размер ключа в битах необязателен
+		bitsz := VMInt(2048)
+		switch len(args) {
+		case 0:
+		case 1:
+			var ok bool
+			if bitsz, ok = args[0].(VMInt); !ok {
+				return VMErrorNeedInt
+			}
+			if bitsz < 1024 || bitsz > 8192 {
+				return errors.New("Размер ключа RSA должен быть от 1024 до 8192 бит")
+			}
+		default:
+			return VMErrorNeedArgs(1)
+		}
+		priv, err := rsa.GenerateKey(rand.Reader, int(bitsz))
+		if err != nil {
+			return err
+		}
+		kp, err := keyPair(priv)
+		if err != nil {
+			return err
+		}
+		rets.Append(kp)
+		return nil
+	}))
+
+	env.DefineS("создатьключиed25519", VMFuncMustParams(0, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		kp, err := keyPair(priv)
+		if err != nil {
+			return err
+		}
+		rets.Append(kp)
+		return nil
+	}))
+
+	env.DefineS("подписать", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		data, err := binaryArg(args[0])
+		if err != nil {
+			return err
+		}
+		priv, err := privateKey(args[1])
+		if err != nil {
+			return err
+		}
+		sig, err := Sign(priv, data)
+		if err != nil {
+			return err
+		}
+		rets.Append(sig)
+		return nil
+	}))
+
+	env.DefineS("проверитьподпись", VMFuncMustParams(3, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		data, err := binaryArg(args[0])
+		if err != nil {
+			return err
+		}
+		sig, err := binaryArg(args[1])
+		if err != nil {
+			return err
+		}
+		pub, err := publicKey(args[2])
+		if err != nil {
+			return err
+		}
+		rets.Append(VMBool(Verify(pub, data, sig)))
+		return nil
+	}))
+
+	env.DefineS("хешпароля", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// сложность необязательна
+		if len(args) != 1 && len(args) != 2 {
+			return VMErrorNeedArgs(2)
+		}
+		pass, err := binaryArg(args[0])
+		if err != nil {
+			return err
+		}
+		cost := passwordCost
+		if len(args) == 2 {
+			c, ok := args[1].(VMInt)
+			if !ok {
+				return VMErrorNeedInt
+			}
+			cost = int(c)
+		}
+		s, err := HashPassword(pass, cost)
+		if err != nil {
+			return err
+		}
+		rets.Append(s)
+		return nil
+	}))
+
+	env.DefineS("проверитьпароль", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		pass, err := binaryArg(args[0])
+		if err != nil {
+			return err
+		}
+		h, ok := args[1].(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		eq, err := CheckPassword(pass, string(h))
+		if err != nil {
+			return err
+		}
+		rets.Append(VMBool(eq))
+		return nil
+	}))
+
+}
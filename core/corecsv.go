@@ -0,0 +1,456 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvOptions - параметры чтения и записи CSV, задаются структурой с полями
+// Разделитель, Заголовки, Кодировка, СвободныеКавычки (чтение), ВсегдаВКавычках (запись)
+type csvOptions struct {
+	comma      rune
+	header     bool     // первая строка - заголовки колонок
+	noHeader   bool     // заголовки явно отключены
+	columns    []string // заголовки заданы явно
+	charset    string
+	lazyQuotes bool
+	quoteAll   bool
+}
+
+func parseCSVOptions(v VMValuer) (*csvOptions, error) {
+	o := &csvOptions{comma: ';'}
+	if v == nil {
+		return o, nil
+	}
+	opts, ok := v.(VMStringMap)
+	if !ok {
+		return nil, VMErrorNeedMap
+	}
+	if err := checkOptions(opts, "Разделитель", "Заголовки", "Кодировка", "СвободныеКавычки", "ВсегдаВКавычках"); err != nil {
+		return nil, err
+	}
+	if sep, ok := opts["Разделитель"]; ok {
+		s, ok := sep.(VMString)
+		if !ok || utf8.RuneCountInString(string(s)) != 1 {
+			return nil, errors.New("Разделитель должен быть одним символом")
+		}
+		o.comma, _ = utf8.DecodeRuneInString(string(s))
+		if o.comma == '"' || o.comma == '\r' || o.comma == '\n' {
+			return nil, errors.New("Недопустимый разделитель")
+		}
+	}
+	switch h := opts["Заголовки"].(type) {
+	case nil, VMNilType, VMNullType:
+	case VMBool:
+		o.header = bool(h)
+		o.noHeader = !bool(h)
+	case VMSlice:
+		o.header = true
+		o.columns = make([]string, len(h))
+		for i, c := range h {
+			s, ok := c.(VMString)
+			if !ok {
+				return nil, VMErrorNeedString
+			}
+			o.columns[i] = string(s)
+		}
+	default:
+		return nil, errors.New("Заголовки должны быть значением Булево или массивом имен колонок")
+	}
+	if enc, ok := opts["Кодировка"]; ok {
+		s, ok := enc.(VMString)
+		if !ok {
+			return nil, VMErrorNeedString
+		}
+		if _, err := charsetName(string(s)); err != nil {
+			return nil, err
+		}
+		o.charset = string(s)
+	}
+	for k, p := range map[string]*bool{"СвободныеКавычки": &o.lazyQuotes, "ВсегдаВКавычках": &o.quoteAll} {
+		if v, ok := opts[k]; ok {
+			b, ok := v.(VMBool)
+			if !ok {
+				return nil, VMErrorNeedBool
+			}
+			*p = bool(b)
+		}
+	}
+	return o, nil
+}
+
+// csvArgs разбирает аргументы конструктора: источник или приемник и необязательные параметры.
+// Если первый аргумент - структура, это параметры, а источник или приемник не задан
+func csvArgs(args VMSlice) (VMValuer, *csvOptions, error) {
+	var target, opts VMValuer
+	switch len(args) {
+	case 1:
+		if m, ok := args[0].(VMStringMap); ok {
+			opts = m
+		} else {
+			target = args[0]
+		}
+	case 2:
+		target, opts = args[0], args[1]
+	default:
+		return nil, nil, VMErrorNeedArgs(2)
+	}
+	o, err := parseCSVOptions(opts)
+	return target, o, err
+}
+
+// VMCSVReader - чтение CSV (ЧтениеCSV) построчно из строки, двоичных данных или потока чтения.
+// Создается как Новый ЧтениеCSV(источник, параметры)
+type VMCSVReader struct {
+	VMMetaObj
+
+	r       *csv.Reader
+	src     io.Reader
+	columns []string
+	opts    *csvOptions
+}
+
+func (x *VMCSVReader) VMRegister() {
+	x.VMRegisterMethod("Прочитать", x.Прочитать)
+	x.VMRegisterMethod("ПрочитатьВсе", x.ПрочитатьВсе)
+	x.VMRegisterMethod("Заголовки", x.Заголовки)
+	x.VMRegisterMethod("Закрыть", x.Закрыть)
+}
+
+// VMNew открывает источник и, если нужно, читает строку заголовков
+func (x *VMCSVReader) VMNew(args VMSlice) error {
+	src, o, err := csvArgs(args)
+	if err != nil {
+		return err
+	}
+	if src == nil {
+		return errors.New("Не указан источник данных")
+	}
+	var r io.Reader
+	switch s := src.(type) {
+	case VMString:
+		// строка уже в UTF-8, кодировка к ней не применяется
+		r = strings.NewReader(string(s))
+		o.charset = ""
+	case VMBinaryData:
+		r = bytes.NewReader(s)
+	case *VMReader:
+		r = s
+	default:
+		return VMErrorNeedBinaryData
+	}
+	dr, err := charsetReader(r, o.charset)
+	if err != nil {
+		return err
+	}
+	x.r = csv.NewReader(dr)
+	x.r.Comma = o.comma
+	x.r.LazyQuotes = o.lazyQuotes
+	x.r.FieldsPerRecord = -1
+	x.src = r
+	x.opts = o
+	x.columns = o.columns
+	if o.header && x.columns == nil {
+		rec, err := x.r.Read()
+		if err != nil && err != io.EOF {
+			return csvError(err)
+		}
+		x.columns = rec
+	}
+	return nil
+}
+
+func (x *VMCSVReader) String() string {
+	return "ЧтениеCSV"
+}
+
+// csvError переводит сообщение об ошибке разбора с указанием строки и колонки
+func csvError(err error) error {
+	if pe, ok := err.(*csv.ParseError); ok {
+		return fmt.Errorf("Ошибка чтения CSV в строке %d, колонке %d: %s", pe.Line, pe.Column, pe.Err)
+	}
+	return err
+}
+
+// row возвращает прочитанную строку как массив или, если есть заголовки, как структуру
+func (x *VMCSVReader) row(rec []string) VMValuer {
+	if x.columns == nil {
+		return NewVMSliceFromStrings(rec)
+	}
+	m := make(VMStringMap, len(x.columns))
+	for i, c := range x.columns {
+		if i < len(rec) {
+			m[c] = VMString(rec[i])
+		} else {
+			m[c] = VMString("")
+		}
+	}
+	return m
+}
+
+// Прочитать возвращает очередную строку и признак того, что она прочитана (Ложь - данные закончились)
+func (x *VMCSVReader) Прочитать(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	if x.r == nil {
+		return VMErrorStreamClosed
+	}
+	rec, err := x.r.Read()
+	if err == io.EOF {
+		rets.Append(VMNil, VMBool(false))
+		return nil
+	}
+	if err != nil {
+		return csvError(err)
+	}
+	rets.Append(x.row(rec), VMBool(true))
+	return nil
+}
+
+// ПрочитатьВсе возвращает массив всех оставшихся строк
+func (x *VMCSVReader) ПрочитатьВсе(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	if x.r == nil {
+		return VMErrorStreamClosed
+	}
+	rv := VMSlice{}
+	for {
+		rec, err := x.r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return csvError(err)
+		}
+		rv = append(rv, x.row(rec))
+	}
+	rets.Append(rv)
+	return nil
+}
+
+// Заголовки возвращает массив имен колонок, пустой, если заголовков нет
+func (x *VMCSVReader) Заголовки(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	rets.Append(NewVMSliceFromStrings(x.columns))
+	return nil
+}
+
+// Закрыть закрывает источник, если это поток чтения
+func (x *VMCSVReader) Закрыть(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	x.r = nil
+	if c, ok := x.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// VMCSVWriter - запись CSV (ЗаписьCSV) в поток записи или в память.
+// Создается как Новый ЗаписьCSV(приемник, параметры); без приемника записанное возвращает метод Содержимое
+type VMCSVWriter struct {
+	VMMetaObj
+
+	w       *bufio.Writer
+	dst     io.Writer
+	buf     *bytes.Buffer
+	columns []string
+	opts    *csvOptions
+	started bool // записана хотя бы одна строка
+}
+
+func (x *VMCSVWriter) VMRegister() {
+	if x.w == nil {
+		x.opts = &csvOptions{comma: ';'}
+		x.buf = new(bytes.Buffer)
+		x.dst = x.buf
+		x.w = bufio.NewWriter(x.buf)
+	}
+	x.VMRegisterMethod("Записать", x.Записать)
+	x.VMRegisterMethod("ЗаписатьВсе", x.ЗаписатьВсе)
+	x.VMRegisterMethod("Содержимое", x.Содержимое)
+	x.VMRegisterMethod("Закрыть", x.Закрыть)
+}
+
+// VMNew задает приемник и параметры записи
+func (x *VMCSVWriter) VMNew(args VMSlice) error {
+	dst, o, err := csvArgs(args)
+	if err != nil {
+		return err
+	}
+	switch d := dst.(type) {
+	case nil, VMNilType, VMNullType:
+		x.buf = new(bytes.Buffer)
+		x.dst = x.buf
+	case *VMWriter:
+		x.buf = nil
+		x.dst = d
+	default:
+		return errors.New("Требуется поток записи")
+	}
+	w, err := charsetWriter(x.dst, o.charset)
+	if err != nil {
+		return err
+	}
+	x.w = bufio.NewWriter(w)
+	x.opts = o
+	x.columns = o.columns
+	return nil
+}
+
+func (x *VMCSVWriter) String() string {
+	return "ЗаписьCSV"
+}
+
+// needQuotes определяет, нужно ли заключать значение в кавычки
+func (x *VMCSVWriter) needQuotes(s string) bool {
+	if x.opts.quoteAll {
+		return true
+	}
+	if s == "" {
+		return false
+	}
+	if s == `\.` || s[0] == ' ' || s[0] == '\t' {
+		return true
+	}
+	return strings.ContainsRune(s, x.opts.comma) || strings.ContainsAny(s, "\"\r\n")
+}
+
+func (x *VMCSVWriter) writeRecord(rec []string) error {
+	if x.w == nil {
+		return VMErrorStreamClosed
+	}
+	for i, s := range rec {
+		if i > 0 {
+			x.w.WriteRune(x.opts.comma)
+		}
+		if x.needQuotes(s) {
+			x.w.WriteByte('"')
+			x.w.WriteString(strings.Replace(s, `"`, `""`, -1))
+			x.w.WriteByte('"')
+		} else {
+			x.w.WriteString(s)
+		}
+	}
+	// Excel и 1С ожидают переводы строк Windows
+	_, err := x.w.WriteString("\r\n")
+	return err
+}
+
+func csvField(v VMValuer) string {
+	switch vv := v.(type) {
+	case nil, VMNilType, VMNullType:
+		return ""
+	case VMString:
+		return string(vv)
+	case VMStringer:
+		return vv.String()
+	}
+	return ""
+}
+
+// writeRow записывает массив значений или структуру. Перед первой строкой записываются заголовки,
+// если они заданы в параметрах массивом, а для структур - и без этого (ключи первой структуры по алфавиту),
+// если только в параметрах не указано Заголовки: Ложь. Значения структур записываются в порядке заголовков
+func (x *VMCSVWriter) writeRow(v VMValuer) error {
+	switch vv := v.(type) {
+	case VMSlice:
+		if !x.started && x.columns != nil {
+			if err := x.writeRecord(x.columns); err != nil {
+				return err
+			}
+		}
+		x.started = true
+		rec := make([]string, len(vv))
+		for i, e := range vv {
+			rec[i] = csvField(e)
+		}
+		return x.writeRecord(rec)
+	case VMStringMap:
+		if x.columns == nil {
+			for k := range vv {
+				x.columns = append(x.columns, k)
+			}
+			sort.Strings(x.columns)
+		}
+		if !x.started && !x.opts.noHeader {
+			if err := x.writeRecord(x.columns); err != nil {
+				return err
+			}
+		}
+		x.started = true
+		rec := make([]string, len(x.columns))
+		for i, c := range x.columns {
+			rec[i] = csvField(vv[c])
+		}
+		return x.writeRecord(rec)
+	}
+	return errors.New("Строка CSV должна быть массивом или структурой")
+}
+
+// Записать записывает одну строку - массив значений или структуру
+func (x *VMCSVWriter) Записать(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 1 {
+		return VMErrorNeedArgs(1)
+	}
+	return x.writeRow(args[0])
+}
+
+// ЗаписатьВсе записывает массив строк
+func (x *VMCSVWriter) ЗаписатьВсе(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 1 {
+		return VMErrorNeedArgs(1)
+	}
+	rows, ok := args[0].(VMSlice)
+	if !ok {
+		return VMErrorNeedSlice
+	}
+	for _, r := range rows {
+		if err := x.writeRow(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Содержимое возвращает двоичные данные, записанные в память
+func (x *VMCSVWriter) Содержимое(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	if x.buf == nil {
+		return errors.New("CSV записывается не в память")
+	}
+	if x.w != nil {
+		if err := x.w.Flush(); err != nil {
+			return err
+		}
+	}
+	rets.Append(append(VMBinaryData{}, x.buf.Bytes()...))
+	return nil
+}
+
+// Закрыть дописывает буферизованные данные и закрывает приемник
+func (x *VMCSVWriter) Закрыть(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if x.w == nil {
+		return nil
+	}
+	err := x.w.Flush()
+	x.w = nil
+	if c, ok := x.dst.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
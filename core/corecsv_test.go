@@ -0,0 +1,115 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCSVErrors(t *testing.T) {
+	src := VMString("а;б\n1;2\n")
+	for _, c := range []struct {
+		args VMSlice
+		want string
+	}{
+		{VMSlice{}, VMErrorNeedArgs(2).Error()},
+		{VMSlice{VMStringMap{}}, "Не указан источник данных"},
+		{VMSlice{VMInt(1)}, VMErrorNeedBinaryData.Error()},
+		{VMSlice{src, VMInt(1)}, VMErrorNeedMap.Error()},
+		{VMSlice{src, VMStringMap{"Разделитель": VMString(";;")}}, "Разделитель должен быть одним символом"},
+		{VMSlice{src, VMStringMap{"Разделитель": VMInt(59)}}, "Разделитель должен быть одним символом"},
+		{VMSlice{src, VMStringMap{"Разделитель": VMString(`"`)}}, "Недопустимый разделитель"},
+		{VMSlice{src, VMStringMap{"Разделитель": VMString("\n")}}, "Недопустимый разделитель"},
+		{VMSlice{src, VMStringMap{"Заголовки": VMString("да")}}, "Заголовки должны быть"},
+		{VMSlice{src, VMStringMap{"Заголовки": VMSlice{VMInt(1)}}}, VMErrorNeedString.Error()},
+		{VMSlice{src, VMStringMap{"Кодировка": VMInt(1251)}}, VMErrorNeedString.Error()},
+		{VMSlice{src, VMStringMap{"Кодировка": VMString("koi8-r")}}, VMErrorUnknownCharset.Error()},
+		{VMSlice{src, VMStringMap{"СвободныеКавычки": VMInt(1)}}, VMErrorNeedBool.Error()},
+		{VMSlice{src, VMStringMap{"Разделители": VMString(",")}}, "Неизвестный параметр Разделители"},
+		{VMSlice{VMString("\"а\nб"), VMStringMap{"Заголовки": VMBool(true)}}, "Ошибка чтения CSV в строке"},
+	} {
+		if err := (&VMCSVReader{}).VMNew(c.args); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Новый ЧтениеCSV%v: %v, ожидается %q", c.args, err, c.want)
+		}
+	}
+
+	// поврежденная строка в середине данных
+	r := &VMCSVReader{}
+	if err := r.VMNew(VMSlice{VMString("1;2\n3;\"4\"5\n")}); err != nil {
+		t.Fatal(err)
+	}
+	var rets VMSlice
+	if err := r.Прочитать(nil, &rets, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Прочитать(nil, &rets, nil); err == nil || !strings.Contains(err.Error(), "в строке 2") {
+		t.Errorf("неверные кавычки: %v", err)
+	}
+	r = &VMCSVReader{}
+	if err := r.VMNew(VMSlice{VMString("1;\"2\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ПрочитатьВсе(nil, &rets, nil); err == nil || !strings.Contains(err.Error(), "Ошибка чтения CSV") {
+		t.Errorf("незакрытая кавычка: %v", err)
+	}
+	if err := r.Прочитать(VMSlice{VMInt(1)}, &rets, nil); err != VMErrorNoNeedArgs {
+		t.Errorf("лишний аргумент: %v", err)
+	}
+	r.Закрыть(nil, &rets, nil)
+	if err := r.Прочитать(nil, &rets, nil); err != VMErrorStreamClosed {
+		t.Errorf("чтение после закрытия: %v", err)
+	}
+	if err := r.ПрочитатьВсе(nil, &rets, nil); err != VMErrorStreamClosed {
+		t.Errorf("чтение всего после закрытия: %v", err)
+	}
+
+	for _, c := range []struct {
+		args VMSlice
+		want string
+	}{
+		{VMSlice{VMString("файл.csv")}, "Требуется поток записи"},
+		{VMSlice{VMSlice{}, VMStringMap{}}, "Требуется поток записи"},
+		{VMSlice{VMStringMap{"ВсегдаВКавычках": VMString("да")}}, VMErrorNeedBool.Error()},
+		{VMSlice{VMStringMap{"ВсегдаКавычки": VMBool(true)}}, "Неизвестный параметр ВсегдаКавычки"},
+		{VMSlice{nil, VMStringMap{"Кодировка": VMString("utf-16")}}, VMErrorUnknownCharset.Error()},
+	} {
+		if err := (&VMCSVWriter{}).VMNew(c.args); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Новый ЗаписьCSV%v: %v, ожидается %q", c.args, err, c.want)
+		}
+	}
+
+	w := &VMCSVWriter{}
+	w.VMInit(w)
+	w.VMRegister()
+	for _, c := range []struct {
+		name string
+		m    VMMethod
+		args VMSlice
+		want string
+	}{
+		{"Записать", w.Записать, VMSlice{}, VMErrorNeedArgs(1).Error()},
+		{"Записать", w.Записать, VMSlice{VMString("а;б")}, "Строка CSV должна быть массивом или структурой"},
+		{"ЗаписатьВсе", w.ЗаписатьВсе, VMSlice{VMStringMap{}}, VMErrorNeedSlice.Error()},
+		{"ЗаписатьВсе", w.ЗаписатьВсе, VMSlice{VMSlice{VMSlice{}, VMInt(1)}}, "Строка CSV должна быть массивом или структурой"},
+		{"Содержимое", w.Содержимое, VMSlice{VMInt(1)}, VMErrorNoNeedArgs.Error()},
+	} {
+		if err := c.m(c.args, &rets, nil); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s%v: %v, ожидается %q", c.name, c.args, err, c.want)
+		}
+	}
+	w.Закрыть(nil, &rets, nil)
+	if err := w.Записать(VMSlice{VMSlice{}}, &rets, nil); err != VMErrorStreamClosed {
+		t.Errorf("запись после закрытия: %v", err)
+	}
+	// ЗаписьCSV в поток записи не хранит записанное
+	fw := &VMCSVWriter{}
+	if err := fw.VMNew(VMSlice{NewVMWriter(errWriter{}, nil)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Содержимое(nil, &rets, nil); err == nil || !strings.Contains(err.Error(), "не в память") {
+		t.Errorf("Содержимое записи в поток: %v", err)
+	}
+	fw.Записать(VMSlice{VMSlice{VMString("а")}}, &rets, nil)
+	if err := fw.Закрыть(nil, &rets, nil); err != errWrite {
+		t.Errorf("ошибка приемника при закрытии: %v", err)
+	}
+}
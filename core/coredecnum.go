@@ -3,7 +3,6 @@ package core
 import (
 	"bytes"
 	"encoding/binary"
-	"math"
 	"reflect"
 	"time"
 
@@ -153,18 +152,21 @@ func (x VMDecNum) Mod(d2 VMDecNum) VMDecNum {
 	return VMDecNum{num: x.num.Mod(d2.num)}
 }
 
-// Pow работает только в диапазоне и точности чисел float64
+// Pow возводит в степень, целая степень вычисляется точно, дробная - через экспоненту и логарифм
 func (x VMDecNum) Pow(d2 VMDecNum) VMDecNum {
-	v1, err := x.num.ToFloat64()
+	r, err := x.pow(d2)
 	if err != nil {
-		panic(err)
+		return VMDecNum{num: decnum.NaN()}
 	}
-	v2, err := d2.num.ToFloat64()
+	return r
+}
+
+func (x VMDecNum) pow(d2 VMDecNum) (VMDecNum, error) {
+	r, err := decPow(x.num, d2.num)
 	if err != nil {
-		panic(err)
+		return x, err
 	}
-	rv := math.Pow(v1, v2)
-	return VMDecNum{num: decnum.FromFloat(rv)}
+	return VMDecNum{num: decRound(r)}, nil
 }
 
 func (x VMDecNum) Equal(d2 VMDecNum) VMBool {
@@ -300,9 +302,9 @@ func (x VMDecNum) EvalBinOp(op VMOperation, y VMOperationer) (VMValuer, error) {
 	case POW:
 		switch yy := y.(type) {
 		case VMInt:
-			return x.Pow(NewVMDecNumFromInt64(int64(yy))), nil
+			return x.pow(NewVMDecNumFromInt64(int64(yy)))
 		case VMDecNum:
-			return x.Pow(yy), nil
+			return x.pow(yy)
 		}
 		return VMNil, VMErrorIncorrectOperation
 	case SHR:
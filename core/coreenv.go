@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"encoding/gob"
 	"fmt"
 	"io"
@@ -8,6 +9,7 @@ import (
 	"reflect"
 	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/covrom/gonec/names"
 )
@@ -77,13 +79,25 @@ func (v *Vals) Destroy() {
 
 // Env provides interface to run VM. This mean function scope and blocked-scope.
 // If stack goes to blocked-scope, it will make new Env.
+//
+// Модель памяти при параллельном исполнении:
+//   - каждый вызов функции и каждая горутина (Старт, Параллельно) получают собственное окружение,
+//     локальные переменные между горутинами не разделяются;
+//   - глобальные переменные и переменные модулей читаются и изменяются под блокировкой окружения,
+//     одиночное чтение или запись атомарны, но составные операции (а = а + 1) - нет;
+//   - значения Массив и Структура не синхронизированы, для совместного изменения из разных горутин
+//     используются СинхроннаяСтруктура, каналы или ГруппаОжидания.
 type Env struct {
 	sync.RWMutex
 	name         string
 	env          *Vals
 	typ          map[int]reflect.Type
 	parent       *Env
-	interrupt    *bool
+	names        *names.EnvNames // таблица имен интерпретатора, общая для всех окружений, порожденных от глобального
+	interrupt    *int32          // общий для всех окружений, порожденных от глобального, изменяется атомарно
+	ctx          context.Context // если nil, то используется контекст родительского окружения
+	prof         interface{}     // состояние профилировщика вирт. машины для кода, исполняемого в этом окружении
+	sandbox      *Sandbox        // политика ограничений, задается только в глобальном окружении
 	stdout       io.Writer
 	sid          string
 	lastid       int
@@ -97,12 +111,13 @@ func (e *Env) vmval() {} // нужно для того, чтобы *Env можн
 // NewEnv creates new global scope.
 // !!!не забывать вызывать core.LoadAllBuiltins(m)!!!
 func NewEnv() *Env {
-	b := false
+	var b int32
 
 	m := &Env{
 		env:          NewVals(),
 		typ:          make(map[int]reflect.Type),
 		parent:       nil,
+		names:        names.NewEnvNames(),
 		interrupt:    &b,
 		stdout:       os.Stdout,
 		lastid:       -1,
@@ -120,6 +135,7 @@ func (e *Env) NewEnv() *Env {
 				env:          NewVals(),
 				typ:          make(map[int]reflect.Type),
 				parent:       ee,
+				names:        e.names,
 				interrupt:    e.interrupt,
 				stdout:       e.stdout,
 				lastid:       -1,
@@ -138,6 +154,7 @@ func (e *Env) NewSubEnv() *Env {
 		env:          NewVals(),
 		typ:          make(map[int]reflect.Type),
 		parent:       e,
+		names:        e.names,
 		interrupt:    e.interrupt,
 		stdout:       e.stdout,
 		lastid:       -1,
@@ -149,7 +166,7 @@ func (e *Env) NewSubEnv() *Env {
 // Находим или создаем новый модуль в глобальном скоупе
 func (e *Env) NewModule(n string) *Env {
 	//ni := strings.ToLower(n)
-	id := names.UniqueNames.Set(n)
+	id := e.names.Set(n)
 	if v, err := e.Get(id); err == nil {
 		if vv, ok := v.(*Env); ok {
 			return vv
@@ -170,6 +187,7 @@ func (e *Env) NewPackage(n string) *Env {
 		typ:          make(map[int]reflect.Type),
 		parent:       e,
 		name:         names.FastToLower(n),
+		names:        e.names,
 		interrupt:    e.interrupt,
 		stdout:       e.stdout,
 		lastid:       -1,
@@ -200,12 +218,19 @@ func (e *Env) Destroy() {
 	// }
 
 	if e.name != "" {
-		id := names.UniqueNames.Set(e.name)
+		id := e.names.Set(e.name)
 		e.DefineGlobal(id, nil)
 	}
+	e.Lock()
 	e.parent = nil
 	e.env.Destroy()
 	e.env = nil
+	e.Unlock()
+}
+
+// Names возвращает таблицу имен интерпретатора, в которой регистрируются идентификаторы байткода
+func (e *Env) Names() *names.EnvNames {
+	return e.names
 }
 
 func (e *Env) SetBuiltsIsLoaded() {
@@ -244,7 +269,7 @@ func (e *Env) TypeName(t reflect.Type) int {
 		}
 		ee.RUnlock()
 	}
-	return names.UniqueNames.Set(t.String())
+	return e.names.Set(t.String())
 }
 
 // Type returns type which specified symbol. It goes to upper scope until
@@ -259,7 +284,7 @@ func (e *Env) Type(k int) (reflect.Type, error) {
 		}
 		ee.RUnlock()
 	}
-	return nil, fmt.Errorf("Тип неопределен '%s'", names.UniqueNames.Get(k))
+	return nil, fmt.Errorf("Тип неопределен '%s'", e.names.Get(k))
 }
 
 // Get returns value which specified symbol. It goes to upper scope until
@@ -286,7 +311,7 @@ func (e *Env) Get(k int) (VMValuer, error) {
 		}
 		ee.RUnlock()
 	}
-	return nil, fmt.Errorf("Имя неопределено '%s'", names.UniqueNames.Get(k))
+	return nil, fmt.Errorf("Имя неопределено '%s'", e.names.Get(k))
 }
 
 // Set modifies value which specified as symbol. It goes to upper scope until
@@ -304,7 +329,7 @@ func (e *Env) Set(k int, v VMValuer) error {
 		}
 		ee.Unlock()
 	}
-	return fmt.Errorf("Имя неопределено '%s'", names.UniqueNames.Get(k))
+	return fmt.Errorf("Имя неопределено '%s'", e.names.Get(k))
 }
 
 // DefineGlobal defines symbol in global scope.
@@ -331,13 +356,13 @@ func (e *Env) DefineType(k int, t reflect.Type) error {
 }
 
 func (e *Env) DefineTypeS(k string, t reflect.Type) error {
-	return e.DefineType(names.UniqueNames.Set(k), t)
+	return e.DefineType(e.names.Set(k), t)
 }
 
 // DefineTypeStruct регистрирует системную функциональную структуру, переданную в виде указателя!
 func (e *Env) DefineTypeStruct(k string, t interface{}) error {
 	gob.Register(t)
-	return e.DefineType(names.UniqueNames.Set(k), reflect.Indirect(reflect.ValueOf(t)).Type())
+	return e.DefineType(e.names.Set(k), reflect.Indirect(reflect.ValueOf(t)).Type())
 }
 
 // Define defines symbol in current scope.
@@ -353,7 +378,7 @@ func (e *Env) Define(k int, v VMValuer) error {
 }
 
 func (e *Env) DefineS(k string, v VMValuer) error {
-	return e.Define(names.UniqueNames.Set(k), v)
+	return e.Define(e.names.Set(k), v)
 }
 
 // String return the name of current scope.
@@ -373,11 +398,36 @@ func (e *Env) Dump() {
 	sort.Ints(sk)
 	for _, k := range sk {
 		v, _ := e.env.Get(k)
-		e.Printf("%d %s = %#v %T\n", k, names.UniqueNames.Get(k), v, v)
+		e.Printf("%d %s = %#v %T\n", k, e.names.Get(k), v, v)
 	}
 	e.RUnlock()
 }
 
+// DefinedNames возвращает отсортированные названия переменных, функций и типов,
+// определенных в окружении и во всех его родительских окружениях
+func (e *Env) DefinedNames() []string {
+	seen := make(map[int]bool)
+	var res []string
+	for ee := e; ee != nil; ee = ee.parent {
+		ee.RLock()
+		for k, i := range ee.env.idx {
+			if !seen[k] && ee.env.vals[i] != nil {
+				seen[k] = true
+				res = append(res, e.names.Get(k))
+			}
+		}
+		for k := range ee.typ {
+			if !seen[k] {
+				seen[k] = true
+				res = append(res, e.names.Get(k))
+			}
+		}
+		ee.RUnlock()
+	}
+	sort.Strings(res)
+	return res
+}
+
 func (e *Env) Println(a ...interface{}) (n int, err error) {
 	// e.RLock()
 	// defer e.RUnlock()
@@ -419,7 +469,7 @@ func (e *Env) SetSid(s string) error {
 	for ee := e; ee != nil; ee = ee.parent {
 		if ee.parent == nil {
 			ee.sid = s
-			return ee.Define(names.UniqueNames.Set("ГлобальныйИдентификаторСессии"), VMString(s))
+			return ee.Define(ee.names.Set("ГлобальныйИдентификаторСессии"), VMString(s))
 		}
 	}
 	return fmt.Errorf("Отсутствует глобальный контекст!")
@@ -435,14 +485,75 @@ func (e *Env) GetSid() string {
 	return ""
 }
 
+// Interrupt прерывает исполнение кода во всех окружениях, порожденных от общего глобального контекста.
+// Признак прерывания остается установленным до вызова ResetInterrupt или до следующего запуска кода через bincode.Run
 func (e *Env) Interrupt() {
-	*(e.interrupt) = true
+	atomic.StoreInt32(e.interrupt, 1)
+}
+
+// ResetInterrupt снимает признак прерывания, например, перед новым запуском кода в том же окружении
+func (e *Env) ResetInterrupt() {
+	atomic.StoreInt32(e.interrupt, 0)
 }
 
+// CheckInterrupt возвращает true, если исполнение было прервано через Interrupt,
+// или если контекст окружения отменен или истек
 func (e *Env) CheckInterrupt() bool {
-	if *(e.interrupt) {
-		*(e.interrupt) = false
+	if atomic.LoadInt32(e.interrupt) != 0 {
 		return true
 	}
-	return false
+	return e.Context().Err() != nil
+}
+
+// SetContext устанавливает контекст исполнения для окружения и всех вложенных в него окружений,
+// возвращает ранее установленный в этом окружении контекст (может быть nil)
+func (e *Env) SetContext(ctx context.Context) context.Context {
+	e.Lock()
+	old := e.ctx
+	e.ctx = ctx
+	e.Unlock()
+	return old
+}
+
+// Context возвращает контекст исполнения, установленный в окружении или в ближайшем родительском окружении.
+// Безопасно вызывать и для nil, тогда возвращается context.Background()
+func (e *Env) Context() context.Context {
+	for ee := e; ee != nil; ee = ee.parent {
+		ee.RLock()
+		ctx := ee.ctx
+		ee.RUnlock()
+		if ctx != nil {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// SetProfState сохраняет в окружении состояние профилировщика исполняемого в нем кода (стек вызовов горутины).
+// Используется вирт. машиной, окружение не интерпретирует это значение
+func (e *Env) SetProfState(v interface{}) {
+	e.Lock()
+	e.prof = v
+	e.Unlock()
+}
+
+// ProfState возвращает состояние профилировщика, установленное через SetProfState, только в этом окружении.
+// Безопасно вызывать и для nil
+func (e *Env) ProfState() interface{} {
+	if e == nil {
+		return nil
+	}
+	e.RLock()
+	defer e.RUnlock()
+	return e.prof
+}
+
+// ContextOf возвращает контекст окружения, переданного в envout.
+// Вирт. машина при вызове функции передает в envout окружение вызывающего кода,
+// поэтому системные функции и методы могут использовать его для прерывания блокирующих операций
+func ContextOf(envout *(*Env)) context.Context {
+	if envout == nil {
+		return context.Background()
+	}
+	return (*envout).Context()
 }
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -24,6 +25,37 @@ var (
 	VMErrorNeedSeconds     = errors.New("Должно быть число секунд (допустимо с дробной частью)")
 	VMErrorNeedHash        = errors.New("Параметр не может быть хэширован")
 	VMErrorNeedBinaryTyper = errors.New("Требуется значение, которое может быть сериализовано в бинарное")
+	VMErrorNeedContext     = errors.New("Требуется значение типа Контекст")
+	VMErrorNoRegExp        = errors.New("Не задан шаблон регулярного выражения")
+	VMErrorNeedBinaryData  = errors.New("Требуется значение типа ДвоичныеДанные или Строка")
+	VMErrorStreamClosed    = errors.New("Поток закрыт")
+
+	VMErrorFSDenied      = errors.New("Доступ к файловой системе запрещен")
+	VMErrorFSReadOnly    = errors.New("Запись в файловую систему запрещена")
+	VMErrorFSOutsideRoot = errors.New("Путь находится за пределами каталога, доступного программе")
+	VMErrorFSRoot        = errors.New("Нельзя удалить или переместить каталог, доступный программе")
+
+	VMErrorUnknownCharset = errors.New("Неизвестная кодировка, допустимы UTF-8, UTF-8-BOM и windows-1251")
+
+	VMErrorStrTemplate   = errors.New("Неверный номер параметра в шаблоне строки")
+	VMErrorIncorrectRune = errors.New("Неверный код символа")
+
+	VMErrorMathDomain    = errors.New("Значение аргумента вне области определения функции")
+	VMErrorMathOverflow  = errors.New("Результат слишком велик")
+	VMErrorMathPrecision = errors.New("Аргумент слишком велик для вычисления с точностью Число")
+
+	VMErrorUnknownHash    = errors.New("Неизвестный алгоритм хеширования, допустимы SHA256, SHA384, SHA512, SHA1 и MD5")
+	VMErrorAESKeySize     = errors.New("Ключ AES должен иметь длину 16, 24 или 32 байта")
+	VMErrorDecrypt        = errors.New("Данные не могут быть расшифрованы этим ключом или повреждены")
+	VMErrorNeedPEMKey     = errors.New("Требуется ключ в формате PEM")
+	VMErrorUnsupportedKey = errors.New("Поддерживаются только ключи RSA и Ed25519")
+	VMErrorPasswordHash   = errors.New("Неверный формат хеша пароля")
+	VMErrorJWTFormat      = errors.New("Неверный формат токена JWT")
+	VMErrorJWTAlgorithm   = errors.New("Алгоритм подписи токена JWT не соответствует ключу")
+	VMErrorJWTSignature   = errors.New("Неверная подпись токена JWT")
+	VMErrorJWTExpired     = errors.New("Срок действия токена JWT истек")
+	VMErrorJWTNotYetValid = errors.New("Срок действия токена JWT еще не наступил")
+	VMErrorJWTEmptyKey    = errors.New("Ключ HMAC для токена JWT не может быть пустым")
 
 	VMErrorIndexOutOfBoundary  = errors.New("Индекс находится за пределами массива")
 	VMErrorNotConverted        = errors.New("Приведение к типу невозможно")
@@ -33,6 +65,9 @@ var (
 	VMErrorNotDefined          = errors.New("Не определено")
 	VMErrorNotBinaryConverted  = errors.New("Значение не может быть преобразовано в бинарный формат")
 
+	VMErrorInterrupted      = errors.New("Выполнение прервано")
+	VMErrorDeadlineExceeded = errors.New("Превышено время ожидания")
+
 	VMErrorNoNeedArgs = errors.New("Параметры не требуются")
 	VMErrorNoArgs     = errors.New("Отсутствуют аргументы")
 
@@ -63,3 +98,16 @@ var (
 func VMErrorNeedArgs(n int) error {
 	return fmt.Errorf("Неверное количество параметров (требуется %d)", n)
 }
+
+// VMErrorFromContext преобразует ошибку отмененного контекста в ошибку вирт. машины
+func VMErrorFromContext(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case context.Canceled:
+		return VMErrorInterrupted
+	case context.DeadlineExceeded:
+		return VMErrorDeadlineExceeded
+	}
+	return err
+}
@@ -0,0 +1,338 @@
+package core
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fsPath проверяет, что v - строка с путем, доступ к которому разрешен политикой ограничений окружения env
+func fsPath(env *Env, v VMValuer, write bool) (string, error) {
+	s, ok := v.(VMString)
+	if !ok {
+		return "", VMErrorNeedString
+	}
+	return env.Sandbox().Path(string(s), write)
+}
+
+// fsRemovablePath проверяет путь так же, как fsPath для записи, и дополнительно запрещает
+// удалять или перемещать сам каталог песочницы
+func fsRemovablePath(env *Env, v VMValuer) (string, error) {
+	path, err := fsPath(env, v, true)
+	if err != nil {
+		return "", err
+	}
+	if env.Sandbox().IsRoot(path) {
+		return "", VMErrorFSRoot
+	}
+	return path, nil
+}
+
+// writeFile записывает в файл двоичные данные или строку, app - дописывать в конец файла
+func writeFile(fn string, v VMValuer, app bool) error {
+	b, err := binaryArg(v)
+	if err != nil {
+		return err
+	}
+	w, err := CreateVMWriter(fn, app)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// findFiles возвращает отсортированные пути файлов и каталогов внутри dir, имена которых соответствуют маске
+func findFiles(dir, mask string, recursive bool) ([]string, error) {
+	if _, err := filepath.Match(mask, ""); err != nil {
+		return nil, err
+	}
+	var res []string
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if ok, _ := filepath.Match(mask, fi.Name()); ok {
+			res = append(res, path)
+		}
+		if fi.IsDir() && !recursive {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	sort.Strings(res)
+	return res, err
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// fileProperties возвращает свойства файла в виде структуры
+func fileProperties(path string, fi os.FileInfo) VMStringMap {
+	return VMStringMap{
+		"Имя":            VMString(fi.Name()),
+		"ПолноеИмя":      VMString(path),
+		"Размер":         VMInt(fi.Size()),
+		"ЭтоКаталог":     VMBool(fi.IsDir()),
+		"ВремяИзменения": VMTime(fi.ModTime()),
+		"ВремяДоступа":   VMTime(fileAccessTime(fi)),
+		"Режим":          VMString(fi.Mode().String()),
+		"Права":          VMInt(fi.Mode().Perm()),
+	}
+}
+
+// importFS регистрирует функции работы с файловой системой.
+// Доступ к файлам проверяется политикой ограничений окружения, см. Sandbox
+func importFS(env *Env) {
+
+	env.DefineS("записатьфайл", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		fn, err := fsPath(env, args[0], true)
+		if err != nil {
+			return err
+		}
+		return writeFile(fn, args[1], false)
+	}))
+
+	env.DefineS("дописатьвфайл", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		fn, err := fsPath(env, args[0], true)
+		if err != nil {
+			return err
+		}
+		return writeFile(fn, args[1], true)
+	}))
+
+	env.DefineS("найтифайлы", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// маска и признак поиска во вложенных каталогах необязательны
+		if len(args) < 1 || len(args) > 3 {
+			return VMErrorNeedArgs(3)
+		}
+		dir, err := fsPath(env, args[0], false)
+		if err != nil {
+			return err
+		}
+		mask := VMString("*")
+		if len(args) > 1 {
+			var ok bool
+			if mask, ok = args[1].(VMString); !ok {
+				return VMErrorNeedString
+			}
+		}
+		var rec VMBool
+		if len(args) > 2 {
+			var ok bool
+			if rec, ok = args[2].(VMBool); !ok {
+				return VMErrorNeedBool
+			}
+		}
+		files, err := findFiles(dir, string(mask), bool(rec))
+		if err != nil {
+			return err
+		}
+		rets.Append(NewVMSliceFromStrings(files))
+		return nil
+	}))
+
+	env.DefineS("файлсуществует", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		fn, err := fsPath(env, args[0], false)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stat(fn)
+		rets.Append(VMBool(err == nil))
+		return nil
+	}))
+
+	env.DefineS("свойствафайла", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		fn, err := fsPath(env, args[0], false)
+		if err != nil {
+			return err
+		}
+		fi, err := os.Stat(fn)
+		if err != nil {
+			return err
+		}
+		rets.Append(fileProperties(fn, fi))
+		return nil
+	}))
+
+	env.DefineS("копироватьфайл", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		from, err := fsPath(env, args[0], false)
+		if err != nil {
+			return err
+		}
+		to, err := fsPath(env, args[1], true)
+		if err != nil {
+			return err
+		}
+		return copyFile(from, to)
+	}))
+
+	env.DefineS("переместитьфайл", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		from, err := fsRemovablePath(env, args[0])
+		if err != nil {
+			return err
+		}
+		to, err := fsRemovablePath(env, args[1])
+		if err != nil {
+			return err
+		}
+		return os.Rename(from, to)
+	}))
+
+	env.DefineS("удалитьфайлы", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// без маски удаляется файл или каталог со всем содержимым,
+		// с маской - соответствующие ей файлы и каталоги внутри каталога
+		if len(args) < 1 || len(args) > 2 {
+			return VMErrorNeedArgs(2)
+		}
+		if len(args) == 1 {
+			path, err := fsRemovablePath(env, args[0])
+			if err != nil {
+				return err
+			}
+			return os.RemoveAll(path)
+		}
+		path, err := fsPath(env, args[0], true)
+		if err != nil {
+			return err
+		}
+		mask, ok := args[1].(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		files, err := findFiles(path, string(mask), false)
+		if err != nil {
+			return err
+		}
+		for _, fn := range files {
+			if err := os.RemoveAll(fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	env.DefineS("создатькаталог", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		dir, err := fsPath(env, args[0], true)
+		if err != nil {
+			return err
+		}
+		return os.MkdirAll(dir, 0755)
+	}))
+
+	env.DefineS("временныйфайл", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// создается пустой файл с уникальным именем и необязательным расширением, возвращается его имя
+		if len(args) > 1 {
+			return VMErrorNeedArgs(1)
+		}
+		var ext VMString
+		if len(args) == 1 {
+			var ok bool
+			if ext, ok = args[0].(VMString); !ok {
+				return VMErrorNeedString
+			}
+			if ext != "" && !strings.HasPrefix(string(ext), ".") {
+				ext = "." + ext
+			}
+		}
+		sb := env.Sandbox()
+		dir, err := sb.Path(sb.TempDir(), true)
+		if err != nil {
+			return err
+		}
+		f, err := ioutil.TempFile(dir, "gonec*"+string(ext))
+		if err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		rets.Append(VMString(f.Name()))
+		return nil
+	}))
+
+	env.DefineS("объединитьпути", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		if len(args) == 0 {
+			return VMErrorNoArgs
+		}
+		parts := make([]string, len(args))
+		for i, v := range args {
+			s, ok := v.(VMString)
+			if !ok {
+				return VMErrorNeedString
+			}
+			parts[i] = string(s)
+		}
+		rets.Append(VMString(filepath.Join(parts...)))
+		return nil
+	}))
+
+	env.DefineS("разложитьпуть", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, ok := args[0].(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		dir, name := filepath.Split(string(s))
+		ext := filepath.Ext(name)
+		rets.Append(VMStringMap{
+			"Каталог":          VMString(dir),
+			"Имя":              VMString(name),
+			"ИмяБезРасширения": VMString(strings.TrimSuffix(name, ext)),
+			"Расширение":       VMString(ext),
+		})
+		return nil
+	}))
+
+	env.DefineS("полныйпуть", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		fn, err := fsPath(env, args[0], false)
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(fn)
+		if err != nil {
+			return err
+		}
+		rets.Append(VMString(abs))
+		return nil
+	}))
+}
@@ -0,0 +1,18 @@
+//go:build linux
+// +build linux
+
+package core
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileAccessTime возвращает время последнего доступа к файлу
+func fileAccessTime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package core
+
+import (
+	"os"
+	"time"
+)
+
+// fileAccessTime возвращает время последнего доступа к файлу,
+// на этой платформе оно не определяется и совпадает со временем изменения
+func fileAccessTime(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
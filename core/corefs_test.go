@@ -0,0 +1,118 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFSErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "есть.txt"), []byte("данные"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := NewEnv()
+	LoadAllBuiltins(env)
+	env.SetSandbox(&Sandbox{Root: dir})
+	s := func(v string) VMString { return VMString(v) }
+	checkBuiltinErrors(t, env, []builtinErrCase{
+		{"ЗаписатьФайл", VMSlice{VMInt(1), s("а")}, VMErrorNeedString.Error()},
+		{"ЗаписатьФайл", VMSlice{s("а.txt"), VMInt(1)}, VMErrorNeedBinaryData.Error()},
+		{"ЗаписатьФайл", VMSlice{s("нет/а.txt"), s("а")}, "нет"},
+		{"ДописатьВФайл", VMSlice{s("а.txt")}, VMErrorNeedArgs(2).Error()},
+		{"НайтиФайлы", VMSlice{}, VMErrorNeedArgs(3).Error()},
+		{"НайтиФайлы", VMSlice{s("."), VMInt(1)}, VMErrorNeedString.Error()},
+		{"НайтиФайлы", VMSlice{s("."), s("*"), s("да")}, VMErrorNeedBool.Error()},
+		{"НайтиФайлы", VMSlice{s("."), s("[")}, "syntax error in pattern"},
+		{"НайтиФайлы", VMSlice{s("нет")}, "нет"},
+		{"СвойстваФайла", VMSlice{s("нет.txt")}, "нет"},
+		{"КопироватьФайл", VMSlice{s("нет.txt"), s("копия.txt")}, "нет"},
+		{"ПереместитьФайл", VMSlice{s("нет.txt"), s("копия.txt")}, "нет"},
+		{"УдалитьФайлы", VMSlice{s("."), s("*"), s("*")}, VMErrorNeedArgs(2).Error()},
+		{"УдалитьФайлы", VMSlice{s("."), VMBool(true)}, VMErrorNeedString.Error()},
+		{"ВременныйФайл", VMSlice{s("txt"), s("txt")}, VMErrorNeedArgs(1).Error()},
+		{"ВременныйФайл", VMSlice{VMInt(1)}, VMErrorNeedString.Error()},
+		{"ОбъединитьПути", VMSlice{}, VMErrorNoArgs.Error()},
+		{"ОбъединитьПути", VMSlice{s("а"), VMInt(1)}, VMErrorNeedString.Error()},
+		{"РазложитьПуть", VMSlice{VMInt(1)}, VMErrorNeedString.Error()},
+		{"ОткрытьФайл", VMSlice{s("нет.txt")}, "нет"},
+		{"СоздатьФайл", VMSlice{s("а.txt"), s("да")}, VMErrorNeedBool.Error()},
+	})
+
+	// сам каталог песочницы нельзя удалить или переместить
+	checkBuiltinErrors(t, env, []builtinErrCase{
+		{"УдалитьФайлы", VMSlice{s(".")}, VMErrorFSRoot.Error()},
+		{"УдалитьФайлы", VMSlice{s(dir + string(filepath.Separator))}, VMErrorFSRoot.Error()},
+		{"УдалитьФайлы", VMSlice{s("нет/..")}, VMErrorFSRoot.Error()},
+		{"ПереместитьФайл", VMSlice{s("."), s("копия")}, VMErrorFSRoot.Error()},
+		{"ПереместитьФайл", VMSlice{s("есть.txt"), s(dir)}, VMErrorFSRoot.Error()},
+	})
+
+	// каждая функция проверяет путь политикой ограничений
+	outside := filepath.Join(filepath.Dir(dir), "снаружи.txt")
+	read := []builtinErrCase{
+		{"НайтиФайлы", VMSlice{s("..")}, ""},
+		{"ФайлСуществует", VMSlice{s(outside)}, ""},
+		{"СвойстваФайла", VMSlice{s("../есть.txt")}, ""},
+		{"КопироватьФайл", VMSlice{s(outside), s("копия.txt")}, ""},
+		{"ПолныйПуть", VMSlice{s("..")}, ""},
+		{"ОткрытьФайл", VMSlice{s(outside)}, ""},
+	}
+	write := []builtinErrCase{
+		{"ЗаписатьФайл", VMSlice{s(outside), s("а")}, ""},
+		{"ДописатьВФайл", VMSlice{s("../снаружи.txt"), s("а")}, ""},
+		{"КопироватьФайл", VMSlice{s("есть.txt"), s(outside)}, ""},
+		{"ПереместитьФайл", VMSlice{s("есть.txt"), s(outside)}, ""},
+		{"ПереместитьФайл", VMSlice{s(outside), s("есть.txt")}, ""},
+		{"УдалитьФайлы", VMSlice{s("..")}, ""},
+		{"УдалитьФайлы", VMSlice{s(".."), s("*")}, ""},
+		{"СоздатьКаталог", VMSlice{s("../каталог")}, ""},
+		{"СоздатьФайл", VMSlice{s(outside)}, ""},
+	}
+	withErr := func(cases []builtinErrCase, err error) []builtinErrCase {
+		res := make([]builtinErrCase, len(cases))
+		for i, c := range cases {
+			c.want = err.Error()
+			res[i] = c
+		}
+		return res
+	}
+	checkBuiltinErrors(t, env, withErr(append(read, write...), VMErrorFSOutsideRoot))
+
+	// только чтение: запись запрещена, в том числе в каталоге песочницы
+	inside := []builtinErrCase{
+		{"ЗаписатьФайл", VMSlice{s("новый.txt"), s("а")}, ""},
+		{"КопироватьФайл", VMSlice{s("есть.txt"), s("новый.txt")}, ""},
+		{"ПереместитьФайл", VMSlice{s("есть.txt"), s("новый.txt")}, ""},
+		{"УдалитьФайлы", VMSlice{s("есть.txt")}, ""},
+		{"СоздатьКаталог", VMSlice{s("каталог")}, ""},
+		{"ВременныйФайл", VMSlice{}, ""},
+		{"СоздатьФайл", VMSlice{s("новый.txt")}, ""},
+	}
+	env.SetSandbox(&Sandbox{FS: FSReadOnly, Root: dir})
+	checkBuiltinErrors(t, env, withErr(inside, VMErrorFSReadOnly))
+	if rets, err := callBuiltin(env, "ФайлСуществует", s("есть.txt")); err != nil || rets[0] != VMBool(true) {
+		t.Errorf("чтение в режиме только чтения: %v, %v", rets, err)
+	}
+
+	env.SetSandbox(&Sandbox{FS: FSDenied})
+	checkBuiltinErrors(t, env, withErr(append(inside, read...), VMErrorFSDenied))
+
+	// ни одна из отклоненных операций не изменила файлы
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil || len(files) != 1 || filepath.Base(files[0]) != "есть.txt" {
+		t.Errorf("файлы в каталоге: %v, %v", files, err)
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("создан файл за пределами каталога: %v", err)
+	}
+}
@@ -1,11 +1,12 @@
 package core
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
-
-	"github.com/covrom/gonec/names"
 )
 
 // VMHttpRequest запрос к http серверу
@@ -44,17 +45,28 @@ func (x *VMHttpRequest) Close() {
 }
 
 func (x *VMHttpRequest) ReadBody() (b VMString, err error) {
+	bb, err := x.readBody()
+	return VMString(bb), err
+}
+
+// readBody читает тело запроса целиком и оставляет его копию для повторного чтения,
+// в том числе для разбора параметров формы
+func (x *VMHttpRequest) readBody() ([]byte, error) {
 	if x.body != nil {
-		return VMString(x.body), nil
+		return x.body, nil
 	}
-	x.body, err = ioutil.ReadAll(x.r.Body)
-	if x.r.Body != nil {
-		x.r.Body.Close()
+	if x.r.Body == nil {
+		x.body = []byte{}
+		return x.body, nil
 	}
+	b, err := ioutil.ReadAll(x.r.Body)
+	x.r.Body.Close()
 	if err != nil {
-		return VMString(""), err
+		return nil, err
 	}
-	return VMString(x.body), nil
+	x.body = b
+	x.r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return x.body, nil
 }
 
 func (x *VMHttpRequest) Path() VMString {
@@ -86,22 +98,25 @@ func (x *VMHttpRequest) Method() VMString {
 //  "ПараметрыФормы":{"Имя":Значение,...},
 //  "Метод":Метод,
 //  "Заголовки":{"Имя":Значение,...},
-//  "Тело":"Строка"
+//  "Тело":"Строка",
+//  "Файлы":{"Имя":{"ИмяФайла":Строка,"ТипСодержимого":Строка,"Размер":ЦелоеЧисло,"Данные":ДвоичныеДанные},...}
 // }
 func (x *VMHttpRequest) RequestAsVMStringMap() (VMStringMap, error) {
 
 	rmap := make(VMStringMap)
 
-	err := x.r.ParseMultipartForm(32 << 20)
-	// if err != nil {
-	// 	return rmap, err
-	// }
-
+	// тело читается до разбора формы, иначе разбор составной формы его поглощает
+	var err error
 	rmap["Тело"], err = x.ReadBody()
 	if err != nil {
 		return rmap, err
 	}
 
+	x.r.ParseMultipartForm(32 << 20)
+	// if err != nil {
+	// 	return rmap, err
+	// }
+
 	rmap["Адрес"] = x.RemoteAddr()
 	rmap["Путь"] = x.Path()
 	rmap["Фрагмент"] = x.Fragment()
@@ -132,14 +147,47 @@ func (x *VMHttpRequest) RequestAsVMStringMap() (VMStringMap, error) {
 	}
 	rmap["ПараметрыФормы"] = m3
 
+	m4 := make(VMStringMap)
+	if x.r.MultipartForm != nil {
+		for k, v := range x.r.MultipartForm.File {
+			if len(v) > 0 {
+				f, err := uploadedFile(v[0])
+				if err != nil {
+					return rmap, err
+				}
+				m4[k] = f
+			}
+		}
+	}
+	rmap["Файлы"] = m4
+
 	return rmap, nil
 }
 
-func (x *VMHttpRequest) MethodMember(name int) (VMFunc, bool) {
+// uploadedFile возвращает структуру с файлом, загруженным через составную форму
+func uploadedFile(fh *multipart.FileHeader) (VMStringMap, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return VMStringMap{
+		"ИмяФайла":       VMString(fh.Filename),
+		"ТипСодержимого": VMString(fh.Header.Get("Content-Type")),
+		"Размер":         VMInt(len(b)),
+		"Данные":         VMBinaryData(b),
+	}, nil
+}
+
+func (x *VMHttpRequest) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
 
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "метод":
 		return VMFuncMustParams(0, x.Метод), true
 	case "заголовок":
@@ -148,6 +196,10 @@ func (x *VMHttpRequest) MethodMember(name int) (VMFunc, bool) {
 		return VMFuncMustParams(2, x.УстановитьЗаголовок), true
 	case "тело":
 		return VMFuncMustParams(0, x.Тело), true
+	case "двоичноетело":
+		return VMFuncMustParams(0, x.ДвоичноеТело), true
+	case "поток":
+		return VMFuncMustParams(0, x.Поток), true
 	case "путь":
 		return VMFuncMustParams(0, x.Путь), true
 	case "адрес":
@@ -198,6 +250,30 @@ func (x *VMHttpRequest) Тело(args VMSlice, rets *VMSlice, envout *(*Env)) er
 	return nil
 }
 
+// ДвоичноеТело возвращает тело запроса без преобразования в строку
+func (x *VMHttpRequest) ДвоичноеТело(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	b, err := x.readBody()
+	if err != nil {
+		return err
+	}
+	rets.Append(VMBinaryData(b))
+	return nil
+}
+
+// Поток возвращает ПотокЧтения тела запроса, если тело еще не прочитано - без чтения в память
+func (x *VMHttpRequest) Поток(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if x.body != nil || x.r.Body == nil {
+		b, err := x.readBody()
+		if err != nil {
+			return err
+		}
+		rets.Append(NewVMReader(bytes.NewReader(b), nil))
+		return nil
+	}
+	rets.Append(NewVMReader(x.r.Body, x.r.Body))
+	return nil
+}
+
 func (x *VMHttpRequest) Путь(args VMSlice, rets *VMSlice, envout *(*Env)) error {
 	rets.Append(x.Path())
 	return nil
@@ -278,7 +354,9 @@ func (x *VMHttpResponse) ReadBody() (b VMString, err error) {
 	return VMString(x.body), nil
 }
 
-func (x *VMHttpResponse) Send(status VMInt, b VMString, h VMStringMap) error {
+// Send отправляет ответ сервера, тело - строка (дополняется переводом строки), ДвоичныеДанные или ПотокЧтения
+// (отправляются без изменений)
+func (x *VMHttpResponse) Send(status VMInt, b VMValuer, h VMStringMap) error {
 	hdrs := x.w.Header()
 	for k, v := range h {
 		vv, ok := v.(VMStringer)
@@ -290,6 +368,14 @@ func (x *VMHttpResponse) Send(status VMInt, b VMString, h VMStringMap) error {
 
 	x.w.WriteHeader(int(status))
 
+	switch bb := b.(type) {
+	case VMBinaryData:
+		_, err := x.w.Write(bb)
+		return err
+	case *VMReader:
+		_, err := io.Copy(x.w, bb)
+		return err
+	}
 	fmt.Fprintln(x.w, b)
 	return nil
 }
@@ -318,20 +404,53 @@ func (x *VMHttpResponse) RequestAsVMStringMap() (VMStringMap, error) {
 	return rmap, nil
 }
 
-func (x *VMHttpResponse) MethodMember(name int) (VMFunc, bool) {
+func (x *VMHttpResponse) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
 
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "отправить":
 		return VMFuncMustParams(1, x.Отправить), true
 	case "сообщение":
 		return VMFuncMustParams(0, x.Сообщение), true
+	case "двоичноетело":
+		return VMFuncMustParams(0, x.ДвоичноеТело), true
+	case "поток":
+		return VMFuncMustParams(0, x.Поток), true
 	}
 
 	return nil, false
 }
 
+// ДвоичноеТело возвращает тело ответа, полученного клиентом, без преобразования в строку
+func (x *VMHttpResponse) ДвоичноеТело(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if x.r == nil {
+		return VMErrorWrongHTTPMethod
+	}
+	if _, err := x.ReadBody(); err != nil {
+		return err
+	}
+	rets.Append(VMBinaryData(x.body))
+	return nil
+}
+
+// Поток в обработчике сервера возвращает ПотокЗаписи ответа (статус 200, если ответ не отправлен),
+// а для ответа, полученного клиентом, - ПотокЧтения его тела
+func (x *VMHttpResponse) Поток(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if x.w != nil {
+		rets.Append(NewVMWriter(x.w, nil))
+		return nil
+	}
+	if x.r == nil {
+		return VMErrorWrongHTTPMethod
+	}
+	if _, err := x.ReadBody(); err != nil {
+		return err
+	}
+	rets.Append(NewVMReader(bytes.NewReader(x.body), nil))
+	return nil
+}
+
 func (x *VMHttpResponse) Отправить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
 	if x.w == nil || x.w == http.ResponseWriter(nil) {
 		return VMErrorHTTPResponseMethod
@@ -358,10 +477,13 @@ func (x *VMHttpResponse) Отправить(args VMSlice, rets *VMSlice, envout
 		sts = http.StatusOK
 	}
 
-	var b VMString
+	var b VMValuer = VMString("")
 	if v, ok := vsm["Тело"]; ok {
-		if b, ok = v.(VMString); !ok {
-			return VMErrorNeedString
+		switch v.(type) {
+		case VMString, VMBinaryData, *VMReader:
+			b = v
+		default:
+			return VMErrorNeedBinaryData
 		}
 	}
 
@@ -141,19 +141,25 @@ type (
 		// !!!эта функция должна быть обязательно реализована в конечном объекте!!!
 		VMRegister()
 
-		VMRegisterMethod(string, VMMethod) // реализовано в VMMetaObj
-		VMRegisterField(string, VMValuer)  // реализовано в VMMetaObj
+		VMRegisterMethod(string, VMMethod)   // реализовано в VMMetaObj
+		VMRegisterField(string, interface{}) // реализовано в VMMetaObj
 
-		VMIsField(int) bool             // реализовано в VMMetaObj
-		VMGetField(int) VMValuer        // реализовано в VMMetaObj
-		VMSetField(int, VMValuer)       // реализовано в VMMetaObj
-		VMGetMethod(int) (VMFunc, bool) // реализовано в VMMetaObj
+		VMIsField(string) bool             // реализовано в VMMetaObj
+		VMGetField(string) VMValuer        // реализовано в VMMetaObj
+		VMSetField(string, VMValuer)       // реализовано в VMMetaObj
+		VMGetMethod(string) (VMFunc, bool) // реализовано в VMMetaObj
+	}
+
+	// VMConstructor реализуется объектом метаданных, который принимает параметры при создании: Новый Тип(параметры)
+	VMConstructor interface {
+		VMMetaObject
+		VMNew(args VMSlice) error
 	}
 
 	// VMMethodImplementer реализует только методы, доступные в языке Гонец
 	VMMethodImplementer interface{
 		VMValuer
-		MethodMember(int) (VMFunc, bool) // возвращает метод в нужном формате		
+		MethodMember(string) (VMFunc, bool) // возвращает метод по названию в нижнем регистре		
 	}
 
 	// VMServicer определяет микросервис, который может регистрироваться в главном менеджере сервисов
@@ -235,14 +235,7 @@ func (x VMInt) EvalBinOp(op VMOperation, y VMOperationer) (VMValuer, error) {
 	case LAND:
 		return VMNil, VMErrorIncorrectOperation
 	case POW:
-		switch yy := y.(type) {
-		case VMInt:
-			// TODO: переделать на math
-			return NewVMDecNumFromInt64(int64(x)).Pow(NewVMDecNumFromInt64(int64(yy))), nil
-		case VMDecNum:
-			return NewVMDecNumFromInt64(int64(x)).Pow(yy), nil
-		}
-		return VMNil, VMErrorIncorrectOperation
+		return NewVMDecNumFromInt64(int64(x)).EvalBinOp(POW, y)
 	case SHR:
 		switch yy := y.(type) {
 		case VMInt:
@@ -0,0 +1,677 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonWriteOptions - параметры записи JSON, задаются структурой с полями Отступ, ПорядокКлючей, ЭкранироватьHTML
+type jsonWriteOptions struct {
+	indent     string
+	keyOrder   map[string]int // ключи, которые записываются первыми в заданном порядке, остальные - по алфавиту
+	escapeHTML bool
+}
+
+func parseJSONWriteOptions(v VMValuer) (*jsonWriteOptions, error) {
+	o := &jsonWriteOptions{}
+	if v == nil {
+		return o, nil
+	}
+	opts, ok := v.(VMStringMap)
+	if !ok {
+		return nil, VMErrorNeedMap
+	}
+	if err := checkOptions(opts, "Отступ", "ПорядокКлючей", "ЭкранироватьHTML"); err != nil {
+		return nil, err
+	}
+	var err error
+	if o.indent, err = indentArg(opts["Отступ"]); err != nil {
+		return nil, err
+	}
+	if ko, ok := opts["ПорядокКлючей"]; ok {
+		sl, ok := ko.(VMSlice)
+		if !ok {
+			return nil, VMErrorNeedSlice
+		}
+		o.keyOrder = make(map[string]int, len(sl))
+		for i, k := range sl {
+			ks, ok := k.(VMString)
+			if !ok {
+				return nil, VMErrorNeedString
+			}
+			o.keyOrder[string(ks)] = i
+		}
+	}
+	if eh, ok := opts["ЭкранироватьHTML"]; ok {
+		b, ok := eh.(VMBool)
+		if !ok {
+			return nil, VMErrorNeedBool
+		}
+		o.escapeHTML = bool(b)
+	}
+	return o, nil
+}
+
+// indentArg возвращает отступ для форматированного вывода, заданный строкой или количеством пробелов
+func indentArg(v VMValuer) (string, error) {
+	switch ind := v.(type) {
+	case nil, VMNilType, VMNullType:
+		return "", nil
+	case VMString:
+		return string(ind), nil
+	case VMInt:
+		if ind < 0 {
+			return "", VMErrorNeedInt
+		}
+		return strings.Repeat(" ", int(ind)), nil
+	}
+	return "", errors.New("Отступ должен быть строкой или количеством пробелов")
+}
+
+// checkOptions возвращает ошибку, если в структуре параметров есть поле, которого нет в keys,
+// чтобы опечатка в названии параметра не приводила к молчаливому использованию значения по умолчанию
+func checkOptions(opts VMStringMap, keys ...string) error {
+	known := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		known[k] = true
+	}
+	var unknown []string
+	for k := range opts {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("Неизвестный параметр %s, допустимы %s", unknown[0], strings.Join(keys, ", "))
+}
+
+// sortKeys упорядочивает ключи структуры: сначала перечисленные в ПорядокКлючей, затем остальные по алфавиту
+func (o *jsonWriteOptions) sortKeys(keys []string) {
+	sort.Slice(keys, func(i, j int) bool {
+		oi, iok := o.keyOrder[keys[i]]
+		oj, jok := o.keyOrder[keys[j]]
+		switch {
+		case iok && jok:
+			return oi < oj
+		case iok != jok:
+			return iok
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+func (o *jsonWriteOptions) encodeString(buf *bytes.Buffer, s string) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(o.escapeHTML)
+	enc.Encode(s)
+	buf.Write(bytes.TrimRight(b.Bytes(), "\n"))
+}
+
+func (o *jsonWriteOptions) encode(buf *bytes.Buffer, v VMValuer) error {
+	switch vv := v.(type) {
+	case nil, VMNilType, VMNullType:
+		buf.WriteString("null")
+	case VMString:
+		o.encodeString(buf, string(vv))
+	case VMInt:
+		buf.WriteString(strconv.FormatInt(int64(vv), 10))
+	case VMDecNum:
+		// десятичные числа записываются числами JSON, а не строками
+		s := vv.String()
+		if !json.Valid([]byte(s)) {
+			return fmt.Errorf("Число %s не может быть записано в JSON", s)
+		}
+		buf.WriteString(s)
+	case VMBool:
+		buf.WriteString(strconv.FormatBool(bool(vv)))
+	case VMBinaryData:
+		o.encodeString(buf, string(vv.Base64()))
+	case VMStringMap:
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		o.sortKeys(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			o.encodeString(buf, k)
+			buf.WriteByte(':')
+			if err := o.encode(buf, vv[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case VMSlice:
+		buf.WriteByte('[')
+		for i, e := range vv {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := o.encode(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case VMStringMaper:
+		return o.encode(buf, vv.StringMap())
+	case VMSlicer:
+		return o.encode(buf, vv.Slice())
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("Значение не может быть записано в JSON: %s", err)
+		}
+		buf.Write(b)
+	}
+	return nil
+}
+
+// WriteJSON возвращает значение в формате JSON с параметрами записи opts (может быть nil)
+func WriteJSON(v, opts VMValuer) (VMString, error) {
+	o, err := parseJSONWriteOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := o.encode(&buf, v); err != nil {
+		return "", err
+	}
+	if o.indent == "" {
+		return VMString(buf.String()), nil
+	}
+	var ibuf bytes.Buffer
+	if err := json.Indent(&ibuf, buf.Bytes(), "", o.indent); err != nil {
+		return "", err
+	}
+	return VMString(ibuf.String()), nil
+}
+
+// типы чисел, в которые читаются числа JSON
+const (
+	jsonNumAuto = iota // целые - ЦелоеЧисло, остальные - Число
+	jsonNumInt         // только целые числа
+	jsonNumDec         // все числа - Число
+)
+
+// jsonReadOptions - параметры чтения JSON, задаются структурой с полями ТипЧисел, ЧитатьДаты
+type jsonReadOptions struct {
+	numbers   int
+	readDates bool // строки в формате ISO 8601 читаются как даты
+}
+
+func parseJSONReadOptions(v VMValuer) (*jsonReadOptions, error) {
+	o := &jsonReadOptions{}
+	if v == nil {
+		return o, nil
+	}
+	opts, ok := v.(VMStringMap)
+	if !ok {
+		return nil, VMErrorNeedMap
+	}
+	if err := checkOptions(opts, "ТипЧисел", "ЧитатьДаты"); err != nil {
+		return nil, err
+	}
+	if tn, ok := opts["ТипЧисел"]; ok {
+		s, ok := tn.(VMString)
+		if !ok {
+			return nil, VMErrorNeedString
+		}
+		switch strings.ToLower(string(s)) {
+		case "авто":
+			o.numbers = jsonNumAuto
+		case "целое":
+			o.numbers = jsonNumInt
+		case "число":
+			o.numbers = jsonNumDec
+		default:
+			return nil, errors.New("ТипЧисел может быть Авто, Целое или Число")
+		}
+	}
+	if rd, ok := opts["ЧитатьДаты"]; ok {
+		b, ok := rd.(VMBool)
+		if !ok {
+			return nil, VMErrorNeedBool
+		}
+		o.readDates = bool(b)
+	}
+	return o, nil
+}
+
+func (o *jsonReadOptions) number(n json.Number) (VMValuer, error) {
+	if o.numbers != jsonNumDec {
+		if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+			return VMInt(i), nil
+		}
+		if o.numbers == jsonNumInt {
+			return nil, fmt.Errorf("Число %s не является целым", n)
+		}
+	}
+	return ParseVMDecNum(string(n))
+}
+
+func (o *jsonReadOptions) str(s string) VMValuer {
+	if o.readDates && len(s) >= 19 && s[4] == '-' && s[10] == 'T' {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return VMTime(t)
+		}
+		if t, err := time.ParseInLocation("2006-01-02T15:04:05", s, time.Local); err == nil {
+			return VMTime(t)
+		}
+	}
+	return VMString(s)
+}
+
+// value преобразует значение, прочитанное json.Decoder с UseNumber, в значение вирт. машины
+func (o *jsonReadOptions) value(v interface{}) (VMValuer, error) {
+	switch vv := v.(type) {
+	case nil:
+		return VMNil, nil
+	case bool:
+		return VMBool(vv), nil
+	case string:
+		return o.str(vv), nil
+	case json.Number:
+		return o.number(vv)
+	case []interface{}:
+		rv := make(VMSlice, len(vv))
+		for i, e := range vv {
+			var err error
+			if rv[i], err = o.value(e); err != nil {
+				return nil, err
+			}
+		}
+		return rv, nil
+	case map[string]interface{}:
+		rv := make(VMStringMap, len(vv))
+		for k, e := range vv {
+			var err error
+			if rv[k], err = o.value(e); err != nil {
+				return nil, err
+			}
+		}
+		return rv, nil
+	}
+	return nil, VMErrorNotConverted
+}
+
+// jsonDecoder - json.Decoder, который считает байты, прочитанные из источника,
+// чтобы сообщать в ошибках позицию в исходных данных
+type jsonDecoder struct {
+	*json.Decoder
+	src *countingReader
+}
+
+func newJSONDecoder(r io.Reader) *jsonDecoder {
+	cr := &countingReader{r: r}
+	dec := json.NewDecoder(cr)
+	dec.UseNumber()
+	return &jsonDecoder{Decoder: dec, src: cr}
+}
+
+// offset возвращает смещение в исходных данных, до которого дошел разбор
+func (d *jsonDecoder) offset() int64 {
+	buf, _ := io.Copy(ioutil.Discard, d.Buffered())
+	return d.src.n - buf
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (o *jsonReadOptions) decode(dec *jsonDecoder) (VMValuer, error) {
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, jsonReadError(dec, err)
+	}
+	return o.value(v)
+}
+
+// jsonReadError дополняет ошибку чтения JSON позицией в исходных данных
+func jsonReadError(dec *jsonDecoder, err error) error {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		return fmt.Errorf("Ошибка чтения JSON в позиции %d: %s", e.Offset, e)
+	case *json.UnmarshalTypeError:
+		return fmt.Errorf("Ошибка чтения JSON в позиции %d: %s", e.Offset, e)
+	}
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return fmt.Errorf("Ошибка чтения JSON в позиции %d: неожиданный конец данных", dec.offset())
+	}
+	return err
+}
+
+// ReadJSON читает одно значение JSON из строки, двоичных данных или потока с параметрами opts (может быть nil)
+func ReadJSON(src, opts VMValuer) (VMValuer, error) {
+	o, err := parseJSONReadOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	r, err := readerArg(src)
+	if err != nil {
+		return nil, err
+	}
+	dec := newJSONDecoder(r)
+	v, err := o.decode(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("Ошибка чтения JSON в позиции %d: лишние данные после значения", dec.offset())
+	}
+	return v, nil
+}
+
+// VMJSONReader - потоковое чтение JSON (ЧтениеJSON): элементы массива верхнего уровня
+// или последовательность значений (JSON Lines) читаются по одному, не загружая все данные в память.
+// Создается как Новый ЧтениеJSON(источник, параметры), источник - строка, двоичные данные или ПотокЧтения
+type VMJSONReader struct {
+	VMMetaObj
+
+	dec   *jsonDecoder
+	opts  *jsonReadOptions
+	src   io.Reader
+	array bool // данные - массив, а не последовательность значений
+	done  bool
+}
+
+func (x *VMJSONReader) VMRegister() {
+	x.VMRegisterMethod("Следующий", x.Следующий)
+	x.VMRegisterMethod("Закрыть", x.Закрыть)
+}
+
+// VMNew открывает источник данных
+func (x *VMJSONReader) VMNew(args VMSlice) error {
+	if len(args) != 1 && len(args) != 2 {
+		return VMErrorNeedArgs(2)
+	}
+	var opts VMValuer
+	if len(args) == 2 {
+		opts = args[1]
+	}
+	o, err := parseJSONReadOptions(opts)
+	if err != nil {
+		return err
+	}
+	r, err := readerArg(args[0])
+	if err != nil {
+		return err
+	}
+	// первый значащий символ определяет, читается массив или последовательность значений
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			break
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			br.ReadByte()
+			continue
+		}
+		x.array = b[0] == '['
+		break
+	}
+	x.dec = newJSONDecoder(br)
+	if x.array {
+		x.dec.Token()
+	}
+	x.opts = o
+	x.src = r
+	return nil
+}
+
+func (x *VMJSONReader) String() string {
+	return "ЧтениеJSON"
+}
+
+// Следующий возвращает очередное значение и признак того, что оно прочитано (Ложь - данные закончились)
+func (x *VMJSONReader) Следующий(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	if x.dec == nil {
+		return VMErrorStreamClosed
+	}
+	if x.done || !x.dec.More() {
+		if x.array && !x.done {
+			// закрывающая скобка массива
+			if _, err := x.dec.Token(); err != nil {
+				return jsonReadError(x.dec, err)
+			}
+		}
+		x.done = true
+		rets.Append(VMNil, VMBool(false))
+		return nil
+	}
+	v, err := x.opts.decode(x.dec)
+	if err != nil {
+		return err
+	}
+	rets.Append(v, VMBool(true))
+	return nil
+}
+
+// Закрыть закрывает источник данных, если это поток чтения
+func (x *VMJSONReader) Закрыть(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	x.dec = nil
+	if c, ok := x.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// jsonPathStep - шаг пути: ключ структуры, индекс массива или все элементы (*)
+type jsonPathStep struct {
+	key   string
+	index int
+	isKey bool
+	all   bool
+}
+
+// parseJSONPath разбирает путь вида $.a[0].b, $['ключ'][*], .* - все элементы, $ в начале необязателен
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	bad := func() ([]jsonPathStep, error) {
+		return nil, fmt.Errorf("Неверный путь: %s", path)
+	}
+	p := strings.TrimPrefix(path, "$")
+	if p != "" && p[0] != '.' && p[0] != '[' {
+		// путь может начинаться сразу с ключа: а.б[0]
+		p = "." + p
+	}
+	var steps []jsonPathStep
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			i := strings.IndexAny(p, ".[")
+			if i < 0 {
+				i = len(p)
+			}
+			if i == 0 {
+				return bad()
+			}
+			if p[:i] == "*" {
+				steps = append(steps, jsonPathStep{all: true})
+			} else {
+				steps = append(steps, jsonPathStep{key: p[:i], isKey: true})
+			}
+			p = p[i:]
+		case '[':
+			if len(p) > 1 && (p[1] == '\'' || p[1] == '"') {
+				i := strings.IndexByte(p[2:], p[1])
+				if i < 0 || len(p) < i+4 || p[i+3] != ']' {
+					return bad()
+				}
+				steps = append(steps, jsonPathStep{key: p[2 : i+2], isKey: true})
+				p = p[i+4:]
+				continue
+			}
+			i := strings.IndexByte(p, ']')
+			if i < 0 {
+				return bad()
+			}
+			if p[1:i] == "*" {
+				steps = append(steps, jsonPathStep{all: true})
+			} else {
+				n, err := strconv.Atoi(p[1:i])
+				if err != nil {
+					return bad()
+				}
+				steps = append(steps, jsonPathStep{index: n})
+			}
+			p = p[i+1:]
+		default:
+			return bad()
+		}
+	}
+	return steps, nil
+}
+
+// children возвращает значения, выбранные шагом пути из v
+func (s jsonPathStep) children(v VMValuer) []VMValuer {
+	var m VMStringMap
+	var sl VMSlice
+	switch vv := v.(type) {
+	case VMStringMap:
+		m = vv
+	case VMSlice:
+		sl = vv
+	case VMString, VMBinaryData:
+	case VMStringMaper:
+		m = vv.StringMap()
+	case VMSlicer:
+		sl = vv.Slice()
+	}
+	switch {
+	case s.all && m != nil:
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		rv := make([]VMValuer, len(keys))
+		for i, k := range keys {
+			rv[i] = m[k]
+		}
+		return rv
+	case s.all:
+		return sl
+	case s.isKey && m != nil:
+		if e, ok := m[s.key]; ok {
+			return []VMValuer{e}
+		}
+	case !s.isKey && sl != nil:
+		i := s.index
+		if i < 0 {
+			i += len(sl)
+		}
+		if i >= 0 && i < len(sl) {
+			return []VMValuer{sl[i]}
+		}
+	}
+	return nil
+}
+
+// GetByPath возвращает значение по пути в стиле JSONPath.
+// Если в пути есть *, возвращается массив всех найденных значений, иначе - значение или Неопределено
+func GetByPath(v VMValuer, path string) (VMValuer, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := []VMValuer{v}
+	multi := false
+	for _, s := range steps {
+		multi = multi || s.all
+		var next []VMValuer
+		for _, c := range cur {
+			next = append(next, s.children(c)...)
+		}
+		cur = next
+	}
+	if multi {
+		return VMSlice(cur), nil
+	}
+	if len(cur) == 0 {
+		return VMNil, nil
+	}
+	return cur[0], nil
+}
+
+// importJSON регистрирует функции работы с JSON
+func importJSON(env *Env) {
+
+	env.DefineS("записатьjson", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// параметры записи необязательны
+		if len(args) != 1 && len(args) != 2 {
+			return VMErrorNeedArgs(2)
+		}
+		var opts VMValuer
+		if len(args) == 2 {
+			opts = args[1]
+		}
+		s, err := WriteJSON(args[0], opts)
+		if err != nil {
+			return err
+		}
+		rets.Append(s)
+		return nil
+	}))
+
+	env.DefineS("прочитатьjson", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// параметры чтения необязательны
+		if len(args) != 1 && len(args) != 2 {
+			return VMErrorNeedArgs(2)
+		}
+		var opts VMValuer
+		if len(args) == 2 {
+			opts = args[1]
+		}
+		v, err := ReadJSON(args[0], opts)
+		if err != nil {
+			return err
+		}
+		rets.Append(v)
+		return nil
+	}))
+
+	env.DefineS("получитьпопути", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		p, ok := args[1].(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		v, err := GetByPath(args[0], string(p))
+		if err != nil {
+			return err
+		}
+		rets.Append(v)
+		return nil
+	}))
+
+	env.DefineTypeStruct("чтениеjson", &VMJSONReader{})
+}
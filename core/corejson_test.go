@@ -0,0 +1,78 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// опечатка в названии параметра не должна молча заменяться значением по умолчанию
+func TestJSONUnknownOptions(t *testing.T) {
+	if _, err := ReadJSON(VMString(`"2024-01-02T03:04:05Z"`), VMStringMap{"Даты": VMBool(true)}); err == nil || !strings.Contains(err.Error(), "Неизвестный параметр Даты") {
+		t.Errorf("чтение с параметром Даты: %v", err)
+	}
+	if _, err := WriteJSON(VMSlice{}, VMStringMap{"Отступ": VMInt(2), "Отступы": VMInt(2)}); err == nil || !strings.Contains(err.Error(), "Неизвестный параметр Отступы") {
+		t.Errorf("запись с параметром Отступы: %v", err)
+	}
+	if err := (&VMJSONReader{}).VMNew(VMSlice{VMString("[]"), VMStringMap{"ТипЧисла": VMString("Целое")}}); err == nil || !strings.Contains(err.Error(), "Неизвестный параметр ТипЧисла") {
+		t.Errorf("ЧтениеJSON с параметром ТипЧисла: %v", err)
+	}
+
+	// известные параметры принимаются
+	v, err := ReadJSON(VMString(`"2024-01-02T03:04:05Z"`), VMStringMap{"ЧитатьДаты": VMBool(true), "ТипЧисел": VMString("Число")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(VMTime); !ok {
+		t.Errorf("прочитано %#v, ожидается дата", v)
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	env := NewEnv()
+	LoadAllBuiltins(env)
+	checkBuiltinErrors(t, env, []builtinErrCase{
+		{"ПрочитатьJSON", VMSlice{}, VMErrorNeedArgs(2).Error()},
+		{"ПрочитатьJSON", VMSlice{VMInt(1)}, VMErrorNeedBinaryData.Error()},
+		{"ПрочитатьJSON", VMSlice{VMString("[1]"), VMInt(1)}, VMErrorNeedMap.Error()},
+		{"ПрочитатьJSON", VMSlice{VMString("[1]"), VMStringMap{"ТипЧисел": VMInt(1)}}, VMErrorNeedString.Error()},
+		{"ПрочитатьJSON", VMSlice{VMString("[1]"), VMStringMap{"ЧитатьДаты": VMString("да")}}, VMErrorNeedBool.Error()},
+		{"ПрочитатьJSON", VMSlice{VMString(`{"а": }`)}, "Ошибка чтения JSON"},
+		{"ПрочитатьJSON", VMSlice{VMString(`{"а": 1`)}, "неожиданный конец"},
+		{"ПрочитатьJSON", VMSlice{VMString(`[1] [2]`)}, "в позиции 5: лишние данные"},
+		{"ПрочитатьJSON", VMSlice{VMString(`{"а": 1}  {"б": 2}`)}, "в позиции 12: лишние данные"},
+		{"ПрочитатьJSON", VMSlice{VMString("")}, "неожиданный конец"},
+		{"ПрочитатьJSON", VMSlice{VMBinaryData("\xff\xfe")}, "Ошибка чтения JSON"},
+		{"ЗаписатьJSON", VMSlice{}, VMErrorNeedArgs(2).Error()},
+		{"ЗаписатьJSON", VMSlice{VMSlice{}, VMSlice{}}, VMErrorNeedMap.Error()},
+		{"ЗаписатьJSON", VMSlice{VMSlice{}, VMStringMap{"Отступ": VMInt(-1)}}, VMErrorNeedInt.Error()},
+		{"ЗаписатьJSON", VMSlice{VMSlice{}, VMStringMap{"Отступ": VMBool(true)}}, "Отступ должен быть"},
+		{"ЗаписатьJSON", VMSlice{VMSlice{}, VMStringMap{"ПорядокКлючей": VMString("а")}}, VMErrorNeedSlice.Error()},
+		{"ЗаписатьJSON", VMSlice{VMSlice{}, VMStringMap{"ПорядокКлючей": VMSlice{VMInt(1)}}}, VMErrorNeedString.Error()},
+		{"ЗаписатьJSON", VMSlice{VMSlice{}, VMStringMap{"ЭкранироватьHTML": VMInt(1)}}, VMErrorNeedBool.Error()},
+		{"ЗаписатьJSON", VMSlice{VMStringMap{"ф": VMFunc(nil)}}, "не может быть записано в JSON"},
+		{"ПолучитьПоПути", VMSlice{VMStringMap{}}, VMErrorNeedArgs(2).Error()},
+		{"ПолучитьПоПути", VMSlice{VMStringMap{}, VMInt(1)}, VMErrorNeedString.Error()},
+		{"ПолучитьПоПути", VMSlice{VMStringMap{}, VMString("$.а[")}, "Неверный путь"},
+		{"ПолучитьПоПути", VMSlice{VMStringMap{}, VMString("$[x]")}, "Неверный путь"},
+	})
+
+	// чтение после закрытия и поврежденный элемент потока
+	r := &VMJSONReader{}
+	if err := r.VMNew(VMSlice{VMString(`[1, {"а": ]`)}); err != nil {
+		t.Fatal(err)
+	}
+	var rets VMSlice
+	if err := r.Следующий(nil, &rets, nil); err != nil || rets[0] != VMInt(1) {
+		t.Fatalf("первый элемент %v, %v", rets, err)
+	}
+	if err := r.Следующий(nil, &rets, nil); err == nil || !strings.Contains(err.Error(), "Ошибка чтения JSON") {
+		t.Errorf("поврежденный элемент: %v", err)
+	}
+	r.Закрыть(nil, &rets, nil)
+	if err := r.Следующий(nil, &rets, nil); err != VMErrorStreamClosed {
+		t.Errorf("чтение после закрытия: %v", err)
+	}
+	if err := r.Следующий(VMSlice{VMInt(1)}, &rets, nil); err != VMErrorNoNeedArgs {
+		t.Errorf("лишний аргумент: %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package core
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"strings"
+	"time"
+)
+
+// JSON Web Token (RFC 7519) с подписью HMAC (HS256, HS384, HS512), RSA (RS256, RS384, RS512) или Ed25519 (EdDSA).
+// Ключ HMAC - непустая строка или двоичные данные, ключи RSA и Ed25519 - в формате PEM
+
+type jwtAlg struct {
+	hash crypto.Hash
+	kind string // hmac, rsa, ed25519
+}
+
+var jwtAlgs = map[string]jwtAlg{
+	"HS256": {crypto.SHA256, "hmac"},
+	"HS384": {crypto.SHA384, "hmac"},
+	"HS512": {crypto.SHA512, "hmac"},
+	"RS256": {crypto.SHA256, "rsa"},
+	"RS384": {crypto.SHA384, "rsa"},
+	"RS512": {crypto.SHA512, "rsa"},
+	"EdDSA": {0, "ed25519"},
+}
+
+func (a jwtAlg) newHash() hash.Hash {
+	switch a.hash {
+	case crypto.SHA384:
+		return sha512.New384()
+	case crypto.SHA512:
+		return sha512.New()
+	}
+	return sha256.New()
+}
+
+func (a jwtAlg) digest(data []byte) []byte {
+	h := a.newHash()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// isPEM определяет, задан ли ключ в формате PEM, иначе это секрет HMAC
+func isPEM(b []byte) bool {
+	return bytes.Contains(b, []byte("-----BEGIN "))
+}
+
+func keyKind(key interface{}) string {
+	switch key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return "rsa"
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return "ed25519"
+	}
+	return "hmac"
+}
+
+// CreateJWT подписывает утверждения claims ключом key. Пустой alg выбирается по типу ключа: HS256, RS256 или EdDSA.
+// Даты в утверждениях верхнего уровня (exp, nbf, iat) записываются как секунды Unix
+func CreateJWT(claims VMStringMap, key VMValuer, alg string) (VMString, error) {
+	kb, err := binaryArg(key)
+	if err != nil {
+		return "", err
+	}
+	if len(kb) == 0 {
+		return "", VMErrorJWTEmptyKey
+	}
+	var signer interface{} = kb
+	if isPEM(kb) {
+		if signer, err = privateKey(key); err != nil {
+			return "", err
+		}
+	}
+	if alg == "" {
+		switch keyKind(signer) {
+		case "rsa":
+			alg = "RS256"
+		case "ed25519":
+			alg = "EdDSA"
+		default:
+			alg = "HS256"
+		}
+	}
+	a, ok := jwtAlgs[alg]
+	if !ok || a.kind != keyKind(signer) {
+		return "", VMErrorJWTAlgorithm
+	}
+
+	payload := make(VMStringMap, len(claims))
+	for k, v := range claims {
+		if t, ok := v.(VMTime); ok {
+			v = t.Unix()
+		}
+		payload[k] = v
+	}
+	pj, err := WriteJSON(payload, nil)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"`+alg+`","typ":"JWT"}`)) +
+		"." + base64.RawURLEncoding.EncodeToString([]byte(pj))
+
+	var sig []byte
+	switch k := signer.(type) {
+	case []byte:
+		m := hmac.New(a.newHash, k)
+		m.Write([]byte(input))
+		sig = m.Sum(nil)
+	case *rsa.PrivateKey:
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, a.hash, a.digest([]byte(input))); err != nil {
+			return "", err
+		}
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(input))
+	}
+	return VMString(input + "." + base64.RawURLEncoding.EncodeToString(sig)), nil
+}
+
+// jwtValidateOptions - параметры проверки токена: Алгоритм, Допуск (секунды на расхождение часов), Издатель, Аудитория
+type jwtValidateOptions struct {
+	alg      string
+	leeway   time.Duration
+	issuer   string
+	audience string
+}
+
+func parseJWTValidateOptions(v VMValuer) (*jwtValidateOptions, error) {
+	o := &jwtValidateOptions{}
+	if v == nil {
+		return o, nil
+	}
+	opts, ok := v.(VMStringMap)
+	if !ok {
+		return nil, VMErrorNeedMap
+	}
+	if err := checkOptions(opts, "Алгоритм", "Допуск", "Издатель", "Аудитория"); err != nil {
+		return nil, err
+	}
+	for k, p := range map[string]*string{"Алгоритм": &o.alg, "Издатель": &o.issuer, "Аудитория": &o.audience} {
+		if s, ok := opts[k]; ok {
+			ss, ok := s.(VMString)
+			if !ok {
+				return nil, VMErrorNeedString
+			}
+			*p = string(ss)
+		}
+	}
+	if l, ok := opts["Допуск"]; ok {
+		sec, ok := l.(VMInt)
+		if !ok {
+			return nil, VMErrorNeedInt
+		}
+		if sec < 0 {
+			return nil, errors.New("Допуск не может быть отрицательным")
+		}
+		o.leeway = time.Duration(sec) * time.Second
+	}
+	return o, nil
+}
+
+// numericDate возвращает время из утверждения exp, nbf или iat, ok - утверждение задано.
+// Заданное утверждение, которое не является числом, - ошибка формата токена
+func numericDate(claims VMStringMap, name string) (t time.Time, ok bool, err error) {
+	v, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	switch n := v.(type) {
+	case VMInt:
+		return time.Unix(int64(n), 0), true, nil
+	case VMDecNum:
+		return time.Unix(n.Int(), 0), true, nil
+	}
+	return time.Time{}, false, VMErrorJWTFormat
+}
+
+// ValidateJWT проверяет подпись и сроки действия токена и возвращает его утверждения.
+// Алгоритм из заголовка токена должен соответствовать типу ключа, токены без подписи (none) не принимаются
+func ValidateJWT(token string, key, opts VMValuer) (VMStringMap, error) {
+	o, err := parseJWTValidateOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, VMErrorJWTFormat
+	}
+	hb, err1 := base64.RawURLEncoding.DecodeString(parts[0])
+	pb, err2 := base64.RawURLEncoding.DecodeString(parts[1])
+	sig, err3 := base64.RawURLEncoding.DecodeString(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, VMErrorJWTFormat
+	}
+	hv, err := ReadJSON(VMBinaryData(hb), nil)
+	if err != nil {
+		return nil, VMErrorJWTFormat
+	}
+	header, ok := hv.(VMStringMap)
+	if !ok {
+		return nil, VMErrorJWTFormat
+	}
+	algv, _ := header["alg"].(VMString)
+	alg := string(algv)
+
+	kb, err := binaryArg(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(kb) == 0 {
+		return nil, VMErrorJWTEmptyKey
+	}
+	var verifier interface{} = kb
+	if isPEM(kb) {
+		if verifier, err = publicKey(key); err != nil {
+			return nil, err
+		}
+	}
+	a, ok := jwtAlgs[alg]
+	if !ok || a.kind != keyKind(verifier) || (o.alg != "" && o.alg != alg) {
+		return nil, VMErrorJWTAlgorithm
+	}
+
+	input := []byte(parts[0] + "." + parts[1])
+	valid := false
+	switch k := verifier.(type) {
+	case []byte:
+		m := hmac.New(a.newHash, k)
+		m.Write(input)
+		valid = hmac.Equal(sig, m.Sum(nil))
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(k, a.hash, a.digest(input), sig) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(k, input, sig)
+	}
+	if !valid {
+		return nil, VMErrorJWTSignature
+	}
+
+	cv, err := ReadJSON(VMBinaryData(pb), nil)
+	if err != nil {
+		return nil, VMErrorJWTFormat
+	}
+	claims, ok := cv.(VMStringMap)
+	if !ok {
+		return nil, VMErrorJWTFormat
+	}
+	exp, hasExp, err := numericDate(claims, "exp")
+	if err != nil {
+		return nil, err
+	}
+	nbf, hasNbf, err := numericDate(claims, "nbf")
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := numericDate(claims, "iat"); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if hasExp && !now.Before(exp.Add(o.leeway)) {
+		return nil, VMErrorJWTExpired
+	}
+	if hasNbf && now.Before(nbf.Add(-o.leeway)) {
+		return nil, VMErrorJWTNotYetValid
+	}
+	if o.issuer != "" {
+		if iss, _ := claims["iss"].(VMString); string(iss) != o.issuer {
+			return nil, errors.New("Токен JWT выдан другим издателем")
+		}
+	}
+	if o.audience != "" && !jwtAudience(claims["aud"], o.audience) {
+		return nil, errors.New("Токен JWT выдан для другой аудитории")
+	}
+	return claims, nil
+}
+
+// jwtAudience проверяет, что аудитория токена (строка или массив строк) содержит aud
+func jwtAudience(v VMValuer, aud string) bool {
+	switch a := v.(type) {
+	case VMString:
+		return string(a) == aud
+	case VMSlice:
+		for _, s := range a {
+			if ss, ok := s.(VMString); ok && string(ss) == aud {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// importJWT регистрирует функции создания и проверки JWT
+func importJWT(env *Env) {
+
+	env.DefineS("создатьjwt", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// алгоритм необязателен
+		if len(args) != 2 && len(args) != 3 {
+			return VMErrorNeedArgs(3)
+		}
+		claims, ok := args[0].(VMStringMap)
+		if !ok {
+			return VMErrorNeedMap
+		}
+		var alg VMString
+		if len(args) == 3 {
+			if alg, ok = args[2].(VMString); !ok {
+				return VMErrorNeedString
+			}
+		}
+		s, err := CreateJWT(claims, args[1], string(alg))
+		if err != nil {
+			return err
+		}
+		rets.Append(s)
+		return nil
+	}))
+
+	env.DefineS("проверитьjwt", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// параметры проверки необязательны
+		if len(args) != 2 && len(args) != 3 {
+			return VMErrorNeedArgs(3)
+		}
+		token, ok := args[0].(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		var opts VMValuer
+		if len(args) == 3 {
+			opts = args[2]
+		}
+		claims, err := ValidateJWT(string(token), args[1], opts)
+		if err != nil {
+			return err
+		}
+		rets.Append(claims)
+		return nil
+	}))
+}
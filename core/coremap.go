@@ -6,8 +6,6 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"reflect"
-
-	"github.com/covrom/gonec/names"
 )
 
 type VMStringMap map[string]VMValuer
@@ -56,11 +54,11 @@ func (x VMStringMap) Hash() VMString {
 	return VMString(hex.EncodeToString(h))
 }
 
-func (x VMStringMap) MethodMember(name int) (VMFunc, bool) {
+func (x VMStringMap) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
 
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "скопировать":
 		return VMFuncMustParams(0, x.Скопировать), true
 	case "ключи":
@@ -0,0 +1,505 @@
+package core
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/covrom/decnum"
+)
+
+// Математические функции вычисляются в десятичной арифметике Число (34 значащие цифры),
+// результат округляется до mathDigits значащих цифр, чтобы погрешность последнего разряда не была видна
+
+const mathDigits = 30
+
+var (
+	decPi   = mustDec("3.141592653589793238462643383279503")
+	decPi2  = decPi.Div(decnum.FromInt64(2))
+	decLn2  = mustDec("0.6931471805599453094172321214581766")
+	decLn10 = mustDec("2.302585092994045684017991454684364")
+	decOne  = decnum.One()
+	decTwo  = decnum.FromInt64(2)
+)
+
+// Приведение аргумента синуса, косинуса и экспоненты выполняется с точностью reducePrec бит по константам
+// из 260 знаков, поэтому остаток сохраняет все значащие цифры Число при |x| < 10^reduceMaxMagnitude
+const (
+	reducePrec         = 1024
+	reduceMaxMagnitude = 200
+
+	piDigits  = "3.14159265358979323846264338327950288419716939937510582097494459230781640628620899862803482534211706798214808651328230664709384460955058223172535940812848111745028410270193852110555964462294895493038196442881097566593344612847564823378678316527120190914564856692"
+	ln2Digits = "0.693147180559945309417232121458176568075500134360255254120680009493393621969694715605863326996418687542001481020570685733685520235758130557032670751635075961930727570828371435190307038623891673471123350115364497955239120475172681574932065155524734139525882950"
+)
+
+var (
+	bigHalfPi = new(big.Float).Quo(mustBig(piDigits), big.NewFloat(2))
+	bigLn2    = mustBig(ln2Digits)
+)
+
+func mustBig(s string) *big.Float {
+	f, _, err := big.ParseFloat(s, 10, reducePrec, big.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func mustDec(s string) decnum.Quad {
+	q, err := decnum.FromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// decPow10 возвращает 10^e
+func decPow10(e int) decnum.Quad {
+	return mustDec("1E" + strconv.Itoa(e))
+}
+
+// decPowInt возвращает x^n для целого n, возведением в квадрат
+func decPowInt(x decnum.Quad, n int64) decnum.Quad {
+	if n < 0 {
+		return decOne.Div(decPowInt(x, -n))
+	}
+	r := decOne
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			r = r.Mul(x)
+		}
+		x = x.Mul(x)
+	}
+	return r
+}
+
+// decMagnitude возвращает десятичный порядок числа - целую часть log10|q|
+func decMagnitude(q decnum.Quad) int {
+	if f, err := q.ToFloat64(); err == nil && f != 0 && !math.IsInf(f, 0) {
+		return int(math.Floor(math.Log10(math.Abs(f))))
+	}
+	// за пределами float64 порядок оценивается по показателю степени и длине коэффициента
+	return int(q.GetExponent()) + 33
+}
+
+// decRound округляет до mathDigits значащих цифр и отбрасывает нули в конце дробной части
+func decRound(q decnum.Quad) decnum.Quad {
+	if q.IsZero() || !q.IsFinite() {
+		return q
+	}
+	exp := decMagnitude(q) - mathDigits + 1
+	r := q.Quantize(decPow10(exp), decnum.RoundHalfEven)
+	for e := exp + 1; e <= 0; e++ {
+		t := r.Quantize(decPow10(e), decnum.RoundHalfEven)
+		if !t.Equal(r) {
+			break
+		}
+		r = t
+	}
+	return r
+}
+
+// decSeries суммирует ряд, начиная с term, пока очередной член влияет на сумму; next вычисляет член i по предыдущему
+func decSeries(term decnum.Quad, next func(i int64, term decnum.Quad) decnum.Quad) decnum.Quad {
+	sum := term
+	for i := int64(1); i < 200; i++ {
+		term = next(i, term)
+		s := sum.Add(term)
+		if s.Equal(sum) {
+			break
+		}
+		sum = s
+	}
+	return sum
+}
+
+// decReduce возвращает n = round(x/c) и остаток x - n*c. Вычисления ведутся в math/big,
+// а не во float64 и не в Число, чтобы при большом n остаток не терял значащие цифры
+func decReduce(x decnum.Quad, c *big.Float) (*big.Int, decnum.Quad, error) {
+	if !x.IsFinite() || decMagnitude(x) >= reduceMaxMagnitude {
+		return nil, x, VMErrorMathPrecision
+	}
+	bx, _, err := big.ParseFloat(x.String(), 10, reducePrec, big.ToNearestEven)
+	if err != nil {
+		return nil, x, err
+	}
+	q := new(big.Float).SetPrec(reducePrec).Quo(bx, c)
+	if q.Signbit() {
+		q.Sub(q, big.NewFloat(0.5))
+	} else {
+		q.Add(q, big.NewFloat(0.5))
+	}
+	n, _ := q.Int(nil)
+	nc := new(big.Float).SetPrec(reducePrec).SetInt(n)
+	nc.Mul(nc, c)
+	r, err := decnum.FromString(nc.Sub(bx, nc).Text('e', 40))
+	return n, r, err
+}
+
+// decSqrt - квадратный корень методом Ньютона
+func decSqrt(x decnum.Quad) (decnum.Quad, error) {
+	switch {
+	case x.IsNegative():
+		return x, VMErrorMathDomain
+	case x.IsZero():
+		return decnum.Zero(), nil
+	}
+	f, err := x.ToFloat64()
+	if err != nil || f == 0 || math.IsInf(f, 0) {
+		// x = y * 10^(2n), корень - sqrt(y) * 10^n
+		n := int(x.GetExponent()) / 2
+		y, err := decSqrt(x.Div(decPow10(2 * n)))
+		return y.Mul(decPow10(n)), err
+	}
+	// приближение из float64 служит только началом итераций, каждая из них удваивает число верных цифр
+	y := decnum.FromFloat(math.Sqrt(f))
+	for i := 0; i < 10; i++ {
+		yn := y.Add(x.Div(y)).Div(decTwo)
+		if yn.Equal(y) {
+			break
+		}
+		y = yn
+	}
+	return y, nil
+}
+
+// decExp - экспонента: x = k*ln2 + r, e^x = 2^k * e^r, e^r - рядом Тейлора
+func decExp(x decnum.Quad) (decnum.Quad, error) {
+	f, _ := x.ToFloat64()
+	switch {
+	case f > 14000:
+		return x, VMErrorMathOverflow
+	case f < -14000:
+		return decnum.Zero(), nil
+	}
+	k, r, err := decReduce(x, bigLn2)
+	if err != nil {
+		return x, err
+	}
+	e := decSeries(decOne, func(i int64, term decnum.Quad) decnum.Quad {
+		return term.Mul(r).Div(decnum.FromInt64(i))
+	})
+	return e.Mul(decPowInt(decTwo, k.Int64())), nil
+}
+
+// decLn - натуральный логарифм: x = m * 2^k, ln(m) = 2*atanh((m-1)/(m+1))
+func decLn(x decnum.Quad) (decnum.Quad, error) {
+	if !x.IsPositive() || x.IsZero() {
+		return x, VMErrorMathDomain
+	}
+	f, err := x.ToFloat64()
+	if err != nil || f == 0 || math.IsInf(f, 0) {
+		// x = y * 10^n
+		n := int(x.GetExponent())
+		y, err := decLn(x.Div(decPow10(n)))
+		return y.Add(decnum.FromInt64(int64(n)).Mul(decLn10)), err
+	}
+	k := int64(math.Floor(math.Log2(f) + 0.5))
+	m := x.Div(decPowInt(decTwo, k))
+	z := m.Sub(decOne).Div(m.Add(decOne))
+	z2 := z.Mul(z)
+	// сумма z^(2i+1)/(2i+1) накапливается отдельно от степеней z
+	pw := z
+	s := decSeries(z, func(i int64, term decnum.Quad) decnum.Quad {
+		pw = pw.Mul(z2)
+		return pw.Div(decnum.FromInt64(2*i + 1))
+	})
+	return s.Mul(decTwo).Add(decnum.FromInt64(k).Mul(decLn2)), nil
+}
+
+// decPow возвращает x^y, для целого y - точным возведением в степень
+func decPow(x, y decnum.Quad) (decnum.Quad, error) {
+	if y.ToIntegral(decnum.RoundDown).Equal(y) {
+		if n, err := y.ToInt64(decnum.RoundDown); err == nil && n > -1e6 && n < 1e6 {
+			if x.IsZero() && n < 0 {
+				return x, VMErrorMathDomain
+			}
+			r := decPowInt(x, n)
+			if !r.IsFinite() {
+				return x, VMErrorMathOverflow
+			}
+			return r, nil
+		}
+	}
+	switch {
+	case x.IsZero() && y.IsPositive():
+		return decnum.Zero(), nil
+	case x.IsNegative() || x.IsZero():
+		return x, VMErrorMathDomain
+	}
+	l, err := decLn(x)
+	if err != nil {
+		return x, err
+	}
+	return decExp(y.Mul(l))
+}
+
+// decSinCos вычисляет синус и косинус, аргумент приводится к [-π/4, π/4] с номером четверти
+func decSinCos(x decnum.Quad) (decnum.Quad, decnum.Quad, error) {
+	n, t, err := decReduce(x, bigHalfPi)
+	if err != nil {
+		return x, x, err
+	}
+	q := new(big.Int).And(n, big.NewInt(3)).Int64()
+	t2 := t.Mul(t).Neg()
+	sin := decSeries(t, func(i int64, term decnum.Quad) decnum.Quad {
+		return term.Mul(t2).Div(decnum.FromInt64((2 * i) * (2*i + 1)))
+	})
+	cos := decSeries(decOne, func(i int64, term decnum.Quad) decnum.Quad {
+		return term.Mul(t2).Div(decnum.FromInt64((2*i - 1) * (2 * i)))
+	})
+	switch q {
+	case 1:
+		return cos, sin.Neg(), nil
+	case 2:
+		return sin.Neg(), cos.Neg(), nil
+	case 3:
+		return cos.Neg(), sin, nil
+	}
+	return sin, cos, nil
+}
+
+// decAtan - арктангенс: аргумент уменьшается формулой atan(x) = 2*atan(x/(1+sqrt(1+x²))), затем ряд Тейлора
+func decAtan(x decnum.Quad) decnum.Quad {
+	if x.Abs().Greater(decOne) {
+		r := decPi2.Sub(decAtan(decOne.Div(x.Abs())))
+		if x.IsNegative() {
+			return r.Neg()
+		}
+		return r
+	}
+	mul := decOne
+	for i := 0; i < 2; i++ {
+		s, _ := decSqrt(decOne.Add(x.Mul(x)))
+		x = x.Div(decOne.Add(s))
+		mul = mul.Mul(decTwo)
+	}
+	x2 := x.Mul(x).Neg()
+	pw := x
+	s := decSeries(x, func(i int64, term decnum.Quad) decnum.Quad {
+		pw = pw.Mul(x2)
+		return pw.Div(decnum.FromInt64(2*i + 1))
+	})
+	return s.Mul(mul)
+}
+
+// decAsin - арксинус через арктангенс
+func decAsin(x decnum.Quad) (decnum.Quad, error) {
+	a := x.Abs()
+	switch {
+	case a.Greater(decOne):
+		return x, VMErrorMathDomain
+	case a.Equal(decOne):
+		if x.IsNegative() {
+			return decPi2.Neg(), nil
+		}
+		return decPi2, nil
+	}
+	c, err := decSqrt(decOne.Sub(x.Mul(x)))
+	if err != nil {
+		return x, err
+	}
+	return decAtan(x.Div(c)), nil
+}
+
+// numberArg возвращает число из ЦелоеЧисло или Число
+func numberArg(v VMValuer) (decnum.Quad, error) {
+	switch n := v.(type) {
+	case VMInt:
+		return decnum.FromInt64(int64(n)), nil
+	case VMDecNum:
+		return n.num, nil
+	}
+	return decnum.Quad{}, VMErrorNeedDecNum
+}
+
+// intPow возвращает x^n для целых чисел, ok=false при переполнении
+func intPow(x, n int64) (int64, bool) {
+	// произведение проверяется с запасом по модулю, чтобы не выйти за пределы int64
+	fits := func(a, b int64) bool {
+		return math.Abs(float64(a))*math.Abs(float64(b)) < 9e18
+	}
+	r := int64(1)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			if !fits(r, x) {
+				return 0, false
+			}
+			r *= x
+		}
+		if n > 1 {
+			if !fits(x, x) {
+				return 0, false
+			}
+			x *= x
+		}
+	}
+	return r, true
+}
+
+// extremum возвращает наибольшее (op=GTR) или наименьшее (op=LSS) из значений, значения могут быть переданы массивом
+func extremum(args VMSlice, op VMOperation) (VMValuer, error) {
+	if len(args) == 1 {
+		if sl, ok := args[0].(VMSlice); ok {
+			args = sl
+		}
+	}
+	if len(args) == 0 {
+		return nil, VMErrorNeedLength
+	}
+	res, ok := args[0].(VMOperationer)
+	if !ok {
+		return nil, VMErrorIncorrectOperation
+	}
+	for _, v := range args[1:] {
+		vo, ok := v.(VMOperationer)
+		if !ok {
+			return nil, VMErrorIncorrectOperation
+		}
+		b, err := vo.EvalBinOp(op, res)
+		if err != nil {
+			return nil, err
+		}
+		if bb, ok := b.(VMBool); ok && bool(bb) {
+			res = vo
+		}
+	}
+	return res, nil
+}
+
+// importMath регистрирует математические функции
+func importMath(env *Env) {
+
+	env.DefineS("макс", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		v, err := extremum(args, GTR)
+		if err != nil {
+			return err
+		}
+		rets.Append(v)
+		return nil
+	}))
+
+	env.DefineS("мин", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		v, err := extremum(args, LSS)
+		if err != nil {
+			return err
+		}
+		rets.Append(v)
+		return nil
+	}))
+
+	env.DefineS("цел", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		switch v := args[0].(type) {
+		case VMInt:
+			rets.Append(v)
+		case VMDecNum:
+			rets.Append(VMDecNum{num: v.num.ToIntegral(decnum.RoundDown)})
+		default:
+			return VMErrorNeedDecNum
+		}
+		return nil
+	}))
+
+	env.DefineS("abs", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		switch v := args[0].(type) {
+		case VMInt:
+			// модуль наименьшего целого не помещается в ЦелоеЧисло
+			if v == math.MinInt64 {
+				rets.Append(VMDecNum{num: decnum.FromInt64(int64(v)).Abs()})
+				return nil
+			}
+			if v < 0 {
+				v = -v
+			}
+			rets.Append(v)
+		case VMDecNum:
+			rets.Append(VMDecNum{num: v.num.Abs()})
+		default:
+			return VMErrorNeedDecNum
+		}
+		return nil
+	}))
+
+	env.DefineS("pow", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// целое в неотрицательной целой степени остается целым, если не переполняется
+		if x, ok := args[0].(VMInt); ok {
+			if n, ok := args[1].(VMInt); ok && n >= 0 {
+				if r, ok := intPow(int64(x), int64(n)); ok {
+					rets.Append(VMInt(r))
+					return nil
+				}
+			}
+		}
+		x, err := numberArg(args[0])
+		if err != nil {
+			return err
+		}
+		y, err := numberArg(args[1])
+		if err != nil {
+			return err
+		}
+		r, err := decPow(x, y)
+		if err != nil {
+			return err
+		}
+		rets.Append(VMDecNum{num: decRound(r)})
+		return nil
+	}))
+
+	env.DefineS("pi", VMFuncMustParams(0, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		rets.Append(VMDecNum{num: decRound(decPi)})
+		return nil
+	}))
+
+	// функции одного аргумента, результат - Число
+	for name, f := range map[string]func(x decnum.Quad) (decnum.Quad, error){
+		"sqrt": decSqrt,
+		"exp":  decExp,
+		"log":  decLn,
+		"log10": func(x decnum.Quad) (decnum.Quad, error) {
+			l, err := decLn(x)
+			return l.Div(decLn10), err
+		},
+		"sin": func(x decnum.Quad) (decnum.Quad, error) {
+			s, _, err := decSinCos(x)
+			return s, err
+		},
+		"cos": func(x decnum.Quad) (decnum.Quad, error) {
+			_, c, err := decSinCos(x)
+			return c, err
+		},
+		"tan": func(x decnum.Quad) (decnum.Quad, error) {
+			s, c, err := decSinCos(x)
+			return s.Div(c), err
+		},
+		"asin": decAsin,
+		"acos": func(x decnum.Quad) (decnum.Quad, error) {
+			a, err := decAsin(x)
+			return decPi2.Sub(a), err
+		},
+		"atan": func(x decnum.Quad) (decnum.Quad, error) {
+			return decAtan(x), nil
+		},
+	} {
+		f := f
+		env.DefineS(name, VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+			*envout = env
+			x, err := numberArg(args[0])
+			if err != nil {
+				return err
+			}
+			r, err := f(x)
+			if err != nil {
+				return err
+			}
+			rets.Append(VMDecNum{num: decRound(r)})
+			return nil
+		}))
+	}
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	"github.com/covrom/decnum"
+)
+
+// контрольные значения вычислены с точностью 400 знаков
+func TestDecReduce(t *testing.T) {
+	tests := []struct {
+		x        decnum.Quad
+		sin, cos string
+	}{
+		{decPow10(20), "-0.645251285265780844205811711313", "0.763970404441728300400146802738"},
+		{decPow10(100), "-0.372376123661276688262086695553", "-0.928081905074655343456194643777"},
+		{mustDec("-123456789012345678.5"), "0.853618392913405045888232917045", "0.520898876251365743080339548105"},
+	}
+	for _, tt := range tests {
+		s, c, err := decSinCos(tt.x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := decRound(s); !got.Equal(mustDec(tt.sin)) {
+			t.Errorf("Sin(%s) = %s, ожидается %s", tt.x, got, tt.sin)
+		}
+		if got := decRound(c); !got.Equal(mustDec(tt.cos)) {
+			t.Errorf("Cos(%s) = %s, ожидается %s", tt.x, got, tt.cos)
+		}
+	}
+
+	for x, want := range map[string]string{
+		"100":      "2.68811714181613544841262555158E+43",
+		"-50.5":    "1.16984591770619646858516251845E-22",
+		"9999.123": "3.66389291398990136529840344939E+4342",
+	} {
+		e, err := decExp(mustDec(x))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := decRound(e); !got.Equal(mustDec(want)) {
+			t.Errorf("Exp(%s) = %s, ожидается %s", x, got, want)
+		}
+	}
+
+	// при большем аргументе верных цифр не осталось бы, поэтому вместо результата - ошибка
+	if _, _, err := decSinCos(decPow10(250)); err != VMErrorMathPrecision {
+		t.Errorf("Sin(1E+250): %v, ожидается %v", err, VMErrorMathPrecision)
+	}
+}
+
+func TestExtremumErrors(t *testing.T) {
+	for _, args := range []VMSlice{
+		{},
+		{VMSlice{}},
+		{VMStringMap{}, VMInt(1)},
+		{VMInt(1), VMStringMap{}},
+		{VMSlice{VMNil, VMInt(1)}},
+		{VMInt(1), VMString("а")},
+	} {
+		if v, err := extremum(args, GTR); err == nil {
+			t.Errorf("Макс(%v) = %v, ожидается ошибка", args, v)
+		}
+	}
+	if v, err := extremum(VMSlice{VMSlice{VMInt(3), NewVMDecNumFromInt64(7), VMInt(5)}}, GTR); err != nil || v.(VMDecNum).Int() != 7 {
+		t.Errorf("Макс = %v, %v", v, err)
+	}
+}
+
+func TestMathErrors(t *testing.T) {
+	env := NewEnv()
+	LoadAllBuiltins(env)
+	half := VMDecNum{num: mustDec("0.5")}
+	checkBuiltinErrors(t, env, []builtinErrCase{
+		{"Макс", VMSlice{}, VMErrorNeedLength.Error()},
+		{"Мин", VMSlice{VMInt(1), VMStringMap{}}, VMErrorIncorrectOperation.Error()},
+		{"Цел", VMSlice{VMString("1")}, VMErrorNeedDecNum.Error()},
+		{"Цел", VMSlice{}, VMErrorNeedArgs(1).Error()},
+		{"ABS", VMSlice{VMString("-1")}, VMErrorNeedDecNum.Error()},
+		{"Pow", VMSlice{VMInt(2)}, VMErrorNeedArgs(2).Error()},
+		{"Pow", VMSlice{VMString("2"), VMInt(2)}, VMErrorNeedDecNum.Error()},
+		{"Pow", VMSlice{VMInt(2), VMString("2")}, VMErrorNeedDecNum.Error()},
+		{"Pow", VMSlice{VMInt(0), VMInt(-1)}, VMErrorMathDomain.Error()},
+		{"Pow", VMSlice{VMInt(-8), half}, VMErrorMathDomain.Error()},
+		{"Pow", VMSlice{VMInt(10), VMInt(100000)}, VMErrorMathOverflow.Error()},
+		{"Pow", VMSlice{VMInt(10), VMDecNum{num: mustDec("10000.5")}}, VMErrorMathOverflow.Error()},
+		{"Pi", VMSlice{VMInt(1)}, VMErrorNoNeedArgs.Error()},
+		{"Sqrt", VMSlice{VMInt(-1)}, VMErrorMathDomain.Error()},
+		{"Sqrt", VMSlice{VMString("4")}, VMErrorNeedDecNum.Error()},
+		{"Exp", VMSlice{VMInt(20000)}, VMErrorMathOverflow.Error()},
+		{"Log", VMSlice{VMInt(0)}, VMErrorMathDomain.Error()},
+		{"Log10", VMSlice{VMInt(-10)}, VMErrorMathDomain.Error()},
+		{"Sin", VMSlice{VMDecNum{num: decPow10(250)}}, VMErrorMathPrecision.Error()},
+		{"Tan", VMSlice{VMNil}, VMErrorNeedDecNum.Error()},
+		{"ASin", VMSlice{VMInt(2)}, VMErrorMathDomain.Error()},
+		{"ACos", VMSlice{VMInt(-2)}, VMErrorMathDomain.Error()},
+		{"ATan", VMSlice{VMInt(1), VMInt(2)}, VMErrorNeedArgs(1).Error()},
+	})
+
+	// модуль наименьшего целого возвращается как Число
+	rets, err := callBuiltin(env, "ABS", VMInt(math.MinInt64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := rets[0].(VMDecNum); !ok || !d.num.Equal(mustDec("9223372036854775808")) {
+		t.Errorf("ABS(%d) = %v", int64(math.MinInt64), rets[0])
+	}
+}
@@ -15,8 +15,8 @@ import (
 // поля и методы должны отличаться друг от друга без учета регистра
 // например, Set и set - в вирт. машине будут считаться одинаковыми, будет использоваться последнее по индексу
 type VMMetaObj struct {
-	vmMetaCacheM map[int]VMFunc
-	vmMetaCacheF map[int]VMValuer
+	vmMetaCacheM map[string]VMFunc   // методы по названию в нижнем регистре
+	vmMetaCacheF map[string]VMValuer // поля по названию в нижнем регистре
 
 	vmOriginal VMMetaObject
 }
@@ -53,36 +53,55 @@ func (x *VMMetaObj) Hash() VMString {
 
 func (v *VMMetaObj) VMRegisterMethod(name string, m VMMethod) {
 	if v.vmMetaCacheM == nil {
-		v.vmMetaCacheM = make(map[int]VMFunc)
+		v.vmMetaCacheM = make(map[string]VMFunc)
 	}
-	namtyp := names.UniqueNames.Set(name)
+	namtyp := names.FastToLower(name)
 	v.vmMetaCacheM[namtyp] = func(meth VMMethod) VMFunc {
 		return VMFunc(meth)
 	}(m)
 }
 
-func (v *VMMetaObj) VMRegisterField(name string, m VMValuer) {
+// goFieldRef - ссылка на поле произвольного типа Го, значение преобразуется при каждом обращении
+type goFieldRef struct {
+	v reflect.Value // указатель на поле
+}
+
+func (x goFieldRef) vmval() {}
+
+// VMRegisterField регистрирует поле по указателю на него.
+// Поля типов вирт. машины используются напрямую, поля остальных типов Го (числа, строки, time.Time,
+// слайсы, карты, структуры) преобразуются через GoToVMValue и VMValueToGo
+func (v *VMMetaObj) VMRegisterField(name string, m interface{}) {
 	if v.vmMetaCacheF == nil {
-		v.vmMetaCacheF = make(map[int]VMValuer)
+		v.vmMetaCacheF = make(map[string]VMValuer)
 	}
-	switch m.(type) {
+	namtyp := names.FastToLower(name)
+	switch mm := m.(type) {
 	case *VMInt, *VMString, *VMBool,
 		*VMChan, *VMDecNum, *VMStringMap,
 		*VMSlice, *VMTime, *VMTimeDuration:
 
-		namtyp := names.UniqueNames.Set(name)
-		v.vmMetaCacheF[namtyp] = m
+		v.vmMetaCacheF[namtyp] = mm.(VMValuer)
 	default:
-		panic("Поле не может быть зарегистрировано")
+		rv := reflect.ValueOf(m)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() {
+			panic("Поле не может быть зарегистрировано")
+		}
+		v.vmMetaCacheF[namtyp] = goFieldRef{v: rv}
 	}
 }
 
-func (v *VMMetaObj) VMIsField(name int) bool {
+// VMRegisterGoMethod регистрирует произвольную функцию Го как метод, см. WrapGoFunc
+func (v *VMMetaObj) VMRegisterGoMethod(name string, f interface{}) {
+	v.VMRegisterMethod(name, VMMethod(WrapGoFunc(f)))
+}
+
+func (v *VMMetaObj) VMIsField(name string) bool {
 	_, ok := v.vmMetaCacheF[name]
 	return ok
 }
 
-func (v *VMMetaObj) VMGetField(name int) VMValuer {
+func (v *VMMetaObj) VMGetField(name string) VMValuer {
 	if r, ok := v.vmMetaCacheF[name]; ok {
 		switch rv := r.(type) {
 		case *VMInt:
@@ -103,12 +122,23 @@ func (v *VMMetaObj) VMGetField(name int) VMValuer {
 			return *rv
 		case *VMTimeDuration:
 			return *rv
+		case goFieldRef:
+			fv := rv.v.Elem()
+			if fv.Kind() == reflect.Struct && fv.Type() != reflectGoTime {
+				// вложенную структуру возвращаем по ссылке
+				return WrapGoStruct(rv.v.Interface())
+			}
+			vv, err := GoToVMValue(fv)
+			if err != nil {
+				panic(err)
+			}
+			return vv
 		}
 	}
 	panic("Невозможно получить значение поля")
 }
 
-func (v *VMMetaObj) VMSetField(name int, val VMValuer) {
+func (v *VMMetaObj) VMSetField(name string, val VMValuer) {
 
 	if r, ok := v.vmMetaCacheF[name]; ok {
 		switch rv := r.(type) {
@@ -139,6 +169,13 @@ func (v *VMMetaObj) VMSetField(name int, val VMValuer) {
 		case *VMTimeDuration:
 			*rv = val.(VMDurationer).Duration()
 			return
+		case goFieldRef:
+			vv, err := VMValueToGo(val, rv.v.Elem().Type())
+			if err != nil {
+				panic(err)
+			}
+			rv.v.Elem().Set(vv)
+			return
 		}
 	}
 
@@ -147,7 +184,7 @@ func (v *VMMetaObj) VMSetField(name int, val VMValuer) {
 
 // VMGetMethod генерит функцию,
 // которая возвращает либо одно значение и ошибку, либо массив значений интерпретатора VMSlice
-func (v *VMMetaObj) VMGetMethod(name int) (VMFunc, bool) {
+func (v *VMMetaObj) VMGetMethod(name string) (VMFunc, bool) {
 
 	// fmt.Println(name)
 
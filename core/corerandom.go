@@ -0,0 +1,201 @@
+package core
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/covrom/decnum"
+)
+
+// VMRandom - генератор псевдослучайных чисел (ГенераторСлучайныхЧисел).
+// Новый ГенераторСлучайныхЧисел(зерно) повторяет одну и ту же последовательность для одного зерна,
+// без зерна генератор инициализируется случайным значением
+type VMRandom struct {
+	VMMetaObj
+
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// randomSeed возвращает случайное зерно из криптографически стойкого генератора
+func randomSeed() int64 {
+	var b [8]byte
+	crand.Read(b[:])
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+// NewVMRandom создает генератор с зерном seed
+func NewVMRandom(seed int64) *VMRandom {
+	x := &VMRandom{r: rand.New(rand.NewSource(seed))}
+	x.VMInit(x)
+	x.VMRegister()
+	return x
+}
+
+func (x *VMRandom) VMRegister() {
+	if x.r == nil {
+		x.r = rand.New(rand.NewSource(randomSeed()))
+	}
+	x.VMRegisterMethod("СлучайноеЧисло", x.СлучайноеЧисло)
+	x.VMRegisterMethod("СлучайноеДробное", x.СлучайноеДробное)
+	x.VMRegisterMethod("Перемешать", x.Перемешать)
+	x.VMRegisterMethod("Выборка", x.Выборка)
+	x.VMRegisterMethod("СлучайныйЭлемент", x.СлучайныйЭлемент)
+}
+
+// VMNew задает зерно генератора
+func (x *VMRandom) VMNew(args VMSlice) error {
+	if len(args) != 1 {
+		return VMErrorNeedArgs(1)
+	}
+	seed, ok := args[0].(VMInt)
+	if !ok {
+		return VMErrorNeedInt
+	}
+	x.r = rand.New(rand.NewSource(int64(seed)))
+	return nil
+}
+
+func (x *VMRandom) String() string {
+	return "ГенераторСлучайныхЧисел"
+}
+
+// uint64n возвращает равномерно распределенное число в [0, n), n=0 означает весь диапазон uint64
+func (x *VMRandom) uint64n(n uint64) uint64 {
+	if n == 0 {
+		return x.r.Uint64()
+	}
+	// значения из неполного последнего интервала отбрасываются, чтобы не было смещения
+	lim := math.MaxUint64 - math.MaxUint64%n
+	for {
+		if v := x.r.Uint64(); v < lim {
+			return v % n
+		}
+	}
+}
+
+// Int возвращает случайное целое в [min, max]
+func (x *VMRandom) Int(min, max int64) int64 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return min + int64(x.uint64n(uint64(max-min)+1))
+}
+
+func (x *VMRandom) intn(n int) int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return int(x.uint64n(uint64(n)))
+}
+
+// СлучайноеЧисло(мин, макс) возвращает целое число от мин до макс включительно, по умолчанию от 0 до 4294967295
+func (x *VMRandom) СлучайноеЧисло(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	min, max := VMInt(0), VMInt(math.MaxUint32)
+	switch len(args) {
+	case 0:
+	case 2:
+		var ok1, ok2 bool
+		min, ok1 = args[0].(VMInt)
+		max, ok2 = args[1].(VMInt)
+		if !ok1 || !ok2 {
+			return VMErrorNeedInt
+		}
+		if min > max {
+			return VMErrorNeedLess
+		}
+	default:
+		return VMErrorNeedArgs(2)
+	}
+	rets.Append(VMInt(x.Int(int64(min), int64(max))))
+	return nil
+}
+
+// СлучайноеДробное() возвращает Число от 0 (включительно) до 1
+func (x *VMRandom) СлучайноеДробное(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNeedArgs(0)
+	}
+	x.mu.Lock()
+	f := x.r.Float64()
+	x.mu.Unlock()
+	rets.Append(VMDecNum{num: decnum.FromFloat(f)})
+	return nil
+}
+
+// Перемешать(массив) переставляет элементы массива в случайном порядке
+func (x *VMRandom) Перемешать(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 1 {
+		return VMErrorNeedArgs(1)
+	}
+	sl, ok := args[0].(VMSlice)
+	if !ok {
+		return VMErrorNeedSlice
+	}
+	x.mu.Lock()
+	x.r.Shuffle(len(sl), func(i, j int) { sl[i], sl[j] = sl[j], sl[i] })
+	x.mu.Unlock()
+	return nil
+}
+
+// Выборка(массив, n) возвращает новый массив из n случайных элементов, каждый элемент выбирается не более одного раза
+func (x *VMRandom) Выборка(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 2 {
+		return VMErrorNeedArgs(2)
+	}
+	sl, ok := args[0].(VMSlice)
+	if !ok {
+		return VMErrorNeedSlice
+	}
+	n, ok := args[1].(VMInt)
+	if !ok {
+		return VMErrorNeedInt
+	}
+	if n < 0 || int(n) > len(sl) {
+		return VMErrorIndexOutOfBoundary
+	}
+	// частичное перемешивание копии индексов, исходный массив не меняется
+	idx := make([]int, len(sl))
+	for i := range idx {
+		idx[i] = i
+	}
+	res := make(VMSlice, n)
+	for i := range res {
+		j := i + x.intn(len(idx)-i)
+		idx[i], idx[j] = idx[j], idx[i]
+		res[i] = sl[idx[i]]
+	}
+	rets.Append(res)
+	return nil
+}
+
+// СлучайныйЭлемент(массив) возвращает случайный элемент массива
+func (x *VMRandom) СлучайныйЭлемент(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 1 {
+		return VMErrorNeedArgs(1)
+	}
+	sl, ok := args[0].(VMSlice)
+	if !ok {
+		return VMErrorNeedSlice
+	}
+	if len(sl) == 0 {
+		return VMErrorIndexOutOfBoundary
+	}
+	rets.Append(sl[x.intn(len(sl))])
+	return nil
+}
+
+// globalRandom используется функцией СлучайноеЧисло
+var globalRandom = NewVMRandom(randomSeed())
+
+// importRandom регистрирует генератор случайных чисел
+func importRandom(env *Env) {
+
+	env.DefineS("случайноечисло", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		return globalRandom.СлучайноеЧисло(args, rets, envout)
+	}))
+
+	env.DefineTypeStruct("генераторслучайныхчисел", &VMRandom{})
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRandomErrors(t *testing.T) {
+	x := NewVMRandom(1)
+	for _, args := range []VMSlice{{}, {VMString("1")}, {VMInt(1), VMInt(2)}} {
+		if err := x.VMNew(args); err == nil {
+			t.Errorf("Новый ГенераторСлучайныхЧисел%v: ожидается ошибка", args)
+		}
+	}
+	sl := VMSlice{VMInt(1), VMInt(2), VMInt(3)}
+	for _, c := range []struct {
+		name string
+		f    VMMethod
+		args VMSlice
+		want error
+	}{
+		{"СлучайноеЧисло", x.СлучайноеЧисло, VMSlice{VMInt(1)}, VMErrorNeedArgs(2)},
+		{"СлучайноеЧисло", x.СлучайноеЧисло, VMSlice{VMInt(1), VMString("2")}, VMErrorNeedInt},
+		{"СлучайноеЧисло", x.СлучайноеЧисло, VMSlice{VMInt(2), VMInt(1)}, VMErrorNeedLess},
+		{"СлучайноеДробное", x.СлучайноеДробное, VMSlice{VMInt(1)}, VMErrorNeedArgs(0)},
+		{"Перемешать", x.Перемешать, VMSlice{}, VMErrorNeedArgs(1)},
+		{"Перемешать", x.Перемешать, VMSlice{VMString("абв")}, VMErrorNeedSlice},
+		{"Выборка", x.Выборка, VMSlice{sl}, VMErrorNeedArgs(2)},
+		{"Выборка", x.Выборка, VMSlice{VMStringMap{}, VMInt(1)}, VMErrorNeedSlice},
+		{"Выборка", x.Выборка, VMSlice{sl, VMString("1")}, VMErrorNeedInt},
+		{"Выборка", x.Выборка, VMSlice{sl, VMInt(-1)}, VMErrorIndexOutOfBoundary},
+		{"Выборка", x.Выборка, VMSlice{sl, VMInt(4)}, VMErrorIndexOutOfBoundary},
+		{"СлучайныйЭлемент", x.СлучайныйЭлемент, VMSlice{VMSlice{}}, VMErrorIndexOutOfBoundary},
+		{"СлучайныйЭлемент", x.СлучайныйЭлемент, VMSlice{VMInt(1)}, VMErrorNeedSlice},
+	} {
+		var rets VMSlice
+		if err := c.f(c.args, &rets, nil); err == nil || !strings.Contains(err.Error(), c.want.Error()) {
+			t.Errorf("%s%v = %v, %v, ожидается ошибка %v", c.name, c.args, rets, err, c.want)
+		}
+	}
+	if sl[0] != VMInt(1) || sl[1] != VMInt(2) || sl[2] != VMInt(3) {
+		t.Errorf("массив изменен при ошибке: %v", sl)
+	}
+}
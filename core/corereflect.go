@@ -0,0 +1,657 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/covrom/decnum"
+	"github.com/covrom/gonec/names"
+)
+
+var (
+	reflectGoError   = reflect.TypeOf((*error)(nil)).Elem()
+	reflectGoContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+	reflectGoTime    = reflect.TypeOf(time.Time{})
+	reflectGoDur     = reflect.TypeOf(time.Duration(0))
+	reflectVMValuer  = reflect.TypeOf((*VMValuer)(nil)).Elem()
+	reflectVMMethod  = reflect.TypeOf(VMMethod(nil))
+)
+
+// GoToVMValue преобразует значение Го в значение вирт. машины:
+// числа, строки, булево, time.Time и time.Duration - в простые типы,
+// слайсы и массивы - в Массив, карты со строковыми ключами - в Структуру,
+// структуры и указатели на структуры - в объект VMGoStruct, функции - через WrapGoFunc
+func GoToVMValue(rv reflect.Value) (VMValuer, error) {
+	if !rv.IsValid() {
+		return VMNil, nil
+	}
+	if rv.CanInterface() {
+		switch v := rv.Interface().(type) {
+		case VMValuer:
+			return v, nil
+		case time.Time:
+			return VMTime(v), nil
+		case time.Duration:
+			return VMTimeDuration(v), nil
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return VMInt(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			// не помещается в ЦелоеЧисло
+			d, err := decnum.FromString(strconv.FormatUint(u, 10))
+			return VMDecNum{num: d}, err
+		}
+		return VMInt(u), nil
+	case reflect.Float32, reflect.Float64:
+		return VMDecNum{num: decnum.FromFloat(rv.Float())}, nil
+	case reflect.String:
+		return VMString(rv.String()), nil
+	case reflect.Bool:
+		return VMBool(rv.Bool()), nil
+	case reflect.Interface:
+		if rv.IsNil() {
+			return VMNil, nil
+		}
+		return GoToVMValue(rv.Elem())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			// []byte передаем как строку
+			return VMString(rv.Bytes()), nil
+		}
+		sl := make(VMSlice, rv.Len())
+		for i := range sl {
+			v, err := GoToVMValue(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			sl[i] = v
+		}
+		return sl, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s: ключи должны быть строками, а не %s", VMErrorNotConverted, rv.Type().Key())
+		}
+		vsm := make(VMStringMap, rv.Len())
+		for _, k := range rv.MapKeys() {
+			v, err := GoToVMValue(rv.MapIndex(k))
+			if err != nil {
+				return nil, err
+			}
+			vsm[k.String()] = v
+		}
+		return vsm, nil
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return VMNil, nil
+		}
+		if rv.Elem().Kind() == reflect.Struct {
+			return WrapGoStruct(rv.Interface()), nil
+		}
+		return GoToVMValue(rv.Elem())
+	case reflect.Struct:
+		// структура передана по значению, поэтому оборачиваем ее копию
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		return WrapGoStruct(p.Interface()), nil
+	case reflect.Func:
+		if rv.IsNil() {
+			return VMNil, nil
+		}
+		return WrapGoFunc(rv.Interface()), nil
+	}
+	return nil, fmt.Errorf("%s: %s", VMErrorNotConverted, rv.Type())
+}
+
+// VMValueToGo преобразует значение вирт. машины в значение Го типа t.
+// Для типа interface{} возвращаются значения Го наиболее подходящих типов
+func VMValueToGo(v VMValuer, t reflect.Type) (reflect.Value, error) {
+	if v == nil || v == VMNil {
+		return reflect.Zero(t), nil
+	}
+
+	vv := reflect.ValueOf(v)
+	if t.Kind() != reflect.Interface || t.Implements(reflectVMValuer) {
+		if vv.Type().AssignableTo(t) {
+			return vv, nil
+		}
+	}
+
+	errconv := fmt.Errorf("%s: %T -> %s", VMErrorNotConverted, v, t)
+
+	switch t {
+	case reflectGoTime:
+		if x, ok := v.(VMDateTimer); ok {
+			return reflect.ValueOf(time.Time(x.Time())), nil
+		}
+		return reflect.Value{}, errconv
+	case reflectGoDur:
+		if x, ok := v.(VMDurationer); ok {
+			return reflect.ValueOf(time.Duration(x.Duration())), nil
+		}
+		return reflect.Value{}, errconv
+	}
+
+	if gs, ok := v.(*VMGoStruct); ok {
+		switch {
+		case gs.v.Type().AssignableTo(t):
+			return gs.v, nil
+		case gs.v.Elem().Type().AssignableTo(t):
+			return gs.v.Elem(), nil
+		}
+	}
+
+	// числа не усекаются: дробное, отрицательное для беззнакового типа
+	// или не помещающееся в тип значение не преобразуется
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if x, ok := v.(VMNumberer); ok {
+			rv := reflect.New(t).Elem()
+			if i, ok := numberToBigInt(x); ok && i.IsInt64() && !rv.OverflowInt(i.Int64()) {
+				rv.SetInt(i.Int64())
+				return rv, nil
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if x, ok := v.(VMNumberer); ok {
+			rv := reflect.New(t).Elem()
+			if i, ok := numberToBigInt(x); ok && i.IsUint64() && !rv.OverflowUint(i.Uint64()) {
+				rv.SetUint(i.Uint64())
+				return rv, nil
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		if x, ok := v.(VMNumberer); ok {
+			rv := reflect.New(t).Elem()
+			if f := x.Float(); !rv.OverflowFloat(f) {
+				rv.SetFloat(f)
+				return rv, nil
+			}
+		}
+	case reflect.String:
+		if x, ok := v.(VMString); ok {
+			return reflect.ValueOf(string(x)).Convert(t), nil
+		}
+	case reflect.Bool:
+		if x, ok := v.(VMBooler); ok {
+			return reflect.ValueOf(x.Bool()).Convert(t), nil
+		}
+	case reflect.Interface:
+		gv := vmValueToNaturalGo(v)
+		if gv == nil {
+			return reflect.Zero(t), nil
+		}
+		if reflect.TypeOf(gv).Implements(t) {
+			rv := reflect.New(t).Elem()
+			rv.Set(reflect.ValueOf(gv))
+			return rv, nil
+		}
+	case reflect.Slice:
+		if x, ok := v.(VMString); ok && t.Elem().Kind() == reflect.Uint8 {
+			return reflect.ValueOf([]byte(x)).Convert(t), nil
+		}
+		if x, ok := v.(VMSlicer); ok {
+			sl := x.Slice()
+			rv := reflect.MakeSlice(t, len(sl), len(sl))
+			for i := range sl {
+				ev, err := VMValueToGo(sl[i], t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				rv.Index(i).Set(ev)
+			}
+			return rv, nil
+		}
+	case reflect.Array:
+		if x, ok := v.(VMSlicer); ok {
+			sl := x.Slice()
+			if len(sl) != t.Len() {
+				return reflect.Value{}, errconv
+			}
+			rv := reflect.New(t).Elem()
+			for i := range sl {
+				ev, err := VMValueToGo(sl[i], t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				rv.Index(i).Set(ev)
+			}
+			return rv, nil
+		}
+	case reflect.Map:
+		if x, ok := v.(VMStringMaper); ok && t.Key().Kind() == reflect.String {
+			vsm := x.StringMap()
+			rv := reflect.MakeMapWithSize(t, len(vsm))
+			for k, kv := range vsm {
+				ev, err := VMValueToGo(kv, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				rv.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), ev)
+			}
+			return rv, nil
+		}
+	case reflect.Struct:
+		if x, ok := v.(VMStringMaper); ok {
+			rv := reflect.New(t).Elem()
+			if err := setGoStructFields(rv, x.StringMap()); err != nil {
+				return reflect.Value{}, err
+			}
+			return rv, nil
+		}
+	case reflect.Ptr:
+		ev, err := VMValueToGo(v, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		rv := reflect.New(t.Elem())
+		rv.Elem().Set(ev)
+		return rv, nil
+	case reflect.Func:
+		if x, ok := v.(VMFuncer); ok {
+			return makeGoFunc(x.Func(), t), nil
+		}
+	}
+	return reflect.Value{}, errconv
+}
+
+// numberToBigInt возвращает значение числа, ok=false для дробного числа
+func numberToBigInt(x VMNumberer) (*big.Int, bool) {
+	if i, ok := x.(VMInt); ok {
+		return big.NewInt(int64(i)), true
+	}
+	// 256 бит хватает для точного представления целых из 34 десятичных знаков Число
+	f, _, err := big.ParseFloat(x.DecNum().String(), 10, 256, big.ToNearestEven)
+	if err != nil || !f.IsInt() {
+		return nil, false
+	}
+	i, _ := f.Int(nil)
+	return i, true
+}
+
+// vmValueToNaturalGo возвращает значение Го наиболее подходящего типа
+func vmValueToNaturalGo(v VMValuer) interface{} {
+	switch x := v.(type) {
+	case nil, VMNilType:
+		return nil
+	case VMInt:
+		return int64(x)
+	case VMDecNum:
+		return x.Float()
+	case VMString:
+		return string(x)
+	case VMBool:
+		return bool(x)
+	case VMTime:
+		return time.Time(x)
+	case VMTimeDuration:
+		return time.Duration(x)
+	case VMSlice:
+		sl := make([]interface{}, len(x))
+		for i := range x {
+			sl[i] = vmValueToNaturalGo(x[i])
+		}
+		return sl
+	case VMStringMap:
+		m := make(map[string]interface{}, len(x))
+		for k := range x {
+			m[k] = vmValueToNaturalGo(x[k])
+		}
+		return m
+	case *VMGoStruct:
+		return x.v.Interface()
+	}
+	return v
+}
+
+// setGoStructFields заполняет экспортируемые поля структуры из Структуры, имена полей сравниваются без учета регистра
+func setGoStructFields(rv reflect.Value, vsm VMStringMap) error {
+	for k, kv := range vsm {
+		lk := names.FastToLower(k)
+		f, ok := rv.Type().FieldByNameFunc(func(n string) bool { return strings.ToLower(n) == lk })
+		if !ok || f.PkgPath != "" {
+			continue
+		}
+		ev, err := VMValueToGo(kv, f.Type)
+		if err != nil {
+			return err
+		}
+		fv, ok := goField(rv, f.Index, true)
+		if !ok {
+			return fmt.Errorf("%s: поле %s", VMErrorNotConverted, f.Name)
+		}
+		fv.Set(ev)
+	}
+	return nil
+}
+
+// makeGoFunc создает функцию Го типа t, которая вызывает функцию вирт. машины
+func makeGoFunc(f VMFunc, t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		args := make(VMSlice, len(in))
+		out := make([]reflect.Value, t.NumOut())
+		for i := range out {
+			out[i] = reflect.Zero(t.Out(i))
+		}
+		seterr := func(err error) []reflect.Value {
+			if t.NumOut() > 0 && t.Out(t.NumOut()-1) == reflectGoError {
+				out[t.NumOut()-1] = reflect.ValueOf(&err).Elem()
+				return out
+			}
+			panic(err)
+		}
+		for i := range in {
+			v, err := GoToVMValue(in[i])
+			if err != nil {
+				return seterr(err)
+			}
+			args[i] = v
+		}
+		var rets VMSlice
+		var env *Env
+		if err := f(args, &rets, &env); err != nil {
+			return seterr(err)
+		}
+		for i := 0; i < len(rets) && i < len(out); i++ {
+			if out[i].Type() == reflectGoError {
+				break
+			}
+			v, err := VMValueToGo(rets[i], t.Out(i))
+			if err != nil {
+				return seterr(err)
+			}
+			out[i] = v
+		}
+		return out
+	})
+}
+
+// WrapGoFunc создает функцию вирт. машины из произвольной функции Го.
+// Параметры и результаты преобразуются через VMValueToGo и GoToVMValue,
+// возвращаемая последней ошибка error становится исключением на языке Гонец.
+// Если первый параметр функции имеет тип context.Context, в него передается контекст исполнения вызывающего кода
+func WrapGoFunc(f interface{}) VMFunc {
+	switch ff := f.(type) {
+	case VMFunc:
+		return ff
+	case VMMethod:
+		return VMFunc(ff)
+	}
+
+	fv := reflect.ValueOf(f)
+	if fv.Kind() != reflect.Func {
+		panic(fmt.Sprintf("Функция не может быть зарегистрирована: %T", f))
+	}
+	ft := fv.Type()
+	if ft.ConvertibleTo(reflectVMMethod) {
+		return VMFunc(fv.Convert(reflectVMMethod).Interface().(VMMethod))
+	}
+
+	withCtx := ft.NumIn() > 0 && ft.In(0) == reflectGoContext
+	first := 0
+	if withCtx {
+		first = 1
+	}
+	nfixed := ft.NumIn() - first
+	if ft.IsVariadic() {
+		nfixed--
+	}
+	witherr := ft.NumOut() > 0 && ft.Out(ft.NumOut()-1) == reflectGoError
+
+	return func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		if len(args) < nfixed || (!ft.IsVariadic() && len(args) > nfixed) {
+			return VMErrorNeedArgs(nfixed)
+		}
+
+		in := make([]reflect.Value, 0, first+len(args))
+		if withCtx {
+			in = append(in, reflect.ValueOf(ContextOf(envout)))
+		}
+		for i, a := range args {
+			var at reflect.Type
+			if i < nfixed {
+				at = ft.In(first + i)
+			} else {
+				at = ft.In(ft.NumIn() - 1).Elem()
+			}
+			v, err := VMValueToGo(a, at)
+			if err != nil {
+				return err
+			}
+			in = append(in, v)
+		}
+
+		out := fv.Call(in)
+
+		if witherr {
+			if err := out[len(out)-1]; !err.IsNil() {
+				return err.Interface().(error)
+			}
+			out = out[:len(out)-1]
+		}
+		for i := range out {
+			v, err := GoToVMValue(out[i])
+			if err != nil {
+				return err
+			}
+			rets.Append(v)
+		}
+		return nil
+	}
+}
+
+// VMGoStruct - объект вирт. машины, который оборачивает произвольную структуру Го.
+// Экспортируемые поля и методы структуры доступны из кода на языке Гонец без учета регистра,
+// поля встроенных структур доступны так же, как в Го.
+// Поля-структуры возвращаются как вложенные объекты, изменения в них видны в исходной структуре,
+// слайсы и карты возвращаются как копии
+type VMGoStruct struct {
+	VMMetaObj
+
+	v       reflect.Value     // указатель на структуру
+	fields  map[string][]int  // индексы полей по названию в нижнем регистре
+	methods map[string]VMFunc // обертки методов по названию в нижнем регистре
+}
+
+// WrapGoStruct создает объект вирт. машины из структуры или указателя на структуру.
+// Если передан указатель, то изменения полей из кода на языке Гонец видны в исходной структуре
+func WrapGoStruct(v interface{}) *VMGoStruct {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Struct {
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		rv = p
+	}
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("Структура не может быть зарегистрирована: %T", v))
+	}
+	x := &VMGoStruct{v: rv}
+	x.VMInit(x)
+	x.VMRegister()
+	return x
+}
+
+func (x *VMGoStruct) VMRegister() {
+	x.methods = make(map[string]VMFunc)
+
+	x.fields = goStructFields(x.v.Elem().Type())
+
+	pt := x.v.Type()
+	for i := 0; i < pt.NumMethod(); i++ {
+		m := pt.Method(i)
+		if m.PkgPath != "" {
+			continue
+		}
+		x.methods[names.FastToLower(m.Name)] = WrapGoFunc(x.v.Method(i).Interface())
+	}
+}
+
+// goStructFields возвращает индексы экспортируемых полей структуры t по названию в нижнем регистре,
+// включая поля встроенных структур, по тем же правилам, что и продвижение полей в Го:
+// поле с меньшей глубиной вложенности скрывает более глубокие, одноименные поля одной глубины недоступны
+func goStructFields(t reflect.Type) map[string][]int {
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+	fields := make(map[string][]int)
+	hidden := make(map[string]bool)
+	visited := make(map[reflect.Type]bool)
+	for level := []embedded{{t: t}}; len(level) > 0; {
+		var next []embedded
+		found := make(map[string][]int)
+		count := make(map[string]int)
+		for _, e := range level {
+			if visited[e.t] {
+				continue
+			}
+			visited[e.t] = true
+			for i := 0; i < e.t.NumField(); i++ {
+				f := e.t.Field(i)
+				index := append(append([]int(nil), e.index...), i)
+				if f.Anonymous {
+					ft := f.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						next = append(next, embedded{ft, index})
+					}
+				}
+				if f.PkgPath != "" {
+					continue // неэкспортируемое поле
+				}
+				name := names.FastToLower(f.Name)
+				if _, ok := fields[name]; ok || hidden[name] {
+					continue
+				}
+				found[name] = index
+				count[name]++
+			}
+		}
+		for name, index := range found {
+			if count[name] == 1 {
+				fields[name] = index
+			} else {
+				hidden[name] = true
+			}
+		}
+		level = next
+	}
+	return fields
+}
+
+// goField возвращает поле структуры v по индексу, полученному из goStructFields.
+// Пустые указатели на встроенные структуры создаются, если alloc=true, иначе возвращается ok=false
+func goField(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, fi := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(fi)
+	}
+	return v, true
+}
+
+func (x *VMGoStruct) Interface() interface{} {
+	return x.v.Interface()
+}
+
+func (x *VMGoStruct) String() string {
+	b, err := json.Marshal(x.v.Interface())
+	if err != nil {
+		return fmt.Sprint(x.v.Interface())
+	}
+	return string(b)
+}
+
+func (x *VMGoStruct) Hash() VMString {
+	return VMString(x.String()).Hash()
+}
+
+func (x *VMGoStruct) VMIsField(name string) bool {
+	_, ok := x.fields[name]
+	return ok
+}
+
+func (x *VMGoStruct) VMGetField(name string) VMValuer {
+	idx, ok := x.fields[name]
+	if !ok {
+		panic("Невозможно получить значение поля")
+	}
+	fv, ok := goField(x.v.Elem(), idx, false)
+	if !ok {
+		// поле встроенной структуры по пустому указателю
+		return VMNil
+	}
+	if fv.Kind() == reflect.Struct && fv.Type() != reflectGoTime {
+		// вложенную структуру возвращаем по ссылке
+		return WrapGoStruct(fv.Addr().Interface())
+	}
+	v, err := GoToVMValue(fv)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (x *VMGoStruct) VMSetField(name string, val VMValuer) {
+	idx, ok := x.fields[name]
+	if !ok {
+		panic("Невозможно установить значение поля")
+	}
+	fv, ok := goField(x.v.Elem(), idx, true)
+	if !ok {
+		panic("Невозможно установить значение поля")
+	}
+	v, err := VMValueToGo(val, fv.Type())
+	if err != nil {
+		panic(err)
+	}
+	fv.Set(v)
+}
+
+func (x *VMGoStruct) VMGetMethod(name string) (VMFunc, bool) {
+	f, ok := x.methods[name]
+	return f, ok
+}
+
+func (x *VMGoStruct) ConvertToType(nt reflect.Type) (VMValuer, error) {
+	switch nt {
+	case ReflectVMString:
+		return VMString(x.String()), nil
+	case ReflectVMStringMap:
+		vsm := make(VMStringMap, len(x.fields))
+		t := x.v.Elem().Type()
+		for _, idx := range x.fields {
+			fv, ok := goField(x.v.Elem(), idx, false)
+			if !ok {
+				continue
+			}
+			v, err := GoToVMValue(fv)
+			if err != nil {
+				return VMNil, err
+			}
+			vsm[t.FieldByIndex(idx).Name] = v
+		}
+		return vsm, nil
+	}
+	return VMNil, VMErrorNotConverted
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestVMValueToGoNumbers(t *testing.T) {
+	dec := func(s string) VMDecNum { return VMDecNum{num: mustDec(s)} }
+	good := []struct {
+		v    VMValuer
+		want interface{}
+	}{
+		{VMInt(127), int8(127)},
+		{VMInt(-128), int8(-128)},
+		{dec("300"), int16(300)},
+		{VMInt(255), uint8(255)},
+		{dec("18446744073709551615"), uint64(math.MaxUint64)},
+		{dec("1E+3"), uint(1000)},
+		{dec("2.5"), float32(2.5)},
+	}
+	for _, c := range good {
+		rv, err := VMValueToGo(c.v, reflect.TypeOf(c.want))
+		if err != nil || rv.Interface() != c.want {
+			t.Errorf("%v -> %T: %v, %v", c.v, c.want, rv, err)
+		}
+	}
+	bad := []struct {
+		v VMValuer
+		t interface{}
+	}{
+		{VMInt(300), int8(0)},
+		{VMInt(-129), int8(0)},
+		{VMInt(-1), uint(0)},
+		{VMInt(256), uint8(0)},
+		{dec("2.7"), 0},
+		{dec("-0.5"), uint(0)},
+		{dec("9223372036854775808"), int64(0)},
+		{dec("18446744073709551616"), uint64(0)},
+		{dec("1E+40"), float32(0)},
+		{VMString("1"), 0},
+	}
+	for _, c := range bad {
+		if rv, err := VMValueToGo(c.v, reflect.TypeOf(c.t)); err == nil {
+			t.Errorf("%v -> %T = %v, ожидается ошибка", c.v, c.t, rv)
+		}
+	}
+}
+
+func TestGoToVMValueUint(t *testing.T) {
+	v, err := GoToVMValue(reflect.ValueOf(uint64(math.MaxUint64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := v.(VMDecNum); !ok || !d.num.Equal(mustDec("18446744073709551615")) {
+		t.Errorf("uint64 %d = %v", uint64(math.MaxUint64), v)
+	}
+	if v, err := GoToVMValue(reflect.ValueOf(uint32(math.MaxUint32))); err != nil || v != VMInt(math.MaxUint32) {
+		t.Errorf("uint32 = %v, %v", v, err)
+	}
+}
+
+type testBase struct {
+	ID   int
+	Имя  string
+	name string
+}
+
+type TestAudit struct {
+	Автор string
+	Имя   string
+}
+
+type testOwner struct {
+	Владелец string
+}
+
+type testDoc struct {
+	testBase
+	*TestAudit
+	*testOwner
+	Имя string
+}
+
+func TestGoStructPromotedFields(t *testing.T) {
+	doc := &testDoc{testBase: testBase{ID: 7, Имя: "база"}, Имя: "документ"}
+	x := WrapGoStruct(doc)
+	if !x.VMIsField("id") || x.VMGetField("id") != VMInt(7) {
+		t.Errorf("ID = %v", x.VMGetField("id"))
+	}
+	// поле внешней структуры скрывает поля встроенных
+	if x.VMGetField("имя") != VMString("документ") {
+		t.Errorf("Имя = %v", x.VMGetField("имя"))
+	}
+	if x.VMIsField("name") {
+		t.Error("неэкспортируемое поле доступно")
+	}
+	// поле по пустому указателю читается как Неопределено, а запись создает встроенную структуру
+	if x.VMGetField("автор") != VMNil {
+		t.Errorf("Автор = %v", x.VMGetField("автор"))
+	}
+	x.VMSetField("автор", VMString("Иванов"))
+	x.VMSetField("id", VMInt(8))
+	if doc.TestAudit == nil || doc.Автор != "Иванов" || doc.ID != 8 {
+		t.Errorf("структура после записи: %+v", doc)
+	}
+	// указатель на неэкспортируемую встроенную структуру не может быть создан
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("запись поля по пустому указателю на неэкспортируемую структуру")
+			}
+		}()
+		x.VMSetField("владелец", VMString("отдел"))
+	}()
+
+	v, err := VMValueToGo(VMStringMap{"id": VMInt(9), "автор": VMString("Петров")}, reflect.TypeOf(testDoc{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := v.Interface().(testDoc); d.ID != 9 || d.TestAudit == nil || d.Автор != "Петров" {
+		t.Errorf("структура из Структуры: %+v", d)
+	}
+}
+
+// одноименные поля на одной глубине вложенности недоступны, как и в Го
+func TestGoStructAmbiguousFields(t *testing.T) {
+	type a struct{ Код int }
+	type b struct{ Код int }
+	type c struct {
+		a
+		b
+	}
+	if x := WrapGoStruct(&c{}); x.VMIsField("код") {
+		t.Error("неоднозначное поле доступно")
+	}
+}
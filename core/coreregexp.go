@@ -0,0 +1,246 @@
+package core
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+)
+
+// regExpCacheSize - количество скомпилированных шаблонов, которые хранятся в кэше
+const regExpCacheSize = 256
+
+var regExpCache = struct {
+	sync.Mutex
+	m map[string]*regexp.Regexp
+}{m: make(map[string]*regexp.Regexp)}
+
+// compileRegExp компилирует шаблон или берет ранее скомпилированный из кэша.
+// При переполнении кэш очищается целиком
+func compileRegExp(pattern string) (*regexp.Regexp, error) {
+	regExpCache.Lock()
+	defer regExpCache.Unlock()
+	if re, ok := regExpCache.m[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.New("Неверное регулярное выражение: " + err.Error())
+	}
+	if len(regExpCache.m) >= regExpCacheSize {
+		regExpCache.m = make(map[string]*regexp.Regexp)
+	}
+	regExpCache.m[pattern] = re
+	return re, nil
+}
+
+// VMRegExp - регулярное выражение (синтаксис RE2), создается как Новый РегулярноеВыражение("шаблон")
+type VMRegExp struct {
+	VMMetaObj
+
+	re *regexp.Regexp
+}
+
+func (x *VMRegExp) VMRegister() {
+	x.VMRegisterMethod("Шаблон", x.Шаблон)
+	x.VMRegisterMethod("Совпадает", x.Совпадает)
+	x.VMRegisterMethod("НайтиВсе", x.НайтиВсе)
+	x.VMRegisterMethod("Заменить", x.Заменить)
+	x.VMRegisterMethod("Разделить", x.Разделить)
+}
+
+// VMNew компилирует шаблон, переданный при создании
+func (x *VMRegExp) VMNew(args VMSlice) error {
+	if len(args) != 1 {
+		return VMErrorNeedArgs(1)
+	}
+	p, ok := args[0].(VMString)
+	if !ok {
+		return VMErrorNeedString
+	}
+	re, err := compileRegExp(string(p))
+	if err != nil {
+		return err
+	}
+	x.re = re
+	return nil
+}
+
+func (x *VMRegExp) String() string {
+	if x.re == nil {
+		return "РегулярноеВыражение"
+	}
+	return x.re.String()
+}
+
+func (x *VMRegExp) Шаблон(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	if x.re == nil {
+		return VMErrorNoRegExp
+	}
+	rets.Append(VMString(x.re.String()))
+	return nil
+}
+
+// Совпадает возвращает истину, если строка содержит совпадение с шаблоном
+func (x *VMRegExp) Совпадает(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	s, err := x.stringArg(args, 1)
+	if err != nil {
+		return err
+	}
+	rets.Append(VMBool(x.re.MatchString(s)))
+	return nil
+}
+
+// НайтиВсе возвращает массив совпадений, каждое - структура с полями
+// Текст, Позиция (в байтах, как у СтрНайти), Группы (массив подвыражений) и ИменованныеГруппы.
+// Необязательный второй параметр ограничивает количество совпадений
+func (x *VMRegExp) НайтиВсе(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	n := -1
+	if len(args) == 2 {
+		v, ok := args[1].(VMInt)
+		if !ok {
+			return VMErrorNeedInt
+		}
+		n = int(v)
+		args = args[:1]
+	}
+	s, err := x.stringArg(args, 1)
+	if err != nil {
+		return err
+	}
+	idxs := x.re.FindAllStringSubmatchIndex(s, n)
+	res := make(VMSlice, len(idxs))
+	for i, idx := range idxs {
+		res[i] = x.match(s, idx)
+	}
+	rets.Append(res)
+	return nil
+}
+
+// Заменить заменяет все совпадения в строке. Замена - строка, в которой $1 и ${имя} означают подвыражения,
+// или функция, которая получает структуру совпадения (см. НайтиВсе) и возвращает строку замены
+func (x *VMRegExp) Заменить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 2 {
+		return VMErrorNeedArgs(2)
+	}
+	s, err := x.stringArg(args[:1], 1)
+	if err != nil {
+		return err
+	}
+	res, err := x.replace(s, args[1])
+	if err != nil {
+		return err
+	}
+	rets.Append(VMString(res))
+	return nil
+}
+
+// Разделить разделяет строку по совпадениям с шаблоном.
+// Необязательный второй параметр ограничивает количество частей
+func (x *VMRegExp) Разделить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	n := -1
+	if len(args) == 2 {
+		v, ok := args[1].(VMInt)
+		if !ok {
+			return VMErrorNeedInt
+		}
+		n = int(v)
+		args = args[:1]
+	}
+	s, err := x.stringArg(args, 1)
+	if err != nil {
+		return err
+	}
+	parts := x.re.Split(s, n)
+	res := make(VMSlice, len(parts))
+	for i, p := range parts {
+		res[i] = VMString(p)
+	}
+	rets.Append(res)
+	return nil
+}
+
+// stringArg проверяет количество параметров и возвращает первый из них как строку
+func (x *VMRegExp) stringArg(args VMSlice, n int) (string, error) {
+	if x.re == nil {
+		return "", VMErrorNoRegExp
+	}
+	if len(args) != n {
+		return "", VMErrorNeedArgs(n)
+	}
+	v, ok := args[0].(VMStringer)
+	if !ok {
+		return "", VMErrorNeedString
+	}
+	return v.String(), nil
+}
+
+// match возвращает структуру совпадения по индексам, полученным из FindStringSubmatchIndex
+func (x *VMRegExp) match(s string, idx []int) VMStringMap {
+	groups := make(VMSlice, len(idx)/2-1)
+	named := make(VMStringMap)
+	for g, name := range x.re.SubexpNames() {
+		if g == 0 {
+			continue
+		}
+		var v VMString
+		if idx[2*g] >= 0 {
+			v = VMString(s[idx[2*g]:idx[2*g+1]])
+		}
+		groups[g-1] = v
+		if name != "" {
+			named[name] = v
+		}
+	}
+	return VMStringMap{
+		"Текст":             VMString(s[idx[0]:idx[1]]),
+		"Позиция":           VMInt(idx[0]),
+		"Группы":            groups,
+		"ИменованныеГруппы": named,
+	}
+}
+
+func (x *VMRegExp) replace(s string, repl VMValuer) (string, error) {
+	switch r := repl.(type) {
+	case VMFunc:
+		// функция вызывается для каждого совпадения, первая ошибка прерывает замену
+		res := []byte{}
+		last := 0
+		for _, idx := range x.re.FindAllStringSubmatchIndex(s, -1) {
+			args := VMSlice{x.match(s, idx)}
+			rets := make(VMSlice, 0)
+			var env *Env // сюда вернется окружение вызываемой функции
+			if err := r(args, &rets, &env); err != nil {
+				return "", err
+			}
+			if len(rets) != 1 {
+				return "", errors.New("Функция замены должна возвращать строку")
+			}
+			v, ok := rets[0].(VMStringer)
+			if !ok {
+				return "", errors.New("Функция замены должна возвращать строку")
+			}
+			res = append(res, s[last:idx[0]]...)
+			res = append(res, v.String()...)
+			last = idx[1]
+		}
+		return string(append(res, s[last:]...)), nil
+	case VMString:
+		return x.re.ReplaceAllString(s, string(r)), nil
+	}
+	return "", errors.New("Замена должна быть строкой или функцией")
+}
+
+// NewVMRegExp компилирует шаблон регулярного выражения, используя кэш
+func NewVMRegExp(pattern string) (*VMRegExp, error) {
+	re, err := compileRegExp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	x := &VMRegExp{re: re}
+	x.VMInit(x)
+	x.VMRegister()
+	return x, nil
+}
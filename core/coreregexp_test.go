@@ -0,0 +1,88 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegExpErrors(t *testing.T) {
+	for _, args := range []struct {
+		args VMSlice
+		want string
+	}{
+		{VMSlice{}, VMErrorNeedArgs(1).Error()},
+		{VMSlice{VMInt(1)}, VMErrorNeedString.Error()},
+		{VMSlice{VMString("(а")}, "Неверное регулярное выражение"},
+		{VMSlice{VMString(`\p{Нет}`)}, "Неверное регулярное выражение"},
+	} {
+		if err := (&VMRegExp{}).VMNew(args.args); err == nil || !strings.Contains(err.Error(), args.want) {
+			t.Errorf("Новый РегулярноеВыражение%v: %v, ожидается %q", args.args, err, args.want)
+		}
+	}
+
+	// методы объекта без шаблона
+	empty := &VMRegExp{}
+	var rets VMSlice
+	for name, m := range map[string]VMMethod{
+		"Шаблон":    empty.Шаблон,
+		"Совпадает": empty.Совпадает,
+		"НайтиВсе":  empty.НайтиВсе,
+		"Разделить": empty.Разделить,
+	} {
+		var args VMSlice
+		if name != "Шаблон" {
+			args = VMSlice{VMString("а")}
+		}
+		if err := m(args, &rets, nil); err != VMErrorNoRegExp {
+			t.Errorf("%s без шаблона: %v", name, err)
+		}
+	}
+
+	re, err := NewVMRegExp(`(\d+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing := errors.New("ошибка функции замены")
+	for _, c := range []struct {
+		name string
+		m    VMMethod
+		args VMSlice
+		want string
+	}{
+		{"Шаблон", re.Шаблон, VMSlice{VMInt(1)}, VMErrorNoNeedArgs.Error()},
+		{"Совпадает", re.Совпадает, VMSlice{}, VMErrorNeedArgs(1).Error()},
+		{"Совпадает", re.Совпадает, VMSlice{nil}, VMErrorNeedString.Error()},
+		{"НайтиВсе", re.НайтиВсе, VMSlice{VMString("1"), VMString("2")}, VMErrorNeedInt.Error()},
+		{"НайтиВсе", re.НайтиВсе, VMSlice{VMString("1"), VMInt(1), VMInt(1)}, VMErrorNeedArgs(1).Error()},
+		{"Разделить", re.Разделить, VMSlice{VMString("1"), VMBool(true)}, VMErrorNeedInt.Error()},
+		{"Заменить", re.Заменить, VMSlice{VMString("1")}, VMErrorNeedArgs(2).Error()},
+		{"Заменить", re.Заменить, VMSlice{VMString("1"), VMInt(2)}, "Замена должна быть строкой или функцией"},
+		{"Заменить", re.Заменить, VMSlice{VMString("а1"), VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+			return failing
+		})}, failing.Error()},
+		{"Заменить", re.Заменить, VMSlice{VMString("а1"), VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+			rets.Append(VMString("а"), VMString("б"))
+			return nil
+		})}, "Функция замены должна возвращать строку"},
+		{"Заменить", re.Заменить, VMSlice{VMString("а1"), VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+			rets.Append(nil)
+			return nil
+		})}, "Функция замены должна возвращать строку"},
+	} {
+		if err := c.m(c.args, &rets, nil); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s%v: %v, ожидается %q", c.name, c.args, err, c.want)
+		}
+	}
+
+	env := NewEnv()
+	LoadAllBuiltins(env)
+	checkBuiltinErrors(t, env, []builtinErrCase{
+		{"СтрСовпадает", VMSlice{VMString("а")}, VMErrorNeedArgs(2).Error()},
+		{"СтрСовпадает", VMSlice{VMString("а"), nil}, VMErrorNeedString.Error()},
+		{"СтрСовпадает", VMSlice{VMString("а"), VMString("[")}, "Неверное регулярное выражение"},
+		{"СтрЗаменитьРег", VMSlice{VMString("а"), VMString("а")}, VMErrorNeedArgs(3).Error()},
+		{"СтрЗаменитьРег", VMSlice{VMString("а"), VMString("*"), VMString("б")}, "Неверное регулярное выражение"},
+		{"СтрЗаменитьРег", VMSlice{VMString("а"), VMString("а"), VMInt(1)}, "Замена должна быть строкой или функцией"},
+	})
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FSAccess - доступ исполняемого кода к файловой системе
+type FSAccess int
+
+const (
+	FSFull     FSAccess = iota // чтение и запись
+	FSReadOnly                 // только чтение
+	FSDenied                   // доступ запрещен
+)
+
+// Sandbox - политика ограничений исполняемого кода (песочница).
+// Устанавливается в глобальном окружении через SetSandbox и действует для всех порожденных от него окружений.
+// Без политики ограничений нет
+type Sandbox struct {
+	FS FSAccess // доступ к файловой системе
+
+	// Root - если не пусто, доступны только файлы внутри этого каталога,
+	// относительные пути отсчитываются от него, в нем же создаются временные файлы
+	Root string
+}
+
+// Path проверяет доступ к файлу path и возвращает путь, который следует использовать для обращения к нему.
+// Для nil доступ не ограничен. При заданном Root возвращается путь с раскрытыми символическими ссылками,
+// чтобы обращение шло к тому же файлу, который был проверен. Проверка все же не атомарна:
+// ссылка, подмененная в пути между проверкой и открытием файла, может вывести за пределы Root,
+// поэтому каталог Root не должен быть доступен для записи другим недоверенным процессам
+func (s *Sandbox) Path(path string, write bool) (string, error) {
+	if s == nil {
+		return path, nil
+	}
+	switch {
+	case s.FS == FSDenied:
+		return "", VMErrorFSDenied
+	case write && s.FS == FSReadOnly:
+		return "", VMErrorFSReadOnly
+	}
+	if s.Root == "" {
+		return path, nil
+	}
+	root, err := filepath.Abs(s.Root)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	// символические ссылки не должны выводить за пределы каталога
+	path = resolveLinks(filepath.Clean(path))
+	if !within(resolveLinks(root), path) {
+		return "", VMErrorFSOutsideRoot
+	}
+	return path, nil
+}
+
+// IsRoot сообщает, что путь path, полученный от Path, указывает на сам каталог Root
+func (s *Sandbox) IsRoot(path string) bool {
+	if s == nil || s.Root == "" {
+		return false
+	}
+	root, err := filepath.Abs(s.Root)
+	return err == nil && resolveLinks(root) == path
+}
+
+// TempDir возвращает каталог для временных файлов
+func (s *Sandbox) TempDir() string {
+	if s == nil || s.Root == "" {
+		return os.TempDir()
+	}
+	return s.Root
+}
+
+// resolveLinks раскрывает символические ссылки в существующей части пути
+func resolveLinks(path string) string {
+	if p, err := filepath.EvalSymlinks(path); err == nil {
+		return p
+	}
+	dir, file := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	if dir == path {
+		return path
+	}
+	return filepath.Join(resolveLinks(dir), file)
+}
+
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// SetSandbox устанавливает политику ограничений для глобального окружения e и всех порожденных от него окружений
+func (e *Env) SetSandbox(s *Sandbox) {
+	for ee := e; ee != nil; ee = ee.parent {
+		if ee.parent == nil {
+			ee.Lock()
+			ee.sandbox = s
+			ee.Unlock()
+			return
+		}
+	}
+}
+
+// Sandbox возвращает политику ограничений глобального окружения, nil - ограничений нет
+func (e *Env) Sandbox() *Sandbox {
+	for ee := e; ee != nil; ee = ee.parent {
+		if ee.parent == nil {
+			ee.RLock()
+			defer ee.RUnlock()
+			return ee.sandbox
+		}
+	}
+	return nil
+}
+
+// SandboxOf возвращает политику ограничений окружения вызывающего кода, переданного в envout.
+// Если окружение неизвестно (метод вызван из Go без окружения), доступ к файловой системе запрещен
+func SandboxOf(envout *(*Env)) *Sandbox {
+	if envout == nil || *envout == nil {
+		return &Sandbox{FS: FSDenied}
+	}
+	return (*envout).Sandbox()
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSandboxPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// каталог может сам находиться за ссылкой (например, /tmp в macOS)
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "внутри"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "внутри"), filepath.Join(dir, "ссылка")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(os.TempDir(), filepath.Join(dir, "наружу")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Sandbox{Root: dir}
+	// возвращается путь с раскрытыми ссылками, а не исходный
+	p, err := s.Path("ссылка/файл", true)
+	if err != nil || p != filepath.Join(dir, "внутри", "файл") {
+		t.Errorf("Path(ссылка/файл) = %q, %v", p, err)
+	}
+	for _, fn := range []string{"наружу/файл", "../файл", "/etc/passwd", "внутри/../../файл"} {
+		if _, err := s.Path(fn, false); err != VMErrorFSOutsideRoot {
+			t.Errorf("Path(%s): %v, ожидается %v", fn, err, VMErrorFSOutsideRoot)
+		}
+	}
+
+	ro := &Sandbox{FS: FSReadOnly, Root: dir}
+	if _, err := ro.Path("файл", false); err != nil {
+		t.Errorf("чтение в FSReadOnly: %v", err)
+	}
+	if _, err := ro.Path("файл", true); err != VMErrorFSReadOnly {
+		t.Errorf("запись в FSReadOnly: %v", err)
+	}
+	if _, err := (&Sandbox{FS: FSDenied}).Path("файл", false); err != VMErrorFSDenied {
+		t.Errorf("чтение в FSDenied: %v", err)
+	}
+	if p, err := (*Sandbox)(nil).Path("файл", true); err != nil || p != "файл" {
+		t.Errorf("без политики: %q, %v", p, err)
+	}
+}
+
+func TestBoltDBSandbox(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := VMSlice{VMString(filepath.Join(dir, "база.db"))}
+
+	env := NewEnv()
+	for _, c := range []struct {
+		sb  *Sandbox
+		err error
+	}{
+		{&Sandbox{FS: FSDenied}, VMErrorFSDenied},
+		{&Sandbox{FS: FSReadOnly}, VMErrorFSReadOnly},
+		{&Sandbox{Root: filepath.Join(dir, "корень")}, VMErrorFSOutsideRoot},
+	} {
+		env.SetSandbox(c.sb)
+		db := &VMBoltDB{}
+		var rets VMSlice
+		if err := db.Открыть(fn, &rets, &env); err != c.err {
+			t.Errorf("%+v: %v, ожидается %v", *c.sb, err, c.err)
+		}
+	}
+	// без окружения вызывающего кода доступ запрещен
+	if err := (&VMBoltDB{}).Открыть(fn, &VMSlice{}, nil); err != VMErrorFSDenied {
+		t.Errorf("без окружения: %v", err)
+	}
+	if _, err := os.Stat(fn[0].(VMString).String()); !os.IsNotExist(err) {
+		t.Errorf("файл базы создан в обход политики: %v", err)
+	}
+
+	env.SetSandbox(&Sandbox{Root: dir})
+	db := &VMBoltDB{}
+	if err := db.Открыть(VMSlice{VMString("база.db")}, &VMSlice{}, &env); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"net/http"
 	"runtime"
 	"sync"
+	"time"
 
 	uuid "github.com/satori/go.uuid"
 )
@@ -18,7 +20,7 @@ type VMServer struct {
 	VMMetaObj //должен передаваться по ссылке, поэтому это будет объект метаданных
 
 	mu       sync.RWMutex
-	addr     string // [addr]:port
+	addr     string // [addr]:port, после открытия - адрес, который слушает сервер
 	protocol string // tcp, tcpzip, tcptls, http, https
 	done     chan error
 	health   chan bool
@@ -30,6 +32,8 @@ type VMServer struct {
 }
 
 func (x *VMServer) String() string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
 	return fmt.Sprintf("Сервер %s %s", x.protocol, x.addr)
 }
 
@@ -63,8 +67,10 @@ func (x *VMServer) Open(proto, addr string, maxconn int, handler VMFunc, data VM
 	x.health = make(chan bool)
 	x.clients = make([]*VMConn, 0)
 
+	x.mu.Lock()
 	x.addr = addr
 	x.protocol = proto
+	x.mu.Unlock()
 	x.maxconn = maxconn
 
 	switch proto {
@@ -88,6 +94,10 @@ func (x *VMServer) Open(proto, addr string, maxconn int, handler VMFunc, data VM
 				gzipped = true
 			}
 		}
+		// для порта 0 запоминаем порт, выбранный системой
+		x.mu.Lock()
+		x.addr = x.lnr.Addr().String()
+		x.mu.Unlock()
 
 		go x.healthSender()
 
@@ -106,12 +116,11 @@ func (x *VMServer) Open(proto, addr string, maxconn int, handler VMFunc, data VM
 				if l < maxconn || maxconn == -1 {
 
 					vcn := &VMConn{
-						conn:   conn,
-						id:     l,
-						closed: false,
-						uid:    uuid.NewV4().String(),
-						data:   data,
-						gzip:   gzipped,
+						conn: conn,
+						id:   l,
+						uid:  uuid.NewV4().String(),
+						data: data,
+						gzip: gzipped,
 					}
 					x.clients = append(x.clients, vcn)
 					go vcn.Handle(handler, true)
@@ -138,7 +147,7 @@ func (x *VMServer) Open(proto, addr string, maxconn int, handler VMFunc, data VM
 					args[1] = req
 					var env *Env // сюда вернется окружение вызываемой функции
 					err := f(args, &rets, &env)
-					if err != nil && env.Valid {
+					if err != nil && env != nil && env.Valid {
 						env.Println(err)
 					}
 					req.Close()
@@ -149,9 +158,18 @@ func (x *VMServer) Open(proto, addr string, maxconn int, handler VMFunc, data VM
 			Addr:    addr,
 			Handler: x.mux,
 		}
+		// слушаем порт сразу, чтобы клиенты могли подключаться после возврата из Open
+		lnr, err := net.Listen("tcp", addr)
+		if err != nil {
+			x.srv = nil
+			return err
+		}
+		x.mu.Lock()
+		x.addr = lnr.Addr().String()
+		x.mu.Unlock()
 		go x.healthSender()
 		go func(s *http.Server) {
-			err := s.ListenAndServe()
+			err := s.Serve(lnr)
 			x.done <- err
 		}(x.srv)
 
@@ -161,6 +179,47 @@ func (x *VMServer) Open(proto, addr string, maxconn int, handler VMFunc, data VM
 	return nil
 }
 
+var (
+	serversMu sync.Mutex
+	servers   = make(map[*VMServer]context.Context) // открытые кодом серверы и контексты исполнения этого кода
+)
+
+// ShutdownServers закрывает серверы, открытые кодом, исполняемым с контекстом ctx (см. Env.SetContext).
+// Серверы http сначала перестают принимать соединения и до истечения timeout ожидают завершения обработки запросов
+func ShutdownServers(ctx context.Context, timeout time.Duration) {
+	serversMu.Lock()
+	var list []*VMServer
+	for x, c := range servers {
+		if c == ctx {
+			list = append(list, x)
+		}
+	}
+	serversMu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, x := range list {
+		wg.Add(1)
+		go func(x *VMServer) {
+			defer wg.Done()
+			x.Shutdown(timeout)
+		}(x)
+	}
+	wg.Wait()
+}
+
+// Shutdown закрывает сервер, для http - после завершения обработки текущих запросов, но не дольше timeout
+func (x *VMServer) Shutdown(timeout time.Duration) error {
+	x.mu.RLock()
+	srv := x.srv
+	x.mu.RUnlock()
+	if srv != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		srv.Shutdown(ctx)
+		cancel()
+	}
+	return x.Close()
+}
+
 // Close закрываем все ресурсы и всегда возвращаем ошибку,
 // которая могла возникнуть на сервере, либо во время закрытия
 // !!! Эту процедуру нужно обязательно вызывать по окончании работы с сервером !!!
@@ -184,12 +243,16 @@ func (x *VMServer) Close() error {
 	x.mux = nil
 	// закрываем все клиентские соединения
 	for i := range x.clients {
-		if !x.clients[i].closed {
+		if !x.clients[i].IsClosed() {
 			x.clients[i].Close()
 		}
 	}
 	x.clients = x.clients[:0]
 	x.mu.Unlock()
+
+	serversMu.Lock()
+	delete(servers, x)
+	serversMu.Unlock()
 	return err
 }
 
@@ -205,7 +268,7 @@ func (x *VMServer) CloseClient(i int) (err error) {
 	l := len(x.clients)
 	if i >= 0 && i < l {
 		err = nil
-		if !x.clients[i].closed {
+		if !x.clients[i].IsClosed() {
 			err = x.clients[i].Close()
 		}
 		return
@@ -219,7 +282,7 @@ func (x *VMServer) RemoveAllClosedClients() {
 	defer x.mu.Unlock()
 	l := len(x.clients)
 	for i := l - 1; i >= 0; i-- {
-		if x.clients[i].closed {
+		if x.clients[i].IsClosed() {
 			copy(x.clients[i:], x.clients[i+1:])
 			nl := len(x.clients) - 1
 			x.clients[nl].conn = nil
@@ -248,6 +311,7 @@ func (x *VMServer) VMRegister() {
 	x.VMRegisterMethod("Закрыть", x.Закрыть)
 	x.VMRegisterMethod("Работает", x.Работает)
 	x.VMRegisterMethod("Открыть", x.Открыть)
+	x.VMRegisterMethod("Адрес", x.Адрес)
 	// tst.VMRegisterField("ПолеСтрока", &tst.ПолеСтрока)
 }
 
@@ -262,6 +326,14 @@ func (x *VMServer) Работает(args VMSlice, rets *VMSlice, envout *(*Env))
 	return nil
 }
 
+// Адрес возвращает адрес, который слушает открытый сервер, в том числе порт, выбранный системой для адреса с портом 0
+func (x *VMServer) Адрес(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	rets.Append(VMString(x.addr))
+	return nil
+}
+
 func (x *VMServer) Открыть(args VMSlice, rets *VMSlice, envout *(*Env)) error {
 	if len(args) != 5 {
 		return VMErrorNeedArgs(5)
@@ -293,5 +365,14 @@ func (x *VMServer) Открыть(args VMSlice, rets *VMSlice, envout *(*Env)) e
 		}
 	}
 
-	return x.Open(string(p), string(adr), int(lim), f, args[4], vsm)
+	if err := x.Open(string(p), string(adr), int(lim), f, args[4], vsm); err != nil {
+		return err
+	}
+	// сервер запоминается вместе с контекстом исполнения открывшего его кода, см. ShutdownServers
+	if envout != nil && *envout != nil {
+		serversMu.Lock()
+		servers[x] = (*envout).Context()
+		serversMu.Unlock()
+	}
+	return nil
 }
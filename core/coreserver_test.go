@@ -0,0 +1,47 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// адрес можно читать, пока сервер открывается в другой горутине (проверяется go test -race)
+func TestServerAddressWhileOpening(t *testing.T) {
+	x := &VMServer{}
+	x.VMInit(x)
+	x.VMRegister()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			if i == 1 {
+				close(started)
+			}
+			select {
+			case <-stop:
+				return
+			default:
+				var rets VMSlice
+				x.Адрес(nil, &rets, nil)
+				_ = x.String()
+			}
+		}
+	}()
+	<-started
+	err := x.Open("tcp", "127.0.0.1:0", -1, nil, VMNil, nil)
+	close(stop)
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.Close()
+	var rets VMSlice
+	if err := x.Адрес(nil, &rets, nil); err != nil {
+		t.Fatal(err)
+	}
+	if a := string(rets[0].(VMString)); !strings.HasPrefix(a, "127.0.0.1:") || strings.HasSuffix(a, ":0") {
+		t.Errorf("Адрес() = %s", a)
+	}
+}
@@ -218,6 +218,24 @@ func (x *VMServiceBus) Deregister(svc VMServicer) error {
 	return nil
 }
 
+// DeregisterAll останавливает и удаляет из менеджера все сервисы
+func (x *VMServiceBus) DeregisterAll() error {
+	x.RLock()
+	list := make([]VMServicer, 0, len(x.services))
+	for _, svc := range x.services {
+		list = append(list, svc)
+	}
+	x.RUnlock()
+
+	var reterr error
+	for _, svc := range list {
+		if err := x.Deregister(svc); err != nil && reterr == nil {
+			reterr = err
+		}
+	}
+	return reterr
+}
+
 // WaitForAll ожидает завершения работы всех сервисов
 func (x *VMServiceBus) WaitForAll() {
 	if x.runned {
@@ -8,8 +8,6 @@ import (
 	"reflect"
 	"sort"
 	"sync"
-
-	"github.com/covrom/gonec/names"
 )
 
 const ChunkVMSlicePool = 64
@@ -91,11 +89,11 @@ func (x VMSlice) SortDefault() {
 	sort.Sort(VMSliceUpSort(x))
 }
 
-func (x VMSlice) MethodMember(name int) (VMFunc, bool) {
+func (x VMSlice) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
 
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "сортировать":
 		return VMFuncMustParams(0, x.Сортировать), true
 	case "сортироватьубыв":
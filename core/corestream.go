@@ -0,0 +1,265 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// VMReader - поток чтения (ПотокЧтения) из файла, тела HTTP-запроса или ответа, или из двоичных данных в памяти.
+// Создается как Новый ПотокЧтения(данные) или функцией ОткрытьФайл
+type VMReader struct {
+	VMMetaObj
+
+	r *bufio.Reader
+	c io.Closer // nil - закрывать источник не требуется
+}
+
+// NewVMReader создает поток чтения из r, c - закрывается при закрытии потока (может быть nil)
+func NewVMReader(r io.Reader, c io.Closer) *VMReader {
+	x := &VMReader{r: bufio.NewReader(r), c: c}
+	x.VMInit(x)
+	x.VMRegister()
+	return x
+}
+
+func (x *VMReader) VMRegister() {
+	x.VMRegisterMethod("Прочитать", x.Прочитать)
+	x.VMRegisterMethod("ПрочитатьВсе", x.ПрочитатьВсе)
+	x.VMRegisterMethod("ПрочитатьСтроку", x.ПрочитатьСтроку)
+	x.VMRegisterMethod("Закрыть", x.Закрыть)
+}
+
+// VMNew создает поток чтения из двоичных данных или строки в памяти
+func (x *VMReader) VMNew(args VMSlice) error {
+	if len(args) != 1 {
+		return VMErrorNeedArgs(1)
+	}
+	b, err := binaryArg(args[0])
+	if err != nil {
+		return err
+	}
+	x.r = bufio.NewReader(bytes.NewReader(b))
+	return nil
+}
+
+func (x *VMReader) String() string {
+	return "ПотокЧтения"
+}
+
+// Read реализует io.Reader
+func (x *VMReader) Read(p []byte) (int, error) {
+	if x.r == nil {
+		return 0, VMErrorStreamClosed
+	}
+	return x.r.Read(p)
+}
+
+// Close закрывает источник потока
+func (x *VMReader) Close() error {
+	x.r = nil
+	if x.c != nil {
+		c := x.c
+		x.c = nil
+		return c.Close()
+	}
+	return nil
+}
+
+// Прочитать читает не более указанного количества байт, в конце потока возвращаются пустые данные
+func (x *VMReader) Прочитать(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 1 {
+		return VMErrorNeedArgs(1)
+	}
+	n, ok := args[0].(VMInt)
+	if !ok || n < 0 {
+		return VMErrorNeedInt
+	}
+	b := make([]byte, int(n))
+	l, err := io.ReadFull(x, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	rets.Append(VMBinaryData(b[:l]))
+	return nil
+}
+
+// ПрочитатьВсе читает данные до конца потока
+func (x *VMReader) ПрочитатьВсе(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	b, err := ioutil.ReadAll(x)
+	if err != nil {
+		return err
+	}
+	rets.Append(VMBinaryData(b))
+	return nil
+}
+
+// ПрочитатьСтроку возвращает очередную строку без символа перевода строки
+// и признак того, что строка прочитана (Ложь - достигнут конец потока)
+func (x *VMReader) ПрочитатьСтроку(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	if x.r == nil {
+		return VMErrorStreamClosed
+	}
+	s, err := x.r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	ok := err == nil || len(s) > 0
+	s = trimEOL(s)
+	rets.Append(VMString(s), VMBool(ok))
+	return nil
+}
+
+func (x *VMReader) Закрыть(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	return x.Close()
+}
+
+// VMWriter - поток записи (ПотокЗаписи) в файл, в ответ HTTP-сервера или в память.
+// Новый ПотокЗаписи пишет в память, записанное возвращает метод Содержимое
+type VMWriter struct {
+	VMMetaObj
+
+	w   io.Writer
+	c   io.Closer     // nil - закрывать приемник не требуется
+	buf *bytes.Buffer // приемник потока в памяти
+}
+
+// NewVMWriter создает поток записи в w, c - закрывается при закрытии потока (может быть nil)
+func NewVMWriter(w io.Writer, c io.Closer) *VMWriter {
+	x := &VMWriter{w: w, c: c}
+	x.VMInit(x)
+	x.VMRegister()
+	return x
+}
+
+func (x *VMWriter) VMRegister() {
+	if x.w == nil {
+		x.buf = new(bytes.Buffer)
+		x.w = x.buf
+	}
+	x.VMRegisterMethod("Записать", x.Записать)
+	x.VMRegisterMethod("Содержимое", x.Содержимое)
+	x.VMRegisterMethod("Закрыть", x.Закрыть)
+}
+
+func (x *VMWriter) String() string {
+	return "ПотокЗаписи"
+}
+
+// Write реализует io.Writer
+func (x *VMWriter) Write(p []byte) (int, error) {
+	if x.w == nil {
+		return 0, VMErrorStreamClosed
+	}
+	return x.w.Write(p)
+}
+
+// Close закрывает приемник потока, записанное в память остается доступным
+func (x *VMWriter) Close() error {
+	x.w = nil
+	if x.c != nil {
+		c := x.c
+		x.c = nil
+		return c.Close()
+	}
+	return nil
+}
+
+// Записать записывает двоичные данные, строку или все содержимое потока чтения
+func (x *VMWriter) Записать(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 1 {
+		return VMErrorNeedArgs(1)
+	}
+	if r, ok := args[0].(*VMReader); ok {
+		_, err := io.Copy(x, r)
+		return err
+	}
+	b, err := binaryArg(args[0])
+	if err != nil {
+		return err
+	}
+	_, err = x.Write(b)
+	return err
+}
+
+// Содержимое возвращает двоичные данные, записанные в поток в памяти
+func (x *VMWriter) Содержимое(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	if x.buf == nil {
+		return errors.New("Поток записывает данные не в память")
+	}
+	rets.Append(append(VMBinaryData{}, x.buf.Bytes()...))
+	return nil
+}
+
+func (x *VMWriter) Закрыть(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	return x.Close()
+}
+
+// binaryArg возвращает байты двоичных данных или строки
+func binaryArg(v VMValuer) ([]byte, error) {
+	switch vv := v.(type) {
+	case VMBinaryData:
+		return vv, nil
+	case VMString:
+		return []byte(vv), nil
+	}
+	return nil, VMErrorNeedBinaryData
+}
+
+// readerArg возвращает источник данных - строку, двоичные данные или поток чтения
+func readerArg(v VMValuer) (io.Reader, error) {
+	switch vv := v.(type) {
+	case VMString:
+		return strings.NewReader(string(vv)), nil
+	case VMBinaryData:
+		return bytes.NewReader(vv), nil
+	case *VMReader:
+		return vv, nil
+	}
+	return nil, VMErrorNeedBinaryData
+}
+
+func trimEOL(s string) string {
+	if len(s) > 0 && s[len(s)-1] == '\n' {
+		s = s[:len(s)-1]
+		if len(s) > 0 && s[len(s)-1] == '\r' {
+			s = s[:len(s)-1]
+		}
+	}
+	return s
+}
+
+// OpenVMReader открывает файл для чтения
+func OpenVMReader(fn string) (*VMReader, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	return NewVMReader(f, f), nil
+}
+
+// CreateVMWriter создает файл (или перезаписывает существующий) для записи, app - дописывать в конец файла
+func CreateVMWriter(fn string, app bool) (*VMWriter, error) {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if app {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(fn, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewVMWriter(f, f), nil
+}
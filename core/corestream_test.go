@@ -0,0 +1,102 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+// errCloser возвращает ошибку при закрытии приемника или источника потока
+type errCloser struct{ err error }
+
+func (c errCloser) Close() error { return c.err }
+
+func TestStreamErrors(t *testing.T) {
+	var rets VMSlice
+	for _, args := range []VMSlice{{}, {VMInt(1)}, {VMString("а"), VMString("б")}} {
+		if err := (&VMReader{}).VMNew(args); err == nil {
+			t.Errorf("Новый ПотокЧтения%v без ошибки", args)
+		}
+	}
+
+	r := NewVMReader(nil, nil)
+	if err := r.VMNew(VMSlice{VMString("строка\n")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		m    VMMethod
+		args VMSlice
+		want error
+	}{
+		{"Прочитать", r.Прочитать, VMSlice{}, VMErrorNeedArgs(1)},
+		{"Прочитать", r.Прочитать, VMSlice{VMInt(-1)}, VMErrorNeedInt},
+		{"Прочитать", r.Прочитать, VMSlice{VMString("1")}, VMErrorNeedInt},
+		{"ПрочитатьВсе", r.ПрочитатьВсе, VMSlice{VMInt(1)}, VMErrorNoNeedArgs},
+		{"ПрочитатьСтроку", r.ПрочитатьСтроку, VMSlice{VMInt(1)}, VMErrorNoNeedArgs},
+	} {
+		if err := c.m(c.args, &rets, nil); err == nil || err.Error() != c.want.Error() {
+			t.Errorf("%s%v: %v, ожидается %v", c.name, c.args, err, c.want)
+		}
+	}
+	// закрытый поток не читается
+	if err := r.Закрыть(nil, &rets, nil); err != nil {
+		t.Fatal(err)
+	}
+	for name, m := range map[string]VMMethod{
+		"Прочитать": func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+			return r.Прочитать(VMSlice{VMInt(1)}, rets, envout)
+		},
+		"ПрочитатьВсе":    r.ПрочитатьВсе,
+		"ПрочитатьСтроку": r.ПрочитатьСтроку,
+	} {
+		if err := m(nil, &rets, nil); err != VMErrorStreamClosed {
+			t.Errorf("%s после закрытия: %v", name, err)
+		}
+	}
+	// ошибка закрытия источника возвращается один раз
+	closeErr := errors.New("ошибка закрытия")
+	r = NewVMReader(nil, errCloser{closeErr})
+	if err := r.Закрыть(nil, &rets, nil); err != closeErr {
+		t.Errorf("закрытие источника: %v", err)
+	}
+	if err := r.Закрыть(nil, &rets, nil); err != nil {
+		t.Errorf("повторное закрытие: %v", err)
+	}
+
+	w := &VMWriter{}
+	w.VMInit(w)
+	w.VMRegister()
+	for _, c := range []struct {
+		name string
+		m    VMMethod
+		args VMSlice
+		want error
+	}{
+		{"Записать", w.Записать, VMSlice{}, VMErrorNeedArgs(1)},
+		{"Записать", w.Записать, VMSlice{VMInt(1)}, VMErrorNeedBinaryData},
+		{"Записать", w.Записать, VMSlice{&VMReader{}}, VMErrorStreamClosed},
+		{"Содержимое", w.Содержимое, VMSlice{VMInt(1)}, VMErrorNoNeedArgs},
+	} {
+		if err := c.m(c.args, &rets, nil); err == nil || err.Error() != c.want.Error() {
+			t.Errorf("%s%v: %v, ожидается %v", c.name, c.args, err, c.want)
+		}
+	}
+	w.Закрыть(nil, &rets, nil)
+	if err := w.Записать(VMSlice{VMString("а")}, &rets, nil); err != VMErrorStreamClosed {
+		t.Errorf("запись после закрытия: %v", err)
+	}
+	// поток в файл или ответ сервера не хранит записанное
+	if err := NewVMWriter(errWriter{}, nil).Содержимое(nil, &rets, nil); err == nil {
+		t.Error("Содержимое потока не в память без ошибки")
+	}
+	if err := NewVMWriter(errWriter{}, nil).Записать(VMSlice{VMString("а")}, &rets, nil); err != errWrite {
+		t.Errorf("ошибка приемника: %v", err)
+	}
+}
+
+var errWrite = errors.New("ошибка записи")
+
+// errWriter - приемник, запись в который всегда завершается ошибкой
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) { return 0, errWrite }
@@ -0,0 +1,442 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Строковые функции в духе 1С: длины и позиции считаются в символах, а не в байтах,
+// номера символов и строк начинаются с 1
+
+// strMaxRepeat - наибольшая длина в байтах строки, которую возвращает СтрПовторить
+const strMaxRepeat = 1 << 30
+
+// strArg возвращает строковое представление значения
+func strArg(v VMValuer) (string, error) {
+	if s, ok := v.(VMStringer); ok {
+		return s.String(), nil
+	}
+	return "", VMErrorNeedString
+}
+
+// intArg возвращает целое из ЦелоеЧисло или Число без дробной части
+func intArg(v VMValuer) (int, error) {
+	switch n := v.(type) {
+	case VMInt:
+		return int(n), nil
+	case VMDecNum:
+		if NewVMDecNumFromInt64(n.Int()).Equal(n) {
+			return int(n.Int()), nil
+		}
+	}
+	return 0, VMErrorNeedInt
+}
+
+// runeSub возвращает n символов строки, начиная с символа from (с 0)
+func runeSub(s string, from, n int) string {
+	if from < 0 {
+		from = 0
+	}
+	if n <= 0 {
+		return ""
+	}
+	i := 0
+	for from > 0 && i < len(s) {
+		_, sz := utf8.DecodeRuneInString(s[i:])
+		i += sz
+		from--
+	}
+	j := i
+	for n > 0 && j < len(s) {
+		_, sz := utf8.DecodeRuneInString(s[j:])
+		j += sz
+		n--
+	}
+	return s[i:j]
+}
+
+// StrSplit разделяет строку по любому из символов seps, как СтрРазделить в 1С
+func StrSplit(s, seps string, withEmpty bool) VMSlice {
+	var parts []string
+	if seps == "" {
+		parts = []string{s}
+	} else {
+		start := 0
+		for i, r := range s {
+			if strings.ContainsRune(seps, r) {
+				parts = append(parts, s[start:i])
+				start = i + utf8.RuneLen(r)
+			}
+		}
+		parts = append(parts, s[start:])
+	}
+	res := make(VMSlice, 0, len(parts))
+	for _, p := range parts {
+		if withEmpty || p != "" {
+			res = append(res, VMString(p))
+		}
+	}
+	return res
+}
+
+// StrTemplate подставляет параметры вместо %1-%9 и %(N), %% заменяется на %
+func StrTemplate(tpl string, params []string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(tpl); i++ {
+		c := tpl[i]
+		if c != '%' || i+1 == len(tpl) {
+			b.WriteByte(c)
+			continue
+		}
+		n := -1
+		switch next := tpl[i+1]; {
+		case next == '%':
+			b.WriteByte('%')
+			i++
+			continue
+		case next >= '1' && next <= '9':
+			n = int(next - '0')
+			i++
+		case next == '(':
+			end := strings.IndexByte(tpl[i:], ')')
+			if end < 0 {
+				return "", VMErrorStrTemplate
+			}
+			var err error
+			if n, err = strconv.Atoi(tpl[i+2 : i+end]); err != nil || n < 1 {
+				return "", VMErrorStrTemplate
+			}
+			i += end
+		default:
+			b.WriteByte(c)
+			continue
+		}
+		if n > len(params) {
+			return "", VMErrorStrTemplate
+		}
+		b.WriteString(params[n-1])
+	}
+	return b.String(), nil
+}
+
+// TitleCase переводит первую букву каждого слова в верхний регистр, остальные - в нижний (ТРег)
+func TitleCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	inWord := false
+	for _, r := range s {
+		isLetter := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isLetter && !inWord:
+			b.WriteRune(unicode.ToUpper(r))
+		case isLetter:
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+		inWord = isLetter
+	}
+	return b.String()
+}
+
+// lines разбивает текст на строки по \n, \r перед \n отбрасывается
+func lines(s string) []string {
+	ls := strings.Split(s, "\n")
+	for i := range ls {
+		ls[i] = strings.TrimSuffix(ls[i], "\r")
+	}
+	return ls
+}
+
+// importStrings регистрирует строковые функции 1С
+func importStrings(env *Env) {
+
+	env.DefineS("стрдлина", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, err := strArg(args[0])
+		if err != nil {
+			return err
+		}
+		rets.Append(VMInt(utf8.RuneCountInString(s)))
+		return nil
+	}))
+
+	for name, trim := range map[string]func(string) string{
+		"сокрлп": strings.TrimSpace,
+		"сокрл": func(s string) string {
+			return strings.TrimLeftFunc(s, unicode.IsSpace)
+		},
+		"сокрп": func(s string) string {
+			return strings.TrimRightFunc(s, unicode.IsSpace)
+		},
+	} {
+		trim := trim
+		env.DefineS(name, VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+			*envout = env
+			s, err := strArg(args[0])
+			if err != nil {
+				return err
+			}
+			rets.Append(VMString(trim(s)))
+			return nil
+		}))
+	}
+
+	env.DefineS("лев", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, err := strArg(args[0])
+		if err != nil {
+			return err
+		}
+		n, err := intArg(args[1])
+		if err != nil {
+			return err
+		}
+		rets.Append(VMString(runeSub(s, 0, n)))
+		return nil
+	}))
+
+	env.DefineS("прав", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, err := strArg(args[0])
+		if err != nil {
+			return err
+		}
+		n, err := intArg(args[1])
+		if err != nil {
+			return err
+		}
+		rets.Append(VMString(runeSub(s, utf8.RuneCountInString(s)-n, n)))
+		return nil
+	}))
+
+	env.DefineS("сред", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// количество символов необязательно, по умолчанию - до конца строки
+		if len(args) != 2 && len(args) != 3 {
+			return VMErrorNeedArgs(3)
+		}
+		s, err := strArg(args[0])
+		if err != nil {
+			return err
+		}
+		from, err := intArg(args[1])
+		if err != nil {
+			return err
+		}
+		if from < 1 {
+			from = 1
+		}
+		n := len(s)
+		if len(args) == 3 {
+			if n, err = intArg(args[2]); err != nil {
+				return err
+			}
+		}
+		rets.Append(VMString(runeSub(s, from-1, n)))
+		return nil
+	}))
+
+	env.DefineS("стрначинаетсяс", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, err1 := strArg(args[0])
+		p, err2 := strArg(args[1])
+		if err1 != nil || err2 != nil {
+			return VMErrorNeedString
+		}
+		if p == "" {
+			return VMErrorNeedLength
+		}
+		rets.Append(VMBool(strings.HasPrefix(s, p)))
+		return nil
+	}))
+
+	env.DefineS("стрзаканчиваетсяна", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, err1 := strArg(args[0])
+		p, err2 := strArg(args[1])
+		if err1 != nil || err2 != nil {
+			return VMErrorNeedString
+		}
+		if p == "" {
+			return VMErrorNeedLength
+		}
+		rets.Append(VMBool(strings.HasSuffix(s, p)))
+		return nil
+	}))
+
+	env.DefineS("стрразделить", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// включать пустые строки необязательно, по умолчанию Истина
+		if len(args) != 2 && len(args) != 3 {
+			return VMErrorNeedArgs(3)
+		}
+		s, err1 := strArg(args[0])
+		seps, err2 := strArg(args[1])
+		if err1 != nil || err2 != nil {
+			return VMErrorNeedString
+		}
+		withEmpty := VMBool(true)
+		if len(args) == 3 {
+			var ok bool
+			if withEmpty, ok = args[2].(VMBool); !ok {
+				return VMErrorNeedBool
+			}
+		}
+		rets.Append(StrSplit(s, seps, bool(withEmpty)))
+		return nil
+	}))
+
+	env.DefineS("стрсоединить", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// разделитель необязателен
+		if len(args) != 1 && len(args) != 2 {
+			return VMErrorNeedArgs(2)
+		}
+		sl, ok := args[0].(VMSlice)
+		if !ok {
+			return VMErrorNeedSlice
+		}
+		var sep string
+		if len(args) == 2 {
+			var err error
+			if sep, err = strArg(args[1]); err != nil {
+				return err
+			}
+		}
+		ss := make([]string, len(sl))
+		for i, v := range sl {
+			var err error
+			if ss[i], err = strArg(v); err != nil {
+				return err
+			}
+		}
+		rets.Append(VMString(strings.Join(ss, sep)))
+		return nil
+	}))
+
+	env.DefineS("стршаблон", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		if len(args) == 0 {
+			return VMErrorNeedArgs(1)
+		}
+		tpl, err := strArg(args[0])
+		if err != nil {
+			return err
+		}
+		params := make([]string, len(args)-1)
+		for i, v := range args[1:] {
+			switch v.(type) {
+			case nil, VMNilType, VMNullType:
+				// Неопределено подставляется пустой строкой
+			default:
+				if params[i], err = strArg(v); err != nil {
+					return err
+				}
+			}
+		}
+		s, err := StrTemplate(tpl, params)
+		if err != nil {
+			return err
+		}
+		rets.Append(VMString(s))
+		return nil
+	}))
+
+	env.DefineS("стрповторить", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, err := strArg(args[0])
+		if err != nil {
+			return err
+		}
+		n, err := intArg(args[1])
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return VMErrorNeedLength
+		}
+		if len(s) > 0 && n > strMaxRepeat/len(s) {
+			return VMErrorMathOverflow
+		}
+		rets.Append(VMString(strings.Repeat(s, n)))
+		return nil
+	}))
+
+	env.DefineS("символ", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		c, err := intArg(args[0])
+		if err != nil {
+			return err
+		}
+		if c < 0 || c > unicode.MaxRune || !utf8.ValidRune(rune(c)) {
+			return VMErrorIncorrectRune
+		}
+		rets.Append(VMString(string(rune(c))))
+		return nil
+	}))
+
+	env.DefineS("трег", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, err := strArg(args[0])
+		if err != nil {
+			return err
+		}
+		rets.Append(VMString(TitleCase(s)))
+		return nil
+	}))
+
+	env.DefineS("стрчислострок", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, err := strArg(args[0])
+		if err != nil {
+			return err
+		}
+		rets.Append(VMInt(strings.Count(s, "\n") + 1))
+		return nil
+	}))
+
+	env.DefineS("стрполучитьстроку", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, err := strArg(args[0])
+		if err != nil {
+			return err
+		}
+		n, err := intArg(args[1])
+		if err != nil {
+			return err
+		}
+		// строки с несуществующими номерами пустые
+		ls := lines(s)
+		if n < 1 || n > len(ls) {
+			rets.Append(VMString(""))
+			return nil
+		}
+		rets.Append(VMString(ls[n-1]))
+		return nil
+	}))
+
+	env.DefineS("пустаястрока", VMFuncMustParams(1, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s, err := strArg(args[0])
+		if err != nil {
+			return err
+		}
+		rets.Append(VMBool(strings.TrimSpace(s) == ""))
+		return nil
+	}))
+
+	env.DefineS("стрсравнить", VMFuncMustParams(2, func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		s1, err1 := strArg(args[0])
+		s2, err2 := strArg(args[1])
+		if err1 != nil || err2 != nil {
+			return VMErrorNeedString
+		}
+		// сравнение без учета регистра, результат -1, 0 или 1
+		rets.Append(VMInt(strings.Compare(strings.ToLower(s1), strings.ToLower(s2))))
+		return nil
+	}))
+}
@@ -0,0 +1,53 @@
+package core
+
+import "testing"
+
+func TestStrFuncErrors(t *testing.T) {
+	env := NewEnv()
+	LoadAllBuiltins(env)
+	half := VMDecNum{num: mustDec("1.5")}
+	checkBuiltinErrors(t, env, []builtinErrCase{
+		{"СтрДлина", VMSlice{}, VMErrorNeedArgs(1).Error()},
+		{"СтрДлина", VMSlice{nil}, VMErrorNeedString.Error()},
+		{"СокрЛП", VMSlice{nil}, VMErrorNeedString.Error()},
+		{"Лев", VMSlice{VMString("абв"), VMString("2")}, VMErrorNeedInt.Error()},
+		{"Лев", VMSlice{VMString("абв"), half}, VMErrorNeedInt.Error()},
+		{"Прав", VMSlice{VMString("абв")}, VMErrorNeedArgs(2).Error()},
+		{"Сред", VMSlice{VMString("абв")}, VMErrorNeedArgs(3).Error()},
+		{"Сред", VMSlice{VMString("абв"), VMBool(true)}, VMErrorNeedInt.Error()},
+		{"Сред", VMSlice{VMString("абв"), VMInt(1), half}, VMErrorNeedInt.Error()},
+		{"СтрНачинаетсяС", VMSlice{VMString("абв"), VMString("")}, VMErrorNeedLength.Error()},
+		{"СтрНачинаетсяС", VMSlice{nil, VMString("а")}, VMErrorNeedString.Error()},
+		{"СтрЗаканчиваетсяНа", VMSlice{VMString("абв"), VMString("")}, VMErrorNeedLength.Error()},
+		{"СтрРазделить", VMSlice{VMString("а,б")}, VMErrorNeedArgs(3).Error()},
+		{"СтрРазделить", VMSlice{VMString("а,б"), VMString(","), VMString("Да")}, VMErrorNeedBool.Error()},
+		{"СтрСоединить", VMSlice{VMString("а,б")}, VMErrorNeedSlice.Error()},
+		{"СтрСоединить", VMSlice{VMSlice{VMString("а"), nil}, VMString(",")}, VMErrorNeedString.Error()},
+		{"СтрСоединить", VMSlice{}, VMErrorNeedArgs(2).Error()},
+		{"СтрШаблон", VMSlice{}, VMErrorNeedArgs(1).Error()},
+		{"СтрШаблон", VMSlice{VMString("%2"), VMString("а")}, VMErrorStrTemplate.Error()},
+		{"СтрШаблон", VMSlice{VMString("%(10)")}, VMErrorStrTemplate.Error()},
+		{"СтрШаблон", VMSlice{VMString("%(1")}, VMErrorStrTemplate.Error()},
+		{"СтрШаблон", VMSlice{VMString("%()")}, VMErrorStrTemplate.Error()},
+		{"СтрШаблон", VMSlice{VMString("%(0)")}, VMErrorStrTemplate.Error()},
+		{"СтрШаблон", VMSlice{VMString("%(-1)")}, VMErrorStrTemplate.Error()},
+		{"СтрШаблон", VMSlice{VMString("%(99999999999999999999)")}, VMErrorStrTemplate.Error()},
+		{"СтрПовторить", VMSlice{VMString("а"), VMInt(-1)}, VMErrorNeedLength.Error()},
+		{"СтрПовторить", VMSlice{VMString("ab"), VMInt(1 << 62)}, VMErrorMathOverflow.Error()},
+		{"СтрПовторить", VMSlice{VMString("а"), VMString("3")}, VMErrorNeedInt.Error()},
+		{"Символ", VMSlice{VMInt(-1)}, VMErrorIncorrectRune.Error()},
+		{"Символ", VMSlice{VMInt(0xD800)}, VMErrorIncorrectRune.Error()},
+		{"Символ", VMSlice{VMInt(0x110000)}, VMErrorIncorrectRune.Error()},
+		{"Символ", VMSlice{VMString("а")}, VMErrorNeedInt.Error()},
+		{"ТРег", VMSlice{nil}, VMErrorNeedString.Error()},
+		{"СтрЧислоСтрок", VMSlice{nil}, VMErrorNeedString.Error()},
+		{"СтрПолучитьСтроку", VMSlice{VMString("а\nб"), VMString("1")}, VMErrorNeedInt.Error()},
+		{"ПустаяСтрока", VMSlice{}, VMErrorNeedArgs(1).Error()},
+		{"СтрСравнить", VMSlice{VMString("а"), nil}, VMErrorNeedString.Error()},
+	})
+
+	// пустая строка повторяется любое число раз
+	if rets, err := callBuiltin(env, "СтрПовторить", VMString(""), VMInt(1<<62)); err != nil || rets[0] != VMString("") {
+		t.Errorf("СтрПовторить(\"\", 1<<62) = %v, %v", rets, err)
+	}
+}
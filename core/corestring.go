@@ -151,8 +151,8 @@ func (x VMString) Slice() VMSlice {
 }
 
 func (x VMString) StringMap() VMStringMap {
-	var rm VMStringMap
-	if err := json.Unmarshal([]byte(x), rm); err != nil {
+	rm, err := VMStringMapFromJson(string(x))
+	if err != nil {
 		panic(err)
 	}
 	return rm
@@ -254,6 +254,8 @@ func (x VMString) ConvertToType(nt reflect.Type) (VMValuer, error) {
 		return VMSliceFromJson(string(x))
 	case ReflectVMStringMap:
 		return VMStringMapFromJson(string(x))
+	case ReflectVMBinaryData:
+		return VMBinaryData(x), nil
 	}
 
 	// попробуем десериализировать структуру из json
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// VMSyncMap - синхронная структура для совместного использования несколькими горутинами.
+// В отличие от Структуры, может одновременно изменяться из разных горутин, например, из обработчиков сервера
+type VMSyncMap struct {
+	m sync.Map
+}
+
+var ReflectVMSyncMap = reflect.TypeOf(VMSyncMap{})
+
+func (x *VMSyncMap) vmval() {}
+
+func (x *VMSyncMap) Interface() interface{} {
+	return x
+}
+
+func (x *VMSyncMap) String() string {
+	b, err := json.Marshal(x.StringMap())
+	if err != nil {
+		return "Синхронная структура"
+	}
+	return string(b)
+}
+
+// Load возвращает значение по ключу
+func (x *VMSyncMap) Load(k string) (VMValuer, bool) {
+	v, ok := x.m.Load(k)
+	if !ok {
+		return VMNil, false
+	}
+	return v.(VMValuer), true
+}
+
+// Store устанавливает значение по ключу
+func (x *VMSyncMap) Store(k string, v VMValuer) {
+	x.m.Store(k, v)
+}
+
+// LoadOrStore возвращает существующее значение, либо устанавливает и возвращает переданное
+func (x *VMSyncMap) LoadOrStore(k string, v VMValuer) (VMValuer, bool) {
+	rv, loaded := x.m.LoadOrStore(k, v)
+	return rv.(VMValuer), loaded
+}
+
+func (x *VMSyncMap) Delete(k string) {
+	x.m.Delete(k)
+}
+
+// StringMap возвращает копию содержимого в виде Структуры
+func (x *VMSyncMap) StringMap() VMStringMap {
+	rv := make(VMStringMap)
+	x.m.Range(func(k, v interface{}) bool {
+		rv[k.(string)] = v.(VMValuer)
+		return true
+	})
+	return rv
+}
+
+func (x *VMSyncMap) Length() VMInt {
+	n := 0
+	x.m.Range(func(k, v interface{}) bool {
+		n++
+		return true
+	})
+	return VMInt(n)
+}
+
+func (x *VMSyncMap) MethodMember(name string) (VMFunc, bool) {
+
+	// только эти методы будут доступны из кода на языке Гонец!
+	switch name {
+	case "получить":
+		return VMFuncMustParams(1, x.Получить), true
+	case "установить":
+		return VMFuncMustParams(2, x.Установить), true
+	case "получитьилиустановить":
+		return VMFuncMustParams(2, x.ПолучитьИлиУстановить), true
+	case "удалить":
+		return VMFuncMustParams(1, x.Удалить), true
+	case "ключи":
+		return VMFuncMustParams(0, x.Ключи), true
+	case "количество":
+		return VMFuncMustParams(0, x.Количество), true
+	case "структура":
+		return VMFuncMustParams(0, x.Структура), true
+	}
+	return nil, false
+}
+
+// Получить возвращает значение по ключу и признак его наличия
+func (x *VMSyncMap) Получить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	k, ok := args[0].(VMString)
+	if !ok {
+		return VMErrorNeedString
+	}
+	v, ok := x.Load(string(k))
+	rets.Append(v)
+	rets.Append(VMBool(ok))
+	return nil
+}
+
+func (x *VMSyncMap) Установить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	k, ok := args[0].(VMString)
+	if !ok {
+		return VMErrorNeedString
+	}
+	x.Store(string(k), args[1])
+	return nil
+}
+
+// ПолучитьИлиУстановить атомарно возвращает существующее значение,
+// либо устанавливает переданное, второе возвращаемое значение - Истина, если значение уже было
+func (x *VMSyncMap) ПолучитьИлиУстановить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	k, ok := args[0].(VMString)
+	if !ok {
+		return VMErrorNeedString
+	}
+	v, loaded := x.LoadOrStore(string(k), args[1])
+	rets.Append(v)
+	rets.Append(VMBool(loaded))
+	return nil
+}
+
+func (x *VMSyncMap) Удалить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	k, ok := args[0].(VMString)
+	if !ok {
+		return VMErrorNeedString
+	}
+	x.Delete(string(k))
+	return nil
+}
+
+// Ключи возвращаются отсортированными по возрастанию
+func (x *VMSyncMap) Ключи(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rv := make(VMSlice, 0)
+	x.m.Range(func(k, v interface{}) bool {
+		rv = append(rv, VMString(k.(string)))
+		return true
+	})
+	rv.SortDefault()
+	rets.Append(rv)
+	return nil
+}
+
+func (x *VMSyncMap) Количество(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rets.Append(x.Length())
+	return nil
+}
+
+// Структура возвращает копию содержимого, которую можно изменять без синхронизации
+func (x *VMSyncMap) Структура(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rets.Append(x.StringMap())
+	return nil
+}
@@ -0,0 +1,387 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// templateCacheSize - количество скомпилированных шаблонов, которые хранятся в кэше
+const templateCacheSize = 256
+
+// в кэше хранятся шаблоны, которые не исполнялись, исполняются их копии
+var templateCache = struct {
+	sync.Mutex
+	m map[string]interface{}
+}{m: make(map[string]interface{})}
+
+// templateHelper - сигнатура, через которую шаблон вызывает функции на языке Гонец
+type templateHelper = func(args ...interface{}) (interface{}, error)
+
+// templateFunc оборачивает функцию на языке Гонец для вызова из шаблона.
+// Функция получает в envout окружение кода, исполняющего шаблон, и подчиняется его контексту и политике ограничений
+func templateFunc(f VMFunc, env *Env) templateHelper {
+	return func(args ...interface{}) (interface{}, error) {
+		vargs := make(VMSlice, len(args))
+		for i, a := range args {
+			v, err := GoToVMValue(reflect.ValueOf(a))
+			if err != nil {
+				return nil, err
+			}
+			vargs[i] = v
+		}
+		var rets VMSlice
+		envout := env
+		if err := f(vargs, &rets, &envout); err != nil {
+			return nil, err
+		}
+		if len(rets) == 0 {
+			return "", nil
+		}
+		return templateValue(rets[0], env), nil
+	}
+}
+
+// templateValue преобразует данные для шаблона. Числа типа Число остаются как есть, чтобы выводиться без потери точности.
+// Функции в данных вызываются в окружении env
+func templateValue(v VMValuer, env *Env) interface{} {
+	switch x := v.(type) {
+	case nil, VMNilType, VMNullType:
+		return nil
+	case VMString:
+		return string(x)
+	case VMInt:
+		return int64(x)
+	case VMBool:
+		return bool(x)
+	case VMSlice:
+		sl := make([]interface{}, len(x))
+		for i := range x {
+			sl[i] = templateValue(x[i], env)
+		}
+		return sl
+	case VMStringMap:
+		m := make(map[string]interface{}, len(x))
+		for k := range x {
+			m[k] = templateValue(x[k], env)
+		}
+		return m
+	case VMFunc:
+		return templateFunc(x, env)
+	}
+	return v
+}
+
+var templateErrorRe = regexp.MustCompile(`^(?:html/)?template: ?([^:]*):(\d+):(?:\d+:)? ?`)
+
+// templateError переводит начало сообщения об ошибке шаблона, сохраняя имя шаблона и номер строки
+func templateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	s := err.Error()
+	if m := templateErrorRe.FindStringSubmatchIndex(s); m != nil {
+		return errors.New("Ошибка в шаблоне " + s[m[2]:m[3]] + ", строка " + s[m[4]:m[5]] + ": " + s[m[1]:])
+	}
+	return errors.New("Ошибка в шаблоне: " + s)
+}
+
+// VMTemplate - шаблон HTML или текста (Шаблон) на основе html/template и text/template:
+// циклы {{range}}, условия {{if}}, вложенные шаблоны {{template}} и макеты {{block}}/{{define}}.
+// Создается как Новый Шаблон(текст, параметры) или функцией ШаблонИзФайла
+type VMTemplate struct {
+	VMMetaObj
+
+	// шаблоны не исполняются, исполняются их копии с функциями, привязанными к окружению вызывающего кода
+	ht    *htmltemplate.Template // шаблон HTML с автоматическим экранированием
+	tt    *texttemplate.Template // текстовый шаблон без экранирования
+	funcs map[string]VMFunc
+}
+
+func (x *VMTemplate) VMRegister() {
+	x.VMRegisterMethod("Добавить", x.Добавить)
+	x.VMRegisterMethod("ДобавитьФайл", x.ДобавитьФайл)
+	x.VMRegisterMethod("Выполнить", x.Выполнить)
+}
+
+// templateOptions - параметры шаблона: Функции (структура функций-помощников) и ЭкранироватьHTML (по умолчанию Истина)
+func templateOptions(v VMValuer) (map[string]VMFunc, bool, error) {
+	funcs := map[string]VMFunc{}
+	if v == nil {
+		return funcs, true, nil
+	}
+	opts, ok := v.(VMStringMap)
+	if !ok {
+		return nil, false, VMErrorNeedMap
+	}
+	if err := checkOptions(opts, "Функции", "ЭкранироватьHTML"); err != nil {
+		return nil, false, err
+	}
+	html := true
+	if eh, ok := opts["ЭкранироватьHTML"]; ok {
+		b, ok := eh.(VMBool)
+		if !ok {
+			return nil, false, VMErrorNeedBool
+		}
+		html = bool(b)
+	}
+	if fv, ok := opts["Функции"]; ok {
+		fm, ok := fv.(VMStringMap)
+		if !ok {
+			return nil, false, errors.New("Функции шаблона задаются структурой")
+		}
+		for name, f := range fm {
+			ff, ok := f.(VMFunc)
+			if !ok {
+				return nil, false, errors.New("Значение " + name + " в функциях шаблона не является функцией")
+			}
+			funcs[name] = ff
+		}
+	}
+	return funcs, html, nil
+}
+
+// newTemplate компилирует шаблон name из text или берет ранее скомпилированный из кэша, key - ключ кэша (пусто - не кэшировать)
+func newTemplate(name, text, key string, funcs map[string]VMFunc, html bool) (*VMTemplate, error) {
+	// при компиляции важны только имена функций, сами функции подставляются в копию шаблона
+	fnames := make([]string, 0, len(funcs))
+	stubs := make(map[string]interface{}, len(funcs))
+	for n := range funcs {
+		fnames = append(fnames, n)
+		stubs[n] = templateHelper(nil)
+	}
+	sort.Strings(fnames)
+	if key != "" {
+		if html {
+			key = "html\x00" + key
+		}
+		key += "\x00" + strings.Join(fnames, ",")
+	}
+
+	templateCache.Lock()
+	cached, ok := templateCache.m[key]
+	templateCache.Unlock()
+	if !ok || key == "" {
+		var err error
+		if html {
+			cached, err = htmltemplate.New(name).Funcs(stubs).Parse(text)
+		} else {
+			cached, err = texttemplate.New(name).Funcs(stubs).Parse(text)
+		}
+		if err != nil {
+			return nil, templateError(err)
+		}
+		if key != "" {
+			templateCache.Lock()
+			if len(templateCache.m) >= templateCacheSize {
+				templateCache.m = make(map[string]interface{})
+			}
+			templateCache.m[key] = cached
+			templateCache.Unlock()
+		}
+	}
+
+	x := &VMTemplate{funcs: funcs}
+	x.VMInit(x)
+	x.VMRegister()
+	var err error
+	switch t := cached.(type) {
+	case *htmltemplate.Template:
+		x.ht, err = t.Clone()
+	case *texttemplate.Template:
+		x.tt, err = t.Clone()
+	}
+	return x, templateError(err)
+}
+
+// VMNew компилирует шаблон из строки, скомпилированные шаблоны кэшируются
+func (x *VMTemplate) VMNew(args VMSlice) error {
+	if len(args) != 1 && len(args) != 2 {
+		return VMErrorNeedArgs(2)
+	}
+	text, ok := args[0].(VMString)
+	if !ok {
+		return VMErrorNeedString
+	}
+	var opts VMValuer
+	if len(args) == 2 {
+		opts = args[1]
+	}
+	funcs, html, err := templateOptions(opts)
+	if err != nil {
+		return err
+	}
+	t, err := newTemplate("шаблон", string(text), string(text), funcs, html)
+	if err != nil {
+		return err
+	}
+	x.ht, x.tt, x.funcs = t.ht, t.tt, t.funcs
+	return nil
+}
+
+// templateFromFile компилирует шаблон из файла, имя шаблона - имя файла.
+// Кэш учитывает время изменения и размер файла, поэтому измененный файл компилируется заново
+func templateFromFile(fn string, opts VMValuer) (*VMTemplate, error) {
+	funcs, html, err := templateOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return nil, err
+	}
+	text, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(fn)
+	if err != nil {
+		return nil, err
+	}
+	key := "file\x00" + abs + "\x00" + fi.ModTime().String() + "\x00" + VMInt(fi.Size()).String()
+	return newTemplate(filepath.Base(fn), string(text), key, funcs, html)
+}
+
+func (x *VMTemplate) String() string {
+	if x.ht != nil {
+		return "Шаблон " + x.ht.Name()
+	}
+	if x.tt != nil {
+		return "Шаблон " + x.tt.Name()
+	}
+	return "Шаблон"
+}
+
+func (x *VMTemplate) add(name, text string) error {
+	var err error
+	switch {
+	case x.ht != nil:
+		_, err = x.ht.New(name).Parse(text)
+	case x.tt != nil:
+		_, err = x.tt.New(name).Parse(text)
+	default:
+		return errors.New("Шаблон не создан")
+	}
+	return templateError(err)
+}
+
+// Добавить(имя, текст) добавляет именованный шаблон, который можно включить через {{template "имя" .}}
+// или определить в нем блоки макета
+func (x *VMTemplate) Добавить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	ss, err := stringArgs(args, 2, 2)
+	if err != nil {
+		return err
+	}
+	return x.add(ss[0], ss[1])
+}
+
+// ДобавитьФайл(путь) добавляет шаблон из файла, его имя - имя файла без каталога
+func (x *VMTemplate) ДобавитьФайл(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	ss, err := stringArgs(args, 1, 1)
+	if err != nil {
+		return err
+	}
+	fn, err := SandboxOf(envout).Path(ss[0], false)
+	if err != nil {
+		return err
+	}
+	text, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	return x.add(filepath.Base(ss[0]), string(text))
+}
+
+// Выполнить(данные, имя) возвращает результат шаблона для данных, имя - необязательное имя вложенного шаблона
+func (x *VMTemplate) Выполнить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 1 && len(args) != 2 {
+		return VMErrorNeedArgs(2)
+	}
+	var name string
+	if len(args) == 2 {
+		s, ok := args[1].(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		name = string(s)
+	}
+	var env *Env
+	if envout != nil {
+		env = *envout
+	}
+	funcs := make(map[string]interface{}, len(x.funcs))
+	for n, f := range x.funcs {
+		funcs[n] = templateFunc(f, env)
+	}
+	data := templateValue(args[0], env)
+	var buf bytes.Buffer
+	var err error
+	switch {
+	case x.ht != nil:
+		var t *htmltemplate.Template
+		if t, err = x.ht.Clone(); err != nil {
+			break
+		}
+		t.Funcs(funcs)
+		if name != "" {
+			err = t.ExecuteTemplate(&buf, name, data)
+		} else {
+			err = t.Execute(&buf, data)
+		}
+	case x.tt != nil:
+		var t *texttemplate.Template
+		if t, err = x.tt.Clone(); err != nil {
+			break
+		}
+		t.Funcs(funcs)
+		if name != "" {
+			err = t.ExecuteTemplate(&buf, name, data)
+		} else {
+			err = t.Execute(&buf, data)
+		}
+	default:
+		return errors.New("Шаблон не создан")
+	}
+	if err != nil {
+		return templateError(err)
+	}
+	rets.Append(VMString(buf.String()))
+	return nil
+}
+
+// importTemplate регистрирует функции и типы для работы с шаблонами
+func importTemplate(env *Env) {
+
+	env.DefineS("шаблонизфайла", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// параметры шаблона необязательны
+		if len(args) != 1 && len(args) != 2 {
+			return VMErrorNeedArgs(2)
+		}
+		fn, err := fsPath(env, args[0], false)
+		if err != nil {
+			return err
+		}
+		var opts VMValuer
+		if len(args) == 2 {
+			opts = args[1]
+		}
+		t, err := templateFromFile(fn, opts)
+		if err != nil {
+			return err
+		}
+		rets.Append(t)
+		return nil
+	}))
+
+	env.DefineTypeStruct("шаблон", &VMTemplate{})
+}
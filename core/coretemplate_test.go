@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// функции-помощники исполняются в окружении кода, вызвавшего Выполнить
+func TestTemplateHelperEnv(t *testing.T) {
+	env := NewEnv()
+	LoadAllBuiltins(env)
+	env.SetSandbox(&Sandbox{FS: FSDenied})
+
+	inner := &VMTemplate{}
+	if err := inner.VMNew(VMSlice{VMString("")}); err != nil {
+		t.Fatal(err)
+	}
+	funcs := VMStringMap{"доб": VMFunc(inner.ДобавитьФайл)}
+	var got *Env
+	funcs["окр"] = VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		got = *envout
+		return nil
+	})
+	pause, _ := env.Get(env.Names().Set("пауза"))
+	funcs["пауза"] = pause
+
+	tpl := &VMTemplate{}
+	if err := tpl.VMNew(VMSlice{VMString(`{{if .Ф}}{{доб "/etc/hostname"}}{{else if .П}}{{пауза 10}}{{else}}{{окр}}{{call .Данные}}{{end}}`),
+		VMStringMap{"Функции": funcs}}); err != nil {
+		t.Fatal(err)
+	}
+	exec := func(data VMStringMap, envout *(*Env)) error {
+		var rets VMSlice
+		return tpl.Выполнить(VMSlice{data}, &rets, envout)
+	}
+
+	var fromData *Env
+	data := VMStringMap{"Данные": VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		fromData = *envout
+		return nil
+	})}
+	if err := exec(data, &env); err != nil {
+		t.Fatal(err)
+	}
+	if got != env || fromData != env {
+		t.Errorf("помощник получил окружение %p и %p, ожидается %p", got, fromData, env)
+	}
+
+	// политика ограничений вызывающего кода действует и в помощниках
+	if err := exec(VMStringMap{"Ф": VMBool(true)}, &env); err == nil || !strings.Contains(err.Error(), VMErrorFSDenied.Error()) {
+		t.Errorf("доступ к файлу из помощника: %v", err)
+	}
+	// без окружения доступ к файлам запрещен
+	if err := exec(VMStringMap{"Ф": VMBool(true)}, nil); err == nil || !strings.Contains(err.Error(), VMErrorFSDenied.Error()) {
+		t.Errorf("доступ к файлу без окружения: %v", err)
+	}
+	var rets VMSlice
+	if err := inner.ДобавитьФайл(VMSlice{VMString("/etc/hostname")}, &rets, nil); err != VMErrorFSDenied {
+		t.Errorf("ДобавитьФайл без окружения: %v", err)
+	}
+
+	// и его контекст прерывает помощник
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	env.SetContext(ctx)
+	start := time.Now()
+	if err := exec(VMStringMap{"П": VMBool(true)}, &env); err == nil || !strings.Contains(err.Error(), VMErrorDeadlineExceeded.Error()) {
+		t.Errorf("прерывание помощника: %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("помощник прерван только через %v", d)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	helper := VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error { return nil })
+	for _, c := range []struct {
+		args VMSlice
+		want string
+	}{
+		{VMSlice{}, VMErrorNeedArgs(2).Error()},
+		{VMSlice{VMInt(1)}, VMErrorNeedString.Error()},
+		{VMSlice{VMString("{{.}}"), VMInt(1)}, VMErrorNeedMap.Error()},
+		{VMSlice{VMString("{{.}}"), VMStringMap{"ЭкранироватьHTML": VMString("нет")}}, VMErrorNeedBool.Error()},
+		{VMSlice{VMString("{{.}}"), VMStringMap{"Функции": VMSlice{helper}}}, "задаются структурой"},
+		{VMSlice{VMString("{{.}}"), VMStringMap{"Функции": VMStringMap{"ф": VMInt(1)}}}, "ф в функциях шаблона не является функцией"},
+		{VMSlice{VMString("{{.}}"), VMStringMap{"Функция": VMStringMap{"ф": helper}}}, "Неизвестный параметр Функция"},
+		{VMSlice{VMString("{{if .}}")}, "Ошибка в шаблоне шаблон, строка 1"},
+		{VMSlice{VMString("строка\n{{.А")}, "Ошибка в шаблоне шаблон, строка 2"},
+		{VMSlice{VMString("{{нет .}}")}, `"нет" not defined`},
+	} {
+		if err := (&VMTemplate{}).VMNew(c.args); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Новый Шаблон%v: %v, ожидается %q", c.args, err, c.want)
+		}
+	}
+
+	// ссылка на неопределенный шаблон обнаруживается только при исполнении
+	tpl := &VMTemplate{}
+	if err := tpl.VMNew(VMSlice{VMString(`{{.А.Б}}{{template "нет" .}}`), VMStringMap{"Функции": VMStringMap{"ф": VMFunc(
+		func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+			return errors.New("ошибка помощника")
+		})}}}); err != nil {
+		t.Fatal(err)
+	}
+	env := NewEnv()
+	var rets VMSlice
+	for _, c := range []struct {
+		name string
+		m    VMMethod
+		args VMSlice
+		want string
+	}{
+		{"Выполнить", tpl.Выполнить, VMSlice{}, VMErrorNeedArgs(2).Error()},
+		{"Выполнить", tpl.Выполнить, VMSlice{VMStringMap{}, VMInt(1)}, VMErrorNeedString.Error()},
+		{"Выполнить", tpl.Выполнить, VMSlice{VMStringMap{"А": VMStringMap{}}}, `no such template "нет"`},
+		{"Выполнить", tpl.Выполнить, VMSlice{VMStringMap{}, VMString("другой")}, `"другой"`},
+		{"Добавить", tpl.Добавить, VMSlice{VMString("а")}, VMErrorNeedArgs(2).Error()},
+		{"Добавить", tpl.Добавить, VMSlice{VMString("а"), VMInt(1)}, VMErrorNeedString.Error()},
+		{"Добавить", tpl.Добавить, VMSlice{VMString("а"), VMString("{{end}}")}, "Ошибка в шаблоне а, строка 1"},
+		{"ДобавитьФайл", tpl.ДобавитьФайл, VMSlice{}, VMErrorNeedArgs(1).Error()},
+		{"ДобавитьФайл", tpl.ДобавитьФайл, VMSlice{VMString("нет.html")}, "нет.html"},
+	} {
+		if err := c.m(c.args, &rets, &env); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s%v: %v, ожидается %q", c.name, c.args, err, c.want)
+		}
+	}
+	// помощник возвращает ошибку
+	if err := tpl.Добавить(VMSlice{VMString("помощник"), VMString("{{ф}}")}, &rets, &env); err != nil {
+		t.Fatal(err)
+	}
+	if err := tpl.Выполнить(VMSlice{VMStringMap{}, VMString("помощник")}, &rets, &env); err == nil || !strings.Contains(err.Error(), "ошибка помощника") {
+		t.Errorf("ошибка помощника: %v", err)
+	}
+
+	// шаблон, созданный без Новый
+	empty := &VMTemplate{}
+	if err := empty.Добавить(VMSlice{VMString("а"), VMString("")}, &rets, &env); err == nil {
+		t.Error("Добавить в несозданный шаблон без ошибки")
+	}
+	if err := empty.Выполнить(VMSlice{VMStringMap{}}, &rets, &env); err == nil {
+		t.Error("Выполнить несозданный шаблон без ошибки")
+	}
+
+	env = NewEnv()
+	LoadAllBuiltins(env)
+	env.SetSandbox(&Sandbox{FS: FSDenied})
+	checkBuiltinErrors(t, env, []builtinErrCase{
+		{"ШаблонИзФайла", VMSlice{}, VMErrorNeedArgs(2).Error()},
+		{"ШаблонИзФайла", VMSlice{VMInt(1)}, VMErrorNeedString.Error()},
+		{"ШаблонИзФайла", VMSlice{VMString("шаблон.html")}, VMErrorFSDenied.Error()},
+	})
+	env.SetSandbox(nil)
+	checkBuiltinErrors(t, env, []builtinErrCase{
+		{"ШаблонИзФайла", VMSlice{VMString("нет.html")}, "нет.html"},
+		{"ШаблонИзФайла", VMSlice{VMString("нет.html"), VMStringMap{"Экранировать": VMBool(false)}}, "Неизвестный параметр Экранировать"},
+	})
+}
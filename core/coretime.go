@@ -8,8 +8,6 @@ import (
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/covrom/gonec/names"
 )
 
 const (
@@ -425,11 +423,11 @@ func (t VMTime) Format(layout string) string {
 	return string(b)
 }
 
-func (t VMTime) MethodMember(name int) (VMFunc, bool) {
+func (t VMTime) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
 
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "год":
 		return VMFuncMustParams(0, t.Год), true
 	case "месяц":
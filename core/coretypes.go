@@ -99,6 +99,7 @@ const (
 	VMDURATION
 	VMNIL
 	VMNULL
+	VMBINARYDATA
 )
 
 func (x VMBinaryType) ParseBinary(data []byte) (VMValuer, error) {
@@ -139,6 +140,10 @@ func (x VMBinaryType) ParseBinary(data []byte) (VMValuer, error) {
 		return VMNil, nil
 	case VMNULL:
 		return VMNullVar, nil
+	case VMBINARYDATA:
+		var v VMBinaryData
+		err := (&v).UnmarshalBinary(data)
+		return v, err
 	}
 	return nil, VMErrorUnknownType
 }
@@ -3,8 +3,6 @@ package core
 import (
 	"reflect"
 	"sync"
-
-	"github.com/covrom/gonec/names"
 )
 
 // VMWaitGroup - группа ожидания исполнения горутин
@@ -36,10 +34,10 @@ func (x *VMWaitGroup) Wait() {
 	x.wg.Wait()
 }
 
-func (x *VMWaitGroup) MethodMember(name int) (VMFunc, bool) {
+func (x *VMWaitGroup) MethodMember(name string) (VMFunc, bool) {
 
 	// только эти методы будут доступны из кода на языке Гонец!
-	switch names.UniqueNames.GetLowerCase(name) {
+	switch name {
 	case "добавить":
 		return VMFuncMustParams(1, x.Добавить), true
 	case "завершить":
@@ -0,0 +1,1098 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// типы узлов, которые возвращает ЧтениеXML.ТипУзла()
+const (
+	xmlNodeNone      = "Ничего"
+	xmlNodeStart     = "НачалоЭлемента"
+	xmlNodeEnd       = "КонецЭлемента"
+	xmlNodeText      = "Текст"
+	xmlNodeComment   = "Комментарий"
+	xmlNodeProcInst  = "ИнструкцияОбработки"
+	xmlNodeDirective = "Директива"
+)
+
+const xmlNamespaceXML = "http://www.w3.org/XML/1998/namespace"
+
+// VMXMLReader - потоковое чтение XML (ЧтениеXML) в стиле 1С: метод Прочитать переходит к следующему узлу,
+// остальные методы возвращают свойства текущего узла. Документ не загружается в память целиком.
+// Создается как Новый ЧтениеXML(источник, параметры), источник - строка, двоичные данные или ПотокЧтения
+type VMXMLReader struct {
+	VMMetaObj
+
+	dec         *xml.Decoder
+	lines       *xmlLineReader
+	src         io.Reader
+	ignoreSpace bool
+
+	kind  string
+	name  xml.Name // Space - префикс, как в исходном тексте
+	value string
+	attrs []xml.Attr
+
+	scopes []map[string]string // области видимости префиксов пространств имен открытых элементов
+	open   []xml.Name          // открытые элементы
+	popped bool                // текущий узел - конец элемента, его область видимости удаляется при следующем чтении
+}
+
+func (x *VMXMLReader) VMRegister() {
+	x.kind = xmlNodeNone
+	x.ignoreSpace = true
+	x.VMRegisterMethod("Прочитать", x.Прочитать)
+	x.VMRegisterMethod("ТипУзла", x.ТипУзла)
+	x.VMRegisterMethod("Имя", x.Имя)
+	x.VMRegisterMethod("ЛокальноеИмя", x.ЛокальноеИмя)
+	x.VMRegisterMethod("Префикс", x.Префикс)
+	x.VMRegisterMethod("URIПространстваИмен", x.URIПространстваИмен)
+	x.VMRegisterMethod("Значение", x.Значение)
+	x.VMRegisterMethod("ЗначениеАтрибута", x.ЗначениеАтрибута)
+	x.VMRegisterMethod("Атрибуты", x.Атрибуты)
+	x.VMRegisterMethod("Пропустить", x.Пропустить)
+	x.VMRegisterMethod("Закрыть", x.Закрыть)
+}
+
+// VMNew открывает источник. Параметры - структура с полями ИгнорироватьПробелы (по умолчанию Истина) и Кодировка
+func (x *VMXMLReader) VMNew(args VMSlice) error {
+	if len(args) != 1 && len(args) != 2 {
+		return VMErrorNeedArgs(2)
+	}
+	var opts VMStringMap
+	if len(args) == 2 {
+		var ok bool
+		if opts, ok = args[1].(VMStringMap); !ok {
+			return VMErrorNeedMap
+		}
+		if err := checkOptions(opts, "ИгнорироватьПробелы", "Кодировка"); err != nil {
+			return err
+		}
+	}
+	if v, ok := opts["ИгнорироватьПробелы"]; ok {
+		b, ok := v.(VMBool)
+		if !ok {
+			return VMErrorNeedBool
+		}
+		x.ignoreSpace = bool(b)
+	}
+	var charset string
+	if v, ok := opts["Кодировка"]; ok {
+		s, ok := v.(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		if _, err := charsetName(string(s)); err != nil {
+			return err
+		}
+		charset = string(s)
+	}
+	r, err := readerArg(args[0])
+	if err != nil {
+		return err
+	}
+	x.src = r
+	if _, ok := args[0].(VMString); !ok && charset != "" {
+		// кодировка задана явно и важнее, чем указанная в объявлении XML
+		if r, err = charsetReader(r, charset); err != nil {
+			return err
+		}
+	}
+	x.lines = newXMLLineReader(r, 1)
+	x.dec = xml.NewDecoder(x.lines)
+	x.dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if charset != "" {
+			return input, nil
+		}
+		cr, err := charsetReader(input, label)
+		if err != nil {
+			return nil, err
+		}
+		// декодер читает дальше уже из перекодированного потока
+		x.lines = newXMLLineReader(cr, x.lines.line)
+		return x.lines, nil
+	}
+	return nil
+}
+
+// xmlLineReader считает строки, прочитанные декодером XML. Для io.ByteReader xml.Decoder
+// не использует собственный буфер и читает данные побайтно, поэтому счетчик указывает на строку,
+// которую декодер разбирает в данный момент
+type xmlLineReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newXMLLineReader(r io.Reader, line int) *xmlLineReader {
+	return &xmlLineReader{r: bufio.NewReader(r), line: line}
+}
+
+func (l *xmlLineReader) ReadByte() (byte, error) {
+	b, err := l.r.ReadByte()
+	if err == nil && b == '\n' {
+		l.line++
+	}
+	return b, err
+}
+
+func (l *xmlLineReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.line += bytes.Count(p[:n], []byte{'\n'})
+	return n, err
+}
+
+func (x *VMXMLReader) String() string {
+	return "ЧтениеXML"
+}
+
+func (x *VMXMLReader) lookupNS(prefix string) string {
+	if prefix == "xml" {
+		return xmlNamespaceXML
+	}
+	for i := len(x.scopes) - 1; i >= 0; i-- {
+		if uri, ok := x.scopes[i][prefix]; ok {
+			return uri
+		}
+	}
+	return ""
+}
+
+func (x *VMXMLReader) error(err error) error {
+	if se, ok := err.(*xml.SyntaxError); ok {
+		return fmt.Errorf("Ошибка чтения XML в строке %d: %s", se.Line, se.Msg)
+	}
+	if (err == io.EOF || err == io.ErrUnexpectedEOF) && len(x.open) > 0 {
+		return fmt.Errorf("Ошибка чтения XML: неожиданный конец данных, не закрыт элемент <%s>", qualifiedName(x.open[len(x.open)-1]))
+	}
+	return err
+}
+
+func qualifiedName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// next переходит к следующему узлу, возвращает Ложь в конце документа
+func (x *VMXMLReader) next() (bool, error) {
+	if x.dec == nil {
+		return false, VMErrorStreamClosed
+	}
+	if x.popped {
+		x.scopes = x.scopes[:len(x.scopes)-1]
+		x.popped = false
+	}
+	for {
+		tok, err := x.dec.RawToken()
+		if err == io.EOF && len(x.open) == 0 {
+			x.kind, x.name, x.value, x.attrs = xmlNodeNone, xml.Name{}, "", nil
+			return false, nil
+		}
+		if err != nil {
+			return false, x.error(err)
+		}
+		x.name, x.value, x.attrs = xml.Name{}, "", nil
+		switch t := tok.(type) {
+		case xml.StartElement:
+			scope := make(map[string]string)
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "xmlns":
+					scope[a.Name.Local] = a.Value
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					scope[""] = a.Value
+				}
+			}
+			x.scopes = append(x.scopes, scope)
+			x.open = append(x.open, t.Name)
+			x.kind, x.name, x.attrs = xmlNodeStart, t.Name, t.Attr
+		case xml.EndElement:
+			if len(x.open) == 0 || x.open[len(x.open)-1] != t.Name {
+				line := x.lines.line
+				if len(x.open) == 0 {
+					return false, fmt.Errorf("Ошибка чтения XML в строке %d: лишний конец элемента </%s>", line, qualifiedName(t.Name))
+				}
+				return false, fmt.Errorf("Ошибка чтения XML в строке %d: ожидается </%s>, получено </%s>",
+					line, qualifiedName(x.open[len(x.open)-1]), qualifiedName(t.Name))
+			}
+			x.open = x.open[:len(x.open)-1]
+			x.popped = true
+			x.kind, x.name = xmlNodeEnd, t.Name
+		case xml.CharData:
+			if x.ignoreSpace && len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			x.kind, x.value = xmlNodeText, string(t)
+		case xml.Comment:
+			x.kind, x.value = xmlNodeComment, string(t)
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				// объявление XML не является узлом
+				continue
+			}
+			x.kind, x.name, x.value = xmlNodeProcInst, xml.Name{Local: t.Target}, string(t.Inst)
+		case xml.Directive:
+			x.kind, x.value = xmlNodeDirective, string(t)
+		}
+		return true, nil
+	}
+}
+
+// Прочитать переходит к следующему узлу и возвращает Ложь, если документ закончился
+func (x *VMXMLReader) Прочитать(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	ok, err := x.next()
+	if err != nil {
+		return err
+	}
+	rets.Append(VMBool(ok))
+	return nil
+}
+
+func (x *VMXMLReader) ТипУзла(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rets.Append(VMString(x.kind))
+	return nil
+}
+
+// Имя возвращает имя элемента с префиксом пространства имен или имя инструкции обработки
+func (x *VMXMLReader) Имя(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rets.Append(VMString(qualifiedName(x.name)))
+	return nil
+}
+
+func (x *VMXMLReader) ЛокальноеИмя(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rets.Append(VMString(x.name.Local))
+	return nil
+}
+
+func (x *VMXMLReader) Префикс(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rets.Append(VMString(x.name.Space))
+	return nil
+}
+
+// URIПространстваИмен возвращает пространство имен текущего элемента
+func (x *VMXMLReader) URIПространстваИмен(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if x.kind != xmlNodeStart && x.kind != xmlNodeEnd {
+		rets.Append(VMString(""))
+		return nil
+	}
+	rets.Append(VMString(x.lookupNS(x.name.Space)))
+	return nil
+}
+
+// Значение возвращает текст, комментарий или содержимое инструкции обработки
+func (x *VMXMLReader) Значение(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	rets.Append(VMString(x.value))
+	return nil
+}
+
+// ЗначениеАтрибута возвращает значение атрибута по имени (с префиксом, если он есть)
+// или по локальному имени и пространству имен. Если атрибута нет, возвращается Неопределено
+func (x *VMXMLReader) ЗначениеАтрибута(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 1 && len(args) != 2 {
+		return VMErrorNeedArgs(2)
+	}
+	name, ok := args[0].(VMString)
+	if !ok {
+		return VMErrorNeedString
+	}
+	var uri VMString
+	if len(args) == 2 {
+		if uri, ok = args[1].(VMString); !ok {
+			return VMErrorNeedString
+		}
+	}
+	for _, a := range x.attrs {
+		if len(args) == 1 && qualifiedName(a.Name) == string(name) ||
+			len(args) == 2 && a.Name.Local == string(name) && a.Name.Space != "" && x.lookupNS(a.Name.Space) == string(uri) {
+			rets.Append(VMString(a.Value))
+			return nil
+		}
+	}
+	rets.Append(VMNil)
+	return nil
+}
+
+// Атрибуты возвращает структуру атрибутов текущего элемента, кроме объявлений пространств имен
+func (x *VMXMLReader) Атрибуты(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	m := make(VMStringMap, len(x.attrs))
+	for _, a := range x.attrs {
+		if a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns" {
+			continue
+		}
+		m[qualifiedName(a.Name)] = VMString(a.Value)
+	}
+	rets.Append(m)
+	return nil
+}
+
+// Пропустить пропускает содержимое текущего элемента, текущим узлом становится его конец
+func (x *VMXMLReader) Пропустить(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if x.kind != xmlNodeStart {
+		return nil
+	}
+	depth := len(x.open)
+	for {
+		if _, err := x.next(); err != nil {
+			return err
+		}
+		if x.kind == xmlNodeEnd && len(x.open) == depth-1 {
+			return nil
+		}
+	}
+}
+
+// Закрыть закрывает источник, если это поток чтения
+func (x *VMXMLReader) Закрыть(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	x.dec = nil
+	if c, ok := x.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// readValue читает содержимое элемента, начало которого является текущим узлом.
+// Элемент без атрибутов и вложенных элементов становится строкой, иначе - структурой,
+// в которой атрибуты записаны с префиксом @, текст - с ключом #text, а повторяющиеся элементы - массивом
+func (x *VMXMLReader) readValue() (VMValuer, error) {
+	m := VMStringMap{}
+	for _, a := range x.attrs {
+		m["@"+qualifiedName(a.Name)] = VMString(a.Value)
+	}
+	var text strings.Builder
+	hasElems := false
+	for {
+		if _, err := x.next(); err != nil {
+			return nil, err
+		}
+		switch x.kind {
+		case xmlNodeEnd:
+			if !hasElems && len(m) == 0 {
+				return VMString(text.String()), nil
+			}
+			if text.Len() > 0 {
+				m["#text"] = VMString(text.String())
+			}
+			return m, nil
+		case xmlNodeText:
+			text.WriteString(x.value)
+		case xmlNodeStart:
+			hasElems = true
+			name := qualifiedName(x.name)
+			v, err := x.readValue()
+			if err != nil {
+				return nil, err
+			}
+			switch prev := m[name].(type) {
+			case nil:
+				m[name] = v
+			case VMSlice:
+				m[name] = append(prev, v)
+			default:
+				m[name] = VMSlice{prev, v}
+			}
+		}
+	}
+}
+
+// ReadXML читает документ XML целиком в структуру с единственным ключом - именем корневого элемента
+func ReadXML(src, opts VMValuer) (VMValuer, error) {
+	args := VMSlice{src}
+	if opts != nil {
+		args = append(args, opts)
+	}
+	x := &VMXMLReader{}
+	x.VMInit(x)
+	x.VMRegister()
+	if err := x.VMNew(args); err != nil {
+		return nil, err
+	}
+	for {
+		ok, err := x.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("Ошибка чтения XML: нет корневого элемента")
+		}
+		if x.kind == xmlNodeStart {
+			name := qualifiedName(x.name)
+			v, err := x.readValue()
+			if err != nil {
+				return nil, err
+			}
+			return VMStringMap{name: v}, nil
+		}
+	}
+}
+
+// xmlNS - объявление префикса пространства имен, пустой префикс - пространство по умолчанию
+type xmlNS struct {
+	prefix, uri string
+}
+
+// xmlWElem - открытый элемент ЗаписьXML
+type xmlWElem struct {
+	name     string
+	ns       []xmlNS           // объявления пространств имен в порядке записи
+	used     map[string]string // префиксы, уже использованные в начальном теге, и их пространства имен
+	hasElems bool
+	hasText  bool
+}
+
+// declared возвращает пространство имен, объявленное для префикса в самом элементе
+func (el *xmlWElem) declared(prefix string) (string, bool) {
+	for _, d := range el.ns {
+		if d.prefix == prefix {
+			return d.uri, true
+		}
+	}
+	return "", false
+}
+
+// VMXMLWriter - запись XML (ЗаписьXML) в поток записи или в память.
+// Создается как Новый ЗаписьXML(приемник, параметры) или Новый ЗаписьXML(параметры),
+// без приемника записанное возвращает метод Содержимое
+type VMXMLWriter struct {
+	VMMetaObj
+
+	w       *bufio.Writer
+	dst     io.Writer
+	buf     *bytes.Buffer
+	indent  string
+	charset string
+
+	stack     []*xmlWElem
+	startOpen bool // начальный тег еще не закрыт символом >, в него можно записывать атрибуты
+	written   bool
+}
+
+func (x *VMXMLWriter) VMRegister() {
+	if x.w == nil {
+		x.buf = new(bytes.Buffer)
+		x.dst = x.buf
+		x.w = bufio.NewWriter(x.buf)
+	}
+	x.VMRegisterMethod("ЗаписатьОбъявлениеXML", x.ЗаписатьОбъявлениеXML)
+	x.VMRegisterMethod("ЗаписатьНачалоЭлемента", x.ЗаписатьНачалоЭлемента)
+	x.VMRegisterMethod("ЗаписатьСоответствиеПространстваИмен", x.ЗаписатьСоответствиеПространстваИмен)
+	x.VMRegisterMethod("ЗаписатьАтрибут", x.ЗаписатьАтрибут)
+	x.VMRegisterMethod("ЗаписатьТекст", x.ЗаписатьТекст)
+	x.VMRegisterMethod("ЗаписатьКомментарий", x.ЗаписатьКомментарий)
+	x.VMRegisterMethod("ЗаписатьКонецЭлемента", x.ЗаписатьКонецЭлемента)
+	x.VMRegisterMethod("Содержимое", x.Содержимое)
+	x.VMRegisterMethod("Закрыть", x.Закрыть)
+}
+
+// VMNew задает приемник и параметры записи: Отступ и Кодировка (UTF-8 или windows-1251)
+func (x *VMXMLWriter) VMNew(args VMSlice) error {
+	var dst, opts VMValuer
+	switch len(args) {
+	case 1:
+		if m, ok := args[0].(VMStringMap); ok {
+			opts = m
+		} else {
+			dst = args[0]
+		}
+	case 2:
+		dst, opts = args[0], args[1]
+	default:
+		return VMErrorNeedArgs(2)
+	}
+	if opts != nil {
+		m, ok := opts.(VMStringMap)
+		if !ok {
+			return VMErrorNeedMap
+		}
+		if err := checkOptions(m, "Отступ", "Кодировка"); err != nil {
+			return err
+		}
+		var err error
+		if x.indent, err = indentArg(m["Отступ"]); err != nil {
+			return err
+		}
+		if v, ok := m["Кодировка"]; ok {
+			s, ok := v.(VMString)
+			if !ok {
+				return VMErrorNeedString
+			}
+			if x.charset, err = charsetName(string(s)); err != nil {
+				return err
+			}
+		}
+	}
+	switch d := dst.(type) {
+	case nil, VMNilType, VMNullType:
+		x.buf = new(bytes.Buffer)
+		x.dst = x.buf
+	case *VMWriter:
+		x.buf = nil
+		x.dst = d
+	default:
+		return errors.New("Требуется поток записи")
+	}
+	w, err := charsetWriter(x.dst, x.charset)
+	if err != nil {
+		return err
+	}
+	x.w = bufio.NewWriter(w)
+	return nil
+}
+
+func (x *VMXMLWriter) String() string {
+	return "ЗаписьXML"
+}
+
+var xmlAttrEscaper = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;", `"`, "&quot;", "\n", "&#xA;", "\r", "&#xD;", "\t", "&#x9;")
+var xmlTextEscaper = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;", "\r", "&#xD;")
+
+// xmlText возвращает представление значения в XML, даты записываются в формате 1С
+func xmlText(v VMValuer) string {
+	switch vv := v.(type) {
+	case nil, VMNilType, VMNullType:
+		return ""
+	case VMString:
+		return string(vv)
+	case VMTime:
+		return time.Time(vv).Format("2006-01-02T15:04:05")
+	case VMStringer:
+		return vv.String()
+	}
+	return ""
+}
+
+// closeStart закрывает начальный тег перед записью содержимого элемента
+func (x *VMXMLWriter) closeStart() {
+	if x.startOpen {
+		x.w.WriteByte('>')
+		x.startOpen = false
+	}
+}
+
+// newLine переводит строку перед дочерним узлом, если задан отступ
+func (x *VMXMLWriter) newLine() {
+	if x.indent == "" || !x.written {
+		return
+	}
+	if n := len(x.stack); n > 0 && x.stack[n-1].hasText {
+		return
+	}
+	x.w.WriteByte('\n')
+	x.w.WriteString(strings.Repeat(x.indent, len(x.stack)))
+}
+
+// resolve возвращает пространство имен, с которым префикс связан в текущем элементе,
+// с учетом переопределений во вложенных элементах. Пространство по умолчанию без объявления пустое
+func (x *VMXMLWriter) resolve(prefix string) (string, bool) {
+	for i := len(x.stack) - 1; i >= 0; i-- {
+		if uri, ok := x.stack[i].declared(prefix); ok {
+			return uri, true
+		}
+	}
+	return "", prefix == ""
+}
+
+// lookupPrefix ищет префикс, связанный с uri в текущем элементе. Объявления просматриваются от внутреннего
+// элемента к внешним и в порядке записи, префикс, переопределенный глубже, не подходит.
+// Атрибуты не относятся к пространству по умолчанию, поэтому для них пустой префикс не ищется
+func (x *VMXMLWriter) lookupPrefix(uri string, attr bool) (string, bool) {
+	for i := len(x.stack) - 1; i >= 0; i-- {
+		for _, d := range x.stack[i].ns {
+			if d.uri != uri || attr && d.prefix == "" {
+				continue
+			}
+			if u, _ := x.resolve(d.prefix); u == uri {
+				return d.prefix, true
+			}
+		}
+	}
+	if !attr && uri == "" {
+		if u, _ := x.resolve(""); u == "" {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// declare объявляет префикс в открытом элементе
+func (x *VMXMLWriter) declare(prefix, uri string) error {
+	el := x.stack[len(x.stack)-1]
+	if u, ok := el.declared(prefix); ok {
+		if u == uri {
+			return nil
+		}
+		return fmt.Errorf("Префикс %q уже объявлен в элементе %s", prefix, el.name)
+	}
+	if u, ok := el.used[prefix]; ok && u != uri {
+		return fmt.Errorf("Префикс %q уже использован в элементе %s для другого пространства имен", prefix, el.name)
+	}
+	if prefix != "" && uri == "" {
+		return fmt.Errorf("Префикс %q нельзя связать с пустым пространством имен", prefix)
+	}
+	name := "xmlns"
+	if prefix != "" {
+		name += ":" + prefix
+	}
+	x.writeAttr(name, uri)
+	el.ns = append(el.ns, xmlNS{prefix, uri})
+	return nil
+}
+
+// splitName возвращает префикс и локальное имя элемента или атрибута в пространстве имен uri.
+// Префикс, указанный в имени, сохраняется, иначе берется уже объявленный для uri префикс
+// или, для элемента, пространство по умолчанию
+func (x *VMXMLWriter) splitName(name, uri string, attr bool) (string, string, error) {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		prefix, local := name[:i], name[i+1:]
+		if prefix == "" || local == "" || strings.IndexByte(local, ':') >= 0 {
+			return "", "", fmt.Errorf("Неверное имя %s", name)
+		}
+		if uri == "" {
+			return "", "", fmt.Errorf("Префикс %q нельзя связать с пустым пространством имен", prefix)
+		}
+		return prefix, local, nil
+	}
+	if p, ok := x.lookupPrefix(uri, attr); ok {
+		return p, name, nil
+	}
+	if attr {
+		return "", "", fmt.Errorf("Не задан префикс для пространства имен %s", uri)
+	}
+	return "", name, nil
+}
+
+// bind связывает префикс с uri в открытом элементе, объявляя его, если он еще не связан с uri
+func (x *VMXMLWriter) bind(prefix, uri string) error {
+	if u, ok := x.resolve(prefix); !ok || u != uri {
+		if err := x.declare(prefix, uri); err != nil {
+			return err
+		}
+	}
+	x.stack[len(x.stack)-1].used[prefix] = uri
+	return nil
+}
+
+// validXMLName проверяет, что name можно записать именем элемента или атрибута:
+// начинается с буквы или _, содержит только буквы, цифры и символы _ - . :
+func validXMLName(name string) bool {
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.' || r == ':'):
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+func qname(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+func (x *VMXMLWriter) writeAttr(name, value string) {
+	x.w.WriteByte(' ')
+	x.w.WriteString(name)
+	x.w.WriteString(`="`)
+	xmlAttrEscaper.WriteString(x.w, value)
+	x.w.WriteByte('"')
+}
+
+func (x *VMXMLWriter) startElement(name, uri string, hasURI bool) error {
+	if x.w == nil {
+		return VMErrorStreamClosed
+	}
+	if name == "" {
+		return errors.New("Не указано имя элемента")
+	}
+	if !validXMLName(name) {
+		return fmt.Errorf("Неверное имя %s", name)
+	}
+	var prefix string
+	if hasURI {
+		var err error
+		if prefix, name, err = x.splitName(name, uri, false); err != nil {
+			return err
+		}
+		name = qname(prefix, name)
+	}
+	x.closeStart()
+	x.newLine()
+	if n := len(x.stack); n > 0 {
+		x.stack[n-1].hasElems = true
+	}
+	x.w.WriteByte('<')
+	x.w.WriteString(name)
+	x.stack = append(x.stack, &xmlWElem{name: name, used: make(map[string]string)})
+	x.startOpen = true
+	x.written = true
+	if hasURI {
+		// объявление записывается в начальный тег после имени
+		return x.bind(prefix, uri)
+	}
+	return nil
+}
+
+func (x *VMXMLWriter) endElement() error {
+	n := len(x.stack)
+	if n == 0 {
+		return errors.New("Нет открытого элемента")
+	}
+	el := x.stack[n-1]
+	x.stack = x.stack[:n-1]
+	if x.startOpen {
+		x.w.WriteString("/>")
+		x.startOpen = false
+		return nil
+	}
+	if el.hasElems && !el.hasText && x.indent != "" {
+		x.w.WriteByte('\n')
+		x.w.WriteString(strings.Repeat(x.indent, len(x.stack)))
+	}
+	x.w.WriteString("</")
+	x.w.WriteString(el.name)
+	_, err := x.w.WriteString(">")
+	return err
+}
+
+func (x *VMXMLWriter) text(s string) error {
+	if x.w == nil {
+		return VMErrorStreamClosed
+	}
+	if len(x.stack) == 0 {
+		return errors.New("Текст записывается только внутри элемента")
+	}
+	x.closeStart()
+	x.stack[len(x.stack)-1].hasText = true
+	_, err := xmlTextEscaper.WriteString(x.w, s)
+	return err
+}
+
+func (x *VMXMLWriter) attr(name, value, uri string, hasURI bool) error {
+	if !x.startOpen {
+		return errors.New("Атрибут записывается только сразу после начала элемента")
+	}
+	if !validXMLName(name) {
+		return fmt.Errorf("Неверное имя %s", name)
+	}
+	if hasURI {
+		prefix, local, err := x.splitName(name, uri, true)
+		if err != nil {
+			return err
+		}
+		if err := x.bind(prefix, uri); err != nil {
+			return err
+		}
+		name = qname(prefix, local)
+	}
+	x.writeAttr(name, value)
+	return nil
+}
+
+// stringArgs проверяет, что передано от min до max строковых аргументов
+func stringArgs(args VMSlice, min, max int) ([]string, error) {
+	if len(args) < min || len(args) > max {
+		return nil, VMErrorNeedArgs(max)
+	}
+	ss := make([]string, len(args))
+	for i, a := range args {
+		s, ok := a.(VMString)
+		if !ok {
+			return nil, VMErrorNeedString
+		}
+		ss[i] = string(s)
+	}
+	return ss, nil
+}
+
+// ЗаписатьОбъявлениеXML записывает <?xml version="1.0" encoding="..."?>
+func (x *VMXMLWriter) ЗаписатьОбъявлениеXML(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	if x.w == nil {
+		return VMErrorStreamClosed
+	}
+	if x.written {
+		return errors.New("Объявление XML записывается в начале документа")
+	}
+	enc := "UTF-8"
+	if x.charset == "windows-1251" {
+		enc = "windows-1251"
+	}
+	x.w.WriteString(`<?xml version="1.0" encoding="` + enc + `"?>`)
+	x.written = true
+	return nil
+}
+
+// ЗаписатьНачалоЭлемента(имя, uri) открывает элемент, имя может содержать префикс.
+// Если указано пространство имен, используется объявленный для него префикс, а если его нет - оно объявляется пространством по умолчанию
+func (x *VMXMLWriter) ЗаписатьНачалоЭлемента(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	ss, err := stringArgs(args, 1, 2)
+	if err != nil {
+		return err
+	}
+	if len(ss) == 2 {
+		return x.startElement(ss[0], ss[1], true)
+	}
+	return x.startElement(ss[0], "", false)
+}
+
+// ЗаписатьСоответствиеПространстваИмен(префикс, uri) объявляет префикс в открытом элементе, пустой префикс - пространство по умолчанию
+func (x *VMXMLWriter) ЗаписатьСоответствиеПространстваИмен(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	ss, err := stringArgs(args, 2, 2)
+	if err != nil {
+		return err
+	}
+	if !x.startOpen {
+		return errors.New("Пространство имен объявляется только сразу после начала элемента")
+	}
+	return x.declare(ss[0], ss[1])
+}
+
+// ЗаписатьАтрибут(имя, значение, uri) записывает атрибут открытого элемента
+func (x *VMXMLWriter) ЗаписатьАтрибут(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 2 && len(args) != 3 {
+		return VMErrorNeedArgs(3)
+	}
+	name, ok := args[0].(VMString)
+	if !ok {
+		return VMErrorNeedString
+	}
+	if len(args) == 3 {
+		uri, ok := args[2].(VMString)
+		if !ok {
+			return VMErrorNeedString
+		}
+		return x.attr(string(name), xmlText(args[1]), string(uri), true)
+	}
+	return x.attr(string(name), xmlText(args[1]), "", false)
+}
+
+// ЗаписатьТекст записывает текст элемента, специальные символы экранируются
+func (x *VMXMLWriter) ЗаписатьТекст(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 1 {
+		return VMErrorNeedArgs(1)
+	}
+	return x.text(xmlText(args[0]))
+}
+
+func (x *VMXMLWriter) ЗаписатьКомментарий(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	ss, err := stringArgs(args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if x.w == nil {
+		return VMErrorStreamClosed
+	}
+	if strings.Contains(ss[0], "--") {
+		return errors.New("Комментарий не может содержать --")
+	}
+	x.closeStart()
+	x.newLine()
+	if n := len(x.stack); n > 0 {
+		x.stack[n-1].hasElems = true
+	}
+	x.w.WriteString("<!--" + ss[0] + "-->")
+	x.written = true
+	return nil
+}
+
+// ЗаписатьКонецЭлемента закрывает последний открытый элемент, элемент без содержимого записывается как <имя/>
+func (x *VMXMLWriter) ЗаписатьКонецЭлемента(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	if x.w == nil {
+		return VMErrorStreamClosed
+	}
+	return x.endElement()
+}
+
+// Содержимое возвращает двоичные данные, записанные в память
+func (x *VMXMLWriter) Содержимое(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if len(args) != 0 {
+		return VMErrorNoNeedArgs
+	}
+	if x.buf == nil {
+		return errors.New("XML записывается не в память")
+	}
+	if x.w != nil {
+		if err := x.w.Flush(); err != nil {
+			return err
+		}
+	}
+	rets.Append(append(VMBinaryData{}, x.buf.Bytes()...))
+	return nil
+}
+
+// Закрыть дописывает данные и закрывает приемник, все элементы должны быть закрыты
+func (x *VMXMLWriter) Закрыть(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+	if x.w == nil {
+		return nil
+	}
+	if n := len(x.stack); n > 0 {
+		return fmt.Errorf("Не закрыт элемент <%s>", x.stack[n-1].name)
+	}
+	err := x.w.Flush()
+	x.w = nil
+	if c, ok := x.dst.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// writeValue записывает значение элементом name: массив - повторяющимися элементами,
+// структуру - атрибутами (ключи с @), текстом (#text) и вложенными элементами в порядке ключей
+func (x *VMXMLWriter) writeValue(name string, v VMValuer) error {
+	switch vv := v.(type) {
+	case VMSlice:
+		for _, e := range vv {
+			if err := x.writeValue(name, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case VMStringMap:
+		if err := x.startElement(name, "", false); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if strings.HasPrefix(k, "@") {
+				if err := x.attr(k[1:], xmlText(vv[k]), "", false); err != nil {
+					return err
+				}
+			}
+		}
+		if t, ok := vv["#text"]; ok {
+			if err := x.text(xmlText(t)); err != nil {
+				return err
+			}
+		}
+		for _, k := range keys {
+			if !strings.HasPrefix(k, "@") && k != "#text" {
+				if err := x.writeValue(k, vv[k]); err != nil {
+					return err
+				}
+			}
+		}
+		return x.endElement()
+	}
+	if err := x.startElement(name, "", false); err != nil {
+		return err
+	}
+	if s := xmlText(v); s != "" {
+		if err := x.text(s); err != nil {
+			return err
+		}
+	}
+	return x.endElement()
+}
+
+// WriteXML записывает структуру с единственным ключом - именем корневого элемента - в строку XML.
+// Параметры - структура с полями Отступ и ОбъявлениеXML (по умолчанию Истина)
+func WriteXML(v, opts VMValuer) (VMString, error) {
+	root, ok := v.(VMStringMap)
+	if !ok || len(root) != 1 {
+		return "", errors.New("Требуется структура с единственным ключом - именем корневого элемента")
+	}
+	x := &VMXMLWriter{}
+	x.VMInit(x)
+	x.VMRegister()
+	decl := true
+	if opts != nil {
+		m, ok := opts.(VMStringMap)
+		if !ok {
+			return "", VMErrorNeedMap
+		}
+		if err := checkOptions(m, "Отступ", "ОбъявлениеXML"); err != nil {
+			return "", err
+		}
+		var err error
+		if x.indent, err = indentArg(m["Отступ"]); err != nil {
+			return "", err
+		}
+		if d, ok := m["ОбъявлениеXML"]; ok {
+			b, ok := d.(VMBool)
+			if !ok {
+				return "", VMErrorNeedBool
+			}
+			decl = bool(b)
+		}
+	}
+	if decl {
+		x.ЗаписатьОбъявлениеXML(nil, nil, nil)
+	}
+	for name, rv := range root {
+		if err := x.writeValue(name, rv); err != nil {
+			return "", err
+		}
+	}
+	x.w.Flush()
+	return VMString(x.buf.String()), nil
+}
+
+// importXML регистрирует функции и типы для работы с XML
+func importXML(env *Env) {
+
+	env.DefineS("прочитатьxml", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// параметры чтения необязательны
+		if len(args) != 1 && len(args) != 2 {
+			return VMErrorNeedArgs(2)
+		}
+		var opts VMValuer
+		if len(args) == 2 {
+			opts = args[1]
+		}
+		v, err := ReadXML(args[0], opts)
+		if err != nil {
+			return err
+		}
+		rets.Append(v)
+		return nil
+	}))
+
+	env.DefineS("записатьxml", VMFunc(func(args VMSlice, rets *VMSlice, envout *(*Env)) error {
+		*envout = env
+		// параметры записи необязательны
+		if len(args) != 1 && len(args) != 2 {
+			return VMErrorNeedArgs(2)
+		}
+		var opts VMValuer
+		if len(args) == 2 {
+			opts = args[1]
+		}
+		s, err := WriteXML(args[0], opts)
+		if err != nil {
+			return err
+		}
+		rets.Append(s)
+		return nil
+	}))
+
+	env.DefineTypeStruct("чтениеxml", &VMXMLReader{})
+	env.DefineTypeStruct("записьxml", &VMXMLWriter{})
+}
@@ -0,0 +1,250 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// xmlOp - вызов ЗаписьXML: начало элемента (имя, uri), "/" - конец элемента,
+// "xmlns" - объявление (префикс, uri), "@" - атрибут (имя, uri)
+type xmlOp struct {
+	op, name, uri string
+}
+
+func writeXMLOps(ops []xmlOp) (string, error) {
+	x := &VMXMLWriter{}
+	x.VMRegister()
+	for _, o := range ops {
+		var err error
+		switch o.op {
+		case "/":
+			err = x.endElement()
+		case "xmlns":
+			err = x.declare(o.name, o.uri)
+		case "@":
+			err = x.attr(o.name, "1", o.uri, true)
+		case "<-":
+			err = x.startElement(o.name, "", false)
+		default:
+			err = x.startElement(o.name, o.uri, true)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	x.w.Flush()
+	return x.buf.String(), nil
+}
+
+func TestXMLWriterNamespaces(t *testing.T) {
+	tests := []struct {
+		ops  []xmlOp
+		want string
+	}{
+		// префикс из имени объявляется для нового пространства имен
+		{[]xmlOp{{"<", "п:эл", "urn:b"}, {"/", "", ""}}, `<п:эл xmlns:п="urn:b"/>`},
+		// и не объявляется повторно, если уже связан с ним
+		{[]xmlOp{{"<", "a:к", "urn:a"}, {"<", "a:эл", "urn:a"}, {"/", "", ""}, {"/", "", ""}},
+			`<a:к xmlns:a="urn:a"><a:эл/></a:к>`},
+		// префикс в имени переопределяется во вложенном элементе
+		{[]xmlOp{{"<", "a:к", "urn:a"}, {"<", "a:эл", "urn:z"}, {"/", "", ""}, {"/", "", ""}},
+			`<a:к xmlns:a="urn:a"><a:эл xmlns:a="urn:z"/></a:к>`},
+		// префикс, переопределенный глубже, не подходит для внешнего пространства имен
+		{[]xmlOp{{"<-", "к", ""}, {"xmlns", "p", "urn:1"}, {"<-", "с", ""}, {"xmlns", "p", "urn:2"},
+			{"<", "эл", "urn:1"}, {"/", "", ""}, {"<", "эл", "urn:2"}, {"/", "", ""}, {"/", "", ""}, {"/", "", ""}},
+			`<к xmlns:p="urn:1"><с xmlns:p="urn:2"><эл xmlns="urn:1"/><p:эл/></с></к>`},
+		// то же для пространства по умолчанию
+		{[]xmlOp{{"<", "к", "urn:d"}, {"<-", "с", ""}, {"xmlns", "", "urn:e"}, {"<", "эл", "urn:d"}, {"/", "", ""},
+			{"<", "эл", "urn:e"}, {"/", "", ""}, {"<", "эл", ""}, {"/", "", ""}, {"/", "", ""}, {"/", "", ""}},
+			`<к xmlns="urn:d"><с xmlns="urn:e"><эл xmlns="urn:d"/><эл/><эл xmlns=""/></с></к>`},
+		// из нескольких префиксов выбирается первый объявленный во внутреннем элементе
+		{[]xmlOp{{"<-", "к", ""}, {"xmlns", "c", "urn:1"}, {"xmlns", "b", "urn:1"}, {"xmlns", "a", "urn:1"},
+			{"<", "эл", "urn:1"}, {"@", "атр", "urn:1"}, {"/", "", ""}, {"/", "", ""}},
+			`<к xmlns:c="urn:1" xmlns:b="urn:1" xmlns:a="urn:1"><c:эл c:атр="1"/></к>`},
+		// атрибут с префиксом в имени объявляет его
+		{[]xmlOp{{"<-", "к", ""}, {"@", "q:атр", "urn:q"}, {"/", "", ""}}, `<к xmlns:q="urn:q" q:атр="1"/>`},
+	}
+	for _, tt := range tests {
+		// выбор префикса не должен зависеть от случайного порядка обхода
+		for i := 0; i < 20; i++ {
+			got, err := writeXMLOps(tt.ops)
+			if err != nil {
+				t.Fatalf("%v: %v", tt.ops, err)
+			}
+			if got != tt.want {
+				t.Fatalf("%v:\n%s\nожидается\n%s", tt.ops, got, tt.want)
+			}
+		}
+	}
+
+	errs := []struct {
+		ops []xmlOp
+		msg string
+	}{
+		{[]xmlOp{{"<", "a:b:эл", "urn:a"}}, "Неверное имя"},
+		{[]xmlOp{{"<", ":эл", "urn:a"}}, "Неверное имя"},
+		{[]xmlOp{{"<", "п:эл", ""}}, "пустым пространством"},
+		{[]xmlOp{{"<-", "к", ""}, {"xmlns", "p", "urn:1"}, {"xmlns", "p", "urn:2"}}, "уже объявлен"},
+		{[]xmlOp{{"<", "p:к", "urn:1"}, {"xmlns", "p", "urn:2"}}, "уже объявлен"},
+		{[]xmlOp{{"<", "к", "urn:1"}, {"xmlns", "", "urn:2"}}, "уже объявлен"},
+		{[]xmlOp{{"<-", "к", ""}, {"xmlns", "p", "urn:1"}, {"<-", "с", ""}, {"@", "p:а", "urn:1"}, {"xmlns", "p", "urn:2"}}, "уже использован"},
+		// атрибут без префикса не относится к пространству по умолчанию
+		{[]xmlOp{{"<", "к", "urn:d"}, {"@", "атр", "urn:d"}}, "Не задан префикс"},
+	}
+	for _, tt := range errs {
+		if _, err := writeXMLOps(tt.ops); err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%v: ошибка %v, ожидается %q", tt.ops, err, tt.msg)
+		}
+	}
+}
+
+func TestXMLErrors(t *testing.T) {
+	for _, c := range []struct {
+		args VMSlice
+		want string
+	}{
+		{VMSlice{}, VMErrorNeedArgs(2).Error()},
+		{VMSlice{VMInt(1)}, VMErrorNeedBinaryData.Error()},
+		{VMSlice{VMString("<а/>"), VMInt(1)}, VMErrorNeedMap.Error()},
+		{VMSlice{VMString("<а/>"), VMStringMap{"ИгнорироватьПробелы": VMString("да")}}, VMErrorNeedBool.Error()},
+		{VMSlice{VMString("<а/>"), VMStringMap{"Кодировка": VMString("koi8-r")}}, VMErrorUnknownCharset.Error()},
+		{VMSlice{VMString("<а/>"), VMStringMap{"Пробелы": VMBool(false)}}, "Неизвестный параметр Пробелы"},
+	} {
+		if err := (&VMXMLReader{}).VMNew(c.args); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Новый ЧтениеXML%v: %v, ожидается %q", c.args, err, c.want)
+		}
+	}
+
+	env := NewEnv()
+	LoadAllBuiltins(env)
+	checkBuiltinErrors(t, env, []builtinErrCase{
+		{"ПрочитатьXML", VMSlice{}, VMErrorNeedArgs(2).Error()},
+		{"ПрочитатьXML", VMSlice{VMString("")}, "нет корневого элемента"},
+		{"ПрочитатьXML", VMSlice{VMString("<!-- только комментарий -->")}, "нет корневого элемента"},
+		{"ПрочитатьXML", VMSlice{VMString("<а><б></а>")}, "Ошибка чтения XML в строке 1"},
+		{"ПрочитатьXML", VMSlice{VMString("<а>\n  <б>\n  </в>\n</а>")}, "в строке 3: ожидается </б>"},
+		{"ПрочитатьXML", VMSlice{VMBinaryData("<?xml version=\"1.0\" encoding=\"windows-1251\"?>\n<\xe0>\n<\xe1>\n</\xe0>")}, "в строке 4: ожидается </б>"},
+		{"ПрочитатьXML", VMSlice{VMBinaryData("\n<\xe0>\n</\xe1>"), VMStringMap{"Кодировка": VMString("windows-1251")}}, "в строке 3: ожидается </а>"},
+		{"ПрочитатьXML", VMSlice{VMString("<а>\n<б>")}, "не закрыт элемент <б>"},
+		{"ПрочитатьXML", VMSlice{VMString("<а б=1/>")}, "Ошибка чтения XML"},
+		{"ПрочитатьXML", VMSlice{VMString("<а>&нет;</а>")}, "Ошибка чтения XML"},
+		{"ПрочитатьXML", VMSlice{VMBinaryData(`<?xml version="1.0" encoding="koi8-r"?><а/>`)}, VMErrorUnknownCharset.Error()},
+		{"ЗаписатьXML", VMSlice{}, VMErrorNeedArgs(2).Error()},
+		{"ЗаписатьXML", VMSlice{VMSlice{}}, "единственным ключом"},
+		{"ЗаписатьXML", VMSlice{VMStringMap{"а": VMInt(1), "б": VMInt(2)}}, "единственным ключом"},
+		{"ЗаписатьXML", VMSlice{VMStringMap{"а": VMInt(1)}, VMSlice{}}, VMErrorNeedMap.Error()},
+		{"ЗаписатьXML", VMSlice{VMStringMap{"а": VMInt(1)}, VMStringMap{"ОбъявлениеXML": VMInt(1)}}, VMErrorNeedBool.Error()},
+		{"ЗаписатьXML", VMSlice{VMStringMap{"а": VMInt(1)}, VMStringMap{"Объявление": VMBool(false)}}, "Неизвестный параметр Объявление"},
+		// имена из ключей структуры не должны нарушать разметку
+		{"ЗаписатьXML", VMSlice{VMStringMap{"а б": VMInt(1)}}, "Неверное имя а б"},
+		{"ЗаписатьXML", VMSlice{VMStringMap{"а": VMStringMap{"1б": VMInt(1)}}}, "Неверное имя 1б"},
+		{"ЗаписатьXML", VMSlice{VMStringMap{"а": VMStringMap{`@б="1"><в`: VMInt(1)}}}, "Неверное имя"},
+		{"ЗаписатьXML", VMSlice{VMStringMap{"а": VMStringMap{"@": VMInt(1)}}}, "Неверное имя"},
+	})
+
+	for _, c := range []struct {
+		args VMSlice
+		want string
+	}{
+		{VMSlice{VMString("файл.xml")}, "Требуется поток записи"},
+		{VMSlice{nil, VMInt(1)}, VMErrorNeedMap.Error()},
+		{VMSlice{VMStringMap{"Отступ": VMBool(true)}}, "Отступ должен быть"},
+		{VMSlice{VMStringMap{"Кодировка": VMInt(1251)}}, VMErrorNeedString.Error()},
+		{VMSlice{VMStringMap{"Отступы": VMInt(2)}}, "Неизвестный параметр Отступы"},
+		{VMSlice{nil, nil, nil}, VMErrorNeedArgs(2).Error()},
+	} {
+		if err := (&VMXMLWriter{}).VMNew(c.args); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Новый ЗаписьXML%v: %v, ожидается %q", c.args, err, c.want)
+		}
+	}
+
+	// нарушения порядка записи
+	var rets VMSlice
+	for _, c := range []struct {
+		calls []func(x *VMXMLWriter) error
+		want  string
+	}{
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьНачалоЭлемента(VMSlice{VMString("а")}, &rets, nil)
+			},
+			func(x *VMXMLWriter) error { return x.ЗаписатьОбъявлениеXML(nil, &rets, nil) },
+		}, "в начале документа"},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьНачалоЭлемента(VMSlice{VMString("")}, &rets, nil)
+			},
+		}, "Не указано имя элемента"},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьНачалоЭлемента(VMSlice{VMString("а<б")}, &rets, nil)
+			},
+		}, "Неверное имя"},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьНачалоЭлемента(VMSlice{VMInt(1)}, &rets, nil)
+			},
+		}, VMErrorNeedString.Error()},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьНачалоЭлемента(VMSlice{VMString("а")}, &rets, nil)
+			},
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьАтрибут(VMSlice{VMString("б в"), VMInt(1)}, &rets, nil)
+			},
+		}, "Неверное имя"},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьНачалоЭлемента(VMSlice{VMString("а")}, &rets, nil)
+			},
+			func(x *VMXMLWriter) error { return x.ЗаписатьТекст(VMSlice{VMString("т")}, &rets, nil) },
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьАтрибут(VMSlice{VMString("б"), VMInt(1)}, &rets, nil)
+			},
+		}, "сразу после начала элемента"},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьНачалоЭлемента(VMSlice{VMString("а")}, &rets, nil)
+			},
+			func(x *VMXMLWriter) error { return x.ЗаписатьТекст(VMSlice{VMString("т")}, &rets, nil) },
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьСоответствиеПространстваИмен(VMSlice{VMString("п"), VMString("urn:п")}, &rets, nil)
+			},
+		}, "сразу после начала элемента"},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error { return x.ЗаписатьТекст(VMSlice{VMString("т")}, &rets, nil) },
+		}, "только внутри элемента"},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьКомментарий(VMSlice{VMString("а--б")}, &rets, nil)
+			},
+		}, "не может содержать --"},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error { return x.ЗаписатьКонецЭлемента(nil, &rets, nil) },
+		}, "Нет открытого элемента"},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьНачалоЭлемента(VMSlice{VMString("а")}, &rets, nil)
+			},
+			func(x *VMXMLWriter) error { return x.Закрыть(nil, &rets, nil) },
+		}, "Не закрыт элемент <а>"},
+		{[]func(x *VMXMLWriter) error{
+			func(x *VMXMLWriter) error { return x.Закрыть(nil, &rets, nil) },
+			func(x *VMXMLWriter) error {
+				return x.ЗаписатьНачалоЭлемента(VMSlice{VMString("а")}, &rets, nil)
+			},
+		}, VMErrorStreamClosed.Error()},
+	} {
+		x := &VMXMLWriter{}
+		x.VMRegister()
+		var err error
+		for _, call := range c.calls {
+			if err = call(x); err != nil {
+				break
+			}
+		}
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("ошибка %v, ожидается %q", err, c.want)
+		}
+	}
+}
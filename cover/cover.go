@@ -0,0 +1,238 @@
+// Package cover - профиль покрытия кода на языке Гонец: запись и чтение файла профиля,
+// сводка по файлам и функциям, отчет HTML с размеченным исходным текстом.
+// Профиль собирается вирт. машиной, см. bincode.StartCoverage
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Pos - позиция в исходном тексте
+type Pos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Pos) less(q Pos) bool {
+	if p.File != q.File {
+		return p.File < q.File
+	}
+	if p.Line != q.Line {
+		return p.Line < q.Line
+	}
+	return p.Column < q.Column
+}
+
+// Stmt - оператор (выражение) исходного текста и количество его исполнений
+type Stmt struct {
+	Pos
+	Count uint64
+}
+
+// Branch - условный переход и количество исполнений, когда условие было истинным и ложным
+type Branch struct {
+	Pos
+	True, False uint64
+}
+
+// Func - функция, операторы которой находятся в строках от Pos.Line до EndLine
+type Func struct {
+	Pos
+	Name    string
+	EndLine int
+}
+
+// Profile - профиль покрытия
+type Profile struct {
+	Stmts    []Stmt
+	Branches []Branch
+	Funcs    []Func
+}
+
+const header = "mode: count"
+
+// Merge добавляет в профиль данные из q, счетчики одинаковых позиций суммируются
+func (p *Profile) Merge(q *Profile) {
+	stmts := make(map[Pos]int, len(p.Stmts))
+	for i, s := range p.Stmts {
+		stmts[s.Pos] = i
+	}
+	for _, s := range q.Stmts {
+		if i, ok := stmts[s.Pos]; ok {
+			p.Stmts[i].Count += s.Count
+		} else {
+			stmts[s.Pos] = len(p.Stmts)
+			p.Stmts = append(p.Stmts, s)
+		}
+	}
+	branches := make(map[Pos]int, len(p.Branches))
+	for i, b := range p.Branches {
+		branches[b.Pos] = i
+	}
+	for _, b := range q.Branches {
+		if i, ok := branches[b.Pos]; ok {
+			p.Branches[i].True += b.True
+			p.Branches[i].False += b.False
+		} else {
+			branches[b.Pos] = len(p.Branches)
+			p.Branches = append(p.Branches, b)
+		}
+	}
+	funcs := make(map[Pos]bool, len(p.Funcs))
+	for _, f := range p.Funcs {
+		funcs[f.Pos] = true
+	}
+	for _, f := range q.Funcs {
+		if !funcs[f.Pos] {
+			funcs[f.Pos] = true
+			p.Funcs = append(p.Funcs, f)
+		}
+	}
+	p.sort()
+}
+
+func (p *Profile) sort() {
+	sort.Slice(p.Stmts, func(i, j int) bool { return p.Stmts[i].Pos.less(p.Stmts[j].Pos) })
+	sort.Slice(p.Branches, func(i, j int) bool { return p.Branches[i].Pos.less(p.Branches[j].Pos) })
+	sort.Slice(p.Funcs, func(i, j int) bool { return p.Funcs[i].Pos.less(p.Funcs[j].Pos) })
+}
+
+// Files возвращает отсортированный список файлов профиля
+func (p *Profile) Files() []string {
+	m := make(map[string]bool)
+	for _, s := range p.Stmts {
+		m[s.File] = true
+	}
+	files := make([]string, 0, len(m))
+	for f := range m {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Write записывает профиль в текстовом формате, по одной записи в строке:
+//
+//	mode: count
+//	"файл.gnc" stmt строка:колонка количество
+//	"файл.gnc" branch строка:колонка истина ложь
+//	"файл.gnc" func строка:колонка-последняястрока "имя"
+func (p *Profile) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, header)
+	for _, f := range p.Funcs {
+		fmt.Fprintf(bw, "%q func %d:%d-%d %q\n", f.File, f.Line, f.Column, f.EndLine, f.Name)
+	}
+	for _, s := range p.Stmts {
+		fmt.Fprintf(bw, "%q stmt %d:%d %d\n", s.File, s.Line, s.Column, s.Count)
+	}
+	for _, b := range p.Branches {
+		fmt.Fprintf(bw, "%q branch %d:%d %d %d\n", b.File, b.Line, b.Column, b.True, b.False)
+	}
+	return bw.Flush()
+}
+
+// Parse читает профиль, записанный Write. Несколько профилей, записанных подряд, объединяются
+func Parse(r io.Reader) (*Profile, error) {
+	p := &Profile{}
+	sc := bufio.NewScanner(r)
+	ln := 0
+	for sc.Scan() {
+		ln++
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || s == header {
+			continue
+		}
+		if err := p.parseLine(s); err != nil {
+			return nil, fmt.Errorf("строка %d: %v", ln, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	// объединение одинаковых позиций из разных профилей
+	res := &Profile{}
+	res.Merge(p)
+	return res, nil
+}
+
+func (p *Profile) parseLine(s string) error {
+	qf, file, err := quotedPrefix(s)
+	if err != nil {
+		return fmt.Errorf("неверное имя файла: %v", err)
+	}
+	fields := strings.Fields(s[len(qf):])
+	if len(fields) < 2 {
+		return fmt.Errorf("неполная запись")
+	}
+	var line, col int
+	if _, err := fmt.Sscanf(fields[1], "%d:%d", &line, &col); err != nil {
+		return fmt.Errorf("неверная позиция %q", fields[1])
+	}
+	pos := Pos{File: file, Line: line, Column: col}
+	switch fields[0] {
+	case "stmt":
+		if len(fields) != 3 {
+			return fmt.Errorf("неверная запись оператора")
+		}
+		n, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return err
+		}
+		p.Stmts = append(p.Stmts, Stmt{Pos: pos, Count: n})
+	case "branch":
+		if len(fields) != 4 {
+			return fmt.Errorf("неверная запись ветвления")
+		}
+		t, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return err
+		}
+		f, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			return err
+		}
+		p.Branches = append(p.Branches, Branch{Pos: pos, True: t, False: f})
+	case "func":
+		var end int
+		if _, err := fmt.Sscanf(fields[1], "%d:%d-%d", &line, &col, &end); err != nil {
+			return fmt.Errorf("неверная позиция функции %q", fields[1])
+		}
+		i := strings.Index(s[len(qf):], "\"")
+		if i < 0 {
+			return fmt.Errorf("не указано имя функции")
+		}
+		name, err := strconv.Unquote(strings.TrimSpace(s[len(qf)+i:]))
+		if err != nil {
+			return fmt.Errorf("неверное имя функции: %v", err)
+		}
+		p.Funcs = append(p.Funcs, Func{Pos: pos, Name: name, EndLine: end})
+	default:
+		return fmt.Errorf("неизвестный вид записи %q", fields[0])
+	}
+	return nil
+}
+
+// quotedPrefix выделяет строку в двойных кавычках в начале s, записанную через %q,
+// и возвращает ее вместе с кавычками и раскодированное значение
+func quotedPrefix(s string) (string, string, error) {
+	if !strings.HasPrefix(s, "\"") {
+		return "", "", strconv.ErrSyntax
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			return s[:i+1], v, err
+		}
+	}
+	return "", "", strconv.ErrSyntax
+}
@@ -0,0 +1,120 @@
+package cover
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const profileText = `mode: count
+"а б.gnc" func 1:1-4 "Функция \"один\""
+"а б.gnc" stmt 2:2 3
+"а б.gnc" stmt 3:3 0
+"а б.gnc" stmt 4:2 3
+"а б.gnc" stmt 6:1 1
+"а б.gnc" branch 2:2 3 0
+`
+
+const source = `функция один(х)
+	если х > 0 тогда
+		возврат 1
+	возврат 0
+конецфункции
+один(1)
+`
+
+func TestParseWrite(t *testing.T) {
+	p, err := Parse(strings.NewReader(profileText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Stmts) != 4 || len(p.Branches) != 1 || len(p.Funcs) != 1 {
+		t.Fatalf("неверный профиль: %+v", p)
+	}
+	if f := p.Funcs[0]; f.File != "а б.gnc" || f.Name != `Функция "один"` || f.EndLine != 4 {
+		t.Errorf("неверная функция: %+v", f)
+	}
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != profileText {
+		t.Errorf("получено:\n%s\nожидалось:\n%s", buf.String(), profileText)
+	}
+
+	// профили, записанные подряд, объединяются
+	p, err = Parse(strings.NewReader(profileText + profileText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Stmts) != 4 || p.Stmts[0].Count != 6 || p.Branches[0].True != 6 {
+		t.Errorf("неверное объединение: %+v", p)
+	}
+
+	for _, s := range []string{
+		`"ф.gnc" stmt 1 2`,
+		`ф.gnc stmt 1:2 3`,
+		`"ф.gnc stmt 1:2 3`,
+		`"ф\q.gnc" stmt 1:2 3`,
+	} {
+		if _, err := Parse(strings.NewReader(s)); err == nil {
+			t.Errorf("%s: ожидалась ошибка разбора", s)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	p, err := Parse(strings.NewReader(profileText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := p.Summary()
+	if len(sum) != 2 || sum[0].Name != MainFunc || sum[1].Name != `Функция "один"` {
+		t.Fatalf("неверная сводка: %+v", sum)
+	}
+	if c := sum[1].Counts; c.Stmts != 3 || c.StmtsCovered != 2 || c.Branches != 2 || c.BranchesCovered != 1 {
+		t.Errorf("неверное покрытие функции: %+v", c)
+	}
+	if c := p.Total(); c.StmtPercent() != 75 || c.BranchPercent() != 50 {
+		t.Errorf("неверный итог: %+v", c)
+	}
+	var buf bytes.Buffer
+	if err := p.WriteSummary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"а б.gnc:1:", "итого по файлу", "операторы 75.0% (3/4)", "ветвления 50.0% (1/2)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("в сводке нет %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	p, err := Parse(strings.NewReader(profileText + `"нет.gnc" stmt 1:1 0` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = p.WriteHTML(&buf, func(name string) ([]byte, error) {
+		if name == "а б.gnc" {
+			return []byte(source), nil
+		}
+		return nil, errors.New("файл не найден")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := buf.String()
+	for _, want := range []string{
+		`<span class="part" title="исполнений: 3` + "\n" + `колонка 2: условие ни разу не было ложным"><span class="num">2</span>	если х &gt; 0 тогда</span>`,
+		`<span class="miss" title="исполнений: 0"><span class="num">3</span>`,
+		`<span class="cov" title="исполнений: 3"><span class="num">4</span>`,
+		`<span class=""><span class="num">5</span>конецфункции</span>`,
+		`файл не найден`,
+	} {
+		if !strings.Contains(res, want) {
+			t.Errorf("в отчете нет %q", want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package cover
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// MainFunc - название кода модуля вне функций в сводке
+const MainFunc = "[главный модуль]"
+
+// Counts - количество операторов и исходов ветвлений, всего и исполненных
+type Counts struct {
+	Stmts, StmtsCovered       int
+	Branches, BranchesCovered int // у каждого ветвления два исхода: истина и ложь
+}
+
+func (c *Counts) add(d Counts) {
+	c.Stmts += d.Stmts
+	c.StmtsCovered += d.StmtsCovered
+	c.Branches += d.Branches
+	c.BranchesCovered += d.BranchesCovered
+}
+
+// StmtPercent возвращает процент исполненных операторов
+func (c Counts) StmtPercent() float64 {
+	return percent(c.StmtsCovered, c.Stmts)
+}
+
+// BranchPercent возвращает процент исполненных исходов ветвлений
+func (c Counts) BranchPercent() float64 {
+	return percent(c.BranchesCovered, c.Branches)
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// FuncCounts - покрытие одной функции
+type FuncCounts struct {
+	Func
+	Counts
+}
+
+// Summary возвращает покрытие по функциям в порядке файлов и строк,
+// код вне функций учитывается отдельной записью MainFunc в каждом файле
+func (p *Profile) Summary() []FuncCounts {
+	var res []FuncCounts
+	for _, file := range p.Files() {
+		main := FuncCounts{Func: Func{Pos: Pos{File: file}, Name: MainFunc}}
+		var funcs []FuncCounts
+		for _, f := range p.Funcs {
+			if f.File == file {
+				funcs = append(funcs, FuncCounts{Func: f})
+			}
+		}
+		// позиция относится к самой вложенной функции, содержащей строку
+		owner := func(line int) *FuncCounts {
+			var fc *FuncCounts
+			for i := range funcs {
+				f := &funcs[i]
+				if f.Line <= line && line <= f.EndLine && (fc == nil || f.Line >= fc.Line) {
+					fc = f
+				}
+			}
+			if fc == nil {
+				return &main
+			}
+			return fc
+		}
+		for _, s := range p.Stmts {
+			if s.File != file {
+				continue
+			}
+			c := &owner(s.Line).Counts
+			c.Stmts++
+			if s.Count > 0 {
+				c.StmtsCovered++
+			}
+		}
+		for _, b := range p.Branches {
+			if b.File != file {
+				continue
+			}
+			c := &owner(b.Line).Counts
+			c.Branches += 2
+			if b.True > 0 {
+				c.BranchesCovered++
+			}
+			if b.False > 0 {
+				c.BranchesCovered++
+			}
+		}
+		if main.Stmts > 0 {
+			res = append(res, main)
+		}
+		res = append(res, funcs...)
+	}
+	return res
+}
+
+// Total возвращает покрытие всего профиля
+func (p *Profile) Total() Counts {
+	var c Counts
+	for _, fc := range p.Summary() {
+		c.add(fc.Counts)
+	}
+	return c
+}
+
+// WriteSummary выводит покрытие операторов и ветвлений по функциям, итоги по файлам и общий итог
+func (p *Profile) WriteSummary(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	var total Counts
+	sum := p.Summary()
+	for i, fc := range sum {
+		if fc.Name == MainFunc {
+			fmt.Fprintf(tw, "%s:\t%s\t%s\n", fc.File, fc.Name, formatCounts(fc.Counts))
+		} else {
+			fmt.Fprintf(tw, "%s:%d:\t%s\t%s\n", fc.File, fc.Line, fc.Name, formatCounts(fc.Counts))
+		}
+		total.add(fc.Counts)
+		if i == len(sum)-1 || sum[i+1].File != fc.File {
+			var fileTotal Counts
+			for _, f := range sum {
+				if f.File == fc.File {
+					fileTotal.add(f.Counts)
+				}
+			}
+			fmt.Fprintf(tw, "%s\tитого по файлу\t%s\n", fc.File, formatCounts(fileTotal))
+		}
+	}
+	fmt.Fprintf(tw, "итого\t\t%s\n", formatCounts(total))
+	return tw.Flush()
+}
+
+func formatCounts(c Counts) string {
+	s := fmt.Sprintf("операторы %.1f%% (%d/%d)", c.StmtPercent(), c.StmtsCovered, c.Stmts)
+	if c.Branches > 0 {
+		s += fmt.Sprintf("\tветвления %.1f%% (%d/%d)", c.BranchPercent(), c.BranchesCovered, c.Branches)
+	}
+	return s
+}
+
+// состояние строки в отчете HTML
+const (
+	lineNone    = ""
+	lineCovered = "cov"
+	linePartial = "part"
+	lineMissed  = "miss"
+)
+
+type htmlLine struct {
+	Num   int
+	Text  string
+	Class string
+	Title string
+}
+
+type htmlFile struct {
+	ID      int
+	Name    string
+	Summary string
+	Error   string
+	Lines   []htmlLine
+}
+
+// WriteHTML записывает отчет HTML, в котором строки исходного текста размечены по покрытию:
+// исполненные, частично исполненные (не все операторы или не все исходы условий) и неисполненные.
+// Исходный текст файлов профиля читается через readFile
+func (p *Profile) WriteHTML(w io.Writer, readFile func(name string) ([]byte, error)) error {
+	var files []htmlFile
+	for i, file := range p.Files() {
+		hf := htmlFile{ID: i, Name: file}
+		var c Counts
+		for _, fc := range p.Summary() {
+			if fc.File == file {
+				c.add(fc.Counts)
+			}
+		}
+		hf.Summary = strings.Replace(formatCounts(c), "\t", ", ", -1)
+		src, err := readFile(file)
+		if err != nil {
+			hf.Error = err.Error()
+		} else {
+			hf.Lines = p.htmlLines(file, string(src))
+		}
+		files = append(files, hf)
+	}
+	return htmlTemplate.Execute(w, files)
+}
+
+func (p *Profile) htmlLines(file, src string) []htmlLine {
+	lines := strings.Split(strings.TrimRight(src, "\n"), "\n")
+	res := make([]htmlLine, len(lines))
+	stmts := make(map[int][]Stmt)
+	for _, s := range p.Stmts {
+		if s.File == file {
+			stmts[s.Line] = append(stmts[s.Line], s)
+		}
+	}
+	branches := make(map[int][]Branch)
+	for _, b := range p.Branches {
+		if b.File == file {
+			branches[b.Line] = append(branches[b.Line], b)
+		}
+	}
+	for i, text := range lines {
+		ln := i + 1
+		hl := htmlLine{Num: ln, Text: strings.TrimRight(text, "\r")}
+		ss := stmts[ln]
+		if len(ss) > 0 {
+			var max uint64
+			run := 0
+			for _, s := range ss {
+				if s.Count > 0 {
+					run++
+				}
+				if s.Count > max {
+					max = s.Count
+				}
+			}
+			var notes []string
+			notes = append(notes, fmt.Sprintf("исполнений: %d", max))
+			partial := run < len(ss)
+			for _, b := range branches[ln] {
+				if b.True+b.False == 0 {
+					continue
+				}
+				if b.True == 0 {
+					partial = true
+					notes = append(notes, fmt.Sprintf("колонка %d: условие ни разу не было истинным", b.Column))
+				} else if b.False == 0 {
+					partial = true
+					notes = append(notes, fmt.Sprintf("колонка %d: условие ни разу не было ложным", b.Column))
+				}
+			}
+			switch {
+			case run == 0:
+				hl.Class = lineMissed
+			case partial:
+				hl.Class = linePartial
+			default:
+				hl.Class = lineCovered
+			}
+			hl.Title = strings.Join(notes, "\n")
+		}
+		res[i] = hl
+	}
+	return res
+}
+
+var htmlTemplate = template.Must(template.New("cover").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Покрытие кода Гонец</title>
+<style>
+body { background: #fff; color: #222; font-family: sans-serif; margin: 0; }
+#nav { background: #eee; padding: 8px; position: sticky; top: 0; }
+#nav span { margin-left: 16px; }
+.file { display: none; }
+.file.active { display: block; }
+pre { font-family: Menlo, monospace; font-size: 13px; margin: 8px; }
+.num { color: #999; display: inline-block; text-align: right; width: 4em; margin-right: 1em; user-select: none; }
+.cov { background: #c8f0c8; }
+.part { background: #f5e6a8; }
+.miss { background: #f5c0c0; }
+.err { color: #a00; margin: 8px; }
+</style>
+</head>
+<body>
+<div id="nav">
+<select id="files" onchange="show(this.value)">
+{{range .}}<option value="{{.ID}}">{{.Name}} ({{.Summary}})</option>
+{{end}}</select>
+<span class="cov">исполнено</span>
+<span class="part">частично</span>
+<span class="miss">не исполнено</span>
+</div>
+{{range .}}<div class="file" id="file{{.ID}}">
+{{if .Error}}<div class="err">{{.Error}}</div>{{end}}<pre>
+{{range .Lines}}<span class="{{.Class}}"{{if .Title}} title="{{.Title}}"{{end}}><span class="num">{{.Num}}</span>{{.Text}}</span>
+{{end}}</pre>
+</div>
+{{end}}<script>
+function show(id) {
+	var files = document.getElementsByClassName("file");
+	for (var i = 0; i < files.length; i++) {
+		files[i].className = files[i].id == "file" + id ? "file active" : "file";
+	}
+}
+show(0);
+</script>
+</body>
+</html>
+`))
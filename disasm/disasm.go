@@ -0,0 +1,241 @@
+// Package disasm - листинг байткода вирт. машины Гонец (gonec disasm).
+// Модули и функции выводятся отдельными разделами, у каждой инструкции указываются ее индекс и позиция
+// в исходном тексте, у переходов - индексы инструкций, на которые указывают метки.
+// Перед инструкциями могут выводиться соответствующие им строки исходного текста
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/covrom/gonec/bincode/binstmt"
+	"github.com/covrom/gonec/names"
+)
+
+// MainModule - название раздела кода верхнего уровня
+const MainModule = "[главный модуль]"
+
+// Options - параметры листинга
+type Options struct {
+	File   string // имя файла, выводится в заголовке
+	Source []byte // исходный текст, nil - строки исходного текста не выводятся
+	Names  bool   // вывести таблицу имен кода
+}
+
+// Fprint выводит листинг кода code в w
+func Fprint(w io.Writer, code binstmt.BinCode, opts Options) error {
+	p := &printer{
+		w:     bufio.NewWriter(w),
+		names: code.Names(),
+	}
+	if opts.Source != nil {
+		p.src = strings.Split(strings.Replace(string(opts.Source), "\r", "", -1), "\n")
+	}
+	if opts.File != "" {
+		p.printf("; файл %s\n", opts.File)
+	}
+	p.printf("; всего инструкций %d\n", countStmts(code))
+	p.unit(MainModule, code)
+	if opts.Names {
+		p.nameTable()
+	}
+	return p.w.Flush()
+}
+
+// countStmts возвращает количество инструкций с учетом вложенных модулей
+func countStmts(code binstmt.BinCode) int {
+	n := len(code.Code)
+	for _, st := range code.Code {
+		if m, ok := st.(*binstmt.BinMODULE); ok {
+			n += countStmts(m.Code)
+		}
+	}
+	return n
+}
+
+type printer struct {
+	w     *bufio.Writer
+	names *names.EnvNames
+	src   []string
+	line  int // последняя выведенная строка исходного текста
+	max   int // наибольшая выведенная строка исходного текста в разделе
+}
+
+func (p *printer) printf(format string, a ...interface{}) {
+	fmt.Fprintf(p.w, format, a...)
+}
+
+// unit выводит код модуля, затем каждую его функцию и вложенные модули
+func (p *printer) unit(title string, code binstmt.BinCode) {
+	p.printf("\n%s, инструкций %d, меток %d, максимальный регистр r%d\n", title, len(code.Code), len(code.Labels), code.MaxReg)
+	p.line, p.max = 0, 0
+	p.block(code, 0, len(code.Code))
+
+	for i, st := range code.Code {
+		switch s := st.(type) {
+		case *binstmt.BinFUNC:
+			start, end := i+1, p.label(code, s.LabelEnd)
+			p.printf("\n%s, инструкции %04d-%04d, максимальный регистр r%d\n", p.funcTitle(s), start, end-1, s.MaxReg)
+			p.line, p.max = 0, 0
+			p.block(code, start, end)
+		case *binstmt.BinMODULE:
+			p.unit("модуль "+p.name(s.Name), s.Code)
+		}
+	}
+}
+
+// block выводит инструкции с from по to, не включая to. Тела вложенных функций пропускаются
+func (p *printer) block(code binstmt.BinCode, from, to int) {
+	for i := from; i < to && i < len(code.Code); i++ {
+		st := code.Code[i]
+		p.source(sourceLine(code, i, to))
+		p.stmt(code, i, st)
+		if s, ok := st.(*binstmt.BinFUNC); ok {
+			i = p.label(code, s.LabelEnd) - 1
+		}
+	}
+}
+
+// sourceLine возвращает строку исходного текста инструкции i. У загрузки констант позиции нет,
+// она относится к строке следующей инструкции
+func sourceLine(code binstmt.BinCode, i, to int) int {
+	for ; i < to && i < len(code.Code); i++ {
+		if ln := code.Code[i].Position().Line; ln > 1 {
+			return ln - 1 // без учета вставки модуля _ по умолчанию
+		}
+	}
+	return 0
+}
+
+// source выводит строку исходного текста ln, если она отличается от предыдущей выведенной
+func (p *printer) source(ln int) {
+	if p.src == nil || ln < 1 || ln > len(p.src) || ln == p.line {
+		return
+	}
+	from := ln
+	if p.max > 0 && p.max < ln && ln-p.max <= 3 {
+		// пропущенные строки без инструкций (КонецЕсли, комментарии) выводятся вместе со следующей
+		from = p.max + 1
+	}
+	for l := from; l <= ln; l++ {
+		p.printf("%11s// %d: %s\n", "", l, strings.TrimSpace(p.src[l-1]))
+	}
+	p.line = ln
+	if ln > p.max {
+		p.max = ln
+	}
+}
+
+func (p *printer) stmt(code binstmt.BinCode, i int, st binstmt.BinStmt) {
+	pos := ""
+	if ps := st.Position(); ps.Line > 1 {
+		pos = fmt.Sprintf("%d:%d", ps.Line-1, ps.Column)
+	}
+	var text string
+	if ns, ok := st.(binstmt.NameStringer); ok {
+		text = ns.StringNames(p.names)
+	} else {
+		text = fmt.Sprint(st)
+	}
+	if _, ok := st.(*binstmt.BinMODULE); ok {
+		text = "MODULE " + p.name(st.(*binstmt.BinMODULE).Name)
+	}
+	indent := "\t"
+	if _, ok := st.(*binstmt.BinLABEL); ok {
+		indent = ""
+	}
+	var refs []string
+	for _, l := range labelRefs(st) {
+		refs = append(refs, fmt.Sprintf("L%d=%04d", l, p.label(code, l)))
+	}
+	if len(refs) > 0 {
+		text += "\t; " + strings.Join(refs, ", ")
+	}
+	p.printf("%04d  %-7s%s%s\n", i, pos, indent, text)
+}
+
+// label возвращает индекс инструкции метки
+func (p *printer) label(code binstmt.BinCode, l int) int {
+	if l >= 0 && l < len(code.Labels) {
+		return code.Labels[l]
+	}
+	return len(code.Code)
+}
+
+func (p *printer) name(id int) string {
+	if p.names != nil {
+		if _, ok := p.names.GetLowerCaseOk(id); ok {
+			return p.names.Get(id)
+		}
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+func (p *printer) funcTitle(s *binstmt.BinFUNC) string {
+	name := "[анонимная функция]"
+	if s.Name != 0 {
+		name = p.name(s.Name)
+	}
+	args := make([]string, len(s.Args))
+	for i, a := range s.Args {
+		args[i] = p.name(a)
+	}
+	vrg := ""
+	if s.VarArg {
+		vrg = "..."
+	}
+	return fmt.Sprintf("функция %s(%s%s)", name, strings.Join(args, ", "), vrg)
+}
+
+// nameTable выводит таблицу имен, в которой зарегистрированы идентификаторы кода
+func (p *printer) nameTable() {
+	if p.names == nil {
+		return
+	}
+	p.printf("\nтаблица имен\n")
+	for id := 1; ; id++ {
+		if _, ok := p.names.GetLowerCaseOk(id); !ok {
+			break
+		}
+		n := p.names.Get(id)
+		if n == "" {
+			continue
+		}
+		p.printf("%6d  %s\n", id, n)
+	}
+}
+
+// labelRefs возвращает метки, на которые ссылается инструкция
+func labelRefs(st binstmt.BinStmt) []int {
+	switch s := st.(type) {
+	case *binstmt.BinJMP:
+		return []int{s.JumpTo}
+	case *binstmt.BinJTRUE:
+		return []int{s.JumpTo}
+	case *binstmt.BinJFALSE:
+		return []int{s.JumpTo}
+	case *binstmt.BinTRY:
+		return []int{s.JumpTo}
+	case *binstmt.BinCATCH:
+		return []int{s.JumpTo}
+	case *binstmt.BinNEXT:
+		return []int{s.JumpTo}
+	case *binstmt.BinNEXTNUM:
+		return []int{s.JumpTo}
+	case *binstmt.BinPOPTRY:
+		return []int{s.CatchLabel}
+	case *binstmt.BinPOPFOR:
+		return []int{s.ContinueLabel}
+	case *binstmt.BinFOREACH:
+		return []int{s.BreakLabel, s.ContinueLabel}
+	case *binstmt.BinFORNUM:
+		return []int{s.BreakLabel, s.ContinueLabel}
+	case *binstmt.BinWHILE:
+		return []int{s.BreakLabel, s.ContinueLabel}
+	case *binstmt.BinFUNC:
+		return []int{s.LabelStart, s.LabelEnd}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package disasm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/bincode"
+	"github.com/covrom/gonec/bincode/binstmt"
+)
+
+const src = `а = 1
+Функция ф(х)
+	Если х > 1 Тогда
+		Возврат х
+	КонецЕсли
+	Возврат 0
+КонецФункции
+Сообщить(ф(а))
+`
+
+func listing(t *testing.T, code binstmt.BinCode, opts Options) string {
+	var buf bytes.Buffer
+	if err := Fprint(&buf, code, opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFprint(t *testing.T) {
+	_, code, err := bincode.ParseSrc(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := listing(t, code, Options{File: "пример.gnc", Source: []byte(src), Names: true})
+	for _, want := range []string{
+		"; файл пример.gnc\n",
+		"[главный модуль], инструкций",
+		"           // 1: а = 1\n0000         \tLOAD r0, 1\n0001  1:1    \tSET \"а\", r0\n",
+		"0002  2:1    \tFUNC r0, \"ф\" (х) BEGIN L1 END L2\t; L1=0003, L2=0016\n0016  2:1    L2:\n",
+		"функция ф(х), инструкции 0003-0015",
+		"\tJFALSE r0, L4\t; L4=0011\n",
+		"           // 5: КонецЕсли\n           // 6: Возврат 0\n",
+		"0018  8:10   \tCALL \"ф\"",
+		"таблица имен\n     1  _\n     2  а\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("нет %q в листинге:\n%s", want, got)
+		}
+	}
+	// тело функции выводится только в ее разделе
+	if strings.Count(got, "JFALSE") != 1 {
+		t.Errorf("тело функции выведено несколько раз:\n%s", got)
+	}
+}
+
+func TestFprintGNX(t *testing.T) {
+	_, code, err := bincode.ParseSrc("Модуль М\nб = [1, 2]\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := binstmt.WriteBinCode(&buf, code); err != nil {
+		t.Fatal(err)
+	}
+	gnx, err := binstmt.ReadBinCode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := listing(t, gnx, Options{Names: true})
+	for _, want := range []string{
+		"\tMODULE М\n",
+		"модуль М, инструкций",
+		"\tSET \"б\", r0\n",
+		"     3  М\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("нет %q в листинге:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "//") {
+		t.Errorf("выведены строки исходного текста:\n%s", got)
+	}
+}
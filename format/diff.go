@@ -0,0 +1,120 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// diffContext - количество неизмененных строк вокруг каждого изменения
+const diffContext = 3
+
+type diffLine struct {
+	kind   byte // ' ', '-' или '+'
+	text   string
+	ai, bi int // количество строк a и b до текущей
+}
+
+// Diff возвращает разницу между исходным текстом a и отформатированным b в унифицированном формате,
+// если тексты совпадают - возвращается пустая строка
+func Diff(name, a, b string) string {
+	if a == b {
+		return ""
+	}
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", name, name)
+	for i := 0; i < len(lines); {
+		if lines[i].kind == ' ' {
+			i++
+			continue
+		}
+		// изменения, разделенные не более чем удвоенным контекстом, попадают в один блок
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(lines) && j <= end+2*diffContext; j++ {
+			if lines[j].kind != ' ' {
+				end = j
+			}
+		}
+		i = end + 1
+		end += diffContext + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+		na, nb := 0, 0
+		for _, l := range lines[start:end] {
+			if l.kind != '+' {
+				na++
+			}
+			if l.kind != '-' {
+				nb++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(lines[start].ai, na), hunkRange(lines[start].bi, nb))
+		for _, l := range lines[start:end] {
+			buf.WriteByte(l.kind)
+			buf.WriteString(l.text)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.String()
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines строит последовательность правок по наибольшей общей подпоследовательности строк
+func diffLines(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	rv := make([]diffLine, 0, n+m)
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			rv = append(rv, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			rv = append(rv, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			rv = append(rv, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+	return rv
+}
@@ -0,0 +1,799 @@
+// Package format реализует форматирование исходного текста на языке Гонец (gonec fmt).
+// Текст разбирается в дерево AST, которое затем печатается в каноническом виде:
+// с единым написанием ключевых слов, отступами блоков и выравниванием длинных литералов.
+// Комментарии сохраняются, пустые строки между операторами сворачиваются в одну.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/covrom/gonec/ast"
+	"github.com/covrom/gonec/names"
+	"github.com/covrom/gonec/parser"
+	"github.com/covrom/gonec/pos"
+)
+
+// Style - стиль написания ключевых слов
+type Style int
+
+const (
+	StyleTitle Style = iota // Если ... Тогда ... КонецЕсли
+	StyleLower              // если ... тогда ... конецесли
+	StyleUpper              // ЕСЛИ ... ТОГДА ... КОНЕЦЕСЛИ
+)
+
+// ParseStyle возвращает стиль по его названию: title, lower или upper
+func ParseStyle(s string) (Style, error) {
+	switch strings.ToLower(s) {
+	case "title", "":
+		return StyleTitle, nil
+	case "lower":
+		return StyleLower, nil
+	case "upper":
+		return StyleUpper, nil
+	}
+	return StyleTitle, fmt.Errorf("Неизвестный стиль ключевых слов %q, допустимы title, lower, upper", s)
+}
+
+// MaxWidth - ширина строки, при превышении которой литералы структур и массивов
+// выводятся по одному элементу в строке
+const MaxWidth = 100
+
+// tabWidth используется только для подсчета ширины строки
+const tabWidth = 4
+
+// header добавляется перед разбором, так же как это делает bincode.ParseSrc
+const header = "Модуль _\n"
+
+const anonFunc = "<анонимная функция>"
+
+// keywords - каноническое написание ключевых слов в стиле StyleTitle
+var keywords = map[string]string{
+	"если":              "Если",
+	"тогда":             "Тогда",
+	"иначеесли":         "ИначеЕсли",
+	"иначе":             "Иначе",
+	"конецесли":         "КонецЕсли",
+	"для":               "Для",
+	"каждого":           "Каждого",
+	"из":                "Из",
+	"по":                "По",
+	"пока":              "Пока",
+	"цикл":              "Цикл",
+	"конеццикла":        "КонецЦикла",
+	"функция":           "Функция",
+	"конецфункции":      "КонецФункции",
+	"возврат":           "Возврат",
+	"вызватьисключение": "ВызватьИсключение",
+	"попытка":           "Попытка",
+	"исключение":        "Исключение",
+	"конецпопытки":      "КонецПопытки",
+	"выбор":             "Выбор",
+	"когда":             "Когда",
+	"другое":            "Другое",
+	"конецвыбора":       "КонецВыбора",
+	"прервать":          "Прервать",
+	"продолжить":        "Продолжить",
+	"старт":             "Старт",
+	"новый":             "Новый",
+	"канал":             "Канал",
+	"модуль":            "Модуль",
+	"истина":            "Истина",
+	"ложь":              "Ложь",
+	"неопределено":      "Неопределено",
+	"null":              "Null",
+	"и":                 "И",
+	"или":               "Или",
+	"не":                "Не",
+
+	"строка":         "Строка",
+	"число":          "Число",
+	"булево":         "Булево",
+	"целоечисло":     "ЦелоеЧисло",
+	"массив":         "Массив",
+	"структура":      "Структура",
+	"дата":           "Дата",
+	"длительность":   "Длительность",
+	"двоичныеданные": "ДвоичныеДанные",
+}
+
+// Keywords возвращает отсортированный список ключевых слов и названий встроенных типов в стиле style
+func Keywords(style Style) []string {
+	p := &printer{style: style}
+	res := make([]string, 0, len(keywords))
+	for k := range keywords {
+		res = append(res, p.kw(k))
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Source форматирует исходный текст программы
+func Source(src string, style Style) (string, error) {
+	full := header + src
+	s := new(parser.Scanner)
+	s.Init(full)
+	nm := names.NewEnvNames()
+	stmts, err := parser.Parse(s, nm)
+	if err != nil {
+		if e, ok := err.(*parser.Error); ok && e.Pos.Line > 1 {
+			// строки считаем без учета добавленного заголовка
+			e.Pos.Line--
+		}
+		return "", err
+	}
+	p := &printer{
+		style:    style,
+		names:    nm,
+		comments: s.Comments(),
+		blank:    blankLines(full),
+		fresh:    true,
+	}
+	p.file(stmts)
+	return p.buf.String(), nil
+}
+
+// blankLines возвращает признаки пустых строк исходного текста, индекс - номер строки
+func blankLines(src string) []bool {
+	lines := strings.Split(src, "\n")
+	rv := make([]bool, len(lines)+2)
+	for i, l := range lines {
+		rv[i+1] = strings.TrimSpace(l) == ""
+	}
+	return rv
+}
+
+type printer struct {
+	style    Style
+	names    *names.EnvNames
+	comments []parser.Comment
+	blank    []bool
+
+	buf    bytes.Buffer
+	indent int
+	bol    bool // текущая позиция в начале строки
+	cline  int  // конец последней строки, содержащей только комментарий
+	flat   bool // вывод в одну строку, используется для оценки ширины выражения
+	inExpr int  // внутри многострочного литерала пустые строки не сохраняются
+
+	lastLine int  // последняя выведенная строка исходного текста
+	fresh    bool // начало блока, пустая строка перед первым элементом не выводится
+}
+
+func (p *printer) kw(k string) string {
+	switch p.style {
+	case StyleLower:
+		return k
+	case StyleUpper:
+		return strings.ToUpper(k)
+	}
+	if t, ok := keywords[k]; ok {
+		return t
+	}
+	return k
+}
+
+func (p *printer) print(s string) {
+	if s == "" {
+		return
+	}
+	if p.bol {
+		for i := 0; i < p.indent; i++ {
+			p.buf.WriteByte('\t')
+		}
+		p.bol = false
+	}
+	p.buf.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.buf.WriteByte('\n')
+	p.bol = true
+}
+
+// column возвращает ширину текущей строки вывода
+func (p *printer) column() int {
+	b := p.buf.Bytes()
+	if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+		b = b[i+1:]
+	}
+	n := 0
+	for _, r := range string(b) {
+		if r == '\t' {
+			n += tabWidth
+		} else {
+			n++
+		}
+	}
+	if p.bol {
+		n += p.indent * tabWidth
+	}
+	return n
+}
+
+// gap выводит одну пустую строку, если в исходном тексте элементы были разделены пустыми строками
+func (p *printer) gap(line int) {
+	if p.fresh || p.inExpr > 0 || p.lastLine == 0 || line <= p.lastLine {
+		return
+	}
+	for l := p.lastLine + 1; l < line && l < len(p.blank); l++ {
+		if p.blank[l] {
+			p.newline()
+			return
+		}
+	}
+}
+
+// flush выводит комментарии, расположенные в исходном тексте до строки line,
+// вызывается только в начале строки вывода
+func (p *printer) flush(line int) {
+	if p.flat {
+		return
+	}
+	for len(p.comments) > 0 && (line < 0 || p.comments[0].Pos.Line < line) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		if c.Inline && p.bol && p.buf.Len() > p.cline {
+			// комментарий в конце строки с кодом остается в конце последней выведенной строки
+			p.buf.Truncate(p.buf.Len() - 1)
+			p.buf.WriteString(" " + c.Text)
+			p.newline()
+		} else {
+			p.gap(c.Pos.Line)
+			p.print(c.Text)
+			p.newline()
+			p.cline = p.buf.Len()
+		}
+		p.fresh = false
+		p.skipTo(c.Pos.Line)
+	}
+}
+
+// skipTo отмечает строки исходного текста до line включительно как выведенные
+func (p *printer) skipTo(line int) {
+	if line > p.lastLine {
+		p.lastLine = line
+	}
+}
+
+func (p *printer) name(id int) string {
+	return p.names.Get(id)
+}
+
+func (p *printer) file(stmts ast.Stmts) {
+	for i, st := range stmts {
+		m, ok := st.(*ast.ModuleStmt)
+		if !ok {
+			p.stmt(st)
+			continue
+		}
+		if i > 0 || m.Name != names.DefaultModule {
+			line := m.Position().Line
+			p.flush(line)
+			p.gap(line)
+			p.print(p.kw("модуль") + " " + p.name(m.Name))
+			p.newline()
+			p.fresh = false
+			p.skipTo(line)
+		}
+		p.stmts(m.Stmts)
+	}
+	p.flush(-1)
+}
+
+func (p *printer) stmts(list ast.Stmts) {
+	for _, st := range list {
+		line := startLine(st)
+		if line > 0 {
+			p.flush(line)
+			p.gap(line)
+		}
+		p.fresh = false
+		p.stmt(st)
+		p.skipTo(endLine(st))
+	}
+}
+
+// block выводит тело блока с отступом, комментарии до строки end остаются внутри блока
+func (p *printer) block(list ast.Stmts, end int) {
+	p.indent++
+	p.fresh = true
+	p.stmts(list)
+	if end > 0 {
+		p.flush(end)
+	}
+	p.fresh = false
+	p.indent--
+}
+
+func (p *printer) line(s string) {
+	p.print(s)
+	p.newline()
+}
+
+func (p *printer) stmt(st ast.Stmt) {
+	switch s := st.(type) {
+	case *ast.ExprStmt:
+		p.expr(s.Expr)
+		p.newline()
+	case *ast.LetsStmt:
+		p.exprs(s.Lhss)
+		p.print(" = ")
+		p.exprs(s.Rhss)
+		p.newline()
+	case *ast.BreakStmt:
+		p.line(p.kw("прервать"))
+	case *ast.ContinueStmt:
+		p.line(p.kw("продолжить"))
+	case *ast.ReturnStmt:
+		p.print(p.kw("возврат"))
+		if len(s.Exprs) > 0 {
+			p.print(" ")
+			p.exprs(s.Exprs)
+		}
+		p.newline()
+	case *ast.ThrowStmt:
+		p.print(p.kw("вызватьисключение") + " ")
+		p.expr(s.Expr)
+		p.newline()
+	case *ast.IfStmt:
+		p.print(p.kw("если") + " ")
+		p.expr(s.If)
+		p.line(" " + p.kw("тогда"))
+		end := s.End.Line
+		if len(s.Else) > 0 {
+			end = s.ElsePos.Line
+		}
+		next := end
+		if len(s.ElseIf) > 0 {
+			next = s.ElseIf[0].Position().Line
+		}
+		p.block(s.Then, next)
+		for i, ei := range s.ElseIf {
+			ei := ei.(*ast.IfStmt)
+			p.skipTo(ei.Position().Line)
+			p.print(p.kw("иначеесли") + " ")
+			p.expr(ei.If)
+			p.line(" " + p.kw("тогда"))
+			next = end
+			if i+1 < len(s.ElseIf) {
+				next = s.ElseIf[i+1].Position().Line
+			}
+			p.block(ei.Then, next)
+		}
+		if len(s.Else) > 0 {
+			p.skipTo(s.ElsePos.Line)
+			p.line(p.kw("иначе"))
+			p.block(s.Else, s.End.Line)
+		}
+		p.line(p.kw("конецесли"))
+	case *ast.ForStmt:
+		p.print(p.kw("для") + " " + p.kw("каждого") + " " + p.name(s.Var) + " " + p.kw("из") + " ")
+		p.expr(s.Value)
+		p.line(" " + p.kw("цикл"))
+		p.block(s.Stmts, s.End.Line)
+		p.line(p.kw("конеццикла"))
+	case *ast.NumForStmt:
+		p.print(p.kw("для") + " " + p.name(s.Name) + " = ")
+		p.expr(s.Expr1)
+		p.print(" " + p.kw("по") + " ")
+		p.expr(s.Expr2)
+		p.line(" " + p.kw("цикл"))
+		p.block(s.Stmts, s.End.Line)
+		p.line(p.kw("конеццикла"))
+	case *ast.LoopStmt:
+		p.print(p.kw("пока") + " ")
+		p.expr(s.Expr)
+		p.line(" " + p.kw("цикл"))
+		p.block(s.Stmts, s.End.Line)
+		p.line(p.kw("конеццикла"))
+	case *ast.TryStmt:
+		p.line(p.kw("попытка"))
+		p.block(s.Try, s.CatchPos.Line)
+		p.skipTo(s.CatchPos.Line)
+		p.line(p.kw("исключение"))
+		p.block(s.Catch, s.End.Line)
+		p.line(p.kw("конецпопытки"))
+	case *ast.SwitchStmt:
+		p.print(p.kw("выбор") + " ")
+		p.expr(s.Expr)
+		p.line(":")
+		p.cases(s.Cases, s.End.Line)
+		p.line(p.kw("конецвыбора"))
+	case *ast.SelectStmt:
+		p.line(p.kw("выбор") + ":")
+		p.cases(s.Cases, s.End.Line)
+		p.line(p.kw("конецвыбора"))
+	default:
+		panic(fmt.Sprintf("Форматирование оператора %T не поддерживается", st))
+	}
+}
+
+// cases выводит ветки оператора Выбор на уровне самого оператора, как в go fmt
+func (p *printer) cases(list ast.Stmts, end int) {
+	for i, st := range list {
+		next := end
+		if i+1 < len(list) {
+			next = list[i+1].Position().Line
+		}
+		p.skipTo(st.Position().Line)
+		switch c := st.(type) {
+		case *ast.CaseStmt:
+			p.print(p.kw("когда") + " ")
+			p.expr(c.Expr)
+			p.line(":")
+			p.block(c.Stmts, next)
+		case *ast.DefaultStmt:
+			p.line(p.kw("другое") + ":")
+			p.block(c.Stmts, next)
+		}
+	}
+}
+
+func (p *printer) exprs(list []ast.Expr) {
+	for i, e := range list {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.expr(e)
+	}
+}
+
+var binOps = map[string]string{
+	"==": "=",
+	"!=": "<>",
+	"&&": "и",
+	"||": "или",
+}
+
+func (p *printer) expr(e ast.Expr) {
+	switch x := e.(type) {
+	case nil:
+	case *ast.NoneExpr:
+	case *ast.NumberExpr:
+		p.print(x.Lit)
+	case *ast.StringExpr:
+		// литерал записывается так же, как в исходном тексте
+		if x.Src != "" {
+			p.print(x.Src)
+		} else {
+			p.print(quote(x.Lit))
+		}
+	case *ast.ConstExpr:
+		p.print(p.kw(x.Value))
+	case *ast.IdentExpr:
+		p.print(x.Lit)
+	case *ast.UnaryExpr:
+		switch x.Operator {
+		case "!":
+			p.print(p.kw("не") + " ")
+		case "-":
+			p.print("-")
+			if u, ok := x.Expr.(*ast.UnaryExpr); ok && u.Operator == "-" {
+				// два минуса подряд сканер прочитает как декремент
+				p.print(" ")
+			}
+		default:
+			p.print(x.Operator)
+		}
+		p.expr(x.Expr)
+	case *ast.ParenExpr:
+		p.print("(")
+		p.expr(x.SubExpr)
+		p.print(")")
+	case *ast.BinOpExpr:
+		p.exprs(x.Lhss)
+		op := x.Operator
+		if o, ok := binOps[op]; ok {
+			op = o
+			if op == "и" || op == "или" {
+				op = p.kw(op)
+			}
+		}
+		p.print(" " + op + " ")
+		p.exprs(x.Rhss)
+	case *ast.AssocExpr:
+		p.expr(x.Lhs)
+		if x.Rhs == nil {
+			p.print(x.Operator)
+		} else {
+			p.print(" " + x.Operator + " ")
+			p.expr(x.Rhs)
+		}
+	case *ast.LetExpr:
+		p.expr(x.Lhs)
+		p.print(" = ")
+		p.expr(x.Rhs)
+	case *ast.TernaryOpExpr:
+		p.print("?(")
+		p.expr(x.Expr)
+		p.print(", ")
+		p.expr(x.Lhs)
+		p.print(", ")
+		p.expr(x.Rhs)
+		p.print(")")
+	case *ast.CallExpr:
+		if x.Go {
+			p.print(p.kw("старт") + " ")
+		}
+		p.print(p.name(x.Name))
+		p.args(x.SubExprs, x.VarArg)
+	case *ast.AnonCallExpr:
+		if x.Go {
+			p.print(p.kw("старт") + " ")
+		}
+		p.expr(x.Expr)
+		p.args(x.SubExprs, x.VarArg)
+	case *ast.MemberExpr:
+		p.expr(x.Expr)
+		p.print("." + p.name(x.Name))
+	case *ast.ItemExpr:
+		p.expr(x.Value)
+		p.print("[")
+		p.expr(x.Index)
+		p.print("]")
+	case *ast.SliceExpr:
+		p.expr(x.Value)
+		p.print("[")
+		p.expr(x.Begin)
+		p.print(":")
+		p.expr(x.End)
+		p.print("]")
+	case *ast.FuncExpr:
+		p.funcExpr(x)
+	case *ast.ArrayExpr:
+		p.list("[", "]", len(x.Exprs), func(i int) (string, ast.Expr) {
+			return "", x.Exprs[i]
+		})
+	case *ast.MapExpr:
+		keys := x.Keys
+		if len(keys) != len(x.MapExpr) {
+			panic("Не определен порядок ключей структуры")
+		}
+		p.list("{", "}", len(keys), func(i int) (string, ast.Expr) {
+			if i < len(x.KeySrc) && x.KeySrc[i] != "" {
+				return x.KeySrc[i] + ":", x.MapExpr[keys[i]]
+			}
+			return quote(keys[i]) + ":", x.MapExpr[keys[i]]
+		})
+	case *ast.ChanExpr:
+		if x.Lhs != nil {
+			p.expr(x.Lhs)
+			p.print(" <- ")
+		} else {
+			p.print("<-")
+		}
+		p.expr(x.Rhs)
+	case *ast.MakeExpr:
+		p.print(p.kw("новый"))
+		if x.TypeExpr != nil {
+			p.print("(")
+			p.expr(x.TypeExpr)
+			p.print(")")
+		} else {
+			p.print(" " + p.name(x.Type))
+		}
+	case *ast.MakeChanExpr:
+		p.print(p.kw("новый") + " " + p.kw("канал"))
+		if _, ok := x.SizeExpr.(*ast.NoneExpr); !ok && x.SizeExpr != nil {
+			p.print("(")
+			p.expr(x.SizeExpr)
+			p.print(")")
+		}
+	case *ast.MakeArrayExpr:
+		p.print("[](")
+		p.expr(x.LenExpr)
+		if x.CapExpr != nil {
+			p.print(", ")
+			p.expr(x.CapExpr)
+		}
+		p.print(")")
+	case *ast.TypeCast:
+		if x.TypeExpr != nil {
+			p.print(p.kw("новый") + "(")
+			p.expr(x.TypeExpr)
+			p.print(", ")
+		} else {
+			p.print(p.kw(p.names.GetLowerCase(x.Type)) + "(")
+		}
+		p.expr(x.CastExpr)
+		p.print(")")
+	default:
+		panic(fmt.Sprintf("Форматирование выражения %T не поддерживается", e))
+	}
+}
+
+func (p *printer) args(list []ast.Expr, vararg bool) {
+	p.print("(")
+	p.exprs(list)
+	if vararg {
+		p.print("...")
+	}
+	p.print(")")
+}
+
+func (p *printer) funcExpr(x *ast.FuncExpr) {
+	p.print(p.kw("функция"))
+	if n := p.name(x.Name); n != anonFunc {
+		p.print(" " + n)
+	}
+	p.print("(")
+	for i, a := range x.Args {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.print(p.name(a))
+	}
+	if x.VarArg {
+		p.print("...")
+	}
+	p.print(")")
+	if p.flat {
+		// функция всегда многострочная, при оценке ширины достаточно признака переноса
+		p.newline()
+		return
+	}
+	p.newline()
+	p.skipTo(x.Position().Line)
+	inExpr := p.inExpr
+	p.inExpr = 0
+	p.block(x.Stmts, x.End.Line)
+	p.inExpr = inExpr
+	p.print(p.kw("конецфункции"))
+}
+
+// list выводит литерал массива или структуры: в одну строку, если он короткий,
+// иначе по одному элементу в строке с выравниванием значений структуры
+func (p *printer) list(open, close string, n int, item func(i int) (string, ast.Expr)) {
+	if n == 0 {
+		p.print(open + close)
+		return
+	}
+	hi := 0
+	for i := 0; i < n; i++ {
+		if _, e := item(i); endLine(e) > hi {
+			hi = endLine(e)
+		}
+	}
+	// литерал с комментариями внутри всегда многострочный, иначе комментарии сместятся
+	if !p.flat && (len(p.comments) == 0 || p.comments[0].Pos.Line > hi) {
+		if s, ok := p.flatten(open, close, n, item); ok && p.column()+utf8.RuneCountInString(s) <= MaxWidth {
+			p.print(s)
+			return
+		}
+	}
+	if p.flat {
+		p.print(open)
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				p.print(", ")
+			}
+			key, e := item(i)
+			if key != "" {
+				p.print(key + " ")
+			}
+			p.expr(e)
+		}
+		p.print(close)
+		return
+	}
+	width := 0
+	for i := 0; i < n; i++ {
+		if key, _ := item(i); utf8.RuneCountInString(key) > width {
+			width = utf8.RuneCountInString(key)
+		}
+	}
+	p.print(open)
+	p.inExpr++
+	p.indent++
+	for i := 0; i < n; i++ {
+		key, e := item(i)
+		p.newline()
+		if l := startLine(e); l > 0 {
+			p.flush(l)
+		}
+		if key != "" {
+			p.print(key + strings.Repeat(" ", width-utf8.RuneCountInString(key)+1))
+		}
+		p.expr(e)
+		p.print(",")
+	}
+	p.newline()
+	p.flush(hi + 1)
+	p.indent--
+	p.inExpr--
+	p.print(close)
+}
+
+// flatten возвращает литерал, записанный в одну строку, если это возможно
+func (p *printer) flatten(open, close string, n int, item func(i int) (string, ast.Expr)) (string, bool) {
+	fp := &printer{style: p.style, names: p.names, flat: true}
+	fp.list(open, close, n, item)
+	s := fp.buf.String()
+	return s, !strings.Contains(s, "\n")
+}
+
+// quote записывает строку в двойных кавычках с экранированием, которое понимает сканер
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+var positionType = reflect.TypeOf(pos.Position{})
+
+// startLine возвращает первую строку исходного текста, занимаемую узлом дерева.
+// Позиции литералов и скобок указывают на лексему после них, поэтому берется минимум по всем вложенным узлам
+func startLine(n interface{}) int {
+	lo, _ := lineRange(n)
+	return lo
+}
+
+// endLine возвращает последнюю строку исходного текста, занимаемую узлом дерева
+func endLine(n interface{}) int {
+	_, hi := lineRange(n)
+	return hi
+}
+
+func lineRange(n interface{}) (lo, hi int) {
+	walkLines(reflect.ValueOf(n), func(l int) {
+		if l > 0 && (lo == 0 || l < lo) {
+			lo = l
+		}
+		if l > hi {
+			hi = l
+		}
+	})
+	return
+}
+
+func walkLines(v reflect.Value, f func(int)) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			walkLines(v.Elem(), f)
+		}
+	case reflect.Struct:
+		if v.Type() == positionType {
+			f(int(v.Field(0).Int()))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			walkLines(v.Field(i), f)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkLines(v.Index(i), f)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			walkLines(v.MapIndex(k), f)
+		}
+	}
+}
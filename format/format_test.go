@@ -0,0 +1,225 @@
+package format
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/ast"
+	"github.com/covrom/gonec/parser"
+)
+
+const sample = `# Образец со всеми конструкциями языка
+модуль тест
+
+функция сумма(б...)   // с переменным числом аргументов
+  рез=0
+  для каждого х из б цикл
+    рез+=х
+  конеццикла
+  возврат рез
+конецфункции
+
+если сумма(1,2,3)<>6 или не истина и ложь тогда вызватьисключение "ошибка \"сложения\""
+иначеесли 1 >= 2 тогда
+  # внутри ветки
+иначе
+  а, б = 1, -2
+конецесли
+
+
+выбор а:
+когда 1:
+  сообщить("один")
+  // перед следующей веткой
+другое:
+  сообщить(?(а > 0, "плюс", "минус"))
+конецвыбора
+
+выбор:
+когда а = 1:
+  прервать
+конецвыбора
+
+пока а < 10 цикл а++; продолжить
+конеццикла
+
+попытка
+  к = новый канал(10)
+  к <- строка(а)
+  з = <-к
+  старт сумма(1, 2)
+  м = [](0, 10)
+  с = м[1:]
+  с = м[:2]
+  н = новый("Структура", {"б": null, "а": неопределено})
+исключение
+  сообщить(ОписаниеОшибки())
+конецпопытки
+
+ф = функция(х)
+  возврат х * 2
+конецфункции
+список = [1, # первый
+  2]
+структ = {"первый ключ": 1, "второй": [1, 2, 3], "третий": {"вложенный": "значение"}, "четвертый": ф(2), "пятый": "длинная строка"}
+`
+
+func TestSourceStable(t *testing.T) {
+	files, err := filepath.Glob("../test/*.gnc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("не найдены исходные тексты в каталоге test")
+	}
+	srcs := map[string]string{"sample": sample}
+	for _, fn := range files {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcs[fn] = string(b)
+	}
+	for fn, src := range srcs {
+		for _, style := range []Style{StyleTitle, StyleLower, StyleUpper} {
+			res, err := Source(src, style)
+			if err != nil {
+				t.Fatalf("%s: %v", fn, err)
+			}
+			res2, err := Source(res, style)
+			if err != nil {
+				t.Fatalf("%s: повторное форматирование: %v\n%s", fn, err, res)
+			}
+			if res != res2 {
+				t.Errorf("%s: форматирование нестабильно:\n%s", fn, Diff(fn, res, res2))
+			}
+			if !reflect.DeepEqual(parseNoPos(t, src), parseNoPos(t, res)) {
+				t.Errorf("%s: после форматирования изменилось дерево разбора:\n%s", fn, res)
+			}
+			if n1, n2 := countComments(src), countComments(res); n1 != n2 {
+				t.Errorf("%s: потеряны комментарии: было %d, стало %d", fn, n1, n2)
+			}
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	res, err := Source(sample, StyleTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Модуль тест\n",
+		"\nФункция сумма(б...) // с переменным числом аргументов\n\tрез = 0\n\tДля Каждого х Из б Цикл\n\t\tрез += х\n\tКонецЦикла\n",
+		"Если сумма(1, 2, 3) <> 6 Или Не Истина И Ложь Тогда\n\tВызватьИсключение \"ошибка \\\"сложения\\\"\"\nИначеЕсли 1 >= 2 Тогда\n\t# внутри ветки\nИначе\n\tа, б = 1, -2\nКонецЕсли\n\nВыбор а:\n",
+		"Когда 1:\n\tсообщить(\"один\")\n\t// перед следующей веткой\nДругое:\n",
+		"Пока а < 10 Цикл\n\tа++\n\tПродолжить\nКонецЦикла\n",
+		"\tк <- Строка(а)\n\tз = <-к\n\tСтарт сумма(1, 2)\n\tм = [](0, 10)\n",
+		"Новый(\"Структура\", {\"б\": Null, \"а\": Неопределено})",
+		"структ = {\n\t\"первый ключ\": 1,\n\t\"второй\":      [1, 2, 3],\n\t\"третий\":      {\"вложенный\": \"значение\"},\n",
+	} {
+		if !strings.Contains(res, want) {
+			t.Errorf("не найдено:\n%s\nв результате:\n%s", want, res)
+		}
+	}
+	if strings.Contains(res, "\n\n\n") {
+		t.Errorf("несколько пустых строк подряд:\n%s", res)
+	}
+
+	res, err = Source(sample, StyleLower)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(res, "\tрез = 0\n\tдля каждого х из б цикл\n") || !strings.Contains(res, "\nконецфункции\n") {
+		t.Errorf("неверное написание ключевых слов:\n%s", res)
+	}
+}
+
+// литералы строк записываются так же, как в исходном тексте
+func TestSourceKeepsLiterals(t *testing.T) {
+	const src = "функция Страница(вых)\n" +
+		"  вых.Отправить({`Тело`: `<html>\n    <body class=\"а\">\\n</body>\n</html>\n`, 'Статус': 200})\n" +
+		"  сообщить('одинарные \\\"кавычки\\\"', \"табуляция\\t\")\n" +
+		"конецфункции\n"
+	res, err := Source(src, StyleTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"\t\t`Тело`:   `<html>\n    <body class=\"а\">\\n</body>\n</html>\n`,\n",
+		"'Статус': 200",
+		"сообщить('одинарные \\\"кавычки\\\"', \"табуляция\\t\")",
+	} {
+		if !strings.Contains(res, want) {
+			t.Errorf("не найдено:\n%s\nв результате:\n%s", want, res)
+		}
+	}
+	if res2, err := Source(res, StyleTitle); err != nil || res2 != res {
+		t.Errorf("форматирование нестабильно: %v\n%s", err, Diff("src", res, res2))
+	}
+	if !reflect.DeepEqual(parseNoPos(t, src), parseNoPos(t, res)) {
+		t.Errorf("после форматирования изменилось дерево разбора:\n%s", res)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if d := Diff("ф.gnc", "а\n", "а\n"); d != "" {
+		t.Errorf("разница одинаковых текстов: %q", d)
+	}
+	want := "--- ф.gnc\n+++ ф.gnc\n@@ -1,3 +1,3 @@\n а\n-б\n+Б\n в\n"
+	if d := Diff("ф.gnc", "а\nб\nв\n", "а\nБ\nв\n"); d != want {
+		t.Errorf("получено:\n%s\nожидалось:\n%s", d, want)
+	}
+}
+
+// parseNoPos разбирает исходный текст и обнуляет позиции в дереве, чтобы сравнивать только структуру
+func parseNoPos(t *testing.T, src string) ast.Stmts {
+	s := new(parser.Scanner)
+	s.Init(header + src)
+	stmts, err := parser.Parse(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearPos(reflect.ValueOf(stmts))
+	return stmts
+}
+
+func clearPos(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			clearPos(v.Elem())
+		}
+	case reflect.Struct:
+		if v.Type() == positionType {
+			v.Set(reflect.Zero(positionType))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			clearPos(v.Field(i))
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			clearPos(v.Index(i))
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			clearPos(v.MapIndex(k))
+		}
+	}
+}
+
+func countComments(src string) int {
+	s := new(parser.Scanner)
+	s.Init(src)
+	for {
+		tok, _, _, err := s.Scan()
+		if err != nil || tok == parser.EOF {
+			break
+		}
+	}
+	return len(s.Comments())
+}
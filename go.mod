@@ -1,6 +1,6 @@
 module github.com/jnovikov/gonec
 
-go 1.12
+go 1.13
 
 require (
 	github.com/boltdb/bolt v1.3.1
@@ -14,7 +14,8 @@ require (
 	github.com/mattn/go-isatty v0.0.4
 	github.com/mitchellh/go-homedir v1.0.0
 	github.com/satori/go.uuid v1.2.0
-	golang.org/x/sys v0.0.0-20181107165924-66b7b1311ac8
+	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad
+	golang.org/x/sys v0.0.0-20191026070338-33540a1f6037
 )
 
 replace github.com/covrom/gonec => ./
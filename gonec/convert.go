@@ -0,0 +1,29 @@
+package gonec
+
+import (
+	"reflect"
+
+	"github.com/covrom/gonec/core"
+)
+
+var reflectInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// ToVMValue преобразует значение Го в значение вирт. машины.
+// Слайсы и массивы становятся Массивом, карты со строковыми ключами - Структурой (рекурсивно),
+// структуры - объектами с полями и методами, функции - функциями языка Гонец,
+// значения, уже являющиеся значениями вирт. машины, возвращаются как есть
+func ToVMValue(v interface{}) (core.VMValuer, error) {
+	return core.GoToVMValue(reflect.ValueOf(v))
+}
+
+// FromVMValue преобразует значение вирт. машины в значение Го.
+// ЦелоеЧисло становится int64, Число - float64, Строка - string, Булево - bool,
+// Дата - time.Time, Длительность - time.Duration, Массив - []interface{}, Структура - map[string]interface{},
+// Неопределено - nil. Остальные значения возвращаются как есть
+func FromVMValue(v core.VMValuer) interface{} {
+	rv, err := core.VMValueToGo(v, reflectInterface)
+	if err != nil || !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
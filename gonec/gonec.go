@@ -0,0 +1,236 @@
+// Package gonec - программный интерфейс для встраивания интерпретатора языка Гонец в приложения на языке Go
+//
+// Пример использования:
+//
+//	intr, err := gonec.New(gonec.Options{Stdout: &buf})
+//	if err != nil { ... }
+//	_, err = intr.Eval(`функция Сумма(а, б) возврат а+б конецфункции`)
+//	rets, err := intr.Call("Сумма", 1, 2) // rets[0] == int64(3)
+package gonec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/covrom/gonec/bincode"
+	"github.com/covrom/gonec/bincode/binstmt"
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/names"
+	"github.com/covrom/gonec/parser"
+)
+
+// Options - параметры создания интерпретатора
+type Options struct {
+	Stdout  io.Writer              // куда выводится результат Сообщить и т.п., по умолчанию os.Stdout
+	Args    []string               // значение глобальной переменной АргументыЗапуска
+	Globals map[string]interface{} // глобальные переменные, преобразуются через ToVMValue
+}
+
+// Program - скомпилированный код, может многократно исполняться в разных интерпретаторах
+type Program struct {
+	code binstmt.BinCode
+}
+
+// Compile компилирует исходный код на языке Гонец.
+// Программа получает собственную таблицу имен и не зависит от интерпретаторов, в которых исполняется
+func Compile(src string) (*Program, error) {
+	return compile(src, nil)
+}
+
+func compile(src string, nm *names.EnvNames) (*Program, error) {
+	_, bins, err := bincode.ParseSrc(src, nm)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{code: bins}, nil
+}
+
+// ReadProgram загружает скомпилированный код в формате .gnx
+func ReadProgram(r io.Reader) (*Program, error) {
+	bins, err := binstmt.ReadBinCode(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{code: bins}, nil
+}
+
+// Save сохраняет скомпилированный код в формате .gnx
+func (p *Program) Save(w io.Writer) error {
+	return binstmt.WriteBinCode(w, p.code)
+}
+
+// Code возвращает байткод программы
+func (p *Program) Code() binstmt.BinCode {
+	return p.code
+}
+
+func (p *Program) String() string {
+	return p.code.String()
+}
+
+// Interpreter - интерпретатор со своим глобальным окружением и таблицей имен.
+// Значения глобальных переменных и функций сохраняются между вызовами Eval и Run,
+// разные интерпретаторы не влияют друг на друга и могут работать параллельно
+type Interpreter struct {
+	env *core.Env
+}
+
+// New создает интерпретатор с загруженной стандартной библиотекой
+func New(opts Options) (*Interpreter, error) {
+	env := core.NewEnv()
+	bincode.LoadBuiltins(env)
+
+	if opts.Stdout != nil {
+		env.SetStdOut(opts.Stdout)
+	}
+	env.DefineS("аргументызапуска", core.NewVMSliceFromStrings(opts.Args))
+
+	x := &Interpreter{env: env}
+	for k, v := range opts.Globals {
+		if err := x.Set(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// Env возвращает глобальное окружение интерпретатора для низкоуровневой работы с вирт. машиной
+func (x *Interpreter) Env() *core.Env {
+	return x.env
+}
+
+// SetStdout перенаправляет стандартный вывод интерпретатора
+func (x *Interpreter) SetStdout(w io.Writer) {
+	x.env.SetStdOut(w)
+}
+
+// Eval компилирует и исполняет исходный код, возвращает значение оператора Возврат верхнего уровня, если он был
+func (x *Interpreter) Eval(src string) (interface{}, error) {
+	return x.EvalContext(context.Background(), src)
+}
+
+// EvalContext аналогичен Eval, исполнение прерывается при отмене контекста
+func (x *Interpreter) EvalContext(ctx context.Context, src string) (interface{}, error) {
+	p, err := compile(src, x.env.Names())
+	if err != nil {
+		return nil, err
+	}
+	return x.RunContext(ctx, p)
+}
+
+// Run исполняет скомпилированный код
+func (x *Interpreter) Run(p *Program) (interface{}, error) {
+	return x.RunContext(context.Background(), p)
+}
+
+// RunContext исполняет скомпилированный код, исполнение прерывается при отмене контекста
+func (x *Interpreter) RunContext(ctx context.Context, p *Program) (interface{}, error) {
+	rv, err := bincode.RunContext(ctx, p.code, x.env)
+	if err == binstmt.ReturnError {
+		// возврат из кода верхнего уровня не является ошибкой
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return FromVMValue(rv), nil
+}
+
+// LoadFile исполняет файл с исходным кодом, или скомпилированный файл, если его расширение .gnx
+func (x *Interpreter) LoadFile(path string) (interface{}, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p *Program
+	if strings.HasSuffix(strings.ToLower(path), ".gnx") {
+		p, err = ReadProgram(bytes.NewReader(b))
+	} else {
+		p, err = compile(string(b), x.env.Names())
+		if pe, ok := err.(*parser.Error); ok {
+			pe.Filename = path
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	// имя файла используется в профилях исполнения и покрытия
+	if p.code.File == "" {
+		p.code.File = path
+	}
+	return x.Run(p)
+}
+
+// Call вызывает функцию по имени, аргументы преобразуются через ToVMValue, результаты - через FromVMValue
+func (x *Interpreter) Call(name string, args ...interface{}) ([]interface{}, error) {
+	return x.CallContext(context.Background(), name, args...)
+}
+
+// CallContext аналогичен Call, исполнение функции прерывается при отмене контекста
+func (x *Interpreter) CallContext(ctx context.Context, name string, args ...interface{}) (res []interface{}, err error) {
+	v, err := x.env.Get(x.env.Names().Set(name))
+	if err != nil {
+		return nil, err
+	}
+	f, ok := v.(core.VMFunc)
+	if !ok {
+		return nil, fmt.Errorf("'%s' не является функцией", name)
+	}
+
+	vargs := make(core.VMSlice, len(args))
+	for i := range args {
+		if vargs[i], err = ToVMValue(args[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	old := x.env.SetContext(ctx)
+	defer x.env.SetContext(old)
+	x.env.ResetInterrupt()
+
+	defer func() {
+		// обрабатываем панику, которая могла возникнуть в вызванной функции
+		if ex := recover(); ex != nil {
+			if e, ok := ex.(error); ok {
+				err = e
+			} else {
+				err = errors.New(fmt.Sprint(ex))
+			}
+			res = nil
+		}
+	}()
+
+	var rets core.VMSlice
+	fenv := x.env
+	if err = f(vargs, &rets, &fenv); err != nil {
+		return nil, err
+	}
+	res = make([]interface{}, len(rets))
+	for i := range rets {
+		res[i] = FromVMValue(rets[i])
+	}
+	return res, nil
+}
+
+// Get возвращает значение глобальной переменной, преобразованное через FromVMValue
+func (x *Interpreter) Get(name string) (interface{}, error) {
+	v, err := x.env.Get(x.env.Names().Set(name))
+	if err != nil {
+		return nil, err
+	}
+	return FromVMValue(v), nil
+}
+
+// Set устанавливает значение глобальной переменной, значение преобразуется через ToVMValue
+func (x *Interpreter) Set(name string, v interface{}) error {
+	vv, err := ToVMValue(v)
+	if err != nil {
+		return err
+	}
+	return x.env.DefineS(name, vv)
+}
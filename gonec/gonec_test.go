@@ -0,0 +1,339 @@
+package gonec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/covrom/gonec/core"
+)
+
+func TestInterpreter(t *testing.T) {
+	var buf bytes.Buffer
+
+	intr, err := New(Options{
+		Stdout:  &buf,
+		Globals: map[string]interface{}{"Множитель": 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = intr.Eval(`
+	функция Умножить(а)
+	  возврат а * Множитель
+	конецфункции
+	функция Сложить(м)
+	  с = 0
+	  для каждого э из м цикл
+	    с = с + э
+	  конеццикла
+	  возврат с
+	конецфункции
+	сообщить("привет")
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "привет\n" {
+		t.Errorf("неверный вывод: %q", buf.String())
+	}
+
+	rets, err := intr.Call("умножить", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rets, []interface{}{int64(50)}) {
+		t.Errorf("неверный результат Умножить: %#v", rets)
+	}
+
+	rets, err = intr.Call("Сложить", []int{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rets, []interface{}{int64(6)}) {
+		t.Errorf("неверный результат Сложить: %#v", rets)
+	}
+
+	if err := intr.Set("Данные", map[string]interface{}{"а": "б", "в": []string{"г"}}); err != nil {
+		t.Fatal(err)
+	}
+	v, err := intr.Eval(`возврат Данные.в[0] + Данные.а`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "гб" {
+		t.Errorf("неверный результат Eval: %#v", v)
+	}
+
+	v, err = intr.Get("множитель")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != int64(10) {
+		t.Errorf("неверное значение глобальной переменной: %#v", v)
+	}
+
+	if _, err := intr.Call("НетТакойФункции"); err == nil {
+		t.Error("ожидалась ошибка при вызове неопределенной функции")
+	}
+}
+
+func TestInterpreterContext(t *testing.T) {
+	intr, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	tstart := time.Now()
+	_, err = intr.EvalContext(ctx, `пока истина цикл конеццикла`)
+	if err == nil {
+		t.Fatal("ожидалась ошибка прерывания")
+	}
+	if time.Since(tstart) > 5*time.Second {
+		t.Errorf("исполнение не было прервано вовремя")
+	}
+
+	// после прерывания интерпретатор остается работоспособным
+	v, err := intr.Eval(`возврат 1+2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != int64(3) {
+		t.Errorf("неверный результат: %#v", v)
+	}
+}
+
+func TestInterpreterIsolation(t *testing.T) {
+	// общий скомпилированный код исполняется в интерпретаторах с разными таблицами имен
+	shared, err := Compile(`возврат [Значение, значение + 1]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// названия полей структуры различаются регистром, каждый интерпретатор должен видеть свое написание
+	cases := []struct {
+		src  string
+		want int64
+	}{
+		{`с = {"Ключ": 1}; Значение = 10; возврат с.Ключ`, 1},
+		{`с = {"ключ": 2}; ЗНАЧЕНИЕ = 20; возврат с.ключ`, 2},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(cases))
+	for _, c := range cases {
+		wg.Add(1)
+		go func(src string, want int64) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				intr, err := New(Options{})
+				if err != nil {
+					errs <- err
+					return
+				}
+				v, err := intr.Eval(src)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if v != want {
+					errs <- fmt.Errorf("%s: получено %#v, ожидалось %d", src, v, want)
+					return
+				}
+				v, err = intr.Run(shared)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !reflect.DeepEqual(v, []interface{}{want * 10, want*10 + 1}) {
+					errs <- fmt.Errorf("%s: неверный результат общего кода %#v", src, v)
+					return
+				}
+			}
+		}(c.src, c.want)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// Тесты конкурентного исполнения имеют смысл при запуске с детектором гонок: go test -race
+func TestConcurrentGoroutines(t *testing.T) {
+	intr, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := intr.Eval(`
+	сс = Новый СинхроннаяСтруктура
+	гр = Новый ГруппаОжидания
+	функция Раб(н)
+	  л = н * 2
+	  сс[Строка(н)] = л
+	  сс.Установить("последний", н)
+	  старт Пауза(0.001) # горутина переживает окружение функции
+	  гр.Завершить()
+	конецфункции
+	для н = 1 по 50 цикл
+	  гр.Добавить(1)
+	  старт Раб(н)
+	конеццикла
+	гр.Ожидать()
+	з, есть = сс.Получить("нет")
+	возврат [сс.Количество(), сс["10"], з, есть]
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, []interface{}{int64(51), int64(20), nil, false}) {
+		t.Errorf("неверный результат: %#v", v)
+	}
+}
+
+func TestConcurrentHandlers(t *testing.T) {
+	intr, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := intr.Eval(`
+	Функция ОбработатьHTTP(вых, вх)
+		вх.Данные().Установить("http" + вх.Параметр("н"), Истина)
+		вых.Отправить({"Статус": 200, "Тело": "ок"})
+	КонецФункции
+
+	Функция ОбработатьTCP(соед)
+		запр = соед.Получить()
+		соед.Данные().Установить("tcp" + запр.н, Истина)
+		соед.Отправить({"Ответ": запр.н})
+	КонецФункции
+
+	данные = Новый СинхроннаяСтруктура
+	сервhttp = Новый Сервер
+	сервhttp.Открыть("http", "127.0.0.1:0", 1000, {"/test": ОбработатьHTTP}, данные)
+	адрhttp = сервhttp.Адрес()
+	сервtcp = Новый Сервер
+	сервtcp.Открыть("tcp", "127.0.0.1:0", 1000, ОбработатьTCP, данные)
+	адрtcp = сервtcp.Адрес()
+
+	гр = Новый ГруппаОжидания
+	для н = 1 по 20 цикл
+		гр.Добавить(2)
+		старт Функция(грп, нн)
+			кли = Новый Клиент
+			соед = кли.Соединить("http", адрhttp)
+			соед.Запрос({"Метод": "GET", "Путь": "http://" + адрhttp + "/test?н=" + Строка(нн)})
+			кли.Закрыть()
+			грп.Завершить()
+		КонецФункции(гр, н)
+		старт Функция(грп, нн)
+			кли = Новый Клиент
+			соед = кли.Соединить("tcp", адрtcp)
+			соед.Отправить({"н": Строка(нн)})
+			соед.Получить()
+			кли.Закрыть()
+			грп.Завершить()
+		КонецФункции(гр, н)
+	конеццикла
+	гр.Ожидать()
+	сервhttp.Закрыть()
+	сервtcp.Закрыть()
+	возврат данные.Количество()
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != int64(40) {
+		t.Errorf("обработаны не все запросы: %#v", v)
+	}
+}
+
+type testPoint struct {
+	X, Y  int
+	Метка string
+	Время time.Time
+	Теги  []string
+}
+
+func (p *testPoint) Сдвинуть(dx, dy int) {
+	p.X += dx
+	p.Y += dy
+}
+
+func (p testPoint) Сумма() int {
+	return p.X + p.Y
+}
+
+type testShape struct {
+	Имя    string
+	Центр  testPoint
+	Размер float64
+}
+
+func TestGoBinding(t *testing.T) {
+	intr, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shape := &testShape{Имя: "круг", Центр: testPoint{X: 1, Y: 2}, Размер: 1.5}
+
+	err = intr.Set("Фигура", shape)
+	if err == nil {
+		err = intr.Set("Разделить", core.WrapGoFunc(func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("деление на ноль")
+			}
+			return a / b, nil
+		}))
+	}
+	if err == nil {
+		err = intr.Set("Склеить", func(sep string, parts ...string) string {
+			return strings.Join(parts, sep)
+		})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := intr.Eval(`
+	Фигура.Центр.Сдвинуть(10, 20)
+	Фигура.Центр.Метка = "центр"
+	Фигура.Центр.Теги = ["а", "б"]
+	Фигура.Размер = Фигура.Размер * 2
+	ошибка = ""
+	попытка
+	  Разделить(1, 0)
+	исключение
+	  ошибка = ОписаниеОшибки()
+	конецпопытки
+	возврат [Фигура.Центр.Сумма(), Разделить(7, 2), Склеить("-", "x", "y", "z"), ошибка]
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, _ := v.([]interface{})
+	want := []interface{}{int64(33), int64(3), "x-y-z"}
+	if len(res) != 4 || !reflect.DeepEqual(res[:3], want) {
+		t.Fatalf("неверный результат: %#v", v)
+	}
+	if s, _ := res[3].(string); !strings.HasSuffix(s, "деление на ноль") {
+		t.Errorf("ошибка Го не передана в исключение: %#v", res[3])
+	}
+	if shape.Центр.X != 11 || shape.Центр.Y != 22 || shape.Центр.Метка != "центр" || shape.Размер != 3 {
+		t.Errorf("структура не изменена: %+v", shape)
+	}
+	if !reflect.DeepEqual(shape.Центр.Теги, []string{"а", "б"}) {
+		t.Errorf("поле-слайс не изменено: %#v", shape.Центр.Теги)
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"flag"
 	"fmt"
@@ -15,6 +14,7 @@ import (
 	"github.com/covrom/gonec/bincode"
 	"github.com/covrom/gonec/bincode/binstmt"
 	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/disasm"
 	"github.com/covrom/gonec/parser"
 	"github.com/covrom/gonec/services/gonecsvc"
 	"github.com/covrom/gonec/version"
@@ -36,6 +36,15 @@ var (
 	w    = fs.Bool("web", false, "Запустить вэб-сервер на порту 5000, если не указан параметр -p")
 	port = fs.String("p", "", "Номер порта вэб-сервера")
 
+	cpuprofile   = fs.String("cpuprofile", "", "Записать в файл профиль исполнения строк кода Гонец (формат pprof)")
+	memprofile   = fs.String("memprofile", "", "Записать в файл профиль создания массивов и структур в коде Гонец (формат pprof)")
+	coverprofile = fs.String("cover", "", "Записать в файл профиль покрытия кода Гонец (отчет строится командой gonec cover)")
+
+	watchMode = fs.Bool("watch", false, "Перезапускать программу при изменении ее файлов и подключаемых модулей")
+
+	signkey = fs.String("sign", "", "Подписать компилируемый файл .gnx закрытым ключом Ed25519 из файла PEM (вместе с -c)")
+	trust   = fs.String("trust", "", "Исполнять только код .gnx, подписанный ключами из файла или каталога (по умолчанию - из переменной окружения "+trustedKeysEnv+")")
+
 	istty = isatty.IsTerminal(os.Stdout.Fd())
 
 	fsArgs []string
@@ -53,21 +62,47 @@ func colortext(color ct.Color, bright bool, f func()) {
 
 func main() {
 
+	setupTrust(os.Getenv(trustedKeysEnv))
+
+	// исполняемый файл, собранный gonec build, сразу исполняет свой код
+	if b := embeddedBundle(); b != nil {
+		os.Exit(runBundle(b))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		os.Exit(runBuild(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(runFmt(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "vet" {
+		os.Exit(runVet(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "cover" {
+		os.Exit(runCover(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "disasm" {
+		os.Exit(runDisasm(os.Args[2:]))
+	}
+
 	fs.Parse(os.Args[1:])
 	if *v {
 		fmt.Println(version.Version)
 		os.Exit(0)
 	}
+	setupTrust(*trust)
+	key := signingKey(*signkey)
+	if key != nil && !*compile {
+		fmt.Fprintln(os.Stderr, "Параметр -sign используется только вместе с -c")
+		os.Exit(1)
+	}
 
 	var (
-		code      string
-		b         []byte
-		reader    *bufio.Reader
-		following bool
-		source    string
+		b      []byte
+		source string
 	)
 
-	interactive := fs.NArg() == 0 && *line == "" && !*compile
+	interactive := fs.NArg() == 0 && *line == "" && !*compile && !*watchMode
 	fsArgs = fs.Args()
 
 	ext := ""
@@ -96,206 +131,168 @@ func main() {
 	// иначе - запуск из командной строки
 
 	if interactive {
-		reader = bufio.NewReader(os.Stdin)
-		source = "typein"
+		if binstmt.SignatureRequired() {
+			fmt.Fprintln(os.Stderr, binstmt.ErrSourceDenied)
+			os.Exit(1)
+		}
 		os.Args = append([]string{os.Args[0]}, fs.Args()...)
+		runREPL()
+		return
+	}
+
+	if *watchMode {
+		if fs.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "Использование: gonec -watch главный.gnc [аргументы]")
+			os.Exit(1)
+		}
+		if binstmt.SignatureRequired() {
+			fmt.Fprintln(os.Stderr, binstmt.ErrSourceDenied)
+			os.Exit(1)
+		}
+		runWatch(fs.Arg(0), fs.Args()[1:])
+		return
+	}
+
+	if *line != "" {
+		b = []byte(*line)
+		source = "argument"
 	} else {
-		if *line != "" {
-			b = []byte(*line)
-			source = "argument"
-		} else {
-			var err error
-			b, err = ioutil.ReadFile(fs.Arg(0))
-			if err != nil {
-				colortext(ct.Red, false, func() {
-					fmt.Fprintln(os.Stderr, err)
-				})
-				os.Exit(1)
-			}
-			fsArgs = fs.Args()[1:]
-			source = filepath.Clean(fs.Arg(0))
+		var err error
+		b, err = ioutil.ReadFile(fs.Arg(0))
+		if err != nil {
+			colortext(ct.Red, false, func() {
+				fmt.Fprintln(os.Stderr, err)
+			})
+			os.Exit(1)
 		}
-		os.Args = fs.Args()
+		fsArgs = fs.Args()[1:]
+		source = filepath.Clean(fs.Arg(0))
 	}
+	os.Args = fs.Args()
 
 	env := core.NewEnv()
 	env.DefineS("аргументызапуска", core.NewVMSliceFromStrings(fsArgs))
 
-	for {
-		if interactive {
-			colortext(ct.Green, true, func() {
-				if following {
-					fmt.Print("  ")
-				} else {
-					fmt.Print("> ")
-				}
-			})
-			var err error
-			b, _, err = reader.ReadLine()
-			if err != nil {
-				break
-			}
-			if len(b) == 0 {
-				continue
-			}
-			if code != "" {
-				code += "\n"
-			}
-			code += string(b)
-		} else {
-			code = string(b)
-		}
+	stopProfile := startProfile(source)
+	defer stopProfile()
+	stopCoverage := startCoverage(source)
+	defer stopCoverage()
 
-		parser.EnableErrorVerbose()
-
-		var (
-			bins binstmt.BinCode
-			// stmts          ast.Stmts
-			err            error
-			tstart         time.Time
-			tsParse, tsRun time.Duration
-		)
-
-		tstart = time.Now()
-
-		isGNX := strings.HasSuffix(strings.ToLower(source), ".gnx")
-		// если это скомпилированный файл, то сразу его выполняем
-		if isGNX {
-			bbuf := bytes.NewBuffer(b)
-			// stmts = nil
-			bins, err = binstmt.ReadBinCode(bbuf)
-			tsParse = time.Since(tstart)
-			if err != nil {
-				log.Fatal(err)
-			}
-			if *testingMode {
-				log.Printf("--Выполняется скомпилированный код-- \n%s\n", bins.String())
-			}
-		} else {
-			if *testingMode {
-				log.Printf("--Выполняется код--\n%s\n", code)
-			}
-			//замер производительности
-			_, bins, err = bincode.ParseSrc(code)
-			tsParse = time.Since(tstart)
+	code := string(b)
 
-			if *testingMode {
-				log.Printf("--Скомпилирован код-- \n%s\n", bins.String())
-			}
+	parser.EnableErrorVerbose()
 
-			if interactive {
-				if e, ok := err.(*parser.Error); ok {
-					es := e.Error()
-					if strings.HasPrefix(es, "syntax error: unexpected") {
-						if strings.HasPrefix(es, "syntax error: unexpected $end,") {
-							following = true
-							continue
-						}
-					} else {
-						if e.Pos.Column == len(b) && !e.Fatal {
-							println(e.Error())
-							following = true
-							continue
-						}
-						if e.Error() == "unexpected EOF" {
-							following = true
-							continue
-						}
-					}
-				}
-			}
+	var (
+		bins           binstmt.BinCode
+		err            error
+		tstart         time.Time
+		tsParse, tsRun time.Duration
+	)
+
+	tstart = time.Now()
+
+	isGNX := strings.HasSuffix(strings.ToLower(source), ".gnx")
+	if !isGNX && !*compile && binstmt.SignatureRequired() {
+		fmt.Fprintln(os.Stderr, binstmt.ErrSourceDenied)
+		os.Exit(1)
+	}
+	// если это скомпилированный файл, то сразу его выполняем
+	if isGNX {
+		bbuf := bytes.NewBuffer(b)
+		bins, err = binstmt.ReadBinCode(bbuf)
+		tsParse = time.Since(tstart)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *testingMode {
+			log.Println("--Выполняется скомпилированный код--")
+			disasm.Fprint(os.Stderr, bins, disasm.Options{})
 		}
+	} else {
+		if *testingMode {
+			log.Printf("--Выполняется код--\n%s\n", code)
+		}
+		//замер производительности
+		_, bins, err = bincode.ParseSrc(code, env.Names())
+		tsParse = time.Since(tstart)
 
-		if *compile {
-			srcname := fs.Arg(0)
-			if srcname != "" && !isGNX {
-				if strings.HasSuffix(strings.ToLower(srcname), ".gnc") {
-					srcname = srcname[:len(srcname)-4]
-				}
-				compilename := srcname + ".gnx"
-				fo, err := os.Create(compilename)
-				if err != nil {
-					log.Fatal(err)
-				}
-				defer func() {
-					if err := fo.Close(); err != nil {
-						log.Fatal(err)
-					}
-				}()
-				if err := binstmt.WriteBinCode(fo, bins); err != nil {
+		if *testingMode && err == nil {
+			log.Println("--Скомпилирован код--")
+			disasm.Fprint(os.Stderr, bins, disasm.Options{Source: b})
+		}
+	}
+
+	if *compile {
+		srcname := fs.Arg(0)
+		if srcname != "" && !isGNX {
+			if strings.HasSuffix(strings.ToLower(srcname), ".gnc") {
+				srcname = srcname[:len(srcname)-4]
+			}
+			compilename := srcname + ".gnx"
+			// имя исходного файла сохраняется в .gnx для сообщений об ошибках
+			bins.File = fs.Arg(0)
+			fo, err := os.Create(compilename)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer func() {
+				if err := fo.Close(); err != nil {
 					log.Fatal(err)
 				}
+			}()
+			if key != nil {
+				err = binstmt.WriteSignedBinCode(fo, bins, key)
 			} else {
-				log.Fatal("Не указано имя файла с исходным кодом на языке Гонец")
+				err = binstmt.WriteBinCode(fo, bins)
 			}
-			break
-		}
-
-		following = false
-		code = ""
-
-		//замер производительности
-		tstart = time.Now()
-		if *testingMode {
-			log.Println("--Результат выполнения кода--")
+			if err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			log.Fatal("Не указано имя файла с исходным кодом на языке Гонец")
 		}
+		return
+	}
 
-		// v := vm.NilValue
+	//замер производительности
+	tstart = time.Now()
+	if *testingMode {
+		log.Println("--Результат выполнения кода--")
+	}
 
-		if err == nil {
-			// v, err = vm.Run(stmts, env)
-			// if *stackvm && stmts != nil {
-			// 	_, err = vm.Run(stmts, env)
-			// } else {
-			_, err = bincode.Run(bins, env)
-			// }
+	if err == nil {
+		if bins.File == "" {
+			bins.File = source
 		}
+		_, err = bincode.Run(bins, env)
+	}
 
-		tsRun = time.Since(tstart)
+	tsRun = time.Since(tstart)
 
-		if *testingMode {
-			env.Printf("Время компиляции: %v\n", tsParse)
-			env.Printf("Время исполнения: %v\n", tsRun)
-		}
+	if *testingMode {
+		env.Printf("Время компиляции: %v\n", tsParse)
+		env.Printf("Время исполнения: %v\n", tsRun)
+	}
 
-		if err != nil {
-			colortext(ct.Red, false, func() {
-				if e, ok := err.(*binstmt.Error); ok {
-					fmt.Fprintf(os.Stderr, "%s:%d:%d %s\n", source, e.Pos.Line, e.Pos.Column, err)
-				} else if e, ok := err.(*parser.Error); ok {
-					if e.Filename != "" {
-						source = e.Filename
-					}
-					fmt.Fprintf(os.Stderr, "%s:%d:%d %s\n", source, e.Pos.Line, e.Pos.Column, err)
-				} else {
-					fmt.Fprintln(os.Stderr, err)
+	if err != nil {
+		colortext(ct.Red, false, func() {
+			if e, ok := err.(*binstmt.Error); ok {
+				if e.File != "" {
+					source = e.File
 				}
-			})
-
-			if interactive {
-				continue
-			} else {
-				os.Exit(1)
-			}
-		} else {
-			if interactive {
-				// colortext(ct.Black, true, func() {
-				// 	if v == vm.NilValue || !v.IsValid() {
-				// 		fmt.Println("nil")
-				// 	} else {
-				// 		s, ok := v.Interface().(fmt.Stringer)
-				// 		if v.Kind() != reflect.String && ok {
-				// 			fmt.Println(s)
-				// 		} else {
-				// 			fmt.Printf("%#v\n", v.Interface())
-				// 		}
-				// 	}
-				// })
-
+				fmt.Fprintf(os.Stderr, "%s:%d:%d %s\n", source, e.Pos.Line, e.Pos.Column, err)
+			} else if e, ok := err.(*parser.Error); ok {
+				if e.Filename != "" {
+					source = e.Filename
+				}
+				fmt.Fprintf(os.Stderr, "%s:%d:%d %s\n", source, e.Pos.Line, e.Pos.Column, err)
 			} else {
-				break
+				fmt.Fprintln(os.Stderr, err)
 			}
-		}
+		})
+		stopProfile()
+		stopCoverage()
+		os.Exit(1)
 	}
 }
 
@@ -163,7 +163,7 @@ func TestRun(t *testing.T) {
 	`
 
 	parser.EnableErrorVerbose()
-	_, stmts, err := bincode.ParseSrc(script)
+	_, stmts, err := bincode.ParseSrc(script, env.Names())
 	if err != nil {
 		log.Fatal(err)
 	}
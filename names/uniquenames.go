@@ -6,10 +6,12 @@ import (
 	"sync"
 )
 
-// все переменные
-var UniqueNames = NewEnvNames()
+// DefaultModule - идентификатор модуля по умолчанию "_", одинаковый во всех таблицах имен
+const DefaultModule = 1
 
-// уникальные названия переменных, индекс используется в AST-дереве
+// EnvNames - таблица уникальных названий переменных, индекс используется в AST-дереве и в байткоде.
+// Таблица принадлежит интерпретатору (глобальному окружению) или единице компиляции,
+// названия сравниваются без учета регистра
 type EnvNames struct {
 	mu      sync.RWMutex
 	Names   map[string]int
@@ -25,6 +27,7 @@ func NewEnvNames() *EnvNames {
 		Handlow: make([]string, 2, 200),
 		Iter:    1,
 	}
+	en.Set("_") // == DefaultModule
 	return &en
 }
 
@@ -37,6 +40,11 @@ func (en *EnvNames) Set(n string) int {
 	}
 	en.mu.RUnlock()
 	en.mu.Lock()
+	// за время между блокировками название могло быть добавлено другой горутиной
+	if i, ok := en.Names[ns]; ok {
+		en.mu.Unlock()
+		return i
+	}
 	i := en.Iter
 	en.Names[ns] = i
 	en.Handles[i] = n
@@ -115,3 +123,23 @@ func FastToLower(s string) string {
 	}
 	return rs.String()
 }
+
+// MergeInto переносит все названия из таблицы в таблицу dst
+// и возвращает соответствие идентификаторов, которые в dst отличаются от исходных
+func (en *EnvNames) MergeInto(dst *EnvNames) map[int]int {
+	en.mu.RLock()
+	handles := make([]string, len(en.Handles))
+	copy(handles, en.Handles)
+	en.mu.RUnlock()
+
+	swap := make(map[int]int)
+	for i, n := range handles {
+		if i == 0 || n == "" {
+			continue
+		}
+		if ii := dst.Set(n); ii != i {
+			swap[i] = ii
+		}
+	}
+	return swap
+}
@@ -39,6 +39,16 @@ type Scanner struct {
 	canequal bool
 	typecast bool
 	castType string
+	tokLine  int       // строка последней значащей лексемы
+	raw      string    // исходная запись последнего литерала строки вместе с кавычками
+	comments []Comment // комментарии сохраняются для форматирования исходного текста
+}
+
+// Comment - комментарий в исходном тексте, при разборе пропускается, но сохраняется сканером
+type Comment struct {
+	Pos    posit.Position
+	Text   string // текст вместе с начальными символами "#" или "//"
+	Inline bool   // комментарий в конце строки с кодом
 }
 
 // opName is correction of operation names.
@@ -85,14 +95,15 @@ var opName = map[string]int{
 	"пока":         WHILE,
 	"иначеесли":    ELSIF,
 
-	"строка":       TYPECAST,
-	"число":        TYPECAST,
-	"булево":       TYPECAST,
-	"целоечисло":   TYPECAST,
-	"массив":       TYPECAST,
-	"структура":    TYPECAST,
-	"дата":         TYPECAST,
-	"длительность": TYPECAST,
+	"строка":         TYPECAST,
+	"число":          TYPECAST,
+	"булево":         TYPECAST,
+	"целоечисло":     TYPECAST,
+	"массив":         TYPECAST,
+	"структура":      TYPECAST,
+	"дата":           TYPECAST,
+	"длительность":   TYPECAST,
+	"двоичныеданные": TYPECAST,
 }
 
 var opCanEqual = map[int]bool{
@@ -121,6 +132,26 @@ var opCanEqual = map[int]bool{
 // Init resets code to scan.
 func (s *Scanner) Init(src string) {
 	s.src = []rune(src)
+	s.offset, s.lineHead, s.line, s.tokLine = 0, 0, 0, 0
+	s.comments = nil
+}
+
+// Comments возвращает комментарии, встреченные при сканировании, в порядке следования
+func (s *Scanner) Comments() []Comment {
+	return s.comments
+}
+
+// scanComment пропускает комментарий до конца строки и запоминает его,
+// start - смещение первого символа комментария
+func (s *Scanner) scanComment(start int, pos posit.Position) {
+	for !isEOL(s.peek()) {
+		s.next()
+	}
+	s.comments = append(s.comments, Comment{
+		Pos:    pos,
+		Text:   string(s.src[start:s.offset]),
+		Inline: s.tokLine == pos.Line,
+	})
 }
 
 // Scan analyses token, and decide identify or literals.
@@ -137,6 +168,8 @@ func (s *Scanner) Scan() (tok int, lit string, pos posit.Position, err error) {
 retry:
 	s.skipBlank()
 	pos = s.pos()
+	start := s.offset
+	s.raw = ""
 	switch ch := s.peek(); {
 	case isLetter(ch):
 		lit, err = s.scanIdentifier()
@@ -183,9 +216,7 @@ retry:
 		case EOF:
 			tok = EOF
 		case '#':
-			for !isEOL(s.peek()) {
-				s.next()
-			}
+			s.scanComment(s.offset, pos)
 			goto retry
 		case '!':
 			s.next()
@@ -261,9 +292,7 @@ retry:
 			s.next()
 			switch s.peek() {
 			case '/':
-				for !isEOL(s.peek()) {
-					s.next()
-				}
+				s.scanComment(s.offset-1, pos)
 				goto retry
 			case '=':
 				tok = DIVEQ
@@ -414,6 +443,12 @@ retry:
 		}
 		s.next()
 	}
+	if tok == STRING {
+		s.raw = string(s.src[start:s.offset])
+	}
+	if tok != EOF && tok != EOL {
+		s.tokLine = s.line + 1
+	}
 	return
 }
 
@@ -615,6 +650,7 @@ type Lexer struct {
 	pos   posit.Position
 	e     error
 	stmts ast.Stmts
+	names *names.EnvNames // таблица имен единицы компиляции
 }
 
 // Lex scans the token and literals.
@@ -623,7 +659,7 @@ func (l *Lexer) Lex(lval *yySymType) int {
 	if err != nil {
 		l.e = &Error{Message: fmt.Sprintf("%s", err.Error()), Pos: pos, Fatal: true}
 	}
-	lval.tok = ast.Token{Tok: tok, Lit: lit}
+	lval.tok = ast.Token{Tok: tok, Lit: lit, Src: l.s.raw}
 	lval.tok.SetPosition(pos)
 	l.lit = lit
 	l.pos = pos
@@ -636,8 +672,12 @@ func (l *Lexer) Error(msg string) {
 }
 
 // Parser provides way to parse the code using Scanner.
-func Parse(s *Scanner) (ast.Stmts, error) {
-	l := Lexer{s: s}
+// Идентификаторы регистрируются в таблице имен nm, если nil - создается новая таблица
+func Parse(s *Scanner, nm *names.EnvNames) (ast.Stmts, error) {
+	if nm == nil {
+		nm = names.NewEnvNames()
+	}
+	l := Lexer{s: s, names: nm}
 	if yyParse(&l) != 0 {
 		return nil, l.e
 	}
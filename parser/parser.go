@@ -9,7 +9,6 @@ import __yyfmt__ "fmt"
 
 import (
 	"github.com/covrom/gonec/ast"
-	"github.com/covrom/gonec/names"
 )
 
 //line parser.y:30
@@ -183,7 +182,7 @@ const yyEofCode = 1
 const yyErrCode = 2
 const yyInitialStackSize = 16
 
-//line parser.y:738
+//line parser.y:750
 
 //line yacctab:1
 var yyExca = [...]int{
@@ -1241,7 +1240,7 @@ yydefault:
 		yyDollar = yyS[yypt-4 : yypt+1]
 //line parser.y:97
 		{
-			yyVAL.module = &ast.ModuleStmt{Name: names.UniqueNames.Set(yyDollar[2].tok.Lit), Stmts: yyDollar[4].compstmt}
+			yyVAL.module = &ast.ModuleStmt{Name: yylex.(*Lexer).names.Set(yyDollar[2].tok.Lit), Stmts: yyDollar[4].compstmt}
 			yyVAL.module.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 5:
@@ -1293,157 +1292,159 @@ yydefault:
 //line parser.y:136
 		{
 			yyVAL.stmt = &ast.ExprStmt{Expr: &ast.BinOpExpr{Lhss: yyDollar[1].expr_many, Operator: "==", Rhss: yyDollar[3].expr_many}}
+			yyVAL.stmt.SetPosition(yyDollar[1].expr_many[0].Position())
 		}
 	case 13:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:140
+//line parser.y:141
 		{
 			yyVAL.stmt = &ast.BreakStmt{}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 14:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:145
+//line parser.y:146
 		{
 			yyVAL.stmt = &ast.ContinueStmt{}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 15:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:150
+//line parser.y:151
 		{
 			yyVAL.stmt = &ast.ReturnStmt{Exprs: yyDollar[2].exprs}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 16:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:155
+//line parser.y:156
 		{
 			yyVAL.stmt = &ast.ThrowStmt{Expr: yyDollar[2].expr}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 17:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:160
+//line parser.y:161
 		{
 			yyVAL.stmt = yyDollar[1].stmt_if
 			yyVAL.stmt.SetPosition(yyDollar[1].stmt_if.Position())
 		}
 	case 18:
 		yyDollar = yyS[yypt-8 : yypt+1]
-//line parser.y:165
+//line parser.y:166
 		{
-			yyVAL.stmt = &ast.ForStmt{Var: names.UniqueNames.Set(yyDollar[3].tok.Lit), Value: yyDollar[5].expr, Stmts: yyDollar[7].compstmt}
+			yyVAL.stmt = &ast.ForStmt{Var: yylex.(*Lexer).names.Set(yyDollar[3].tok.Lit), Value: yyDollar[5].expr, Stmts: yyDollar[7].compstmt, BlockEnd: ast.BlockEnd{End: yyDollar[8].tok.Position()}}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 19:
 		yyDollar = yyS[yypt-9 : yypt+1]
-//line parser.y:170
+//line parser.y:171
 		{
-			yyVAL.stmt = &ast.NumForStmt{Name: names.UniqueNames.Set(yyDollar[2].tok.Lit), Expr1: yyDollar[4].expr, Expr2: yyDollar[6].expr, Stmts: yyDollar[8].compstmt}
+			yyVAL.stmt = &ast.NumForStmt{Name: yylex.(*Lexer).names.Set(yyDollar[2].tok.Lit), Expr1: yyDollar[4].expr, Expr2: yyDollar[6].expr, Stmts: yyDollar[8].compstmt, BlockEnd: ast.BlockEnd{End: yyDollar[9].tok.Position()}}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 20:
 		yyDollar = yyS[yypt-9 : yypt+1]
-//line parser.y:175
+//line parser.y:176
 		{
-			yyVAL.stmt = &ast.NumForStmt{Name: names.UniqueNames.Set(yyDollar[2].tok.Lit), Expr1: yyDollar[4].expr, Expr2: yyDollar[6].expr, Stmts: yyDollar[8].compstmt}
+			yyVAL.stmt = &ast.NumForStmt{Name: yylex.(*Lexer).names.Set(yyDollar[2].tok.Lit), Expr1: yyDollar[4].expr, Expr2: yyDollar[6].expr, Stmts: yyDollar[8].compstmt, BlockEnd: ast.BlockEnd{End: yyDollar[9].tok.Position()}}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 21:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:180
+//line parser.y:181
 		{
-			yyVAL.stmt = &ast.LoopStmt{Expr: yyDollar[2].expr, Stmts: yyDollar[4].compstmt}
+			yyVAL.stmt = &ast.LoopStmt{Expr: yyDollar[2].expr, Stmts: yyDollar[4].compstmt, BlockEnd: ast.BlockEnd{End: yyDollar[5].tok.Position()}}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 22:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:185
+//line parser.y:186
 		{
-			yyVAL.stmt = &ast.TryStmt{Try: yyDollar[2].compstmt, Catch: yyDollar[4].compstmt}
+			yyVAL.stmt = &ast.TryStmt{Try: yyDollar[2].compstmt, Catch: yyDollar[4].compstmt, CatchPos: yyDollar[3].tok.Position(), BlockEnd: ast.BlockEnd{End: yyDollar[5].tok.Position()}}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 23:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:190
+//line parser.y:191
 		{
-			yyVAL.stmt = &ast.SwitchStmt{Expr: yyDollar[2].expr, Cases: yyDollar[4].stmt_cases}
+			yyVAL.stmt = &ast.SwitchStmt{Expr: yyDollar[2].expr, Cases: yyDollar[4].stmt_cases, BlockEnd: ast.BlockEnd{End: yyDollar[5].tok.Position()}}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 24:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:195
+//line parser.y:196
 		{
-			yyVAL.stmt = &ast.SelectStmt{Cases: yyDollar[3].stmt_cases}
+			yyVAL.stmt = &ast.SelectStmt{Cases: yyDollar[3].stmt_cases, BlockEnd: ast.BlockEnd{End: yyDollar[4].tok.Position()}}
 			yyVAL.stmt.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 25:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:200
+//line parser.y:201
 		{
 			yyVAL.stmt = &ast.ExprStmt{Expr: yyDollar[1].expr}
 			yyVAL.stmt.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 26:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line parser.y:206
+//line parser.y:207
 		{
 			yyVAL.stmt_elsifs = ast.Stmts{}
 		}
 	case 27:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:210
+//line parser.y:211
 		{
 			yyVAL.stmt_elsifs = append(yyDollar[1].stmt_elsifs, yyDollar[2].stmt_elsif)
 		}
 	case 28:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:216
+//line parser.y:217
 		{
 			yyVAL.stmt_elsif = &ast.IfStmt{If: yyDollar[2].expr, Then: yyDollar[4].compstmt}
+			yyVAL.stmt_elsif.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 29:
 		yyDollar = yyS[yypt-8 : yypt+1]
-//line parser.y:222
+//line parser.y:224
 		{
-			yyVAL.stmt_if = &ast.IfStmt{If: yyDollar[2].expr, Then: yyDollar[4].compstmt, ElseIf: yyDollar[5].stmt_elsifs, Else: yyDollar[7].compstmt}
+			yyVAL.stmt_if = &ast.IfStmt{If: yyDollar[2].expr, Then: yyDollar[4].compstmt, ElseIf: yyDollar[5].stmt_elsifs, Else: yyDollar[7].compstmt, ElsePos: yyDollar[6].tok.Position(), BlockEnd: ast.BlockEnd{End: yyDollar[8].tok.Position()}}
 			yyVAL.stmt_if.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 30:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:227
+//line parser.y:229
 		{
-			yyVAL.stmt_if = &ast.IfStmt{If: yyDollar[2].expr, Then: yyDollar[4].compstmt, ElseIf: yyDollar[5].stmt_elsifs, Else: nil}
+			yyVAL.stmt_if = &ast.IfStmt{If: yyDollar[2].expr, Then: yyDollar[4].compstmt, ElseIf: yyDollar[5].stmt_elsifs, Else: nil, BlockEnd: ast.BlockEnd{End: yyDollar[6].tok.Position()}}
 			yyVAL.stmt_if.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 31:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line parser.y:233
+//line parser.y:235
 		{
 			yyVAL.stmt_cases = ast.Stmts{}
 		}
 	case 32:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:237
+//line parser.y:239
 		{
 			yyVAL.stmt_cases = ast.Stmts{yyDollar[2].stmt_case}
 		}
 	case 33:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:241
+//line parser.y:243
 		{
 			yyVAL.stmt_cases = ast.Stmts{yyDollar[2].stmt_default}
 		}
 	case 34:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:245
+//line parser.y:247
 		{
 			yyVAL.stmt_cases = append(yyDollar[1].stmt_cases, yyDollar[2].stmt_case)
 		}
 	case 35:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:249
+//line parser.y:251
 		{
 			for _, stmt := range yyDollar[1].stmt_cases {
 				if _, ok := stmt.(*ast.DefaultStmt); ok {
@@ -1454,227 +1455,229 @@ yydefault:
 		}
 	case 36:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:260
+//line parser.y:262
 		{
 			yyVAL.stmt_case = &ast.CaseStmt{Expr: yyDollar[2].expr, Stmts: yyDollar[5].compstmt}
+			yyVAL.stmt_case.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 37:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:266
+//line parser.y:269
 		{
 			yyVAL.stmt_default = &ast.DefaultStmt{Stmts: yyDollar[4].compstmt}
+			yyVAL.stmt_default.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 38:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:272
+//line parser.y:276
 		{
-			yyVAL.expr_pair = &ast.PairExpr{Key: yyDollar[1].tok.Lit, Value: yyDollar[3].expr}
+			yyVAL.expr_pair = &ast.PairExpr{Key: yyDollar[1].tok.Lit, KeySrc: yyDollar[1].tok.Src, Value: yyDollar[3].expr}
 		}
 	case 39:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line parser.y:277
+//line parser.y:281
 		{
 			yyVAL.expr_pairs = []ast.Expr{}
 		}
 	case 40:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:281
+//line parser.y:285
 		{
 			yyVAL.expr_pairs = []ast.Expr{yyDollar[1].expr_pair}
 		}
 	case 41:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:285
+//line parser.y:289
 		{
 			yyVAL.expr_pairs = append(yyDollar[1].expr_pairs, yyDollar[4].expr_pair)
 		}
 	case 42:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line parser.y:290
+//line parser.y:294
 		{
 			yyVAL.expr_idents = []int{}
 		}
 	case 43:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:294
+//line parser.y:298
 		{
-			yyVAL.expr_idents = []int{names.UniqueNames.Set(yyDollar[1].tok.Lit)}
+			yyVAL.expr_idents = []int{yylex.(*Lexer).names.Set(yyDollar[1].tok.Lit)}
 		}
 	case 44:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:298
+//line parser.y:302
 		{
-			yyVAL.expr_idents = append(yyDollar[1].expr_idents, names.UniqueNames.Set(yyDollar[4].tok.Lit))
+			yyVAL.expr_idents = append(yyDollar[1].expr_idents, yylex.(*Lexer).names.Set(yyDollar[4].tok.Lit))
 		}
 	case 45:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:304
+//line parser.y:308
 		{
 			yyVAL.expr_many = []ast.Expr{yyDollar[1].expr}
 		}
 	case 46:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:308
+//line parser.y:312
 		{
 			yyVAL.expr_many = append(yyDollar[1].exprs, yyDollar[4].expr)
 		}
 	case 47:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:312
+//line parser.y:316
 		{
-			yyVAL.expr_many = append(yyDollar[1].exprs, &ast.IdentExpr{Lit: yyDollar[4].tok.Lit, Id: names.UniqueNames.Set(yyDollar[4].tok.Lit)})
+			yyVAL.expr_many = append(yyDollar[1].exprs, &ast.IdentExpr{Lit: yyDollar[4].tok.Lit, Id: yylex.(*Lexer).names.Set(yyDollar[4].tok.Lit)})
 		}
 	case 48:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:317
+//line parser.y:321
 		{
-			yyVAL.typ = ast.Type{Name: names.UniqueNames.Set(yyDollar[1].tok.Lit)}
+			yyVAL.typ = ast.Type{Name: yylex.(*Lexer).names.Set(yyDollar[1].tok.Lit)}
 		}
 	case 49:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:321
+//line parser.y:325
 		{
-			yyVAL.typ = ast.Type{Name: names.UniqueNames.Set(names.UniqueNames.Get(yyDollar[1].typ.Name) + "." + yyDollar[3].tok.Lit)}
+			yyVAL.typ = ast.Type{Name: yylex.(*Lexer).names.Set(yylex.(*Lexer).names.Get(yyDollar[1].typ.Name) + "." + yyDollar[3].tok.Lit)}
 		}
 	case 50:
 		yyDollar = yyS[yypt-0 : yypt+1]
-//line parser.y:326
+//line parser.y:330
 		{
 			yyVAL.exprs = nil
 		}
 	case 51:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:330
+//line parser.y:334
 		{
 			yyVAL.exprs = []ast.Expr{yyDollar[1].expr}
 		}
 	case 52:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:334
+//line parser.y:338
 		{
 			yyVAL.exprs = append(yyDollar[1].exprs, yyDollar[4].expr)
 		}
 	case 53:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:338
+//line parser.y:342
 		{
-			yyVAL.exprs = append(yyDollar[1].exprs, &ast.IdentExpr{Lit: yyDollar[4].tok.Lit, Id: names.UniqueNames.Set(yyDollar[4].tok.Lit)})
+			yyVAL.exprs = append(yyDollar[1].exprs, &ast.IdentExpr{Lit: yyDollar[4].tok.Lit, Id: yylex.(*Lexer).names.Set(yyDollar[4].tok.Lit)})
 		}
 	case 54:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:344
+//line parser.y:348
 		{
-			yyVAL.expr = &ast.IdentExpr{Lit: yyDollar[1].tok.Lit, Id: names.UniqueNames.Set(yyDollar[1].tok.Lit)}
+			yyVAL.expr = &ast.IdentExpr{Lit: yyDollar[1].tok.Lit, Id: yylex.(*Lexer).names.Set(yyDollar[1].tok.Lit)}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 55:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:349
+//line parser.y:353
 		{
 			yyVAL.expr = &ast.NumberExpr{Lit: yyDollar[1].tok.Lit}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 56:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:354
+//line parser.y:358
 		{
 			yyVAL.expr = &ast.UnaryExpr{Operator: "-", Expr: yyDollar[2].expr}
 			yyVAL.expr.SetPosition(yyDollar[2].expr.Position())
 		}
 	case 57:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:359
+//line parser.y:363
 		{
 			yyVAL.expr = &ast.UnaryExpr{Operator: "!", Expr: yyDollar[2].expr}
 			yyVAL.expr.SetPosition(yyDollar[2].expr.Position())
 		}
 	case 58:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:364
+//line parser.y:368
 		{
 			yyVAL.expr = &ast.UnaryExpr{Operator: "^", Expr: yyDollar[2].expr}
 			yyVAL.expr.SetPosition(yyDollar[2].expr.Position())
 		}
 	case 59:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:369
+//line parser.y:373
 		{
-			yyVAL.expr = &ast.StringExpr{Lit: yyDollar[1].tok.Lit}
+			yyVAL.expr = &ast.StringExpr{Lit: yyDollar[1].tok.Lit, Src: yyDollar[1].tok.Src}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 60:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:374
+//line parser.y:378
 		{
 			yyVAL.expr = &ast.ConstExpr{Value: "истина"}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 61:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:379
+//line parser.y:383
 		{
 			yyVAL.expr = &ast.ConstExpr{Value: "ложь"}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 62:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:384
+//line parser.y:388
 		{
 			yyVAL.expr = &ast.ConstExpr{Value: "неопределено"}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 63:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:389
+//line parser.y:393
 		{
 			yyVAL.expr = &ast.ConstExpr{Value: "null"}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 64:
 		yyDollar = yyS[yypt-7 : yypt+1]
-//line parser.y:394
+//line parser.y:398
 		{
 			yyVAL.expr = &ast.TernaryOpExpr{Expr: yyDollar[2].expr, Lhs: yyDollar[4].expr, Rhs: yyDollar[6].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 65:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:399
+//line parser.y:403
 		{
-			yyVAL.expr = &ast.MemberExpr{Expr: yyDollar[1].expr, Name: names.UniqueNames.Set(yyDollar[3].tok.Lit)}
+			yyVAL.expr = &ast.MemberExpr{Expr: yyDollar[1].expr, Name: yylex.(*Lexer).names.Set(yyDollar[3].tok.Lit)}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 66:
 		yyDollar = yyS[yypt-7 : yypt+1]
-//line parser.y:404
+//line parser.y:408
 		{
-			yyVAL.expr = &ast.FuncExpr{Name: names.UniqueNames.Set("<анонимная функция>"), Args: yyDollar[3].expr_idents, Stmts: yyDollar[6].compstmt}
+			yyVAL.expr = &ast.FuncExpr{Name: yylex.(*Lexer).names.Set("<анонимная функция>"), Args: yyDollar[3].expr_idents, Stmts: yyDollar[6].compstmt, BlockEnd: ast.BlockEnd{End: yyDollar[7].tok.Position()}}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 67:
 		yyDollar = yyS[yypt-8 : yypt+1]
-//line parser.y:409
+//line parser.y:413
 		{
-			yyVAL.expr = &ast.FuncExpr{Name: names.UniqueNames.Set("<анонимная функция>"), Args: []int{names.UniqueNames.Set(yyDollar[3].tok.Lit)}, Stmts: yyDollar[7].compstmt, VarArg: true}
+			yyVAL.expr = &ast.FuncExpr{Name: yylex.(*Lexer).names.Set("<анонимная функция>"), Args: []int{yylex.(*Lexer).names.Set(yyDollar[3].tok.Lit)}, Stmts: yyDollar[7].compstmt, VarArg: true, BlockEnd: ast.BlockEnd{End: yyDollar[8].tok.Position()}}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 68:
 		yyDollar = yyS[yypt-8 : yypt+1]
-//line parser.y:414
+//line parser.y:418
 		{
-			yyVAL.expr = &ast.FuncExpr{Name: names.UniqueNames.Set(yyDollar[2].tok.Lit), Args: yyDollar[4].expr_idents, Stmts: yyDollar[7].compstmt}
+			yyVAL.expr = &ast.FuncExpr{Name: yylex.(*Lexer).names.Set(yyDollar[2].tok.Lit), Args: yyDollar[4].expr_idents, Stmts: yyDollar[7].compstmt, BlockEnd: ast.BlockEnd{End: yyDollar[8].tok.Position()}}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 69:
 		yyDollar = yyS[yypt-9 : yypt+1]
-//line parser.y:419
+//line parser.y:423
 		{
-			yyVAL.expr = &ast.FuncExpr{Name: names.UniqueNames.Set(yyDollar[2].tok.Lit), Args: []int{names.UniqueNames.Set(yyDollar[4].tok.Lit)}, Stmts: yyDollar[8].compstmt, VarArg: true}
+			yyVAL.expr = &ast.FuncExpr{Name: yylex.(*Lexer).names.Set(yyDollar[2].tok.Lit), Args: []int{yylex.(*Lexer).names.Set(yyDollar[4].tok.Lit)}, Stmts: yyDollar[8].compstmt, VarArg: true, BlockEnd: ast.BlockEnd{End: yyDollar[9].tok.Position()}}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 70:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:424
+//line parser.y:428
 		{
 			yyVAL.expr = &ast.ArrayExpr{Exprs: yyDollar[3].exprs}
 			if l, ok := yylex.(*Lexer); ok {
@@ -1683,7 +1686,7 @@ yydefault:
 		}
 	case 71:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:429
+//line parser.y:433
 		{
 			yyVAL.expr = &ast.ArrayExpr{Exprs: yyDollar[3].exprs}
 			if l, ok := yylex.(*Lexer); ok {
@@ -1692,33 +1695,41 @@ yydefault:
 		}
 	case 72:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:434
+//line parser.y:438
 		{
 			mapExpr := make(map[string]ast.Expr)
+			keys := make([]string, 0, len(yyDollar[3].expr_pairs))
 			for _, v := range yyDollar[3].expr_pairs {
+				if _, ok := mapExpr[v.(*ast.PairExpr).Key]; !ok {
+					keys = append(keys, v.(*ast.PairExpr).Key)
+				}
 				mapExpr[v.(*ast.PairExpr).Key] = v.(*ast.PairExpr).Value
 			}
-			yyVAL.expr = &ast.MapExpr{MapExpr: mapExpr}
+			yyVAL.expr = &ast.MapExpr{MapExpr: mapExpr, Keys: keys, KeySrc: ast.PairKeySrc(yyDollar[3].expr_pairs)}
 			if l, ok := yylex.(*Lexer); ok {
 				yyVAL.expr.SetPosition(l.pos)
 			}
 		}
 	case 73:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:443
+//line parser.y:451
 		{
 			mapExpr := make(map[string]ast.Expr)
+			keys := make([]string, 0, len(yyDollar[3].expr_pairs))
 			for _, v := range yyDollar[3].expr_pairs {
+				if _, ok := mapExpr[v.(*ast.PairExpr).Key]; !ok {
+					keys = append(keys, v.(*ast.PairExpr).Key)
+				}
 				mapExpr[v.(*ast.PairExpr).Key] = v.(*ast.PairExpr).Value
 			}
-			yyVAL.expr = &ast.MapExpr{MapExpr: mapExpr}
+			yyVAL.expr = &ast.MapExpr{MapExpr: mapExpr, Keys: keys, KeySrc: ast.PairKeySrc(yyDollar[3].expr_pairs)}
 			if l, ok := yylex.(*Lexer); ok {
 				yyVAL.expr.SetPosition(l.pos)
 			}
 		}
 	case 74:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:452
+//line parser.y:464
 		{
 			yyVAL.expr = &ast.ParenExpr{SubExpr: yyDollar[2].expr}
 			if l, ok := yylex.(*Lexer); ok {
@@ -1727,386 +1738,386 @@ yydefault:
 		}
 	case 75:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:457
+//line parser.y:469
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "+", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 76:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:462
+//line parser.y:474
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "-", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 77:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:467
+//line parser.y:479
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "*", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 78:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:472
+//line parser.y:484
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "/", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 79:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:477
+//line parser.y:489
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "%", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 80:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:482
+//line parser.y:494
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "**", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 81:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:487
+//line parser.y:499
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "<<", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 82:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:492
+//line parser.y:504
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: ">>", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 83:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:497
+//line parser.y:509
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "==", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 84:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:502
+//line parser.y:514
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "!=", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 85:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:507
+//line parser.y:519
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: ">", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 86:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:512
+//line parser.y:524
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: ">=", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 87:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:517
+//line parser.y:529
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "<", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 88:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:522
+//line parser.y:534
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "<=", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 89:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:527
+//line parser.y:539
 		{
 			yyVAL.expr = &ast.AssocExpr{Lhs: yyDollar[1].expr, Operator: "+=", Rhs: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 90:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:532
+//line parser.y:544
 		{
 			yyVAL.expr = &ast.AssocExpr{Lhs: yyDollar[1].expr, Operator: "-=", Rhs: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 91:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:537
+//line parser.y:549
 		{
 			yyVAL.expr = &ast.AssocExpr{Lhs: yyDollar[1].expr, Operator: "*=", Rhs: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 92:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:542
+//line parser.y:554
 		{
 			yyVAL.expr = &ast.AssocExpr{Lhs: yyDollar[1].expr, Operator: "/=", Rhs: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 93:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:547
+//line parser.y:559
 		{
 			yyVAL.expr = &ast.AssocExpr{Lhs: yyDollar[1].expr, Operator: "&=", Rhs: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 94:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:552
+//line parser.y:564
 		{
 			yyVAL.expr = &ast.AssocExpr{Lhs: yyDollar[1].expr, Operator: "|=", Rhs: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 95:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:557
+//line parser.y:569
 		{
 			yyVAL.expr = &ast.AssocExpr{Lhs: yyDollar[1].expr, Operator: "++"}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 96:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:562
+//line parser.y:574
 		{
 			yyVAL.expr = &ast.AssocExpr{Lhs: yyDollar[1].expr, Operator: "--"}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 97:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:567
+//line parser.y:579
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "|", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 98:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:572
+//line parser.y:584
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "||", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 99:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:577
+//line parser.y:589
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "&", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 100:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:582
+//line parser.y:594
 		{
 			yyVAL.expr = &ast.BinOpExpr{Lhss: []ast.Expr{yyDollar[1].expr}, Operator: "&&", Rhss: []ast.Expr{yyDollar[3].expr}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 101:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:587
+//line parser.y:599
 		{
-			yyVAL.expr = &ast.CallExpr{Name: names.UniqueNames.Set(yyDollar[1].tok.Lit), SubExprs: yyDollar[3].exprs, VarArg: true}
+			yyVAL.expr = &ast.CallExpr{Name: yylex.(*Lexer).names.Set(yyDollar[1].tok.Lit), SubExprs: yyDollar[3].exprs, VarArg: true}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 102:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:592
+//line parser.y:604
 		{
-			yyVAL.expr = &ast.CallExpr{Name: names.UniqueNames.Set(yyDollar[1].tok.Lit), SubExprs: yyDollar[3].exprs}
+			yyVAL.expr = &ast.CallExpr{Name: yylex.(*Lexer).names.Set(yyDollar[1].tok.Lit), SubExprs: yyDollar[3].exprs}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 103:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:597
+//line parser.y:609
 		{
-			yyVAL.expr = &ast.CallExpr{Name: names.UniqueNames.Set(yyDollar[2].tok.Lit), SubExprs: yyDollar[4].exprs, VarArg: true, Go: true}
+			yyVAL.expr = &ast.CallExpr{Name: yylex.(*Lexer).names.Set(yyDollar[2].tok.Lit), SubExprs: yyDollar[4].exprs, VarArg: true, Go: true}
 			yyVAL.expr.SetPosition(yyDollar[2].tok.Position())
 		}
 	case 104:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:602
+//line parser.y:614
 		{
-			yyVAL.expr = &ast.CallExpr{Name: names.UniqueNames.Set(yyDollar[2].tok.Lit), SubExprs: yyDollar[4].exprs, Go: true}
+			yyVAL.expr = &ast.CallExpr{Name: yylex.(*Lexer).names.Set(yyDollar[2].tok.Lit), SubExprs: yyDollar[4].exprs, Go: true}
 			yyVAL.expr.SetPosition(yyDollar[2].tok.Position())
 		}
 	case 105:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:607
+//line parser.y:619
 		{
 			yyVAL.expr = &ast.AnonCallExpr{Expr: yyDollar[1].expr, SubExprs: yyDollar[3].exprs, VarArg: true}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 106:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:612
+//line parser.y:624
 		{
 			yyVAL.expr = &ast.AnonCallExpr{Expr: yyDollar[1].expr, SubExprs: yyDollar[3].exprs}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 107:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:617
+//line parser.y:629
 		{
 			yyVAL.expr = &ast.AnonCallExpr{Expr: yyDollar[2].expr, SubExprs: yyDollar[4].exprs, VarArg: true, Go: true}
 			yyVAL.expr.SetPosition(yyDollar[2].expr.Position())
 		}
 	case 108:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:622
+//line parser.y:634
 		{
 			yyVAL.expr = &ast.AnonCallExpr{Expr: yyDollar[2].expr, SubExprs: yyDollar[4].exprs, Go: true}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 109:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:627
+//line parser.y:639
 		{
-			yyVAL.expr = &ast.ItemExpr{Value: &ast.IdentExpr{Lit: yyDollar[1].tok.Lit, Id: names.UniqueNames.Set(yyDollar[1].tok.Lit)}, Index: yyDollar[3].expr}
+			yyVAL.expr = &ast.ItemExpr{Value: &ast.IdentExpr{Lit: yyDollar[1].tok.Lit, Id: yylex.(*Lexer).names.Set(yyDollar[1].tok.Lit)}, Index: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 110:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:632
+//line parser.y:644
 		{
 			yyVAL.expr = &ast.ItemExpr{Value: yyDollar[1].expr, Index: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 111:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:637
+//line parser.y:649
 		{
-			yyVAL.expr = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: yyDollar[1].tok.Lit, Id: names.UniqueNames.Set(yyDollar[1].tok.Lit)}, Begin: yyDollar[3].expr, End: yyDollar[5].expr}
+			yyVAL.expr = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: yyDollar[1].tok.Lit, Id: yylex.(*Lexer).names.Set(yyDollar[1].tok.Lit)}, Begin: yyDollar[3].expr, End: yyDollar[5].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 112:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:642
+//line parser.y:654
 		{
-			yyVAL.expr = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: yyDollar[1].tok.Lit, Id: names.UniqueNames.Set(yyDollar[1].tok.Lit)}, Begin: yyDollar[3].expr, End: &ast.NoneExpr{}}
+			yyVAL.expr = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: yyDollar[1].tok.Lit, Id: yylex.(*Lexer).names.Set(yyDollar[1].tok.Lit)}, Begin: yyDollar[3].expr, End: &ast.NoneExpr{}}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 113:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:647
+//line parser.y:659
 		{
-			yyVAL.expr = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: yyDollar[1].tok.Lit, Id: names.UniqueNames.Set(yyDollar[1].tok.Lit)}, Begin: &ast.NoneExpr{}, End: yyDollar[4].expr}
+			yyVAL.expr = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: yyDollar[1].tok.Lit, Id: yylex.(*Lexer).names.Set(yyDollar[1].tok.Lit)}, Begin: &ast.NoneExpr{}, End: yyDollar[4].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 114:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:652
+//line parser.y:664
 		{
 			yyVAL.expr = &ast.SliceExpr{Value: yyDollar[1].expr, Begin: yyDollar[3].expr, End: yyDollar[5].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 115:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:657
+//line parser.y:669
 		{
 			yyVAL.expr = &ast.SliceExpr{Value: yyDollar[1].expr, Begin: yyDollar[3].expr, End: &ast.NoneExpr{}}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 116:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:662
+//line parser.y:674
 		{
 			yyVAL.expr = &ast.SliceExpr{Value: yyDollar[1].expr, Begin: &ast.NoneExpr{}, End: yyDollar[4].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 117:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:667
+//line parser.y:679
 		{
 			yyVAL.expr = &ast.MakeExpr{Type: yyDollar[2].typ.Name}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 118:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:672
+//line parser.y:684
 		{
 			yyVAL.expr = &ast.MakeChanExpr{SizeExpr: &ast.NoneExpr{}}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 119:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:677
+//line parser.y:689
 		{
 			yyVAL.expr = &ast.MakeChanExpr{SizeExpr: yyDollar[4].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 120:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:682
+//line parser.y:694
 		{
 			yyVAL.expr = &ast.MakeArrayExpr{LenExpr: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 121:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:687
+//line parser.y:699
 		{
 			yyVAL.expr = &ast.MakeArrayExpr{LenExpr: yyDollar[3].expr, CapExpr: yyDollar[5].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 122:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.y:692
+//line parser.y:704
 		{
 			yyVAL.expr = &ast.TypeCast{Type: yyDollar[2].typ.Name, CastExpr: yyDollar[4].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 123:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.y:697
+//line parser.y:709
 		{
 			yyVAL.expr = &ast.MakeExpr{TypeExpr: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 124:
 		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.y:702
+//line parser.y:714
 		{
 			yyVAL.expr = &ast.TypeCast{TypeExpr: yyDollar[3].expr, CastExpr: yyDollar[5].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].tok.Position())
 		}
 	case 125:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.y:707
+//line parser.y:719
 		{
 			yyVAL.expr = &ast.ChanExpr{Lhs: yyDollar[1].expr, Rhs: yyDollar[3].expr}
 			yyVAL.expr.SetPosition(yyDollar[1].expr.Position())
 		}
 	case 126:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:712
+//line parser.y:724
 		{
 			yyVAL.expr = &ast.ChanExpr{Rhs: yyDollar[2].expr}
 			yyVAL.expr.SetPosition(yyDollar[2].expr.Position())
 		}
 	case 129:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:723
+//line parser.y:735
 		{
 		}
 	case 130:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.y:726
+//line parser.y:738
 		{
 		}
 	case 131:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:731
+//line parser.y:743
 		{
 		}
 	case 132:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.y:734
+//line parser.y:746
 		{
 		}
 	}
@@ -3,7 +3,6 @@ package parser
 
 import (
 	"github.com/covrom/gonec/ast"
-	"github.com/covrom/gonec/names"
 )
 
 %}
@@ -95,7 +94,7 @@ modules :
 module :
 	MODULE IDENT terms compstmt
 	{
-		$$ = &ast.ModuleStmt{Name: names.UniqueNames.Set($2.Lit), Stmts: $4}
+		$$ = &ast.ModuleStmt{Name: yylex.(*Lexer).names.Set($2.Lit), Stmts: $4}
 		$$.SetPosition($1.Position())
 	}
 
@@ -135,6 +134,7 @@ stmt :
 	| expr_many EQEQ expr_many
 	{
 		$$ = &ast.ExprStmt{Expr: &ast.BinOpExpr{Lhss: $1, Operator: "==", Rhss: $3}}
+		$$.SetPosition($1[0].Position())
 	}
 	| BREAK
 	{
@@ -163,37 +163,37 @@ stmt :
 	}
 	| FOR EACH IDENT IN expr '{' compstmt '}'
 	{
-		$$ = &ast.ForStmt{Var: names.UniqueNames.Set($3.Lit), Value: $5, Stmts: $7}
+		$$ = &ast.ForStmt{Var: yylex.(*Lexer).names.Set($3.Lit), Value: $5, Stmts: $7, BlockEnd: ast.BlockEnd{End: $<tok>8.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| FOR IDENT '=' expr TO expr '{' compstmt '}'
 	{
-		$$ = &ast.NumForStmt{Name: names.UniqueNames.Set($2.Lit), Expr1: $4, Expr2: $6, Stmts: $8}
+		$$ = &ast.NumForStmt{Name: yylex.(*Lexer).names.Set($2.Lit), Expr1: $4, Expr2: $6, Stmts: $8, BlockEnd: ast.BlockEnd{End: $<tok>9.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| FOR IDENT EQEQ expr TO expr '{' compstmt '}'
 	{
-		$$ = &ast.NumForStmt{Name: names.UniqueNames.Set($2.Lit), Expr1: $4, Expr2: $6, Stmts: $8}
+		$$ = &ast.NumForStmt{Name: yylex.(*Lexer).names.Set($2.Lit), Expr1: $4, Expr2: $6, Stmts: $8, BlockEnd: ast.BlockEnd{End: $<tok>9.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| WHILE expr '{' compstmt '}'
 	{
-		$$ = &ast.LoopStmt{Expr: $2, Stmts: $4}
+		$$ = &ast.LoopStmt{Expr: $2, Stmts: $4, BlockEnd: ast.BlockEnd{End: $<tok>5.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| TRY compstmt CATCH compstmt '}'
 	{
-		$$ = &ast.TryStmt{Try: $2, Catch: $4}
+		$$ = &ast.TryStmt{Try: $2, Catch: $4, CatchPos: $3.Position(), BlockEnd: ast.BlockEnd{End: $<tok>5.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| SWITCH expr ':' stmt_cases '}'
 	{
-		$$ = &ast.SwitchStmt{Expr: $2, Cases: $4}
+		$$ = &ast.SwitchStmt{Expr: $2, Cases: $4, BlockEnd: ast.BlockEnd{End: $<tok>5.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| SWITCH ':' stmt_cases '}'
 	{
-		$$ = &ast.SelectStmt{Cases: $3}
+		$$ = &ast.SelectStmt{Cases: $3, BlockEnd: ast.BlockEnd{End: $<tok>4.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| expr
@@ -215,17 +215,18 @@ stmt_elsif :
 	ELSIF expr '{' compstmt
 	{
 		$$ = &ast.IfStmt{If: $2, Then: $4}
+		$$.SetPosition($1.Position())
 	}
 
 stmt_if :
 	IF expr '{' compstmt stmt_elsifs ELSE compstmt '}'
 	{
-		$$ = &ast.IfStmt{If: $2, Then: $4, ElseIf: $5, Else: $7}
+		$$ = &ast.IfStmt{If: $2, Then: $4, ElseIf: $5, Else: $7, ElsePos: $6.Position(), BlockEnd: ast.BlockEnd{End: $<tok>8.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| IF expr '{' compstmt stmt_elsifs '}'
 	{
-		$$ = &ast.IfStmt{If: $2, Then: $4, ElseIf: $5, Else: nil}
+		$$ = &ast.IfStmt{If: $2, Then: $4, ElseIf: $5, Else: nil, BlockEnd: ast.BlockEnd{End: $<tok>6.Position()}}
 		$$.SetPosition($1.Position())
 	}
 
@@ -259,18 +260,20 @@ stmt_case :
 	CASE expr ':' opt_terms compstmt
 	{
 		$$ = &ast.CaseStmt{Expr: $2, Stmts: $5}
+		$$.SetPosition($1.Position())
 	}
 
 stmt_default :
 	DEFAULT ':' opt_terms compstmt
 	{
 		$$ = &ast.DefaultStmt{Stmts: $4}
+		$$.SetPosition($1.Position())
 	}
 
 expr_pair :
 	STRING ':' expr
 	{
-		$$ = &ast.PairExpr{Key: $1.Lit, Value: $3}
+		$$ = &ast.PairExpr{Key: $1.Lit, KeySrc: $1.Src, Value: $3}
 	}
 
 expr_pairs :
@@ -292,11 +295,11 @@ expr_idents :
 	}
 	| IDENT
 	{
-		$$ = []int{names.UniqueNames.Set($1.Lit)}
+		$$ = []int{yylex.(*Lexer).names.Set($1.Lit)}
 	}
 	| expr_idents ',' opt_terms IDENT
 	{
-		$$ = append($1, names.UniqueNames.Set($4.Lit))
+		$$ = append($1, yylex.(*Lexer).names.Set($4.Lit))
 	}
 
 expr_many :
@@ -310,16 +313,16 @@ expr_many :
 	}
 	| exprs ',' opt_terms IDENT
 	{
-		$$ = append($1, &ast.IdentExpr{Lit: $4.Lit, Id: names.UniqueNames.Set($4.Lit)})
+		$$ = append($1, &ast.IdentExpr{Lit: $4.Lit, Id: yylex.(*Lexer).names.Set($4.Lit)})
 	}
 
 typ : IDENT
 	{
-		$$ = ast.Type{Name: names.UniqueNames.Set($1.Lit)}
+		$$ = ast.Type{Name: yylex.(*Lexer).names.Set($1.Lit)}
 	}
 	| typ '.' IDENT
 	{
-		$$ = ast.Type{Name: names.UniqueNames.Set(names.UniqueNames.Get($1.Name) + "." + $3.Lit)}
+		$$ = ast.Type{Name: yylex.(*Lexer).names.Set(yylex.(*Lexer).names.Get($1.Name) + "." + $3.Lit)}
 	}
 
 exprs :
@@ -336,13 +339,13 @@ exprs :
 	}
 	| exprs ',' opt_terms IDENT
 	{
-		$$ = append($1, &ast.IdentExpr{Lit: $4.Lit, Id: names.UniqueNames.Set($4.Lit)})
+		$$ = append($1, &ast.IdentExpr{Lit: $4.Lit, Id: yylex.(*Lexer).names.Set($4.Lit)})
 	}
 
 expr :
 	IDENT
 	{
-		$$ = &ast.IdentExpr{Lit: $1.Lit, Id: names.UniqueNames.Set($1.Lit)}
+		$$ = &ast.IdentExpr{Lit: $1.Lit, Id: yylex.(*Lexer).names.Set($1.Lit)}
 		$$.SetPosition($1.Position())
 	}
 	| NUMBER
@@ -367,7 +370,7 @@ expr :
 	}
 	| STRING
 	{
-		$$ = &ast.StringExpr{Lit: $1.Lit}
+		$$ = &ast.StringExpr{Lit: $1.Lit, Src: $1.Src}
 		$$.SetPosition($1.Position())
 	}
 	| TRUE
@@ -397,27 +400,27 @@ expr :
 	}
 	| expr '.' IDENT
 	{
-		$$ = &ast.MemberExpr{Expr: $1, Name: names.UniqueNames.Set($3.Lit)}
+		$$ = &ast.MemberExpr{Expr: $1, Name: yylex.(*Lexer).names.Set($3.Lit)}
 		$$.SetPosition($1.Position())
 	}
 	| FUNC '(' expr_idents ')' opt_terms compstmt '}'
 	{
-		$$ = &ast.FuncExpr{Name:names.UniqueNames.Set("<анонимная функция>"), Args: $3, Stmts: $6}
+		$$ = &ast.FuncExpr{Name:yylex.(*Lexer).names.Set("<анонимная функция>"), Args: $3, Stmts: $6, BlockEnd: ast.BlockEnd{End: $<tok>7.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| FUNC '(' IDENT VARARG ')' opt_terms compstmt '}'
 	{
-		$$ = &ast.FuncExpr{Name:names.UniqueNames.Set("<анонимная функция>"), Args: []int{names.UniqueNames.Set($3.Lit)}, Stmts: $7, VarArg: true}
+		$$ = &ast.FuncExpr{Name:yylex.(*Lexer).names.Set("<анонимная функция>"), Args: []int{yylex.(*Lexer).names.Set($3.Lit)}, Stmts: $7, VarArg: true, BlockEnd: ast.BlockEnd{End: $<tok>8.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| FUNC IDENT '(' expr_idents ')' opt_terms compstmt '}'
 	{
-		$$ = &ast.FuncExpr{Name: names.UniqueNames.Set($2.Lit), Args: $4, Stmts: $7}
+		$$ = &ast.FuncExpr{Name: yylex.(*Lexer).names.Set($2.Lit), Args: $4, Stmts: $7, BlockEnd: ast.BlockEnd{End: $<tok>8.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| FUNC IDENT '(' IDENT VARARG ')' opt_terms compstmt '}'
 	{
-		$$ = &ast.FuncExpr{Name: names.UniqueNames.Set($2.Lit), Args: []int{names.UniqueNames.Set($4.Lit)}, Stmts: $8, VarArg: true}
+		$$ = &ast.FuncExpr{Name: yylex.(*Lexer).names.Set($2.Lit), Args: []int{yylex.(*Lexer).names.Set($4.Lit)}, Stmts: $8, VarArg: true, BlockEnd: ast.BlockEnd{End: $<tok>9.Position()}}
 		$$.SetPosition($1.Position())
 	}
 	| '[' opt_terms exprs opt_terms ']'
@@ -433,19 +436,27 @@ expr :
 	| '{' opt_terms expr_pairs opt_terms '}'
 	{
 		mapExpr := make(map[string]ast.Expr)
+		keys := make([]string, 0, len($3))
 		for _, v := range $3 {
+			if _, ok := mapExpr[v.(*ast.PairExpr).Key]; !ok {
+				keys = append(keys, v.(*ast.PairExpr).Key)
+			}
 			mapExpr[v.(*ast.PairExpr).Key] = v.(*ast.PairExpr).Value
 		}
-		$$ = &ast.MapExpr{MapExpr: mapExpr}
+		$$ = &ast.MapExpr{MapExpr: mapExpr, Keys: keys, KeySrc: ast.PairKeySrc($3)}
 		if l, ok := yylex.(*Lexer); ok { $$.SetPosition(l.pos) }
 	}
 	| '{' opt_terms expr_pairs ',' opt_terms '}'
 	{
 		mapExpr := make(map[string]ast.Expr)
+		keys := make([]string, 0, len($3))
 		for _, v := range $3 {
+			if _, ok := mapExpr[v.(*ast.PairExpr).Key]; !ok {
+				keys = append(keys, v.(*ast.PairExpr).Key)
+			}
 			mapExpr[v.(*ast.PairExpr).Key] = v.(*ast.PairExpr).Value
 		}
-		$$ = &ast.MapExpr{MapExpr: mapExpr}
+		$$ = &ast.MapExpr{MapExpr: mapExpr, Keys: keys, KeySrc: ast.PairKeySrc($3)}
 		if l, ok := yylex.(*Lexer); ok { $$.SetPosition(l.pos) }
 	}
 	| '(' expr ')'
@@ -585,22 +596,22 @@ expr :
 	}
 	| IDENT '(' exprs VARARG ')'
 	{
-		$$ = &ast.CallExpr{Name: names.UniqueNames.Set($1.Lit), SubExprs: $3, VarArg: true}
+		$$ = &ast.CallExpr{Name: yylex.(*Lexer).names.Set($1.Lit), SubExprs: $3, VarArg: true}
 		$$.SetPosition($1.Position())
 	}
 	| IDENT '(' exprs ')'
 	{
-		$$ = &ast.CallExpr{Name: names.UniqueNames.Set($1.Lit), SubExprs: $3}
+		$$ = &ast.CallExpr{Name: yylex.(*Lexer).names.Set($1.Lit), SubExprs: $3}
 		$$.SetPosition($1.Position())
 	}
 	| GO IDENT '(' exprs VARARG ')'
 	{
-		$$ = &ast.CallExpr{Name: names.UniqueNames.Set($2.Lit), SubExprs: $4, VarArg: true, Go: true}
+		$$ = &ast.CallExpr{Name: yylex.(*Lexer).names.Set($2.Lit), SubExprs: $4, VarArg: true, Go: true}
 		$$.SetPosition($2.Position())
 	}
 	| GO IDENT '(' exprs ')'
 	{
-		$$ = &ast.CallExpr{Name: names.UniqueNames.Set($2.Lit), SubExprs: $4, Go: true}
+		$$ = &ast.CallExpr{Name: yylex.(*Lexer).names.Set($2.Lit), SubExprs: $4, Go: true}
 		$$.SetPosition($2.Position())
 	}
 	| expr '(' exprs VARARG ')'
@@ -625,7 +636,7 @@ expr :
 	}
 	| IDENT '[' expr ']'
 	{
-		$$ = &ast.ItemExpr{Value: &ast.IdentExpr{Lit: $1.Lit, Id: names.UniqueNames.Set($1.Lit)}, Index: $3}
+		$$ = &ast.ItemExpr{Value: &ast.IdentExpr{Lit: $1.Lit, Id: yylex.(*Lexer).names.Set($1.Lit)}, Index: $3}
 		$$.SetPosition($1.Position())
 	}
 	| expr '[' expr ']'
@@ -635,17 +646,17 @@ expr :
 	}
 	| IDENT '[' expr ':' expr ']'
 	{
-		$$ = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: $1.Lit, Id: names.UniqueNames.Set($1.Lit)}, Begin: $3, End: $5}
+		$$ = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: $1.Lit, Id: yylex.(*Lexer).names.Set($1.Lit)}, Begin: $3, End: $5}
 		$$.SetPosition($1.Position())
 	}
 	| IDENT '[' expr ':' ']'
 	{
-		$$ = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: $1.Lit, Id: names.UniqueNames.Set($1.Lit)}, Begin: $3, End: &ast.NoneExpr{}}
+		$$ = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: $1.Lit, Id: yylex.(*Lexer).names.Set($1.Lit)}, Begin: $3, End: &ast.NoneExpr{}}
 		$$.SetPosition($1.Position())
 	}
 	| IDENT '[' ':' expr ']'
 	{
-		$$ = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: $1.Lit, Id: names.UniqueNames.Set($1.Lit)}, Begin: &ast.NoneExpr{}, End: $4}
+		$$ = &ast.SliceExpr{Value: &ast.IdentExpr{Lit: $1.Lit, Id: yylex.(*Lexer).names.Set($1.Lit)}, Begin: &ast.NoneExpr{}, End: $4}
 		$$.SetPosition($1.Position())
 	}
 	| expr '[' expr ':' expr ']'
@@ -0,0 +1,78 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/covrom/gonec/format"
+	"github.com/covrom/gonec/names"
+)
+
+var keywords = format.Keywords(format.StyleTitle)
+
+// Complete дополняет слово перед курсором ключевым словом, именем стандартной библиотеки
+// или именем, определенным в сеансе. Регистр первой буквы варианта совпадает с введенным.
+// В начале строки после двоеточия дополняются служебные команды
+func (s *Session) Complete(line []rune, pos int) (int, []string) {
+	start := pos
+	for start > 0 && isIdent(line[start-1]) {
+		start--
+	}
+	if start == 1 && line[0] == ':' && s.code == "" {
+		return 0, matchCommands(string(line[:pos]))
+	}
+	if start == pos || (start > 0 && line[start-1] == '.') {
+		// поля и методы значений не дополняются
+		return start, nil
+	}
+	word := string(line[start:pos])
+	low := names.FastToLower(word)
+	upper := unicode.IsUpper([]rune(word)[0])
+
+	seen := make(map[string]bool)
+	var res []string
+	add := func(c string) {
+		lc := names.FastToLower(c)
+		if seen[lc] || !strings.HasPrefix(lc, low) {
+			return
+		}
+		seen[lc] = true
+		switch {
+		case !upper:
+			c = lc
+		case c == lc:
+			r := []rune(c)
+			r[0] = unicode.ToUpper(r[0])
+			c = string(r)
+		}
+		res = append(res, c)
+	}
+	for _, k := range keywords {
+		add(k)
+	}
+	for _, n := range s.mod.DefinedNames() {
+		if strings.HasPrefix(n, "_") {
+			// служебные имена
+			continue
+		}
+		add(n)
+	}
+	sort.Strings(res)
+	return start, res
+}
+
+func matchCommands(prefix string) []string {
+	var res []string
+	for _, c := range commands {
+		name := strings.Fields(c[0])[0]
+		if strings.HasPrefix(name, names.FastToLower(prefix)) {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+func isIdent(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
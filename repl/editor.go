@@ -0,0 +1,371 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ErrInterrupted возвращается ReadLine при нажатии Ctrl+C
+var ErrInterrupted = errors.New("Ввод прерван")
+
+// CompleteFunc возвращает начало дополняемого слова в строке line перед позицией курсора pos
+// и варианты, которыми можно заменить line[start:pos]
+type CompleteFunc func(line []rune, pos int) (start int, candidates []string)
+
+// Editor - редактор строки ввода с историей и автодополнением.
+// Если ввод не является терминалом, строки читаются без редактирования
+type Editor struct {
+	in   *bufio.Reader
+	out  io.Writer
+	fd   uintptr
+	term bool // ввод с терминала, строка редактируется
+	raw  bool // перед чтением терминал переводится в посимвольный режим
+
+	// Complete вызывается при нажатии Tab, nil - автодополнение отключено
+	Complete CompleteFunc
+	// MaxHistory - максимальное количество строк истории
+	MaxHistory int
+
+	history []string
+}
+
+// NewEditor создает редактор, читающий in и выводящий в out
+func NewEditor(in *os.File, out io.Writer) *Editor {
+	e := newEditor(in, out, isatty.IsTerminal(in.Fd()))
+	e.fd = in.Fd()
+	e.raw = e.term
+	return e
+}
+
+func newEditor(in io.Reader, out io.Writer, term bool) *Editor {
+	return &Editor{
+		in:         bufio.NewReader(in),
+		out:        out,
+		term:       term,
+		MaxHistory: 1000,
+	}
+}
+
+// History возвращает строки истории, начиная с самой старой
+func (e *Editor) History() []string {
+	return e.history
+}
+
+// AddHistory добавляет строку в историю, пустые строки и повтор последней строки не добавляются
+func (e *Editor) AddHistory(line string) {
+	if strings.TrimSpace(line) == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if e.MaxHistory > 0 && len(e.history) > e.MaxHistory {
+		e.history = e.history[len(e.history)-e.MaxHistory:]
+	}
+}
+
+// LoadHistory читает историю, по одной строке в строке текста
+func (e *Editor) LoadHistory(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		e.AddHistory(sc.Text())
+	}
+	return sc.Err()
+}
+
+// SaveHistory записывает историю, по одной строке в строке текста
+func (e *Editor) SaveHistory(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, l := range e.history {
+		fmt.Fprintln(bw, l)
+	}
+	return bw.Flush()
+}
+
+// ReadLine выводит приглашение и читает строку. В конце ввода (Ctrl+D на пустой строке) возвращается io.EOF,
+// при нажатии Ctrl+C - ErrInterrupted
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if !e.term {
+		return e.readPlain(prompt)
+	}
+	if e.raw {
+		restore, err := makeRaw(e.fd)
+		if err != nil {
+			// терминал не поддерживает посимвольный ввод
+			return e.readPlain(prompt)
+		}
+		defer restore()
+	}
+	st := &lineState{e: e, prompt: prompt, hist: len(e.history)}
+	st.refresh()
+	return st.edit()
+}
+
+func (e *Editor) readPlain(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	line, err := e.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// lineState - состояние редактируемой строки
+type lineState struct {
+	e      *Editor
+	prompt string
+	buf    []rune
+	pos    int
+
+	hist    int    // позиция в истории, len(history) - редактируемая строка
+	saved   []rune // редактируемая строка при переходе по истории
+	lastTab bool   // предыдущая клавиша - Tab
+}
+
+// клавиши, распознаваемые в управляющих последовательностях
+const (
+	keyUp rune = -(iota + 1)
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+func (st *lineState) edit() (string, error) {
+	for {
+		r, _, err := st.e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(st.buf) > 0 {
+				break
+			}
+			return "", err
+		}
+		if r == 27 {
+			r = st.escape()
+		}
+		tab := false
+		switch r {
+		case '\r', '\n':
+			st.pos = len(st.buf)
+			st.refresh()
+			fmt.Fprint(st.e.out, "\r\n")
+			return string(st.buf), nil
+		case 3: // Ctrl+C
+			fmt.Fprint(st.e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case 4: // Ctrl+D
+			if len(st.buf) == 0 {
+				fmt.Fprint(st.e.out, "\r\n")
+				return "", io.EOF
+			}
+			st.delete()
+		case 1, keyHome: // Ctrl+A
+			st.pos = 0
+		case 5, keyEnd: // Ctrl+E
+			st.pos = len(st.buf)
+		case 2, keyLeft: // Ctrl+B
+			if st.pos > 0 {
+				st.pos--
+			}
+		case 6, keyRight: // Ctrl+F
+			if st.pos < len(st.buf) {
+				st.pos++
+			}
+		case 8, 127: // Backspace
+			if st.pos > 0 {
+				st.pos--
+				st.delete()
+			}
+		case keyDelete:
+			st.delete()
+		case 11: // Ctrl+K
+			st.buf = st.buf[:st.pos]
+		case 21: // Ctrl+U
+			st.buf = append([]rune{}, st.buf[st.pos:]...)
+			st.pos = 0
+		case 23: // Ctrl+W
+			i := st.pos
+			for i > 0 && unicode.IsSpace(st.buf[i-1]) {
+				i--
+			}
+			for i > 0 && !unicode.IsSpace(st.buf[i-1]) {
+				i--
+			}
+			st.buf = append(st.buf[:i], st.buf[st.pos:]...)
+			st.pos = i
+		case 12: // Ctrl+L
+			fmt.Fprint(st.e.out, "\x1b[H\x1b[2J")
+		case 16, keyUp: // Ctrl+P
+			st.historyMove(-1)
+		case 14, keyDown: // Ctrl+N
+			st.historyMove(1)
+		case '\t':
+			tab = true
+			st.complete()
+		case keyUnknown:
+		default:
+			if unicode.IsPrint(r) {
+				st.buf = append(st.buf, 0)
+				copy(st.buf[st.pos+1:], st.buf[st.pos:])
+				st.buf[st.pos] = r
+				st.pos++
+			}
+		}
+		st.lastTab = tab
+		st.refresh()
+	}
+	return string(st.buf), nil
+}
+
+// escape разбирает управляющую последовательность после ESC
+func (st *lineState) escape() rune {
+	r, _, err := st.e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return keyUnknown
+	}
+	var arg []rune
+	for {
+		r, _, err = st.e.in.ReadRune()
+		if err != nil {
+			return keyUnknown
+		}
+		if r >= '0' && r <= '9' || r == ';' {
+			arg = append(arg, r)
+			continue
+		}
+		break
+	}
+	switch r {
+	case 'A':
+		return keyUp
+	case 'B':
+		return keyDown
+	case 'C':
+		return keyRight
+	case 'D':
+		return keyLeft
+	case 'H':
+		return keyHome
+	case 'F':
+		return keyEnd
+	case '~':
+		switch string(arg) {
+		case "1", "7":
+			return keyHome
+		case "4", "8":
+			return keyEnd
+		case "3":
+			return keyDelete
+		}
+	}
+	return keyUnknown
+}
+
+func (st *lineState) delete() {
+	if st.pos < len(st.buf) {
+		st.buf = append(st.buf[:st.pos], st.buf[st.pos+1:]...)
+	}
+}
+
+func (st *lineState) historyMove(d int) {
+	h := st.e.history
+	n := st.hist + d
+	if n < 0 || n > len(h) {
+		return
+	}
+	if st.hist == len(h) {
+		st.saved = st.buf
+	}
+	st.hist = n
+	if n == len(h) {
+		st.buf = st.saved
+	} else {
+		st.buf = []rune(h[n])
+	}
+	st.pos = len(st.buf)
+}
+
+func (st *lineState) complete() {
+	if st.e.Complete == nil {
+		return
+	}
+	start, cands := st.e.Complete(st.buf, st.pos)
+	if len(cands) == 0 {
+		fmt.Fprint(st.e.out, "\a")
+		return
+	}
+	word := st.buf[start:st.pos]
+	repl := []rune(cands[0])
+	if len(cands) > 1 {
+		repl = commonPrefix(cands)
+		if len(repl) <= len(word) {
+			if st.lastTab {
+				st.list(cands)
+			} else {
+				fmt.Fprint(st.e.out, "\a")
+			}
+			return
+		}
+	}
+	tail := append([]rune{}, st.buf[st.pos:]...)
+	st.buf = append(append(st.buf[:start], repl...), tail...)
+	st.pos = start + len(repl)
+}
+
+// list выводит варианты дополнения под строкой ввода
+func (st *lineState) list(cands []string) {
+	sorted := append([]string{}, cands...)
+	sort.Strings(sorted)
+	width := 0
+	for _, c := range sorted {
+		if n := utf8.RuneCountInString(c); n > width {
+			width = n
+		}
+	}
+	width += 2
+	cols := 80 / width
+	if cols < 1 {
+		cols = 1
+	}
+	fmt.Fprint(st.e.out, "\r\n")
+	for i, c := range sorted {
+		fmt.Fprint(st.e.out, c+strings.Repeat(" ", width-utf8.RuneCountInString(c)))
+		if (i+1)%cols == 0 || i == len(sorted)-1 {
+			fmt.Fprint(st.e.out, "\r\n")
+		}
+	}
+}
+
+// commonPrefix возвращает общее начало вариантов без учета регистра, в написании первого варианта
+func commonPrefix(cands []string) []rune {
+	first := []rune(cands[0])
+	n := len(first)
+	for _, c := range cands[1:] {
+		rc := []rune(c)
+		i := 0
+		for i < n && i < len(rc) && unicode.ToLower(rc[i]) == unicode.ToLower(first[i]) {
+			i++
+		}
+		n = i
+	}
+	return first[:n]
+}
+
+// refresh перерисовывает строку ввода и устанавливает курсор
+func (st *lineState) refresh() {
+	s := "\r" + st.prompt + string(st.buf) + "\x1b[K"
+	if back := len(st.buf) - st.pos; back > 0 {
+		s += fmt.Sprintf("\x1b[%dD", back)
+	}
+	fmt.Fprint(st.e.out, s)
+}
@@ -0,0 +1,78 @@
+package repl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/format"
+)
+
+// maxWidth - ширина, при превышении которой элементы массива и структуры выводятся по одному в строке
+const maxWidth = format.MaxWidth
+
+// Pretty возвращает значение в виде литерала языка Гонец: строки в кавычках,
+// массивы и структуры поэлементно, длинные - с отступами по одному элементу в строке
+func Pretty(v core.VMValuer) string {
+	return pretty(v, "")
+}
+
+func pretty(v core.VMValuer, indent string) string {
+	switch x := v.(type) {
+	case nil, core.VMNilType:
+		return "Неопределено"
+	case core.VMString:
+		return strconv.Quote(string(x))
+	case core.VMBool:
+		if x {
+			return "Истина"
+		}
+		return "Ложь"
+	case core.VMSlice:
+		items := make([]string, len(x))
+		for i, e := range x {
+			items[i] = pretty(e, indent+"\t")
+		}
+		return compose("[", "]", items, indent)
+	case core.VMStringMap:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			items[i] = strconv.Quote(k) + ": " + pretty(x[k], indent+"\t")
+		}
+		return compose("{", "}", items, indent)
+	case fmt.Stringer:
+		return x.String()
+	}
+	return fmt.Sprint(v)
+}
+
+func compose(open, close string, items []string, indent string) string {
+	flat := open + strings.Join(items, ", ") + close
+	if len(items) == 0 || (len([]rune(flat))+len(indent)*4 <= maxWidth && !strings.Contains(flat, "\n")) {
+		return flat
+	}
+	var sb strings.Builder
+	sb.WriteString(open)
+	sb.WriteString("\n")
+	for _, it := range items {
+		sb.WriteString(indent + "\t" + it + ",\n")
+	}
+	sb.WriteString(indent + close)
+	return sb.String()
+}
+
+// typeName возвращает название типа значения так же, как функция ТипЗнч
+func typeName(env *core.Env, v core.VMValuer) string {
+	if v == nil || v == core.VMNil {
+		return "Неопределено"
+	}
+	return env.Names().Get(env.TypeName(reflect.TypeOf(v)))
+}
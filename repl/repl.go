@@ -0,0 +1,98 @@
+package repl
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/covrom/gonec/bincode/binstmt"
+	"github.com/daviddengcn/go-colortext"
+	"github.com/mattn/go-isatty"
+)
+
+// HistoryFile - имя файла истории ввода в домашнем каталоге пользователя
+const HistoryFile = ".gonec_history"
+
+// Run запускает интерактивный режим: читает код из in, пока не будет введен конец ввода или команда :выход.
+// История ввода загружается из файла HistoryFile и сохраняется в него при завершении.
+// Ctrl+C отменяет ввод, а во время исполнения - прерывает исполняемый код
+func (s *Session) Run(in *os.File) error {
+	ed := NewEditor(in, s.Out)
+	ed.Complete = s.Complete
+	color := isatty.IsTerminal(in.Fd())
+
+	hist := historyPath()
+	if hist != "" {
+		if f, err := os.Open(hist); err == nil {
+			ed.LoadHistory(f)
+			f.Close()
+		}
+		defer func() {
+			if f, err := os.Create(hist); err == nil {
+				ed.SaveHistory(f)
+				f.Close()
+			}
+		}()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		for range sig {
+			s.Interrupt()
+		}
+	}()
+
+	for {
+		prompt := "> "
+		if s.Pending() {
+			prompt = "  "
+		}
+		if color {
+			prompt = "\x1b[1;32m" + prompt + "\x1b[0m"
+		}
+		line, err := ed.ReadLine(prompt)
+		if err == ErrInterrupted {
+			s.Cancel()
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ed.AddHistory(line)
+		err = s.Execute(line)
+		switch err {
+		case nil, ErrIncomplete:
+		case ErrExit:
+			return nil
+		default:
+			s.printError(err, color)
+		}
+	}
+}
+
+func (s *Session) printError(err error, color bool) {
+	if color {
+		ct.ChangeColor(ct.Red, false, ct.None, false)
+		defer ct.ResetColor()
+	}
+	msg := err.Error()
+	if e, ok := err.(*binstmt.Error); ok && e.File != "" {
+		// ошибка в коде загруженного файла
+		msg = e.File + ":" + msg
+	}
+	io.WriteString(s.Err, msg+"\n")
+}
+
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HistoryFile)
+}
@@ -0,0 +1,240 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+func newTestSession() (*Session, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	s := NewSession(func() *core.Env {
+		env := core.NewEnv()
+		env.SetStdOut(out)
+		return env
+	}, out, out)
+	return s, out
+}
+
+func TestEditor(t *testing.T) {
+	cases := []struct {
+		keys string
+		want string
+	}{
+		{"абв\r", "абв"},
+		{"абв\x7f\x7fг\r", "аг"},
+		{"ав\x1b[Dб\r", "абв"},
+		{"бв\x01а\x05г\r", "абвг"},
+		{"один два\x17три\r", "один три"},
+		{"абвг\x02\x02\x0b\r", "аб"},
+		{"абвг\x02\x02\x15\r", "вг"},
+		{"абв\x1b[H\x1b[3~\r", "бв"},
+	}
+	for _, c := range cases {
+		e := newEditor(strings.NewReader(c.keys), ioutil.Discard, true)
+		got, err := e.ReadLine("> ")
+		if err != nil || got != c.want {
+			t.Errorf("%q: получено %q, %v, ожидалось %q", c.keys, got, err, c.want)
+		}
+	}
+
+	e := newEditor(strings.NewReader("\x03"), ioutil.Discard, true)
+	if _, err := e.ReadLine("> "); err != ErrInterrupted {
+		t.Errorf("Ctrl+C: %v", err)
+	}
+	e = newEditor(strings.NewReader("\x04"), ioutil.Discard, true)
+	if _, err := e.ReadLine("> "); err != io.EOF {
+		t.Errorf("Ctrl+D: %v", err)
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	e := newEditor(strings.NewReader("первая\rвторая\r\x1b[A\x1b[A\r\x10\x10\x0e!\r"), ioutil.Discard, true)
+	var got []string
+	for i := 0; i < 4; i++ {
+		l, err := e.ReadLine("> ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.AddHistory(l)
+		got = append(got, l)
+	}
+	want := []string{"первая", "вторая", "первая", "первая!"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("получено %q, ожидалось %q", got, want)
+	}
+	if h := e.History(); !reflect.DeepEqual(h, []string{"первая", "вторая", "первая", "первая!"}) {
+		t.Errorf("история %q", h)
+	}
+
+	var buf bytes.Buffer
+	if err := e.SaveHistory(&buf); err != nil {
+		t.Fatal(err)
+	}
+	e2 := newEditor(strings.NewReader(""), ioutil.Discard, false)
+	e2.MaxHistory = 2
+	if err := e2.LoadHistory(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if h := e2.History(); !reflect.DeepEqual(h, []string{"первая", "первая!"}) {
+		t.Errorf("загруженная история %q", h)
+	}
+}
+
+func TestEditorComplete(t *testing.T) {
+	e := newEditor(strings.NewReader("сооб\t(1)\r"), ioutil.Discard, true)
+	e.Complete = func(line []rune, pos int) (int, []string) {
+		return pos - 4, []string{"Сообщить", "СообщитьФункцию"}
+	}
+	got, err := e.ReadLine("> ")
+	if err != nil || got != "Сообщить(1)" {
+		t.Errorf("получено %q, %v", got, err)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	s, out := newTestSession()
+	run := func(line string) string {
+		out.Reset()
+		if err := s.Execute(line); err != nil && err != ErrIncomplete {
+			return "ошибка: " + err.Error()
+		}
+		return out.String()
+	}
+	cases := []struct {
+		line string
+		want string
+	}{
+		{"а = 1", ""},
+		{"а + 2", "3 // целоечисло\n"},
+		{`"стр" + "ока"`, "\"строка\" // строка\n"},
+		{"[а, \"б\", Истина]", "[1, \"б\", Истина] // массив\n"},
+		{"Функция ф(х)", ""},
+		{"  Возврат х * 2", ""},
+		{"КонецФункции", ""},
+		{"ф(а)", "2 // целоечисло\n"},
+		{"Сообщить(а)", "1\n"},
+		{":тип 1.5", "число\n"},
+		{":тип а = 1", "Неопределено\n"},
+		{":сброс", ""},
+		{":тип а", "ошибка: [1:1] Невозможно получить значение"},
+		{":неизвестная", "ошибка: Неизвестная команда :неизвестная, список команд - :помощь"},
+	}
+	for _, c := range cases {
+		if got := run(c.line); got != c.want {
+			t.Errorf("%q: получено %q, ожидалось %q", c.line, got, c.want)
+		}
+	}
+	if err := s.Execute(":выход"); err != ErrExit {
+		t.Errorf(":выход: %v", err)
+	}
+}
+
+func TestExecuteIncomplete(t *testing.T) {
+	s, _ := newTestSession()
+	for _, l := range []string{"Для й = 1 По 3 Цикл", "Если й = 2 Тогда"} {
+		if err := s.Execute(l); err != ErrIncomplete {
+			t.Fatalf("%q: %v", l, err)
+		}
+	}
+	s.Cancel()
+	if s.Pending() {
+		t.Fatal("ввод не отменен")
+	}
+	for _, l := range []string{"Функция ф()", "Возврат 1"} {
+		if err := s.Execute(l); err != ErrIncomplete {
+			t.Fatalf("%q: %v", l, err)
+		}
+	}
+	if err := s.Execute("КонецФункции"); err != nil || s.Pending() {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "мод.gnc")
+	if err := ioutil.WriteFile(fn, []byte("Функция Удвоить(х)\n\tВозврат х * 2\nКонецФункции\nб = 10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, out := newTestSession()
+	if err := s.Execute(":загрузить " + fn); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Execute("Удвоить(б)"); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "20 // целоечисло\n" {
+		t.Errorf("получено %q", got)
+	}
+	out.Reset()
+	if err := s.Execute(":байткод б"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "GET") {
+		t.Errorf("байткод %q", out.String())
+	}
+}
+
+func TestComplete(t *testing.T) {
+	s, _ := newTestSession()
+	if err := s.Execute("МояПеременная = 1"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		line  string
+		start int
+		want  []string
+	}{
+		{"моя", 0, []string{"мояпеременная"}},
+		{"х = Моя", 4, []string{"МояПеременная"}},
+		{"КонецЦ", 0, []string{"КонецЦикла"}},
+		{"конецц", 0, []string{"конеццикла"}},
+		{"Сообщит", 0, []string{"Сообщить", "Сообщитьф"}},
+		{"а.моя", 2, nil},
+		{":за", 0, []string{":загрузить"}},
+		{"", 0, nil},
+	}
+	for _, c := range cases {
+		line := []rune(c.line)
+		start, got := s.Complete(line, len(line))
+		if start != c.start || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: получено %d %q, ожидалось %d %q", c.line, start, got, c.start, c.want)
+		}
+	}
+}
+
+func TestPretty(t *testing.T) {
+	long := core.VMSlice{}
+	for i := 0; i < 30; i++ {
+		long = append(long, core.VMString("элемент"))
+	}
+	cases := []struct {
+		v    core.VMValuer
+		want string
+	}{
+		{nil, "Неопределено"},
+		{core.VMString("а\"б"), `"а\"б"`},
+		{core.VMStringMap{"б": core.VMInt(2), "а": core.VMSlice{}}, `{"а": [], "б": 2}`},
+		{core.VMStringMap{"а": long[:2]}, `{"а": ["элемент", "элемент"]}`},
+	}
+	for _, c := range cases {
+		if got := Pretty(c.v); got != c.want {
+			t.Errorf("получено %s, ожидалось %s", got, c.want)
+		}
+	}
+	if got := Pretty(core.VMStringMap{"а": long}); !strings.HasPrefix(got, "{\n\t\"а\": [\n\t\t\"элемент\",\n") || !strings.HasSuffix(got, "\t],\n}") {
+		t.Errorf("многострочный вывод:\n%s", got)
+	}
+}
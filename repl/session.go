@@ -0,0 +1,295 @@
+// Package repl - интерактивный режим интерпретатора Гонец: редактирование строки ввода с историей
+// и автодополнением, вывод значения последнего выражения с его типом и служебные команды
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+
+	"github.com/covrom/gonec/ast"
+	"github.com/covrom/gonec/bincode"
+	"github.com/covrom/gonec/bincode/binstmt"
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/names"
+	"github.com/covrom/gonec/parser"
+)
+
+var (
+	// ErrIncomplete возвращается Execute, если введенный код не завершен и ожидается его продолжение
+	ErrIncomplete = errors.New("Ожидается продолжение ввода")
+	// ErrExit возвращается Execute по команде :выход
+	ErrExit = errors.New("Выход из интерактивного режима")
+)
+
+// header добавляется перед разбором, так же как это делает bincode.ParseSrc
+const header = "Модуль _\n"
+
+// Session - сеанс интерактивного режима. Переменные и функции, определенные в сеансе,
+// сохраняются между вводами до команды :сброс
+type Session struct {
+	Out io.Writer // результаты выражений и вывод служебных команд
+	Err io.Writer // сообщения об ошибках
+
+	newEnv  func() *core.Env
+	env     *core.Env
+	mod     *core.Env // модуль по умолчанию, в котором исполняется введенный код
+	code    string    // начало незавершенного ввода
+	running int32     // исполняется код, изменяется атомарно
+}
+
+// NewSession создает сеанс, окружение создается функцией newEnv при создании сеанса и по команде :сброс
+func NewSession(newEnv func() *core.Env, out, errw io.Writer) *Session {
+	s := &Session{Out: out, Err: errw, newEnv: newEnv}
+	s.Reset()
+	return s
+}
+
+// Env возвращает глобальное окружение сеанса
+func (s *Session) Env() *core.Env {
+	return s.env
+}
+
+// Reset удаляет все определенные в сеансе переменные и функции
+func (s *Session) Reset() {
+	if s.env != nil {
+		s.env.Destroy()
+	}
+	s.env = s.newEnv()
+	bincode.LoadBuiltins(s.env)
+	s.mod = s.env.NewModule("_")
+	s.code = ""
+}
+
+// Pending возвращает true, если ожидается продолжение ввода
+func (s *Session) Pending() bool {
+	return s.code != ""
+}
+
+// Cancel отменяет незавершенный ввод
+func (s *Session) Cancel() {
+	s.code = ""
+}
+
+// Interrupt прерывает исполняемый код, возвращает false, если код не исполняется
+func (s *Session) Interrupt() bool {
+	if atomic.LoadInt32(&s.running) == 0 {
+		return false
+	}
+	s.env.Interrupt()
+	return true
+}
+
+// Execute исполняет введенную строку. Если код не завершен, строка запоминается и возвращается ErrIncomplete.
+// Значение последнего выражения, если оно определено, выводится в Out вместе с типом.
+// Строки, начинающиеся с двоеточия, являются служебными командами, см. :помощь
+func (s *Session) Execute(line string) error {
+	if s.code == "" && strings.HasPrefix(strings.TrimSpace(line), ":") {
+		return s.command(strings.TrimSpace(line))
+	}
+	if s.code == "" && strings.TrimSpace(line) == "" {
+		return nil
+	}
+	code := line
+	if s.code != "" {
+		code = s.code + "\n" + line
+	}
+	bins, echo, err := s.compile(code)
+	if err != nil {
+		if incomplete(err, line) {
+			s.code = code
+			return ErrIncomplete
+		}
+		s.code = ""
+		return err
+	}
+	s.code = ""
+	v, err := s.run(bins)
+	if err != nil {
+		return err
+	}
+	if echo && v != nil && v != core.VMNil {
+		fmt.Fprintf(s.Out, "%s // %s\n", Pretty(v), typeName(s.env, v))
+	}
+	return nil
+}
+
+// incomplete определяет, что ошибка разбора вызвана незавершенным вводом
+func incomplete(err error, line string) bool {
+	e, ok := err.(*parser.Error)
+	if !ok {
+		return false
+	}
+	es := e.Error()
+	if strings.HasPrefix(es, "syntax error: unexpected") {
+		return strings.HasPrefix(es, "syntax error: unexpected $end,")
+	}
+	return (e.Pos.Column == len(line) && !e.Fatal) || es == "unexpected EOF"
+}
+
+// compile компилирует код. Если весь код находится в модуле по умолчанию, он компилируется для исполнения
+// в окружении этого модуля, а последнее выражение становится возвращаемым значением (echo равно true)
+func (s *Session) compile(code string) (bins binstmt.BinCode, echo bool, err error) {
+	defer func() {
+		if ex := recover(); ex != nil {
+			if e, ok := ex.(error); ok {
+				err = e
+			} else {
+				err = errors.New(fmt.Sprint(ex))
+			}
+		}
+	}()
+	parser.EnableErrorVerbose()
+	sc := &parser.Scanner{}
+	sc.Init(header + code)
+	nm := s.env.Names()
+	stmts, err := parser.Parse(sc, nm)
+	if err != nil {
+		return bins, false, err
+	}
+	if len(stmts) == 1 {
+		if m, ok := stmts[0].(*ast.ModuleStmt); ok && m.Name == names.DefaultModule {
+			stmts, echo = echoLast(m.Stmts), true
+		}
+	}
+	stmts = parser.ConstFolding(stmts)
+	lid := 0
+	bins = stmts.BinaryCode(0, &lid)
+	bins.SetNames(nm)
+	return bins, echo, nil
+}
+
+// echoLast заменяет последний оператор-выражение на возврат его значения.
+// Присваивания, определения функций, отправка в канал и запуск горутин значения не возвращают
+func echoLast(stmts ast.Stmts) ast.Stmts {
+	if len(stmts) == 0 {
+		return stmts
+	}
+	es, ok := stmts[len(stmts)-1].(*ast.ExprStmt)
+	if !ok {
+		return stmts
+	}
+	switch x := es.Expr.(type) {
+	case *ast.BinOpExpr:
+		if x.Operator == "==" {
+			// в операторе знак = означает присваивание
+			return stmts
+		}
+	case *ast.AssocExpr, *ast.LetExpr, *ast.FuncExpr:
+		return stmts
+	case *ast.ChanExpr:
+		if x.Lhs != nil {
+			return stmts
+		}
+	case *ast.CallExpr:
+		if x.Go {
+			return stmts
+		}
+	case *ast.AnonCallExpr:
+		if x.Go {
+			return stmts
+		}
+	}
+	ret := &ast.ReturnStmt{Exprs: []ast.Expr{es.Expr}}
+	ret.SetPosition(es.Position())
+	res := append(ast.Stmts{}, stmts[:len(stmts)-1]...)
+	return append(res, ret)
+}
+
+// run исполняет код в окружении модуля по умолчанию, либо весь код с модулями в глобальном окружении
+func (s *Session) run(bins binstmt.BinCode) (core.VMValuer, error) {
+	atomic.StoreInt32(&s.running, 1)
+	defer func() {
+		atomic.StoreInt32(&s.running, 0)
+		s.env.ResetInterrupt()
+	}()
+	env := s.mod
+	if len(bins.Code) > 0 {
+		if _, ok := bins.Code[0].(*binstmt.BinMODULE); ok {
+			env = s.env
+		}
+	}
+	v, err := bincode.Run(bins, env)
+	if err == binstmt.ReturnError {
+		err = nil
+	}
+	return v, err
+}
+
+// commands - служебные команды и их описание
+var commands = [][2]string{
+	{":тип выражение", "вывести тип значения выражения"},
+	{":байткод код", "вывести байткод вирт. машины для кода, не исполняя его"},
+	{":сброс", "удалить все определенные переменные и функции"},
+	{":загрузить файл", "исполнить файл в текущем сеансе"},
+	{":помощь", "вывести список команд"},
+	{":выход", "завершить работу (также Ctrl+D)"},
+}
+
+func (s *Session) command(line string) error {
+	cmd, arg := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		cmd, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	switch names.FastToLower(cmd) {
+	case ":тип":
+		if arg == "" {
+			return errors.New("Не указано выражение")
+		}
+		bins, _, err := s.compile(arg)
+		if err != nil {
+			return err
+		}
+		v, err := s.run(bins)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(s.Out, typeName(s.env, v))
+	case ":байткод":
+		if arg == "" {
+			return errors.New("Не указан код")
+		}
+		bins, _, err := s.compile(arg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(s.Out, bins.String())
+	case ":сброс":
+		s.Reset()
+	case ":загрузить":
+		if arg == "" {
+			return errors.New("Не указано имя файла")
+		}
+		b, err := ioutil.ReadFile(arg)
+		if err != nil {
+			return err
+		}
+		_, bins, err := bincode.ParseSrc(string(b), s.env.Names())
+		if err != nil {
+			if e, ok := err.(*parser.Error); ok {
+				e.Filename = arg
+			}
+			return err
+		}
+		bins.File = arg
+		atomic.StoreInt32(&s.running, 1)
+		defer func() {
+			atomic.StoreInt32(&s.running, 0)
+			s.env.ResetInterrupt()
+		}()
+		_, err = bincode.Run(bins, s.env)
+		return err
+	case ":помощь":
+		for _, c := range commands {
+			fmt.Fprintf(s.Out, "  %-18s %s\n", c[0], c[1])
+		}
+	case ":выход":
+		return ErrExit
+	default:
+		return fmt.Errorf("Неизвестная команда %s, список команд - :помощь", cmd)
+	}
+	return nil
+}
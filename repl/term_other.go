@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package repl
+
+import "errors"
+
+// makeRaw на этой платформе не поддерживается, строки читаются без редактирования
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("Редактирование строки не поддерживается")
+}
@@ -2,7 +2,7 @@ package gonecsvc
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 	"io"
 	"io/ioutil"
 	"log"
@@ -16,6 +16,9 @@ import (
 	"github.com/covrom/gonec/parser"
 )
 
+// SessionRunTimeout - максимальное время исполнения кода, переданного в одном запросе к интерпретатору
+var SessionRunTimeout = time.Minute
+
 func NewGonecInterpreter(header core.VMServiceHeader, args []string, tmode bool) *VMGonecInterpreterService {
 	v := &VMGonecInterpreterService{
 		hdr:          header,
@@ -206,6 +209,8 @@ func (x *VMGonecInterpreterService) handlerAPI(w http.ResponseWriter, r *http.Re
 
 			//создаем новое окружение
 			env = core.NewEnv()
+			// код из браузера не должен иметь доступа к файлам сервера
+			env.SetSandbox(&core.Sandbox{FS: core.FSDenied})
 			env.DefineS("аргументызапуска", core.NewVMSliceFromStrings(x.fsArgs))
 
 			x.lockSessions.Lock()
@@ -224,7 +229,11 @@ func (x *VMGonecInterpreterService) handlerAPI(w http.ResponseWriter, r *http.Re
 		env.SetSid(sid)
 		//log.Println("Сессия:",sid)
 
-		err := x.parseAndRun(r.Body, w, env)
+		// исполнение прерывается, если клиент отключился или истекло время SessionRunTimeout
+		ctx, cancel := context.WithTimeout(r.Context(), SessionRunTimeout)
+		defer cancel()
+
+		err := x.parseAndRun(ctx, r.Body, w, env)
 
 		if err != nil {
 			time.Sleep(time.Second) //анти-ddos
@@ -240,10 +249,10 @@ func (x *VMGonecInterpreterService) handlerAPI(w http.ResponseWriter, r *http.Re
 }
 
 func (x *VMGonecInterpreterService) handlerIndex(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprint(w, indexPage)
+	io.WriteString(w, indexPage)
 }
 
-func (x *VMGonecInterpreterService) parseAndRun(r io.Reader, w io.Writer, env *core.Env) (err error) {
+func (x *VMGonecInterpreterService) parseAndRun(ctx context.Context, r io.Reader, w io.Writer, env *core.Env) (err error) {
 	b, err := ioutil.ReadAll(r)
 	if err != nil {
 		return err
@@ -258,7 +267,7 @@ func (x *VMGonecInterpreterService) parseAndRun(r io.Reader, w io.Writer, env *c
 
 	//замер производительности
 	tstart := time.Now()
-	_, bins, err := bincode.ParseSrc(sb)
+	_, bins, err := bincode.ParseSrc(sb, env.Names())
 	tsParse := time.Since(tstart)
 
 	if x.testingMode {
@@ -276,7 +285,7 @@ func (x *VMGonecInterpreterService) parseAndRun(r io.Reader, w io.Writer, env *c
 	// if *stackvm {
 	// 	_, err = vm.Run(stmts, env)
 	// } else {
-	_, err = bincode.Run(bins, env)
+	_, err = bincode.RunContext(ctx, bins, env)
 	// }
 	tsRun := time.Since(tstart)
 
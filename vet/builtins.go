@@ -0,0 +1,165 @@
+package vet
+
+import (
+	"strconv"
+	"strings"
+)
+
+// arity - допустимое количество аргументов встроенной функции
+type arity struct {
+	counts []int // допустимые количества по возрастанию
+	more   bool  // допускается и больше последнего из counts
+}
+
+func (a arity) allows(n int) bool {
+	for _, c := range a.counts {
+		if n == c {
+			return true
+		}
+	}
+	return a.more && n > a.counts[len(a.counts)-1]
+}
+
+func (a arity) String() string {
+	if a.more {
+		return "не меньше " + strconv.Itoa(a.counts[0])
+	}
+	s := make([]string, len(a.counts))
+	for i, c := range a.counts {
+		s[i] = strconv.Itoa(c)
+	}
+	return strings.Join(s, " или ")
+}
+
+func args(counts ...int) arity { return arity{counts: counts} }
+
+func argsFrom(n int) arity { return arity{counts: []int{n}, more: true} }
+
+// builtinArgs - количество аргументов функций стандартной библиотеки core по именам в нижнем регистре.
+// Функции, которые принимают любое количество аргументов (например, Сообщить), в таблицу не входят
+var builtinArgs = map[string]arity{
+	// core.go
+	"импорт":         args(1),
+	"длина":          args(1),
+	"диапазон":       args(1, 2),
+	"текущаядата":    args(0),
+	"прошловременис": args(1),
+	"пауза":          args(1),
+	"прочитатьфайл":  args(1),
+	"открытьфайл":    args(1),
+	"создатьфайл":    args(1, 2),
+	"base64строка":   args(1),
+	"base64значение": args(1),
+	"hexстрока":      args(1),
+	"hexзначение":    args(1),
+	"хэш":            args(1),
+	"уникальныйидентификатор": args(0),
+	"получитьмассивизпула":    args(0),
+	"вернутьмассиввпул":       args(1),
+	"случайнаястрока":         args(1),
+	"нрег":                    args(1),
+	"врег":                    args(1),
+	"стрсодержит":             args(2),
+	"стрсодержитлюбой":        args(2),
+	"стрколичество":           args(2),
+	"стрнайти":                args(2),
+	"стрнайтилюбой":           args(2),
+	"стрнайтипоследний":       args(2),
+	"стрзаменить":             args(3),
+	"стрсовпадает":            args(2),
+	"стрзаменитьрег":          args(3),
+	"стрдекодироватьзапрос":   args(1),
+	"окр":                 args(2),
+	"формат":              argsFrom(2),
+	"кодсимвола":          args(1),
+	"типзнч":              args(1),
+	"сообщитьф":           argsFrom(2),
+	"обработатьгорутины":  args(0),
+	"переменнаяокружения": args(1),
+
+	// corecrypto.go
+	"хешsha256":           args(1),
+	"хешsha512":           args(1),
+	"хешsha1":             args(1),
+	"хешmd5":              args(1),
+	"hmac":                args(3),
+	"сравнитьбезопасно":   args(2),
+	"base64urlстрока":     args(1),
+	"base64urlзначение":   args(1),
+	"случайныеданные":     args(1),
+	"зашифроватьaes":      args(2, 3),
+	"расшифроватьaes":     args(2, 3),
+	"создатьключиrsa":     args(0, 1),
+	"создатьключиed25519": args(0),
+	"подписать":           args(2),
+	"проверитьподпись":    args(3),
+	"хешпароля":           args(1, 2),
+	"проверитьпароль":     args(2),
+
+	// corefs.go
+	"записатьфайл":    args(2),
+	"дописатьвфайл":   args(2),
+	"найтифайлы":      args(1, 2, 3),
+	"файлсуществует":  args(1),
+	"свойствафайла":   args(1),
+	"копироватьфайл":  args(2),
+	"переместитьфайл": args(2),
+	"удалитьфайлы":    args(1, 2),
+	"создатькаталог":  args(1),
+	"временныйфайл":   args(0, 1),
+	"объединитьпути":  argsFrom(1),
+	"разложитьпуть":   args(1),
+	"полныйпуть":      args(1),
+
+	// corejson.go, corejwt.go, corexml.go, coretemplate.go
+	"записатьjson":   args(1, 2),
+	"прочитатьjson":  args(1, 2),
+	"получитьпопути": args(2),
+	"создатьjwt":     args(2, 3),
+	"проверитьjwt":   args(2, 3),
+	"прочитатьxml":   args(1, 2),
+	"записатьxml":    args(1, 2),
+	"шаблонизфайла":  args(1, 2),
+
+	// coremath.go
+	"макс":  argsFrom(1),
+	"мин":   argsFrom(1),
+	"цел":   args(1),
+	"abs":   args(1),
+	"pow":   args(2),
+	"pi":    args(0),
+	"sqrt":  args(1),
+	"exp":   args(1),
+	"log":   args(1),
+	"log10": args(1),
+	"sin":   args(1),
+	"cos":   args(1),
+	"tan":   args(1),
+	"asin":  args(1),
+	"acos":  args(1),
+	"atan":  args(1),
+
+	// corerandom.go
+	"случайноечисло": args(0, 2),
+
+	// corestrfunc.go
+	"стрдлина":           args(1),
+	"сокрлп":             args(1),
+	"сокрл":              args(1),
+	"сокрп":              args(1),
+	"лев":                args(2),
+	"прав":               args(2),
+	"сред":               args(2, 3),
+	"стрначинаетсяс":     args(2),
+	"стрзаканчиваетсяна": args(2),
+	"стрразделить":       args(2, 3),
+	"стрсоединить":       args(1, 2),
+	"стршаблон":          argsFrom(1),
+	"стрповторить":       args(2),
+	"символ":             args(1),
+	"трег":               args(1),
+	"стрчислострок":      args(1),
+	"стрполучитьстроку":  args(2),
+	"пустаястрока":       args(1),
+	"стрсравнить":        args(2),
+}
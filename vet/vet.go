@@ -0,0 +1,488 @@
+// Package vet реализует статический анализ исходного текста на языке Гонец (gonec vet).
+// Анализ находит ошибки, которые иначе проявятся только при исполнении:
+// неопределенные имена, неиспользуемые переменные и функции, недостижимый код,
+// неверное количество аргументов при вызове функций, объявленных в тексте, и встроенных функций,
+// присваивание переменной цикла и захват переменной цикла функцией, запущенной через Старт.
+//
+// Диагностику можно подавить комментарием "// vet:ignore" в конце строки или на предыдущей строке,
+// после которого через запятую перечисляются подавляемые правила (без перечисления подавляются все).
+package vet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/covrom/gonec/ast"
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/names"
+	"github.com/covrom/gonec/parser"
+	"github.com/covrom/gonec/pos"
+)
+
+// Правила анализа, их идентификаторы выводятся в диагностике и используются в комментариях подавления
+const (
+	RuleUndefined   = "undefined"   // имя не объявлено ни в тексте, ни во встроенной библиотеке
+	RuleUnusedVar   = "unused-var"  // переменной присваивается значение, но оно не используется
+	RuleUnusedFunc  = "unused-func" // функция объявлена, но не вызывается
+	RuleUnreachable = "unreachable" // оператор после Возврат, ВызватьИсключение, Прервать или Продолжить
+	RuleArgCount    = "arg-count"   // количество аргументов не совпадает с объявлением функции или встроенной функцией
+	RuleLoopAssign  = "loop-assign" // присваивание переменной цикла внутри цикла
+	RuleGoLoopVar   = "go-loop-var" // функция, запущенная через Старт, использует переменную цикла
+	ignoreDirective = "vet:ignore"
+)
+
+// header добавляется перед разбором, так же как это делает bincode.ParseSrc
+const header = "Модуль _\n"
+
+const anonFunc = "<анонимная функция>"
+
+// runtimeNames определяются виртуальной машиной во время исполнения, например при обработке исключения
+var runtimeNames = map[string]bool{
+	"описаниеошибки": true,
+}
+
+// Diagnostic - найденная проблема
+type Diagnostic struct {
+	Pos     pos.Position
+	Rule    string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s [%s]", d.Pos.Line, d.Pos.Column, d.Message, d.Rule)
+}
+
+// Check анализирует исходный текст. Встроенные имена берутся из окружения env,
+// если env равно nil - используется стандартная библиотека core.
+// Ошибка возвращается, только если текст не удалось разобрать.
+func Check(src string, env *core.Env) ([]Diagnostic, error) {
+	if env == nil {
+		env = core.NewEnv()
+		core.LoadAllBuiltins(env)
+	}
+	s := new(parser.Scanner)
+	s.Init(header + src)
+	stmts, err := parser.Parse(s, env.Names())
+	if err != nil {
+		if e, ok := err.(*parser.Error); ok && e.Pos.Line > 1 {
+			e.Pos.Line--
+		}
+		return nil, err
+	}
+
+	c := &checker{
+		env:    env,
+		names:  env.Names(),
+		scopes: make(map[*ast.FuncExpr]*scope),
+	}
+	c.root = c.newScope(nil)
+	for _, st := range stmts {
+		m, ok := st.(*ast.ModuleStmt)
+		if !ok {
+			continue
+		}
+		sc := c.root
+		if m.Name != names.DefaultModule {
+			// модуль объявляется в глобальном контексте, его имена доступны снаружи
+			c.root.define(m.Name, kindModule, m.Position())
+			sc = c.newScope(c.root)
+			sc.exported = true
+			c.modules = append(c.modules, moduleScope{m, sc})
+		}
+		c.declare(m.Stmts, sc)
+	}
+	for _, st := range stmts {
+		if m, ok := st.(*ast.ModuleStmt); ok {
+			c.check(m.Stmts, c.moduleScope(m), nil)
+		}
+	}
+	c.unused()
+
+	ignored := suppressed(s.Comments())
+	rv := make([]Diagnostic, 0, len(c.diags))
+	for _, d := range c.diags {
+		d.Pos.Line-- // строки считаем без учета добавленного заголовка
+		if rules, ok := ignored[d.Pos.Line]; ok && (len(rules) == 0 || rules[d.Rule]) {
+			continue
+		}
+		rv = append(rv, d)
+	}
+	sort.SliceStable(rv, func(i, j int) bool {
+		if rv[i].Pos.Line != rv[j].Pos.Line {
+			return rv[i].Pos.Line < rv[j].Pos.Line
+		}
+		return rv[i].Pos.Column < rv[j].Pos.Column
+	})
+	return rv, nil
+}
+
+// suppressed возвращает подавленные правила по номерам строк (без учета заголовка),
+// пустой набор означает подавление всех правил
+func suppressed(comments []parser.Comment) map[int]map[string]bool {
+	rv := make(map[int]map[string]bool)
+	for _, c := range comments {
+		text := strings.TrimSpace(strings.TrimLeft(c.Text, "#/"))
+		if !strings.HasPrefix(text, ignoreDirective) {
+			continue
+		}
+		rules := make(map[string]bool)
+		for _, r := range strings.FieldsFunc(text[len(ignoreDirective):], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		}) {
+			rules[r] = true
+		}
+		line := c.Pos.Line - 1
+		if !c.Inline {
+			// комментарий на отдельной строке относится к следующей строке
+			line++
+		}
+		rv[line] = rules
+	}
+	return rv
+}
+
+type kind int
+
+const (
+	kindVar kind = iota
+	kindParam
+	kindLoopVar
+	kindFunc
+	kindModule
+)
+
+type symbol struct {
+	name   string
+	kind   kind
+	pos    pos.Position
+	used   bool
+	fn     *ast.FuncExpr // объявление функции или единственная присвоенная переменной функция
+	assign int           // количество присваиваний
+}
+
+type scope struct {
+	parent   *scope
+	syms     map[int]*symbol
+	exported bool // имена модуля доступны снаружи, поэтому не проверяются на использование
+}
+
+type moduleScope struct {
+	m     *ast.ModuleStmt
+	scope *scope
+}
+
+func (sc *scope) define(id int, k kind, p pos.Position) *symbol {
+	if s, ok := sc.syms[id]; ok {
+		s.assign++
+		return s
+	}
+	s := &symbol{kind: k, pos: p, assign: 1}
+	sc.syms[id] = s
+	return s
+}
+
+func (sc *scope) lookup(id int) (*symbol, *scope) {
+	for s := sc; s != nil; s = s.parent {
+		if sym, ok := s.syms[id]; ok {
+			return sym, s
+		}
+	}
+	return nil, nil
+}
+
+type checker struct {
+	env     *core.Env
+	names   *names.EnvNames
+	root    *scope
+	all     []*scope
+	modules []moduleScope
+	scopes  map[*ast.FuncExpr]*scope
+	diags   []Diagnostic
+}
+
+func (c *checker) newScope(parent *scope) *scope {
+	sc := &scope{parent: parent, syms: make(map[int]*symbol)}
+	c.all = append(c.all, sc)
+	return sc
+}
+
+func (c *checker) moduleScope(m *ast.ModuleStmt) *scope {
+	for _, ms := range c.modules {
+		if ms.m == m {
+			return ms.scope
+		}
+	}
+	return c.root
+}
+
+func (c *checker) report(p pos.Position, rule, format string, args ...interface{}) {
+	c.diags = append(c.diags, Diagnostic{Pos: p, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) isAnon(f *ast.FuncExpr) bool {
+	return c.names.Get(f.Name) == anonFunc
+}
+
+// declare собирает имена, объявленные в области видимости: присваивания, переменные циклов и функции.
+// Порядок объявления не учитывается, т.к. функции могут обращаться к глобальным переменным, присвоенным позже
+func (c *checker) declare(list ast.Stmts, sc *scope) {
+	for _, st := range list {
+		switch s := st.(type) {
+		case *ast.ExprStmt:
+			if lhss, rhss, ok := assignment(s); ok {
+				for i, l := range lhss {
+					if id, ok := l.(*ast.IdentExpr); ok {
+						sym := sc.define(id.Id, kindVar, posOf(id, s))
+						sym.name = id.Lit
+						if len(lhss) == len(rhss) {
+							if f, ok := rhss[i].(*ast.FuncExpr); ok && c.isAnon(f) {
+								sym.fn = f
+							}
+						}
+					}
+				}
+			}
+		case *ast.ForStmt:
+			c.loopVar(sc, s.Var, s)
+		case *ast.NumForStmt:
+			c.loopVar(sc, s.Name, s)
+		}
+		forEachBlock(st, func(b ast.Stmts) { c.declare(b, sc) })
+		forEachExpr(st, func(e ast.Expr) { c.declareExpr(e, sc) })
+	}
+}
+
+func (c *checker) loopVar(sc *scope, id int, st ast.Stmt) {
+	sym := sc.define(id, kindLoopVar, st.Position())
+	sym.name = c.names.Get(id)
+}
+
+// declareExpr находит функции внутри выражения и создает для них области видимости
+func (c *checker) declareExpr(e ast.Expr, sc *scope) {
+	walkExpr(e, func(e ast.Expr) bool {
+		f, ok := e.(*ast.FuncExpr)
+		if !ok {
+			return true
+		}
+		var fsc *scope
+		if c.isAnon(f) {
+			// анонимная функция - замыкание, видит окружение, в котором создана
+			fsc = c.newScope(sc)
+		} else {
+			// именованная функция объявляется в текущем окружении, но исполняется под глобальным
+			sym := sc.define(f.Name, kindFunc, f.Position())
+			sym.name = c.names.Get(f.Name)
+			sym.fn = f
+			fsc = c.newScope(c.root)
+		}
+		for _, a := range f.Args {
+			sym := fsc.define(a, kindParam, f.Position())
+			sym.name = c.names.Get(a)
+		}
+		c.scopes[f] = fsc
+		c.declare(f.Stmts, fsc)
+		return false
+	})
+}
+
+// loopCtx - переменные циклов, внутри которых находится проверяемый оператор
+type loopCtx map[int]bool
+
+func (l loopCtx) with(id int) loopCtx {
+	rv := make(loopCtx, len(l)+1)
+	for k := range l {
+		rv[k] = true
+	}
+	rv[id] = true
+	return rv
+}
+
+func (c *checker) check(list ast.Stmts, sc *scope, loops loopCtx) {
+	reported := false
+	for i, st := range list {
+		if i > 0 && !reported && terminates(list[i-1]) {
+			c.report(posOf(st, st), RuleUnreachable, "недостижимый код")
+			reported = true
+		}
+		switch s := st.(type) {
+		case *ast.ExprStmt:
+			if lhss, rhss, ok := assignment(s); ok {
+				for _, r := range rhss {
+					c.read(r, sc, loops, s)
+				}
+				for _, l := range lhss {
+					if id, ok := l.(*ast.IdentExpr); ok {
+						c.assignLoopVar(id.Id, id.Lit, posOf(id, s), loops)
+					} else {
+						c.read(l, sc, loops, s)
+					}
+				}
+				continue
+			}
+			c.read(s.Expr, sc, loops, s)
+		case *ast.ForStmt:
+			c.read(s.Value, sc, loops, s)
+			c.check(s.Stmts, sc, loops.with(s.Var))
+			continue
+		case *ast.NumForStmt:
+			c.read(s.Expr1, sc, loops, s)
+			c.read(s.Expr2, sc, loops, s)
+			c.check(s.Stmts, sc, loops.with(s.Name))
+			continue
+		default:
+			forEachExpr(st, func(e ast.Expr) { c.read(e, sc, loops, st) })
+		}
+		forEachBlock(st, func(b ast.Stmts) { c.check(b, sc, loops) })
+	}
+}
+
+func (c *checker) assignLoopVar(id int, name string, p pos.Position, loops loopCtx) {
+	if loops[id] {
+		c.report(p, RuleLoopAssign, "присваивание переменной цикла '%s'", name)
+	}
+}
+
+// read проверяет имена, используемые в выражении
+func (c *checker) read(e ast.Expr, sc *scope, loops loopCtx, st ast.Stmt) {
+	walkExpr(e, func(e ast.Expr) bool {
+		switch x := e.(type) {
+		case *ast.IdentExpr:
+			c.use(x.Id, x.Lit, posOf(x, st), sc)
+		case *ast.CallExpr:
+			sym := c.use(x.Name, c.names.Get(x.Name), posOf(x, st), sc)
+			if sym != nil && sym.fn != nil && (sym.kind == kindFunc || sym.assign == 1) {
+				c.argCount(x, sym.fn, posOf(x, st))
+				if x.Go {
+					c.goLoopVar(sym.fn, loops, posOf(x, st))
+				}
+			} else if sym == nil {
+				c.builtinArgCount(x, posOf(x, st))
+			}
+		case *ast.AnonCallExpr:
+			if f, ok := x.Expr.(*ast.FuncExpr); ok && x.Go {
+				c.goLoopVar(f, loops, posOf(x, st))
+			}
+		case *ast.AssocExpr:
+			if id, ok := x.Lhs.(*ast.IdentExpr); ok {
+				c.assignLoopVar(id.Id, id.Lit, posOf(id, st), loops)
+			}
+		case *ast.FuncExpr:
+			fsc := c.scopes[x]
+			c.check(x.Stmts, fsc, nil)
+			return false
+		}
+		return true
+	})
+}
+
+func (c *checker) use(id int, name string, p pos.Position, sc *scope) *symbol {
+	if sym, _ := sc.lookup(id); sym != nil {
+		sym.used = true
+		return sym
+	}
+	if _, err := c.env.Get(id); err == nil {
+		return nil
+	}
+	if runtimeNames[c.names.GetLowerCase(id)] {
+		return nil
+	}
+	c.report(p, RuleUndefined, "имя неопределено '%s'", name)
+	return nil
+}
+
+func (c *checker) argCount(x *ast.CallExpr, f *ast.FuncExpr, p pos.Position) {
+	if f.VarArg || x.VarArg {
+		return
+	}
+	if len(x.SubExprs) != len(f.Args) {
+		c.report(p, RuleArgCount, "функция '%s' ожидает аргументов: %d, передано: %d",
+			c.names.Get(x.Name), len(f.Args), len(x.SubExprs))
+	}
+}
+
+// builtinArgCount сверяет количество аргументов при вызове встроенной функции с таблицей builtinArgs
+func (c *checker) builtinArgCount(x *ast.CallExpr, p pos.Position) {
+	if x.VarArg {
+		return
+	}
+	if _, err := c.env.Get(x.Name); err != nil {
+		return
+	}
+	a, ok := builtinArgs[c.names.GetLowerCase(x.Name)]
+	if ok && !a.allows(len(x.SubExprs)) {
+		c.report(p, RuleArgCount, "функция '%s' ожидает аргументов: %s, передано: %d",
+			c.names.Get(x.Name), a, len(x.SubExprs))
+	}
+}
+
+// goLoopVar проверяет, что функция, запускаемая в горутине, не обращается к переменным цикла напрямую:
+// их значения меняются на следующих итерациях, пока горутина исполняется
+func (c *checker) goLoopVar(f *ast.FuncExpr, loops loopCtx, p pos.Position) {
+	if len(loops) == 0 || !c.isAnon(f) {
+		return
+	}
+	fsc := c.scopes[f]
+	captured := map[int]bool{}
+	for _, st := range f.Stmts {
+		forEachExprDeep(st, func(e ast.Expr) {
+			if id, ok := e.(*ast.IdentExpr); ok && loops[id.Id] {
+				if sym, _ := fsc.lookup(id.Id); sym == nil || sym.kind == kindLoopVar {
+					captured[id.Id] = true
+				}
+			}
+		})
+	}
+	ids := make([]int, 0, len(captured))
+	for id := range captured {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		c.report(p, RuleGoLoopVar, "горутина использует переменную цикла '%s', передайте ее значение аргументом", c.names.Get(id))
+	}
+}
+
+// unused сообщает о неиспользуемых переменных и функциях
+func (c *checker) unused() {
+	for _, sc := range c.all {
+		if sc.exported {
+			continue
+		}
+		for _, sym := range sc.syms {
+			if sym.used || strings.HasPrefix(sym.name, "_") {
+				continue
+			}
+			switch sym.kind {
+			case kindVar:
+				c.report(sym.pos, RuleUnusedVar, "переменная '%s' не используется", sym.name)
+			case kindFunc:
+				c.report(sym.pos, RuleUnusedFunc, "функция '%s' не используется", sym.name)
+			}
+		}
+	}
+}
+
+// assignment возвращает левую и правую части, если оператор является присваиванием
+func assignment(s *ast.ExprStmt) (lhss, rhss []ast.Expr, ok bool) {
+	if b, ok := s.Expr.(*ast.BinOpExpr); ok && b.Operator == "==" {
+		return b.Lhss, b.Rhss, true
+	}
+	return nil, nil, false
+}
+
+func terminates(st ast.Stmt) bool {
+	switch st.(type) {
+	case *ast.ReturnStmt, *ast.ThrowStmt, *ast.BreakStmt, *ast.ContinueStmt:
+		return true
+	}
+	return false
+}
+
+// posOf возвращает позицию узла, а если она не известна - позицию оператора
+func posOf(n pos.Pos, st ast.Stmt) pos.Position {
+	if p := n.Position(); p.Line > 0 {
+		return p
+	}
+	return st.Position()
+}
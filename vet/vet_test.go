@@ -0,0 +1,144 @@
+package vet
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/covrom/gonec/core"
+)
+
+const sample = `функция сумма(а, б)
+	возврат а + б
+	сообщить("никогда")
+конецфункции
+
+функция лишняя()
+конецфункции
+
+х = сумма(1)
+неисп = 5
+сообщить(х, опечатка)
+для н = 1 по 10 цикл
+	н = н + 1
+	старт функция()
+		сообщить(н)
+	конецфункции()
+	ф = функция()
+		сообщить(н)
+	конецфункции
+	старт ф()
+	старт функция(з)
+		сообщить(з)
+	конецфункции(н)
+	старт сумма(н, н)
+конеццикла
+сообщить(нету) // vet:ignore undefined
+# vet:ignore
+сообщить(нету2)
+сообщить(нету3) // vet:ignore unused-var
+попытка
+	вызватьисключение "ошибка"
+исключение
+	сообщить(ОписаниеОшибки())
+конецпопытки
+сообщить(СтрДлина("а", "б"), Сред())
+сообщить(Формат("%d"), Макс(1, 2), Сред("абв", 1))
+`
+
+func TestCheck(t *testing.T) {
+	diags, err := Check(sample, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"3:2: недостижимый код [unreachable]",
+		"6:1: функция 'лишняя' не используется [unused-func]",
+		"9:5: функция 'сумма' ожидает аргументов: 2, передано: 1 [arg-count]",
+		"10:1: переменная 'неисп' не используется [unused-var]",
+		"11:1: имя неопределено 'опечатка' [undefined]",
+		"13:2: присваивание переменной цикла 'н' [loop-assign]",
+		"14:2: горутина использует переменную цикла 'н', передайте ее значение аргументом [go-loop-var]",
+		"20:8: горутина использует переменную цикла 'н', передайте ее значение аргументом [go-loop-var]",
+		"29:10: имя неопределено 'нету3' [undefined]",
+		"35:10: функция 'стрдлина' ожидает аргументов: 1, передано: 2 [arg-count]",
+		"35:30: функция 'сред' ожидает аргументов: 2 или 3, передано: 0 [arg-count]",
+		"36:10: функция 'формат' ожидает аргументов: не меньше 2, передано: 1 [arg-count]",
+	}
+	if len(diags) != len(want) {
+		for _, d := range diags {
+			t.Log(d)
+		}
+		t.Fatalf("получено замечаний: %d, ожидалось: %d", len(diags), len(want))
+	}
+	for i, d := range diags {
+		if d.String() != want[i] {
+			t.Errorf("получено %q, ожидалось %q", d, want[i])
+		}
+	}
+}
+
+func TestCheckSyntaxError(t *testing.T) {
+	if _, err := Check("а = (", nil); err == nil {
+		t.Fatal("ожидалась ошибка разбора")
+	}
+}
+
+func TestCheckClean(t *testing.T) {
+	files, err := filepath.Glob("../test/*.gnc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("не найдены исходные тексты в каталоге test")
+	}
+	for _, fn := range files {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		diags, err := Check(string(b), nil)
+		if err != nil {
+			t.Fatalf("%s: %v", fn, err)
+		}
+		for _, d := range diags {
+			t.Errorf("%s:%s", fn, d)
+		}
+	}
+}
+
+// TestBuiltinArgs сверяет таблицу builtinArgs со стандартной библиотекой:
+// каждая функция существует и отказывается от недопустимого количества аргументов
+func TestBuiltinArgs(t *testing.T) {
+	env := core.NewEnv()
+	core.LoadAllBuiltins(env)
+	for name, a := range builtinArgs {
+		v, err := env.Get(env.Names().Set(name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		f, ok := v.(core.VMFunc)
+		if !ok {
+			t.Errorf("%s не является функцией", name)
+			continue
+		}
+		var wrong []int
+		if min := a.counts[0]; min > 0 {
+			wrong = append(wrong, min-1)
+		}
+		if !a.more {
+			wrong = append(wrong, a.counts[len(a.counts)-1]+1)
+		}
+		for _, n := range wrong {
+			if a.allows(n) {
+				t.Fatalf("%s: %d аргументов не должно быть допустимо", name, n)
+			}
+			var rets core.VMSlice
+			envout := env
+			if err := f(make(core.VMSlice, n), &rets, &envout); err == nil {
+				t.Errorf("%s: вызов с %d аргументами не вернул ошибку", name, n)
+			}
+		}
+	}
+}
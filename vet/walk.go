@@ -0,0 +1,166 @@
+package vet
+
+import (
+	"github.com/covrom/gonec/ast"
+)
+
+// walkExpr обходит выражение в прямом порядке, вложенные выражения не обходятся, если f вернула false.
+// Тела функций не обходятся, их обрабатывает f
+func walkExpr(e ast.Expr, f func(ast.Expr) bool) {
+	if e == nil || !f(e) {
+		return
+	}
+	for _, ch := range children(e) {
+		walkExpr(ch, f)
+	}
+}
+
+func children(e ast.Expr) []ast.Expr {
+	switch x := e.(type) {
+	case *ast.UnaryExpr:
+		return []ast.Expr{x.Expr}
+	case *ast.ParenExpr:
+		return []ast.Expr{x.SubExpr}
+	case *ast.BinOpExpr:
+		return append(append([]ast.Expr{}, x.Lhss...), x.Rhss...)
+	case *ast.AssocExpr:
+		return []ast.Expr{x.Lhs, x.Rhs}
+	case *ast.LetExpr:
+		return []ast.Expr{x.Lhs, x.Rhs}
+	case *ast.TernaryOpExpr:
+		return []ast.Expr{x.Expr, x.Lhs, x.Rhs}
+	case *ast.CallExpr:
+		return x.SubExprs
+	case *ast.AnonCallExpr:
+		return append([]ast.Expr{x.Expr}, x.SubExprs...)
+	case *ast.MemberExpr:
+		return []ast.Expr{x.Expr}
+	case *ast.ItemExpr:
+		return []ast.Expr{x.Value, x.Index}
+	case *ast.SliceExpr:
+		return []ast.Expr{x.Value, x.Begin, x.End}
+	case *ast.ArrayExpr:
+		return x.Exprs
+	case *ast.MapExpr:
+		rv := make([]ast.Expr, 0, len(x.MapExpr))
+		for _, k := range x.Keys {
+			rv = append(rv, x.MapExpr[k])
+		}
+		return rv
+	case *ast.PairExpr:
+		return []ast.Expr{x.Value}
+	case *ast.ChanExpr:
+		return []ast.Expr{x.Lhs, x.Rhs}
+	case *ast.MakeExpr:
+		return []ast.Expr{x.TypeExpr}
+	case *ast.MakeChanExpr:
+		return []ast.Expr{x.SizeExpr}
+	case *ast.MakeArrayExpr:
+		return []ast.Expr{x.LenExpr, x.CapExpr}
+	case *ast.TypeCast:
+		return []ast.Expr{x.TypeExpr, x.CastExpr}
+	}
+	return nil
+}
+
+// forEachExpr вызывает f для выражений самого оператора, без вложенных блоков
+func forEachExpr(st ast.Stmt, f func(ast.Expr)) {
+	switch s := st.(type) {
+	case *ast.ExprStmt:
+		f(s.Expr)
+	case *ast.LetsStmt:
+		for _, e := range s.Lhss {
+			f(e)
+		}
+		for _, e := range s.Rhss {
+			f(e)
+		}
+	case *ast.ReturnStmt:
+		for _, e := range s.Exprs {
+			f(e)
+		}
+	case *ast.ThrowStmt:
+		f(s.Expr)
+	case *ast.IfStmt:
+		f(s.If)
+		for _, ei := range s.ElseIf {
+			f(ei.(*ast.IfStmt).If)
+		}
+	case *ast.ForStmt:
+		f(s.Value)
+	case *ast.NumForStmt:
+		f(s.Expr1)
+		f(s.Expr2)
+	case *ast.LoopStmt:
+		f(s.Expr)
+	case *ast.SwitchStmt:
+		f(s.Expr)
+		for _, cs := range s.Cases {
+			if cs, ok := cs.(*ast.CaseStmt); ok {
+				f(cs.Expr)
+			}
+		}
+	case *ast.SelectStmt:
+		for _, cs := range s.Cases {
+			if cs, ok := cs.(*ast.CaseStmt); ok {
+				f(cs.Expr)
+			}
+		}
+	}
+}
+
+// forEachBlock вызывает f для вложенных в оператор блоков
+func forEachBlock(st ast.Stmt, f func(ast.Stmts)) {
+	switch s := st.(type) {
+	case *ast.IfStmt:
+		f(s.Then)
+		for _, ei := range s.ElseIf {
+			f(ei.(*ast.IfStmt).Then)
+		}
+		f(s.Else)
+	case *ast.ForStmt:
+		f(s.Stmts)
+	case *ast.NumForStmt:
+		f(s.Stmts)
+	case *ast.LoopStmt:
+		f(s.Stmts)
+	case *ast.TryStmt:
+		f(s.Try)
+		f(s.Catch)
+	case *ast.SwitchStmt:
+		forEachCase(s.Cases, f)
+	case *ast.SelectStmt:
+		forEachCase(s.Cases, f)
+	}
+}
+
+func forEachCase(cases ast.Stmts, f func(ast.Stmts)) {
+	for _, cs := range cases {
+		switch c := cs.(type) {
+		case *ast.CaseStmt:
+			f(c.Stmts)
+		case *ast.DefaultStmt:
+			f(c.Stmts)
+		}
+	}
+}
+
+// forEachExprDeep вызывает f для всех выражений оператора, включая вложенные блоки и тела функций
+func forEachExprDeep(st ast.Stmt, f func(ast.Expr)) {
+	forEachExpr(st, func(e ast.Expr) {
+		walkExpr(e, func(e ast.Expr) bool {
+			f(e)
+			if fn, ok := e.(*ast.FuncExpr); ok {
+				for _, st := range fn.Stmts {
+					forEachExprDeep(st, f)
+				}
+			}
+			return true
+		})
+	})
+	forEachBlock(st, func(b ast.Stmts) {
+		for _, st := range b {
+			forEachExprDeep(st, f)
+		}
+	})
+}
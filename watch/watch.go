@@ -0,0 +1,260 @@
+// Package watch - режим наблюдения (gonec -watch): программа перезапускается при изменении ее исходного текста
+// или модулей, подключаемых через ЗагрузитьИВыполнить.
+// Перед перезапуском код компилируется заново, при ошибке компиляции продолжает работать предыдущая версия.
+// Предыдущая версия останавливается: закрываются открытые ей серверы, останавливаются сервисы
+// менеджера сервисов, прерывается исполнение ее кода
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/covrom/gonec/bincode"
+	"github.com/covrom/gonec/bincode/binstmt"
+	"github.com/covrom/gonec/bundle"
+	"github.com/covrom/gonec/core"
+	"github.com/covrom/gonec/parser"
+)
+
+const (
+	// DefaultInterval - период проверки изменений файлов по умолчанию
+	DefaultInterval = 500 * time.Millisecond
+	// DefaultTimeout - время ожидания остановки предыдущей версии по умолчанию
+	DefaultTimeout = 5 * time.Second
+)
+
+// Options - параметры наблюдения
+type Options struct {
+	Interval time.Duration    // период проверки изменений файлов
+	Timeout  time.Duration    // время ожидания завершения запросов к серверам и исполнения кода предыдущей версии
+	NewEnv   func() *core.Env // создает окружение для очередного запуска, nil - core.NewEnv
+	Log      io.Writer        // сообщения о перезапуске и ошибки, nil - не выводятся
+}
+
+// Watcher перезапускает программу при изменении ее файлов
+type Watcher struct {
+	main string
+	opts Options
+
+	mu    sync.Mutex
+	files map[string]stamp // наблюдаемые файлы
+	run   *running         // исполняемая версия, nil - не запущена
+}
+
+type stamp struct {
+	mod  time.Time
+	size int64
+}
+
+// running - запущенная версия программы
+type running struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	env    *core.Env
+	done   chan struct{} // закрывается по завершении исполнения кода верхнего уровня
+}
+
+// New создает наблюдение за программой с главным модулем main
+func New(main string, opts Options) *Watcher {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.NewEnv == nil {
+		opts.NewEnv = core.NewEnv
+	}
+	if opts.Log == nil {
+		opts.Log = ioutil.Discard
+	}
+	return &Watcher{main: main, opts: opts, files: make(map[string]stamp)}
+}
+
+// Run запускает программу и перезапускает ее при изменении файлов, пока не будет отменен ctx.
+// При отмене ctx программа останавливается
+func (w *Watcher) Run(ctx context.Context) error {
+	w.Reload()
+	t := time.NewTicker(w.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			w.Stop()
+			return nil
+		case <-t.C:
+			if changed := w.Changed(); len(changed) > 0 {
+				w.logf("Изменены файлы %v, перезапуск\n", changed)
+				w.Reload()
+			}
+		}
+	}
+}
+
+// Files возвращает наблюдаемые файлы
+func (w *Watcher) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	res := make([]string, 0, len(w.files))
+	for fn := range w.files {
+		res = append(res, fn)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Changed возвращает файлы, измененные с момента предыдущей проверки
+func (w *Watcher) Changed() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var res []string
+	for fn, st := range w.files {
+		cur := statFile(fn)
+		if cur != st {
+			w.files[fn] = cur
+			res = append(res, fn)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Reload компилирует программу и, если ошибок нет, перезапускает ее.
+// При ошибке компиляции она возвращается, а предыдущая версия продолжает работать
+func (w *Watcher) Reload() error {
+	b, err := bundle.Build(w.main, func(warn string) {
+		w.logf("%s\n", warn)
+	})
+	w.watch(b, err == nil)
+	if err != nil {
+		w.mu.Lock()
+		running := w.run != nil
+		w.mu.Unlock()
+		if running {
+			w.logf("Ошибка компиляции, продолжает работать предыдущая версия: %s\n", errorText(err, w.main))
+		} else {
+			w.logf("Ошибка компиляции: %s\n", errorText(err, w.main))
+		}
+		return err
+	}
+
+	w.Stop()
+	bincode.SetEmbedded(b.Modules)
+	w.start(b.Modules[b.Main])
+	return nil
+}
+
+// watch обновляет список наблюдаемых файлов. После успешной компиляции наблюдаются только файлы программы,
+// после ошибки к прежним файлам добавляются файлы, которые удалось определить
+func (w *Watcher) watch(b *bundle.Bundle, replace bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	files := make(map[string]stamp)
+	if !replace {
+		for fn, st := range w.files {
+			files[fn] = st
+		}
+	}
+	// главный модуль наблюдается, даже если он не прочитан
+	names := []string{w.main}
+	if b != nil {
+		names = append(names, b.Files...)
+	}
+	for _, fn := range names {
+		if st, ok := w.files[fn]; ok {
+			files[fn] = st
+		} else {
+			files[fn] = statFile(fn)
+		}
+	}
+	w.files = files
+}
+
+func (w *Watcher) start(code binstmt.BinCode) {
+	if code.File == "" {
+		code.File = w.main
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &running{
+		ctx:    ctx,
+		cancel: cancel,
+		env:    w.opts.NewEnv(),
+		done:   make(chan struct{}),
+	}
+	// контекст остается в окружении после завершения кода верхнего уровня,
+	// его отмена прерывает и обработчики запросов, и горутины программы
+	r.env.SetContext(ctx)
+
+	w.mu.Lock()
+	w.run = r
+	w.mu.Unlock()
+
+	go func() {
+		defer close(r.done)
+		_, err := bincode.Run(code, r.env)
+		if err != nil && ctx.Err() == nil {
+			w.logf("%s\n", errorText(err, w.main))
+		}
+	}()
+}
+
+// Stop останавливает исполняемую версию программы: закрывает открытые ей серверы
+// (с ожиданием завершения текущих запросов), останавливает сервисы менеджера сервисов и прерывает исполнение кода
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	r := w.run
+	w.run = nil
+	w.mu.Unlock()
+	if r == nil {
+		return
+	}
+
+	core.ShutdownServers(r.ctx, w.opts.Timeout)
+	if err := core.VMMainServiceBus.DeregisterAll(); err != nil {
+		w.logf("Остановка сервисов: %v\n", err)
+	}
+	r.cancel()
+	r.env.Interrupt()
+	select {
+	case <-r.done:
+	case <-time.After(w.opts.Timeout):
+		w.logf("Предыдущая версия не завершилась за %v\n", w.opts.Timeout)
+	}
+}
+
+func (w *Watcher) logf(format string, a ...interface{}) {
+	fmt.Fprintf(w.opts.Log, format, a...)
+}
+
+func statFile(fn string) stamp {
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return stamp{size: -1}
+	}
+	return stamp{mod: fi.ModTime(), size: fi.Size()}
+}
+
+// errorText возвращает текст ошибки с файлом и позицией, как при запуске программы из командной строки
+func errorText(err error, main string) string {
+	switch e := err.(type) {
+	case *binstmt.Error:
+		fn := main
+		if e.File != "" {
+			fn = e.File
+		}
+		return fmt.Sprintf("%s:%d:%d %s", fn, e.Pos.Line, e.Pos.Column, err)
+	case *parser.Error:
+		fn := main
+		if e.Filename != "" {
+			fn = e.Filename
+		}
+		return fmt.Sprintf("%s:%d:%d %s", fn, e.Pos.Line, e.Pos.Column, err)
+	}
+	return err.Error()
+}
@@ -0,0 +1,196 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/covrom/gonec/bincode"
+	"github.com/covrom/gonec/core"
+)
+
+// syncBuffer - буфер для вывода из нескольких горутин
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// get выполняет запрос к серверу. Программа запускается асинхронно, поэтому запрос повторяется,
+// пока сервер не откроется (если wait)
+func get(t *testing.T, url string, wait bool) string {
+	resp, err := http.Get(url)
+	for deadline := time.Now().Add(5 * time.Second); err != nil && wait && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		resp, err = http.Get(url)
+	}
+	if err != nil {
+		return "ошибка: " + err.Error()
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return strings.TrimSpace(string(b))
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer bincode.SetEmbedded(nil)
+
+	addr := freeAddr(t)
+	main := filepath.Join(dir, "main.gnc")
+	lib := filepath.Join(dir, "lib.gnc")
+	write := func(fn, src string) {
+		if err := ioutil.WriteFile(fn, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := `ЗагрузитьИВыполнить("` + lib + `")
+Функция Обработать(вых, вх)
+	вых.Отправить({"Статус": 200, "Тело": Версия()})
+КонецФункции
+серв = Новый Сервер
+серв.Открыть("http", "` + addr + `", 100, {"/": Обработать}, 0)
+`
+	write(main, server)
+	write(lib, "Функция Версия()\n\tВозврат \"первая\"\nКонецФункции\n")
+
+	var log syncBuffer
+	w := New(main, Options{Log: &log, Timeout: time.Second})
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	if got := w.Files(); !reflect.DeepEqual(got, []string{lib, main}) {
+		t.Errorf("наблюдаемые файлы %q", got)
+	}
+	url := "http://" + addr + "/"
+	if got := get(t, url, true); got != "первая" {
+		t.Fatalf("ответ %q, вывод %s", got, log.String())
+	}
+
+	// изменение подключаемого модуля - сервер перезапускается с новым кодом на том же адресе
+	write(lib, "Функция Версия()\n\tВозврат \"вторая\"\nКонецФункции\n")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(lib, future, future)
+	if got := w.Changed(); !reflect.DeepEqual(got, []string{lib}) {
+		t.Fatalf("изменены %q", got)
+	}
+	if w.Changed() != nil {
+		t.Error("повторное обнаружение изменений")
+	}
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := get(t, url, true); got != "вторая" {
+		t.Fatalf("ответ после перезапуска %q, вывод %s", got, log.String())
+	}
+
+	// ошибка компиляции не останавливает работающую версию
+	write(lib, "Функция Версия(\n")
+	if err := w.Reload(); err == nil {
+		t.Fatal("нет ошибки компиляции")
+	}
+	if got := get(t, url, false); got != "вторая" {
+		t.Errorf("ответ после ошибки компиляции %q", got)
+	}
+	if !strings.Contains(log.String(), "Ошибка компиляции, продолжает работать предыдущая версия: "+lib+":1:16 syntax error") {
+		t.Errorf("вывод %s", log.String())
+	}
+
+	w.Stop()
+	if got := get(t, url, false); !strings.HasPrefix(got, "ошибка") {
+		t.Errorf("сервер не остановлен: %q", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer bincode.SetEmbedded(nil)
+
+	main := filepath.Join(dir, "main.gnc")
+	if err := ioutil.WriteFile(main, []byte("Пока Истина Цикл\n\tПауза(0.01)\nКонецЦикла\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var log, out syncBuffer
+	w := New(main, Options{
+		Log:      &log,
+		Interval: 10 * time.Millisecond,
+		Timeout:  time.Second,
+		NewEnv: func() *core.Env {
+			env := core.NewEnv()
+			env.SetStdOut(&out)
+			return env
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	// бесконечный цикл прерывается, новая версия запускается после изменения файла
+	time.Sleep(50 * time.Millisecond)
+	if err := ioutil.WriteFile(main, []byte("Сообщить(\"новая\")\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(main, future, future)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if out.String() == "новая\n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("новая версия не запущена, вывод %s", log.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("наблюдение не завершено")
+	}
+	if strings.Contains(log.String(), "не завершилась") {
+		t.Errorf("предыдущая версия не прервана: %s", log.String())
+	}
+}